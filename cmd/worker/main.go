@@ -0,0 +1,14 @@
+package main
+
+import (
+	"context"
+
+	"github.com/atrian/go-notify-customer/internal/worker"
+)
+
+func main() {
+	ctx := context.Background()
+
+	application := worker.New()
+	application.Run(ctx)
+}
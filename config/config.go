@@ -2,19 +2,71 @@ package config
 
 import (
 	"flag"
+	"fmt"
+	"time"
 
 	"github.com/caarlos0/env/v6"
 
+	"github.com/atrian/go-notify-customer/internal/dto"
 	"github.com/atrian/go-notify-customer/internal/interfaces"
 )
 
 var (
-	_ senderConfig   = (*Config)(nil)
-	_ grpcConfig     = (*Config)(nil)
-	_ webConfig      = (*Config)(nil)
-	_ securityConfig = (*Config)(nil)
+	_ senderConfig      = (*Config)(nil)
+	_ grpcConfig        = (*Config)(nil)
+	_ webConfig         = (*Config)(nil)
+	_ securityConfig    = (*Config)(nil)
+	_ databaseConfig    = (*Config)(nil)
+	_ schedulerConfig   = (*Config)(nil)
+	_ deadLetterConfig  = (*Config)(nil)
+	_ idempotencyConfig = (*Config)(nil)
+	_ dispatcherConfig  = (*Config)(nil)
+	_ journalConfig     = (*Config)(nil)
+	_ queueConfig       = (*Config)(nil)
+	_ busConfig         = (*Config)(nil)
+	_ workerConfig      = (*Config)(nil)
 )
 
+type busConfig interface {
+	GetBus() string
+	GetAmpqDSN() string
+	GetNatsURL() string
+	GetNatsStream() string
+}
+
+type databaseConfig interface {
+	GetDatabaseDSN() string
+}
+
+type schedulerConfig interface {
+	GetSchedulerInterval() time.Duration
+	GetSchedulerBatchSize() int
+}
+
+type deadLetterConfig interface {
+	GetTopology() dto.Topology
+	GetDeadLetterQueue() string
+}
+
+type dispatcherConfig interface {
+	GetTopology() dto.Topology
+	GetFailedWorksQueue() string
+	GetCriticalPriority() uint
+}
+
+type journalConfig interface {
+	GetIngestJournalPath() string
+}
+
+type queueConfig interface {
+	GetQueuePolicy() string
+	GetQueueAgingRate() float64
+}
+
+type idempotencyConfig interface {
+	GetIdempotencyTTL() time.Duration
+}
+
 type webConfig interface {
 	GetHttpServerAddress() string
 }
@@ -23,16 +75,36 @@ type securityConfig interface {
 	GetTrustedSubnetAddress() string
 }
 
+type workerConfig interface {
+	GetWorkerChannels() []string
+	IsEmbeddedWorkers() bool
+	GetStatQueue() string
+}
+
 type grpcConfig interface {
 	GetGRPCAddress() string
 }
 
 type senderConfig interface {
-	GetAmpqDSN() string
-	GetNotificationQueue() string
+	GetTopology() dto.Topology
 	GetFailedWorksQueue() string
+	GetDeadLetterQueue() string
+	GetWorkerConcurrency() int
+	GetWorkerPrefetch() int
+	GetWorkerDrainTimeout() time.Duration
+	retryConfig
 	mailConfig
 	twilioConfig
+	webhookConfig
+	telegramConfig
+	slackConfig
+}
+
+type retryConfig interface {
+	GetRetryMaxAttempts() int
+	GetRetryBaseDelay() time.Duration
+	GetRetryMaxDelay() time.Duration
+	GetRetryJitter() float64
 }
 
 type mailConfig interface {
@@ -44,6 +116,21 @@ type mailConfig interface {
 	IsMailTLSRequired() bool
 }
 
+type webhookConfig interface {
+	GetWebhookSecret() string
+	GetWebhookTimeout() time.Duration
+}
+
+type telegramConfig interface {
+	GetTelegramBotToken() string
+	GetTelegramBaseURL() string
+}
+
+type slackConfig interface {
+	GetSlackBotToken() string
+	GetSlackBaseURL() string
+}
+
 type twilioConfig interface {
 	GetTwilioAccountSid() string
 	GetTwilioAuthToken() string
@@ -60,8 +147,10 @@ type Params struct {
 	HttpTrustedSubnet       string `env:"NC_TRUSTED_SUBNET"`
 	GrpcVaultAddress        string `env:"NC_GRPC_VAULT_ADDRESS"`
 	AmpqDSN                 string `env:"NC_AMPQDSN"`
-	NotificationQueue       string `env:"NC_DISPATCH_QUEUE" envDefault:"planned_notifications"`
+	DatabaseDSN             string `env:"NC_DATABASE_DSN"` // пустое значение - in-memory хранилища
+	NotificationExchange    string `env:"NC_DISPATCH_EXCHANGE" envDefault:"notify"`
 	FailedWorksQueue        string `env:"NC_FAILED_QUEUE" envDefault:"failed_notifications"`
+	DeadLetterQueue         string `env:"NC_DEAD_LETTER_QUEUE" envDefault:"dead_notifications"`
 	MailSenderAddress       string `env:"NC_MAIL_SENDER_ADDRESS"`
 	MailSMTPHost            string `env:"NC_MAIL_SMTP_HOST"`
 	MailTLSRequired         bool   `env:"NC_MAIL_TLS_REQUIRED"`
@@ -71,6 +160,72 @@ type Params struct {
 	TwilioAccountSid        string `env:"NC_TWILIO_ACCOUNT_ID"`
 	TwilioAuthToken         string `env:"NC_TWILIO_ACCOUNT_ID"`
 	TwilioSenderPhone       string `env:"NC_TWILIO_SENDER_PHONE"`
+	// WebhookSecret секрет подписи HMAC-SHA256 запросов webhook, без него канал webhook не отправляет сообщения
+	WebhookSecret  string        `env:"NC_WEBHOOK_SECRET"`
+	WebhookTimeout time.Duration `env:"NC_WEBHOOK_TIMEOUT" envDefault:"10s"`
+	// BaseURL мессенджеров переопределяются для прокси или тестовых серверов
+	TelegramBotToken string `env:"NC_TELEGRAM_BOT_TOKEN"`
+	TelegramBaseURL  string `env:"NC_TELEGRAM_BASE_URL" envDefault:"https://api.telegram.org"`
+	SlackBotToken    string `env:"NC_SLACK_BOT_TOKEN"`
+	SlackBaseURL     string `env:"NC_SLACK_BASE_URL" envDefault:"https://slack.com/api"`
+	// RateLimitMode reject - отклонять уведомления сверх лимита, delay - ждать освобождения лимита
+	RateLimitMode     string        `env:"NC_RATE_LIMIT_MODE" envDefault:"reject"`
+	RateLimitMaxDelay time.Duration `env:"NC_RATE_LIMIT_MAX_DELAY" envDefault:"5s"`
+	// Лимиты в сообщениях в секунду, 0 - без ограничений. Burst - емкость корзины
+	RateLimitGlobal      float64 `env:"NC_RATE_LIMIT_GLOBAL"`
+	RateLimitGlobalBurst int     `env:"NC_RATE_LIMIT_GLOBAL_BURST"`
+	RateLimitPerson      float64 `env:"NC_RATE_LIMIT_PERSON"`
+	RateLimitPersonBurst int     `env:"NC_RATE_LIMIT_PERSON_BURST"`
+	// RateLimitChannels лимиты каналов в формате channel:rate[:burst], пример sms:10:20,mail:50
+	RateLimitChannels []string `env:"NC_RATE_LIMIT_CHANNELS" envSeparator:","`
+	// SchedulerInterval периодичность проверки наступивших отложенных уведомлений
+	SchedulerInterval  time.Duration `env:"NC_SCHEDULER_INTERVAL" envDefault:"1s"`
+	SchedulerBatchSize int           `env:"NC_SCHEDULER_BATCH_SIZE" envDefault:"100"`
+	// RetryMaxAttempts количество попыток отправки сообщения, после которого оно помещается в очередь недоставленных
+	RetryMaxAttempts int `env:"NC_RETRY_MAX_ATTEMPTS" envDefault:"5"`
+	// RetryBaseDelay задержка перед второй попыткой, каждая следующая задержка удваивается, но не больше RetryMaxDelay
+	RetryBaseDelay time.Duration `env:"NC_RETRY_BASE_DELAY" envDefault:"1s"`
+	RetryMaxDelay  time.Duration `env:"NC_RETRY_MAX_DELAY" envDefault:"5m"`
+	// RetryJitter доля случайного разброса задержки, от 0 до 1
+	RetryJitter float64 `env:"NC_RETRY_JITTER" envDefault:"0.2"`
+	// WorkerConcurrency количество одновременных отправок на очередь канала
+	WorkerConcurrency int `env:"NC_WORKER_CONCURRENCY" envDefault:"4"`
+	// WorkerPrefetch количество неподтвержденных сообщений, выдаваемых брокером потребителю очереди канала, 0 - без ограничения
+	WorkerPrefetch int `env:"NC_WORKER_PREFETCH" envDefault:"8"`
+	// WorkerChannels каналы, очереди которых потребляют воркеры, пустое значение - все каналы NC_DISPATCH_CHANNELS
+	WorkerChannels []string `env:"NC_WORKER_CHANNELS" envSeparator:","`
+	// WorkerDrainTimeout время на завершение начатых отправок при остановке воркера
+	WorkerDrainTimeout time.Duration `env:"NC_WORKER_DRAIN_TIMEOUT" envDefault:"30s"`
+	// EmbeddedWorkers воркеры отправки запускаются в процессе API, false - отправку выполняют отдельные процессы cmd/worker
+	EmbeddedWorkers bool `env:"NC_EMBEDDED_WORKERS" envDefault:"true"`
+	// StatQueue очередь результатов, в которую воркеры публикуют статистику отправки для сервиса статистики
+	StatQueue string `env:"NC_STAT_QUEUE" envDefault:"notify_stats"`
+	// Bus шина сообщений: rabbitmq, nats - NATS JetStream или memory - в памяти процесса для запуска одним бинарником
+	Bus     string `env:"NC_BUS" envDefault:"rabbitmq"`
+	NatsURL string `env:"NC_NATS_URL" envDefault:"nats://localhost:4222"`
+	// NatsStream stream JetStream, в котором хранятся очереди шины
+	NatsStream string `env:"NC_NATS_STREAM" envDefault:"NOTIFY"`
+	// IdempotencyTTL окно в течение которого повторный ключ идемпотентности возвращает исходные уведомления
+	IdempotencyTTL time.Duration `env:"NC_IDEMPOTENCY_TTL" envDefault:"24h"`
+	// IngestJournalPath файл журнала принятых уведомлений, пустое значение - журнал в памяти, уведомления не переживают перезапуск.
+	// Относительный путь по умолчанию разрешается от рабочего каталога процесса: для запуска в контейнере
+	// или под systemd задается абсолютный путь на постоянном томе, иначе журнал не переживет пересоздание
+	IngestJournalPath string `env:"NC_INGEST_JOURNAL_PATH" envDefault:"data/ingest.journal"`
+	// CriticalPriority уведомления с приоритетом от порога и выше отправляются вне окон доставки, 0 - окна соблюдаются всегда
+	CriticalPriority uint `env:"NC_CRITICAL_PRIORITY" envDefault:"1000"`
+	// QueuePolicy порядок передачи принятых уведомлений диспетчеру: strict (по умолчанию) - строго по приоритету,
+	// aging - приоритет растет с ожиданием, включается явно
+	QueuePolicy string `env:"NC_QUEUE_POLICY" envDefault:"strict"`
+	// QueueAgingRate прирост приоритета за секунду ожидания в политике aging
+	QueueAgingRate float64 `env:"NC_QUEUE_AGING_RATE" envDefault:"1"`
+	// DeprecatedNotificationQueue прежняя общая очередь отправки, заменена очередями каналов NC_DISPATCH_EXCHANGE.channel
+	DeprecatedNotificationQueue string `env:"NC_DISPATCH_QUEUE"`
+	// DispatchChannels каналы доставки, для каждого в шине создается очередь NC_DISPATCH_EXCHANGE.channel
+	DispatchChannels []string `env:"NC_DISPATCH_CHANNELS" envSeparator:"," envDefault:"sms,mail,webhook,telegram,slack"`
+	// DispatchMaxPriority максимальный приоритет очередей каналов, приоритет CriticalPriority и выше соответствует максимальному
+	DispatchMaxPriority uint8 `env:"NC_DISPATCH_MAX_PRIORITY" envDefault:"10"`
+	// DispatchMessageTTL время жизни сообщения в очереди канала, 0 - без ограничения. Просроченные сообщения уходят в очередь недоставленных
+	DispatchMessageTTL time.Duration `env:"NC_DISPATCH_MESSAGE_TTL" envDefault:"24h"`
 }
 
 func (config *Config) GetDefaultResponseContentType() string {
@@ -92,10 +247,26 @@ func NewConfig(logger interfaces.Logger) Config {
 	return conf
 }
 
+func (config *Config) GetBus() string {
+	return config.data.Bus
+}
+
 func (config *Config) GetAmpqDSN() string {
 	return config.data.AmpqDSN
 }
 
+func (config *Config) GetNatsURL() string {
+	return config.data.NatsURL
+}
+
+func (config *Config) GetNatsStream() string {
+	return config.data.NatsStream
+}
+
+func (config *Config) GetDatabaseDSN() string {
+	return config.data.DatabaseDSN
+}
+
 func (config *Config) GetHttpServerAddress() string {
 	return config.data.HttpAddress
 }
@@ -104,14 +275,25 @@ func (config *Config) GetGRPCAddress() string {
 	return config.data.GrpcVaultAddress
 }
 
-func (config *Config) GetNotificationQueue() string {
-	return config.data.NotificationQueue
+// GetTopology топология шины сообщений на отправку
+func (config *Config) GetTopology() dto.Topology {
+	return dto.Topology{
+		Exchange:        config.data.NotificationExchange,
+		Channels:        config.data.DispatchChannels,
+		MaxPriority:     config.data.DispatchMaxPriority,
+		MessageTTL:      config.data.DispatchMessageTTL,
+		DeadLetterQueue: config.data.DeadLetterQueue,
+	}
 }
 
 func (config *Config) GetFailedWorksQueue() string {
 	return config.data.FailedWorksQueue
 }
 
+func (config *Config) GetDeadLetterQueue() string {
+	return config.data.DeadLetterQueue
+}
+
 func (config *Config) GetMailSenderAddress() string {
 	return config.data.MailSenderAddress
 }
@@ -148,6 +330,126 @@ func (config *Config) GetTwilioSenderPhone() string {
 	return config.data.TwilioSenderPhone
 }
 
+func (config *Config) GetWebhookSecret() string {
+	return config.data.WebhookSecret
+}
+
+func (config *Config) GetWebhookTimeout() time.Duration {
+	return config.data.WebhookTimeout
+}
+
+func (config *Config) GetTelegramBotToken() string {
+	return config.data.TelegramBotToken
+}
+
+func (config *Config) GetTelegramBaseURL() string {
+	return config.data.TelegramBaseURL
+}
+
+func (config *Config) GetSlackBotToken() string {
+	return config.data.SlackBotToken
+}
+
+func (config *Config) GetSlackBaseURL() string {
+	return config.data.SlackBaseURL
+}
+
+func (config *Config) GetRateLimitMode() string {
+	return config.data.RateLimitMode
+}
+
+func (config *Config) GetRateLimitMaxDelay() time.Duration {
+	return config.data.RateLimitMaxDelay
+}
+
+func (config *Config) GetGlobalRateLimit() float64 {
+	return config.data.RateLimitGlobal
+}
+
+func (config *Config) GetGlobalRateBurst() int {
+	return config.data.RateLimitGlobalBurst
+}
+
+func (config *Config) GetPersonRateLimit() float64 {
+	return config.data.RateLimitPerson
+}
+
+func (config *Config) GetPersonRateBurst() int {
+	return config.data.RateLimitPersonBurst
+}
+
+func (config *Config) GetChannelRateLimits() []string {
+	return config.data.RateLimitChannels
+}
+
+func (config *Config) GetSchedulerInterval() time.Duration {
+	return config.data.SchedulerInterval
+}
+
+func (config *Config) GetSchedulerBatchSize() int {
+	return config.data.SchedulerBatchSize
+}
+
+func (config *Config) GetRetryMaxAttempts() int {
+	return config.data.RetryMaxAttempts
+}
+
+func (config *Config) GetRetryBaseDelay() time.Duration {
+	return config.data.RetryBaseDelay
+}
+
+func (config *Config) GetRetryMaxDelay() time.Duration {
+	return config.data.RetryMaxDelay
+}
+
+func (config *Config) GetRetryJitter() float64 {
+	return config.data.RetryJitter
+}
+
+func (config *Config) GetWorkerConcurrency() int {
+	return config.data.WorkerConcurrency
+}
+
+func (config *Config) GetWorkerPrefetch() int {
+	return config.data.WorkerPrefetch
+}
+
+func (config *Config) GetWorkerChannels() []string {
+	return config.data.WorkerChannels
+}
+
+func (config *Config) GetWorkerDrainTimeout() time.Duration {
+	return config.data.WorkerDrainTimeout
+}
+
+func (config *Config) IsEmbeddedWorkers() bool {
+	return config.data.EmbeddedWorkers
+}
+
+func (config *Config) GetStatQueue() string {
+	return config.data.StatQueue
+}
+
+func (config *Config) GetIdempotencyTTL() time.Duration {
+	return config.data.IdempotencyTTL
+}
+
+func (config *Config) GetIngestJournalPath() string {
+	return config.data.IngestJournalPath
+}
+
+func (config *Config) GetCriticalPriority() uint {
+	return config.data.CriticalPriority
+}
+
+func (config *Config) GetQueuePolicy() string {
+	return config.data.QueuePolicy
+}
+
+func (config *Config) GetQueueAgingRate() float64 {
+	return config.data.QueueAgingRate
+}
+
 // loadFlags загрузка в конфигурацию флагов запуска приложения
 func (config *Config) loadFlags() {
 	httpAddress := flag.String("a", "127.0.0.1:8080", "Address and port used for GO-notify-customer app webserver.")
@@ -162,6 +464,14 @@ func (config *Config) loadFlags() {
 	config.log.Debug("Flag params processed")
 }
 
+// warnDeprecated предупреждение о заданных устаревших переменных окружения, которые больше не используются
+func (config *Config) warnDeprecated() {
+	if config.data.DeprecatedNotificationQueue != "" {
+		config.log.Warning(fmt.Sprintf("NC_DISPATCH_QUEUE=%v is deprecated and ignored: messages are routed through exchange %v to queues %v",
+			config.data.DeprecatedNotificationQueue, config.data.NotificationExchange, config.GetTopology().Queues()))
+	}
+}
+
 // loadEnv загрузка в конфигурацию данных из переменных окружения
 func (config *Config) loadEnv() {
 	params := Params{}
@@ -172,5 +482,6 @@ func (config *Config) loadEnv() {
 	}
 
 	config.data = params
+	config.warnDeprecated()
 	config.log.Debug("Env params processed")
 }
@@ -16,6 +16,173 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/v1/dead-letters": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DeadLetters"
+                ],
+                "summary": "Запрос сообщений исчерпавших попытки отправки",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.Message"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DeadLetters"
+                ],
+                "summary": "Удаление всех недоставленных сообщений",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeadLettersResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/v1/dead-letters/replay": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DeadLetters"
+                ],
+                "summary": "Повторная отправка всех недоставленных сообщений",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeadLettersResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/v1/dead-letters/{message_uuid}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DeadLetters"
+                ],
+                "summary": "Запрос недоставленного сообщения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сообщения в формате UUID v4",
+                        "name": "message_uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "DeadLetters"
+                ],
+                "summary": "Удаление недоставленного сообщения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сообщения в формате UUID v4",
+                        "name": "message_uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/v1/dead-letters/{message_uuid}/replay": {
+            "post": {
+                "tags": [
+                    "DeadLetters"
+                ],
+                "summary": "Повторная отправка недоставленного сообщения со сбросом счетчика попыток",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сообщения в формате UUID v4",
+                        "name": "message_uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
         "/api/v1/events": {
             "get": {
                 "produces": [
@@ -72,6 +239,9 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request"
                     },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
@@ -156,6 +326,9 @@ const docTemplate = `{
                     "404": {
                         "description": "Not Found"
                     },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
@@ -208,7 +381,13 @@ const docTemplate = `{
                 "summary": "отправка уведомлений",
                 "parameters": [
                     {
-                        "description": "Принимает JSON dto уведомлений, возвращает код 200 при успешной постановке, 429 при привышении лимита",
+                        "type": "string",
+                        "description": "Ключ идемпотентности запроса, повтор в течение окна возвращает исходные уведомления",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Принимает JSON dto уведомлений, возвращает код 202 при успешной постановке, 429 при привышении лимита",
                         "name": "notification",
                         "in": "body",
                         "required": true,
@@ -221,12 +400,27 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK"
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.NotificationReceipt"
+                            }
+                        }
                     },
                     "400": {
                         "description": "Bad Request"
                     },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.MissingParams"
+                            }
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests"
                     },
@@ -236,47 +430,41 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/notifications/seed": {
+        "/api/v1/notifications/queue": {
             "get": {
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Notifications"
                 ],
-                "summary": "Создает бизнес событие и шаблон к нему, возвращает подготовленный JSON для запроса POST /api/v1/notifications",
+                "summary": "Метрики очереди уведомлений и времени ожидания по диапазонам приоритета",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dto.IncomingNotification"
+                            "$ref": "#/definitions/dto.QueueStats"
                         }
-                    },
-                    "500": {
-                        "description": "Internal Server Error"
                     }
                 }
             }
         },
-        "/api/v1/stats": {
+        "/api/v1/notifications/scheduled": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Stat"
+                    "Notifications"
                 ],
-                "summary": "Запрос всей статистики",
+                "summary": "Запрос ожидающих отправки отложенных уведомлений",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/dto.Stat"
+                                "$ref": "#/definitions/dto.Notification"
                             }
                         }
                     },
@@ -286,15 +474,15 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/stats/notification/{notification_uuid}": {
+        "/api/v1/notifications/scheduled/{notification_uuid}": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Stat"
+                    "Notifications"
                 ],
-                "summary": "Запрос статистики отправок по уведомлению",
+                "summary": "Запрос отложенного уведомления",
                 "parameters": [
                     {
                         "type": "string",
@@ -308,10 +496,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/dto.Stat"
-                            }
+                            "$ref": "#/definitions/dto.Notification"
                         }
                     },
                     "400": {
@@ -324,35 +509,27 @@ const docTemplate = `{
                         "description": "Internal Server Error"
                     }
                 }
-            }
-        },
-        "/api/v1/stats/person/{person_uuid}": {
-            "get": {
+            },
+            "delete": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Stat"
+                    "Notifications"
                 ],
-                "summary": "Запрос статистики отправок по пользователю",
+                "summary": "Отмена отложенного уведомления. Уже отправленное уведомление отменить нельзя",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID пользователя в формате UUID v4",
-                        "name": "person_uuid",
+                        "description": "ID уведомления в формате UUID v4",
+                        "name": "notification_uuid",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/dto.Stat"
-                            }
-                        }
+                        "description": "OK"
                     },
                     "400": {
                         "description": "Bad Request"
@@ -366,82 +543,82 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/templates": {
+        "/api/v1/notifications/seed": {
             "get": {
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Template"
+                    "Notifications"
                 ],
-                "summary": "Запрос деталей шаблона сообщения",
+                "summary": "Создает бизнес событие и шаблон к нему, возвращает подготовленный JSON для запроса POST /api/v1/notifications",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/dto.Template"
-                            }
+                            "$ref": "#/definitions/dto.IncomingNotification"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error"
                     }
                 }
-            },
-            "post": {
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/api/v1/notifications/{notification_uuid}": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Template"
+                    "Notifications"
                 ],
-                "summary": "сохранение шаблона сообщения",
+                "summary": "Сводное состояние уведомления по получателям и каналам",
                 "parameters": [
                     {
-                        "description": "Принимает dto нового шаблона сообщения, возвращает JSON сохраненными данными и идентификатором",
-                        "name": "template",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.IncomingTemplate"
-                        }
+                        "type": "string",
+                        "description": "ID уведомления в формате UUID v4",
+                        "name": "notification_uuid",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dto.Template"
+                            "$ref": "#/definitions/dto.NotificationStatus"
                         }
                     },
                     "400": {
                         "description": "Bad Request"
                     },
+                    "404": {
+                        "description": "Not Found"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
                 }
             }
         },
-        "/api/v1/templates/{template_uuid}": {
+        "/api/v1/persons/{person_uuid}/preferences": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Template"
+                    "Preferences"
                 ],
-                "summary": "Запрос деталей шаблона сообщения",
+                "summary": "Запрос предпочтений получателя по категориям событий и каналам",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID шаблона в формате UUID v4",
-                        "name": "template_uuid",
+                        "description": "ID получателя в формате UUID v4",
+                        "name": "person_uuid",
                         "in": "path",
                         "required": true
                     }
@@ -450,15 +627,15 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dto.Template"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.Preference"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request"
                     },
-                    "404": {
-                        "description": "Not Found"
-                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
@@ -472,24 +649,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Template"
+                    "Preferences"
                 ],
-                "summary": "обновление шаблона сообщения",
+                "summary": "Сохранение предпочтения получателя: opt_in, opt_out или quiet",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID шаблона сообщения в формате UUID v4",
-                        "name": "template_uuid",
+                        "description": "ID получателя в формате UUID v4",
+                        "name": "person_uuid",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Принимает dto шаблона сообщения, возвращает JSON с обновленными данными",
-                        "name": "template",
+                        "description": "Правило для категории событий и канала, пустые значения - все",
+                        "name": "preference",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.IncomingTemplate"
+                            "$ref": "#/definitions/dto.IncomingPreference"
                         }
                     }
                 ],
@@ -497,7 +674,153 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dto.Template"
+                            "$ref": "#/definitions/dto.Preference"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "Preferences"
+                ],
+                "summary": "Удаление предпочтения получателя для категории событий и канала",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID получателя в формате UUID v4",
+                        "name": "person_uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Категория события, пустое значение - правило для всех категорий",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Канал отправки, пустое значение - правило для всех каналов",
+                        "name": "channel",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/v1/stats": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stat"
+                ],
+                "summary": "Запрос всей статистики",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.Stat"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/v1/stats/notification/{notification_uuid}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stat"
+                ],
+                "summary": "Запрос статистики отправок по уведомлению",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID уведомления в формате UUID v4",
+                        "name": "notification_uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.Stat"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/v1/stats/person/{person_uuid}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stat"
+                ],
+                "summary": "Запрос статистики отправок по пользователю",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя в формате UUID v4",
+                        "name": "person_uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.Stat"
+                            }
                         }
                     },
                     "400": {
@@ -510,15 +833,82 @@ const docTemplate = `{
                         "description": "Internal Server Error"
                     }
                 }
+            }
+        },
+        "/api/v1/templates": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Template"
+                ],
+                "summary": "Запрос деталей шаблона сообщения",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.Template"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
             },
-            "delete": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Template"
                 ],
-                "summary": "удаление шаблона сообщения",
+                "summary": "сохранение шаблона сообщения",
+                "parameters": [
+                    {
+                        "description": "Принимает dto нового шаблона сообщения, возвращает JSON сохраненными данными и идентификатором",
+                        "name": "template",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.IncomingTemplate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Template"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/v1/templates/{template_uuid}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Template"
+                ],
+                "summary": "Запрос деталей шаблона сообщения",
                 "parameters": [
                     {
                         "type": "string",
@@ -530,7 +920,10 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Template"
+                        }
                     },
                     "400": {
                         "description": "Bad Request"
@@ -542,69 +935,747 @@ const docTemplate = `{
                         "description": "Internal Server Error"
                     }
                 }
+            },
+            "put": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Template"
+                ],
+                "summary": "обновление шаблона сообщения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID шаблона сообщения в формате UUID v4",
+                        "name": "template_uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Принимает dto шаблона сообщения, возвращает JSON с обновленными данными",
+                        "name": "template",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.IncomingTemplate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TemplateVersion"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Template"
+                ],
+                "summary": "удаление шаблона сообщения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID шаблона в формате UUID v4",
+                        "name": "template_uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/v1/templates/{template_uuid}/render": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Template"
+                ],
+                "summary": "предпросмотр шаблона сообщения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID шаблона в формате UUID v4",
+                        "name": "template_uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер версии, по умолчанию опубликованная",
+                        "name": "version",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Параметры подстановки в шаблон",
+                        "name": "params",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.IncomingRender"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TemplateRender"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/v1/templates/{template_uuid}/versions": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Template"
+                ],
+                "summary": "история версий шаблона сообщения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID шаблона в формате UUID v4",
+                        "name": "template_uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.TemplateVersion"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/v1/templates/{template_uuid}/versions/diff": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Template"
+                ],
+                "summary": "сравнение двух версий шаблона сообщения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID шаблона в формате UUID v4",
+                        "name": "template_uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер исходной версии",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер сравниваемой версии",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TemplateDiff"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/v1/templates/{template_uuid}/versions/{version}/publish": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Template"
+                ],
+                "summary": "публикация версии шаблона сообщения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID шаблона в формате UUID v4",
+                        "name": "template_uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер версии",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Template"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/v1/templates/{template_uuid}/versions/{version}/rollback": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Template"
+                ],
+                "summary": "откат шаблона сообщения к ранее сохраненной версии",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID шаблона в формате UUID v4",
+                        "name": "template_uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер версии",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Template"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dto.DeliveryWindow": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "description": "Days дни недели mon..sun, weekdays или weekends, пустой список - каждый день",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "from": {
+                    "description": "From начало окна HH:MM",
+                    "type": "string"
+                },
+                "to": {
+                    "description": "To окончание окна HH:MM, не включается в окно",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.DiffLine": {
+            "type": "object",
+            "properties": {
+                "op": {
+                    "description": "Op операция",
+                    "type": "string"
+                },
+                "text": {
+                    "description": "Text текст строки",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.Event": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "description": "Category категория события для предпочтений получателей, например marketing",
+                    "type": "string"
+                },
+                "default_priority": {
+                    "description": "DefaultPriority приоритет уведомления с таким событием по умолчанию",
+                    "type": "integer"
+                },
+                "delivery_windows": {
+                    "description": "DeliveryWindows окна доставки по времени получателя, пустой список - без ограничений",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.DeliveryWindow"
+                    }
+                },
+                "description": {
+                    "description": "Description описание бизнес события",
+                    "type": "string"
+                },
+                "event_uuid": {
+                    "description": "EventUUID связь с UUID бизнес события",
+                    "type": "string"
+                },
+                "fallback": {
+                    "description": "Fallback цепочка резервных каналов, сообщение уходит в следующий канал при недоставке",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.FallbackStep"
+                    }
+                },
+                "notification_channels": {
+                    "description": "NotificationChannels каналы отправки для данного события",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "routing_rules": {
+                    "description": "RoutingRules правила приоритета и дополнительных каналов по параметрам уведомления",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.RoutingRule"
+                    }
+                },
+                "title": {
+                    "description": "Title название бизнес события",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.FallbackStep": {
+            "type": "object",
+            "properties": {
+                "after": {
+                    "description": "After секунды ожидания отправки в предыдущий канал, 0 - до исчерпания попыток",
+                    "type": "integer"
+                },
+                "channel": {
+                    "description": "Channel канал отправки",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.FieldChange": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "Field имя поля в JSON",
+                    "type": "string"
+                },
+                "from": {
+                    "description": "From значение в исходной версии",
+                    "type": "string"
+                },
+                "to": {
+                    "description": "To значение в сравниваемой версии",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.IncomingEvent": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "description": "Category категория события для предпочтений получателей, например marketing",
+                    "type": "string"
+                },
+                "default_priority": {
+                    "description": "DefaultPriority приоритет уведомления с таким событием по умолчанию",
+                    "type": "integer"
+                },
+                "delivery_windows": {
+                    "description": "DeliveryWindows окна доставки по времени получателя, пустой список - без ограничений",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.DeliveryWindow"
+                    }
+                },
+                "description": {
+                    "description": "Description описание бизнес события",
+                    "type": "string"
+                },
+                "fallback": {
+                    "description": "Fallback цепочка резервных каналов, сообщение уходит в следующий канал при недоставке",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.FallbackStep"
+                    }
+                },
+                "notification_channels": {
+                    "description": "NotificationChannels каналы отправки для данного события",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "routing_rules": {
+                    "description": "RoutingRules правила приоритета и дополнительных каналов по параметрам уведомления",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.RoutingRule"
+                    }
+                },
+                "title": {
+                    "description": "Title название бизнес события",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.IncomingNotification": {
+            "type": "object",
+            "properties": {
+                "event_uuid": {
+                    "description": "EventUUID связь с UUID бизнес события",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt опциональный срок актуальности, например для кодов подтверждения, RFC 3339",
+                    "type": "string"
+                },
+                "idempotency_key": {
+                    "description": "IdempotencyKey опциональный ключ защиты от повторной отправки",
+                    "type": "string"
+                },
+                "locale": {
+                    "description": "Locale опциональная локаль для всех получателей, приоритетнее локали из контактов",
+                    "type": "string"
+                },
+                "message_params": {
+                    "description": "MessageParams key-value подстановки в шаблон уведомления",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.MessageParam"
+                    }
+                },
+                "person_uuids": {
+                    "description": "PersonUUIDs связь с пользователями - получателями уведомления",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "priority": {
+                    "description": "Priority опциональный приоритет уведомления, без приоритета - DefaultPriority события",
+                    "type": "integer"
+                },
+                "send_at": {
+                    "description": "SendAt опциональное время отложенной отправки, RFC 3339",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.IncomingPreference": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "description": "Category категория события, пустое значение - все категории",
+                    "type": "string"
+                },
+                "channel": {
+                    "description": "Channel канал отправки, пустое значение - все каналы",
+                    "type": "string"
+                },
+                "min_priority": {
+                    "description": "MinPriority минимальный приоритет уведомления в режиме quiet",
+                    "type": "integer"
+                },
+                "mode": {
+                    "description": "Mode режим: opt_in, opt_out, quiet",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.IncomingRender": {
+            "type": "object",
+            "properties": {
+                "message_params": {
+                    "description": "MessageParams key-value подстановки, как в уведомлении",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.MessageParam"
+                    }
+                }
+            }
+        },
+        "dto.IncomingTemplate": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "description": "Body тело шаблона",
+                    "type": "string"
+                },
+                "channel_type": {
+                    "description": "ChannelType связь с каналом отправки",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Description описание шаблона",
+                    "type": "string"
+                },
+                "event_uuid": {
+                    "description": "EventUUID связь с UUID бизнес события",
+                    "type": "string"
+                },
+                "locale": {
+                    "description": "Locale локаль шаблона, например ru-RU. Пустое значение - локаль по умолчанию",
+                    "type": "string"
+                },
+                "syntax": {
+                    "description": "Syntax синтаксис тела шаблона: bracket (по умолчанию) или template",
+                    "type": "string"
+                },
+                "title": {
+                    "description": "Title название шаблона",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.Message": {
+            "type": "object",
+            "properties": {
+                "attempt": {
+                    "description": "Attempt номер попытки отправки, начиная с 1",
+                    "type": "integer"
+                },
+                "channel": {
+                    "type": "string"
+                },
+                "content_type": {
+                    "description": "ContentType тип содержимого Text, пустое значение - ContentTypeText",
+                    "type": "string"
+                },
+                "deferred_until": {
+                    "description": "DeferredUntil открытие окна доставки, до которого отправка отложена",
+                    "type": "string"
+                },
+                "destination_address": {
+                    "type": "string"
+                },
+                "dispatched_at": {
+                    "description": "DispatchedAt время передачи сообщения в очередь на отправку",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt срок актуальности, после него сообщение уходит в очередь недоставленных",
+                    "type": "string"
+                },
+                "failed_at": {
+                    "description": "FailedAt время последней неудачной попытки",
+                    "type": "string"
+                },
+                "fallback": {
+                    "description": "Fallback подготовленные сообщения резервных каналов по порядку",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.Message"
+                    }
+                },
+                "fallback_after": {
+                    "description": "FallbackAfter секунды ожидания отправки предыдущего сообщения цепочки",
+                    "type": "integer"
+                },
+                "last_error": {
+                    "description": "LastError ошибка последней неудачной попытки",
+                    "type": "string"
+                },
+                "message_uuid": {
+                    "description": "MessageUUID id сообщения, не меняется при повторных попытках",
+                    "type": "string"
+                },
+                "notification_uuid": {
+                    "type": "string"
+                },
+                "person_uuid": {
+                    "type": "string"
+                },
+                "priority": {
+                    "description": "Priority приоритет сообщения в шине от 0 до Topology.MaxPriority",
+                    "type": "integer"
+                },
+                "retry_at": {
+                    "description": "RetryAt время следующей попытки",
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
             }
-        }
-    },
-    "definitions": {
-        "dto.Event": {
+        },
+        "dto.MessageParam": {
             "type": "object",
             "properties": {
-                "default_priority": {
-                    "description": "DefaultPriority приоритет уведомления с таким событием по умолчанию",
-                    "type": "integer"
-                },
-                "description": {
-                    "description": "Description описание бизнес события",
+                "key": {
+                    "description": "Key ключ по которому будет произведен поиск в теле уведомления",
                     "type": "string"
                 },
+                "value": {
+                    "description": "Value значение которое будет подставлено вместо ключа в шаблоне",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.MissingParams": {
+            "type": "object",
+            "properties": {
                 "event_uuid": {
                     "description": "EventUUID связь с UUID бизнес события",
                     "type": "string"
                 },
-                "notification_channels": {
-                    "description": "NotificationChannels каналы отправки для данного события",
+                "index": {
+                    "description": "Index позиция уведомления в пачке",
+                    "type": "integer"
+                },
+                "missing_params": {
+                    "description": "MissingParams ключи без значений в MessageParams",
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
-                },
-                "title": {
-                    "description": "Title название бизнес события",
-                    "type": "string"
                 }
             }
         },
-        "dto.IncomingEvent": {
+        "dto.Notification": {
             "type": "object",
             "properties": {
-                "default_priority": {
-                    "description": "DefaultPriority приоритет уведомления с таким событием по умолчанию",
-                    "type": "integer"
-                },
-                "description": {
-                    "description": "Description описание бизнес события",
+                "accepted_at": {
+                    "description": "AcceptedAt время приема уведомления сервисом, от него считается ожидание в очереди",
                     "type": "string"
                 },
-                "notification_channels": {
-                    "description": "NotificationChannels каналы отправки для данного события",
+                "channels": {
+                    "description": "Channels дополнительные к каналам события каналы по правилам маршрутизации",
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
-                "title": {
-                    "description": "Title название бизнес события",
-                    "type": "string"
-                }
-            }
-        },
-        "dto.IncomingNotification": {
-            "type": "object",
-            "properties": {
                 "event_uuid": {
                     "description": "EventUUID связь с UUID бизнес события",
                     "type": "string"
                 },
+                "expires_at": {
+                    "description": "ExpiresAt опциональный срок актуальности, после него сообщения не отправляются",
+                    "type": "string"
+                },
+                "idempotency_key": {
+                    "description": "IdempotencyKey опциональный ключ защиты от повторной отправки",
+                    "type": "string"
+                },
+                "index": {
+                    "description": "Index индекс уведомления в очереди",
+                    "type": "integer"
+                },
+                "locale": {
+                    "description": "Locale опциональная локаль для всех получателей, приоритетнее локали из контактов",
+                    "type": "string"
+                },
                 "message_params": {
                     "description": "MessageParams key-value подстановки в шаблон уведомления",
                     "type": "array",
@@ -612,6 +1683,10 @@ const docTemplate = `{
                         "$ref": "#/definitions/dto.MessageParam"
                     }
                 },
+                "notification_uuid": {
+                    "description": "NotificationUUID id уведомления в системе",
+                    "type": "string"
+                },
                 "person_uuids": {
                     "description": "PersonUUIDs связь с пользователями - получателями уведомления",
                     "type": "array",
@@ -620,52 +1695,193 @@ const docTemplate = `{
                     }
                 },
                 "priority": {
-                    "description": "Priority опциональный приоритет уведомления",
+                    "description": "Priority опциональный приоритет уведомления, без приоритета - DefaultPriority события",
                     "type": "integer"
+                },
+                "send_at": {
+                    "description": "SendAt опциональное время отложенной отправки",
+                    "type": "string"
                 }
             }
         },
-        "dto.IncomingTemplate": {
+        "dto.NotificationReceipt": {
             "type": "object",
             "properties": {
-                "body": {
-                    "description": "Body тело шаблона",
+                "duplicate": {
+                    "description": "Duplicate повтор ранее принятого уведомления, повторно не отправляется",
+                    "type": "boolean"
+                },
+                "idempotency_key": {
+                    "description": "IdempotencyKey ключ идемпотентности уведомления",
                     "type": "string"
                 },
-                "channel_type": {
-                    "description": "ChannelType связь с каналом отправки",
+                "notification_uuid": {
+                    "description": "NotificationUUID id уведомления в системе",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.NotificationStatus": {
+            "type": "object",
+            "properties": {
+                "notification_uuid": {
+                    "description": "NotificationUUID id уведомления",
                     "type": "string"
                 },
-                "description": {
-                    "description": "Description описание шаблона",
+                "recipients": {
+                    "description": "Recipients состояние по каждому получателю и каналу",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.RecipientStatus"
+                    }
+                },
+                "state": {
+                    "description": "State наименее продвинутое состояние среди получателей",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.Preference": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "description": "Category категория события, пустое значение - все категории",
                     "type": "string"
                 },
-                "event_uuid": {
-                    "description": "EventUUID связь с UUID бизнес события",
+                "channel": {
+                    "description": "Channel канал отправки, пустое значение - все каналы",
                     "type": "string"
                 },
-                "title": {
-                    "description": "Title название шаблона",
+                "min_priority": {
+                    "description": "MinPriority минимальный приоритет уведомления в режиме quiet",
+                    "type": "integer"
+                },
+                "mode": {
+                    "description": "Mode режим: opt_in, opt_out, quiet",
+                    "type": "string"
+                },
+                "person_uuid": {
+                    "description": "PersonUUID получатель",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "description": "UpdatedAt время последнего изменения",
                     "type": "string"
                 }
             }
         },
-        "dto.MessageParam": {
+        "dto.QueueBandStats": {
             "type": "object",
             "properties": {
-                "key": {
-                    "description": "Key ключ по которому будет произведен поиск в теле уведомления",
+                "delivered": {
+                    "description": "Delivered уведомлений передано диспетчеру",
+                    "type": "integer"
+                },
+                "min_priority": {
+                    "description": "MinPriority нижняя граница диапазона, верхняя - граница следующего диапазона",
+                    "type": "integer"
+                },
+                "wait_avg_seconds": {
+                    "description": "WaitAvgSeconds среднее ожидание",
+                    "type": "number"
+                },
+                "wait_max_seconds": {
+                    "description": "WaitMaxSeconds максимальное ожидание",
+                    "type": "number"
+                }
+            }
+        },
+        "dto.QueueStats": {
+            "type": "object",
+            "properties": {
+                "bands": {
+                    "description": "Bands ожидание переданных диспетчеру уведомлений по диапазонам приоритета",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.QueueBandStats"
+                    }
+                },
+                "length": {
+                    "description": "Length уведомлений в очереди",
+                    "type": "integer"
+                },
+                "oldest_wait_seconds": {
+                    "description": "OldestWaitSeconds ожидание самого давнего уведомления в очереди",
+                    "type": "number"
+                },
+                "policy": {
+                    "description": "Policy политика очереди: strict, aging",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.RecipientStatus": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "Attempts количество попыток отправки",
+                    "type": "integer"
+                },
+                "channel": {
+                    "description": "Channel канал, пустой пока сообщения не сформированы",
                     "type": "string"
                 },
-                "value": {
-                    "description": "Value значение которое будет подставлено вместо ключа в шаблоне",
+                "person_uuid": {
+                    "description": "PersonUUID получатель",
+                    "type": "string"
+                },
+                "state": {
+                    "description": "State состояние жизненного цикла",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status последний значимый статус статистики",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.StatStatus"
+                        }
+                    ]
+                },
+                "updated_at": {
+                    "description": "UpdatedAt время последней записи статистики",
                     "type": "string"
                 }
             }
         },
+        "dto.RoutingRule": {
+            "type": "object",
+            "properties": {
+                "channels": {
+                    "description": "Channels дополнительные каналы отправки при срабатывании",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "priority": {
+                    "description": "Priority приоритет уведомления при срабатывании, 0 - без изменения",
+                    "type": "integer"
+                },
+                "when": {
+                    "description": "When ключи и значения MessageParams, например {\"vip\": \"true\"}",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "dto.Stat": {
             "type": "object",
             "properties": {
+                "attempt": {
+                    "description": "Attempt номер попытки отправки",
+                    "type": "integer"
+                },
+                "channel": {
+                    "description": "Channel канал отправки, пустой для записей до выбора канала",
+                    "type": "string"
+                },
                 "created_at": {
                     "description": "CreatedAt дата и время отправки",
                     "type": "string"
@@ -678,6 +1894,10 @@ const docTemplate = `{
                     "description": "PersonUUID связь отправленного уведомления с клиентом",
                     "type": "string"
                 },
+                "reason": {
+                    "description": "Reason причина неудачи, например ошибка подстановки параметров в шаблон",
+                    "type": "string"
+                },
                 "stat_uuid": {
                     "description": "StatUUID id записи статистики",
                     "type": "string"
@@ -697,17 +1917,35 @@ const docTemplate = `{
             "enum": [
                 1,
                 2,
-                3
+                3,
+                4,
+                5,
+                6,
+                7,
+                8,
+                9
             ],
             "x-enum-comments": {
+                "Accepted": "Уведомление принято сервисом",
                 "BadChannel": "Канал отправки не поддерживается",
+                "DeadLettered": "Попытки исчерпаны, сообщение помещено в очередь недоставленных",
+                "Deferred": "Отправка отложена до открытия окна доставки получателя",
+                "Dispatched": "Сообщение передано в очередь на отправку",
                 "Failed": "Ошибка отправки",
+                "OptedOut": "Сообщение не отправлено по предпочтениям получателя",
+                "Rerouted": "Сообщение не отправлено и передано в следующий канал цепочки Fallback",
                 "Sent": "Уведомление отправлено"
             },
             "x-enum-varnames": [
                 "Sent",
                 "Failed",
-                "BadChannel"
+                "BadChannel",
+                "DeadLettered",
+                "Accepted",
+                "Dispatched",
+                "OptedOut",
+                "Rerouted",
+                "Deferred"
             ]
         },
         "dto.Template": {
@@ -729,6 +1967,21 @@ const docTemplate = `{
                     "description": "EventUUID связь с UUID бизнес события",
                     "type": "string"
                 },
+                "locale": {
+                    "description": "Locale локаль шаблона, пустое значение - локаль по умолчанию",
+                    "type": "string"
+                },
+                "required_params": {
+                    "description": "RequiredParams обязательные ключи MessageParams, заполняется при сохранении",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "syntax": {
+                    "description": "Syntax синтаксис тела шаблона, пустое значение - SyntaxBracket",
+                    "type": "string"
+                },
                 "template_uuid": {
                     "description": "TemplateUUID - id шаблона",
                     "type": "string"
@@ -736,6 +1989,133 @@ const docTemplate = `{
                 "title": {
                     "description": "Title название шаблона",
                     "type": "string"
+                },
+                "version": {
+                    "description": "Version номер опубликованной версии",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.TemplateDiff": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "description": "Body построчное сравнение тела шаблона",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.DiffLine"
+                    }
+                },
+                "fields": {
+                    "description": "Fields изменения полей кроме тела",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.FieldChange"
+                    }
+                },
+                "from": {
+                    "description": "From номер исходной версии",
+                    "type": "integer"
+                },
+                "template_uuid": {
+                    "description": "TemplateUUID - id шаблона",
+                    "type": "string"
+                },
+                "to": {
+                    "description": "To номер сравниваемой версии",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.TemplateRender": {
+            "type": "object",
+            "properties": {
+                "channel_type": {
+                    "description": "ChannelType канал отправки",
+                    "type": "string"
+                },
+                "missing_params": {
+                    "description": "MissingParams обязательные ключи без значений",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "placeholders": {
+                    "description": "Placeholders все ключи параметров шаблона",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "render_error": {
+                    "description": "RenderError ошибка рендера, с ней диспетчер не отправит сообщение",
+                    "type": "string"
+                },
+                "sms_encoding": {
+                    "description": "SMSEncoding кодировка SMS: GSM-7 или UCS-2",
+                    "type": "string"
+                },
+                "sms_segments": {
+                    "description": "SMSSegments число частей SMS, только для канала sms",
+                    "type": "integer"
+                },
+                "template_uuid": {
+                    "description": "TemplateUUID - id шаблона",
+                    "type": "string"
+                },
+                "text": {
+                    "description": "Text текст сообщения",
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version номер отрендеренной версии",
+                    "type": "integer"
+                },
+                "warnings": {
+                    "description": "Warnings предупреждения канала отправки",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dto.TemplateVersion": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "CreatedAt время создания версии",
+                    "type": "string"
+                },
+                "state": {
+                    "description": "State состояние: draft, published, archived",
+                    "type": "string"
+                },
+                "template": {
+                    "description": "Template содержимое шаблона в этой версии",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.Template"
+                        }
+                    ]
+                },
+                "template_uuid": {
+                    "description": "TemplateUUID - id шаблона",
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version номер версии, начиная с 1",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.DeadLettersResult": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "description": "Count количество обработанных сообщений",
+                    "type": "integer"
                 }
             }
         }
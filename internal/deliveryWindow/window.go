@@ -0,0 +1,140 @@
+// Package deliveryWindow окна доставки сообщений по местному времени получателя.
+// Окна задаются в бизнес событии dto.Event.DeliveryWindows, часовой пояс получателя
+// приходит из vault вместе с контактами dto.PersonContacts.TimeZone
+package deliveryWindow
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// InvalidWindow окно доставки задано неверно
+var InvalidWindow = errors.New("invalid delivery window")
+
+// clockFormat формат времени начала и окончания окна
+const clockFormat = "15:04"
+
+// dayNames дни недели по сокращенным названиям и группам дней
+var dayNames = map[string][]time.Weekday{
+	"mon":      {time.Monday},
+	"tue":      {time.Tuesday},
+	"wed":      {time.Wednesday},
+	"thu":      {time.Thursday},
+	"fri":      {time.Friday},
+	"sat":      {time.Saturday},
+	"sun":      {time.Sunday},
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekends": {time.Saturday, time.Sunday},
+}
+
+// window разобранное окно доставки, границы в минутах от полуночи
+type window struct {
+	days map[time.Weekday]struct{}
+	from int
+	to   int
+}
+
+// Validate проверяет дни недели и время окон, окно нулевой длины не допускается
+func Validate(windows []dto.DeliveryWindow) error {
+	_, err := parse(windows)
+	return err
+}
+
+// Location часовой пояс получателя, пустое значение - UTC
+func Location(timeZone string) (*time.Location, error) {
+	if timeZone == "" {
+		return time.UTC, nil
+	}
+
+	return time.LoadLocation(timeZone)
+}
+
+// NextOpen ближайший момент не раньше at, когда открыто одно из окон. Расчет ведется
+// в часовом поясе at. Без окон и при неверных окнах возвращается at
+func NextOpen(windows []dto.DeliveryWindow, at time.Time) time.Time {
+	parsed, err := parse(windows)
+	if err != nil || len(parsed) == 0 {
+		return at
+	}
+
+	var next time.Time
+	year, month, day := at.Date()
+
+	// окно предыдущего дня может переходить через полночь, неделя вперед покрывает все дни
+	for offset := -1; offset <= 7; offset++ {
+		date := time.Date(year, month, day+offset, 0, 0, 0, 0, at.Location())
+
+		for _, w := range parsed {
+			if _, ok := w.days[date.Weekday()]; !ok && len(w.days) > 0 {
+				continue
+			}
+
+			start := clock(date, w.from)
+			end := clock(date, w.to)
+			if w.to <= w.from {
+				end = clock(date.AddDate(0, 0, 1), w.to)
+			}
+
+			if !at.Before(start) && at.Before(end) {
+				return at
+			}
+			if start.After(at) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+	}
+
+	if next.IsZero() {
+		return at
+	}
+
+	return next
+}
+
+// parse разбор окон доставки
+func parse(windows []dto.DeliveryWindow) ([]window, error) {
+	parsed := make([]window, 0, len(windows))
+
+	for i, w := range windows {
+		from, err := time.Parse(clockFormat, w.From)
+		if err != nil {
+			return nil, fmt.Errorf("%w: window %d bad from %q", InvalidWindow, i, w.From)
+		}
+		to, err := time.Parse(clockFormat, w.To)
+		if err != nil {
+			return nil, fmt.Errorf("%w: window %d bad to %q", InvalidWindow, i, w.To)
+		}
+		if from.Equal(to) {
+			return nil, fmt.Errorf("%w: window %d is empty", InvalidWindow, i)
+		}
+
+		days := make(map[time.Weekday]struct{}, len(w.Days))
+		for _, name := range w.Days {
+			weekdays, ok := dayNames[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				return nil, fmt.Errorf("%w: window %d unknown day %q", InvalidWindow, i, name)
+			}
+			for _, weekday := range weekdays {
+				days[weekday] = struct{}{}
+			}
+		}
+
+		parsed = append(parsed, window{
+			days: days,
+			from: from.Hour()*60 + from.Minute(),
+			to:   to.Hour()*60 + to.Minute(),
+		})
+	}
+
+	return parsed, nil
+}
+
+// clock время дня date через minutes минут от полуночи по местным часам
+func clock(date time.Time, minutes int) time.Time {
+	year, month, day := date.Date()
+	return time.Date(year, month, day, minutes/60, minutes%60, 0, 0, date.Location())
+}
@@ -0,0 +1,115 @@
+package deliveryWindow
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+func TestNextOpen(t *testing.T) {
+	moscow, err := Location("Europe/Moscow")
+	assert.NoError(t, err)
+
+	weekdays := []dto.DeliveryWindow{{Days: []string{"weekdays"}, From: "09:00", To: "21:00"}}
+	night := []dto.DeliveryWindow{{From: "22:00", To: "06:00"}}
+
+	tests := []struct {
+		name    string
+		windows []dto.DeliveryWindow
+		at      time.Time
+		want    time.Time
+	}{
+		{
+			name:    "no windows",
+			windows: nil,
+			at:      time.Date(2023, 3, 22, 3, 0, 0, 0, moscow),
+			want:    time.Date(2023, 3, 22, 3, 0, 0, 0, moscow),
+		},
+		{
+			name:    "inside window",
+			windows: weekdays,
+			at:      time.Date(2023, 3, 22, 12, 30, 0, 0, moscow),
+			want:    time.Date(2023, 3, 22, 12, 30, 0, 0, moscow),
+		},
+		{
+			name:    "night before window",
+			windows: weekdays,
+			at:      time.Date(2023, 3, 22, 3, 0, 0, 0, moscow),
+			want:    time.Date(2023, 3, 22, 9, 0, 0, 0, moscow),
+		},
+		{
+			name:    "end of window is excluded",
+			windows: weekdays,
+			at:      time.Date(2023, 3, 22, 21, 0, 0, 0, moscow),
+			want:    time.Date(2023, 3, 23, 9, 0, 0, 0, moscow),
+		},
+		{
+			name:    "friday evening waits for monday",
+			windows: weekdays,
+			at:      time.Date(2023, 3, 24, 22, 0, 0, 0, moscow),
+			want:    time.Date(2023, 3, 27, 9, 0, 0, 0, moscow),
+		},
+		{
+			name:    "window over midnight from previous day",
+			windows: night,
+			at:      time.Date(2023, 3, 22, 3, 0, 0, 0, moscow),
+			want:    time.Date(2023, 3, 22, 3, 0, 0, 0, moscow),
+		},
+		{
+			name:    "window over midnight opens in the evening",
+			windows: night,
+			at:      time.Date(2023, 3, 22, 12, 0, 0, 0, moscow),
+			want:    time.Date(2023, 3, 22, 22, 0, 0, 0, moscow),
+		},
+		{
+			name: "nearest of several windows",
+			windows: []dto.DeliveryWindow{
+				{Days: []string{"sat", "sun"}, From: "11:00", To: "18:00"},
+				{Days: []string{"fri"}, From: "10:00", To: "12:00"},
+			},
+			at:   time.Date(2023, 3, 25, 9, 0, 0, 0, moscow),
+			want: time.Date(2023, 3, 25, 11, 0, 0, 0, moscow),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, tt.want.Equal(NextOpen(tt.windows, tt.at)), "got %v", NextOpen(tt.windows, tt.at))
+		})
+	}
+}
+
+func TestNextOpen_timeZone(t *testing.T) {
+	// 03:00 UTC - полдень во Владивостоке и ночь в Москве
+	at := time.Date(2023, 3, 22, 3, 0, 0, 0, time.UTC)
+	windows := []dto.DeliveryWindow{{From: "09:00", To: "21:00"}}
+
+	vladivostok, err := Location("Asia/Vladivostok")
+	assert.NoError(t, err)
+	assert.True(t, at.Equal(NextOpen(windows, at.In(vladivostok))))
+
+	moscow, err := Location("Europe/Moscow")
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2023, 3, 22, 6, 0, 0, 0, time.UTC).Equal(NextOpen(windows, at.In(moscow))))
+
+	_, err = Location("Mars/Olympus")
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(nil))
+	assert.NoError(t, Validate([]dto.DeliveryWindow{{Days: []string{"Mon", "weekends"}, From: "09:00", To: "21:00"}}))
+
+	for _, windows := range [][]dto.DeliveryWindow{
+		{{From: "9", To: "21:00"}},
+		{{From: "09:00", To: "24:30"}},
+		{{From: "09:00", To: "09:00"}},
+		{{Days: []string{"someday"}, From: "09:00", To: "21:00"}},
+	} {
+		assert.True(t, errors.Is(Validate(windows), InvalidWindow), "%v", windows)
+	}
+}
@@ -5,6 +5,8 @@ import "github.com/google/uuid"
 type PersonContacts struct {
 	PersonUUID uuid.UUID `json:"person_uuid"`
 	Contacts   []Contact `json:"contacts,omitempty"`
+	Locale     string    `json:"locale,omitempty"`    // Locale предпочитаемая локаль получателя
+	TimeZone   string    `json:"time_zone,omitempty"` // TimeZone часовой пояс получателя IANA, например Europe/Moscow
 }
 
 type Contact struct {
@@ -0,0 +1,41 @@
+package dto
+
+import "errors"
+
+// ErrDeliveryNotInitialized сообщение получено не из шины, подтверждать его некому
+var ErrDeliveryNotInitialized = errors.New("delivery not initialized")
+
+// Delivery сообщение шины, полученное подписчиком очереди.
+// Обработка сообщения завершается Ack или Nack, неподтвержденное сообщение шина выдаст повторно
+type Delivery struct {
+	Body         []byte               // Body тело сообщения, обычно json dto.Message
+	Priority     uint8                // Priority приоритет сообщения в очереди
+	Redelivered  bool                 // Redelivered сообщение выдается повторно
+	DeathReason  string               // DeathReason причина перевода сообщения в очередь недоставленных, если известна шине
+	Acknowledger DeliveryAcknowledger // Acknowledger подтверждение сообщения в шине
+}
+
+// DeliveryAcknowledger подтверждение сообщения в реализации шины
+type DeliveryAcknowledger interface {
+	Ack() error
+	Nack(requeue bool) error
+}
+
+// Ack сообщение обработано и удаляется из очереди
+func (d Delivery) Ack() error {
+	if d.Acknowledger == nil {
+		return ErrDeliveryNotInitialized
+	}
+
+	return d.Acknowledger.Ack()
+}
+
+// Nack отказ от сообщения: requeue возвращает его в очередь, иначе сообщение уходит
+// в очередь недоставленных топологии или отбрасывается
+func (d Delivery) Nack(requeue bool) error {
+	if d.Acknowledger == nil {
+		return ErrDeliveryNotInitialized
+	}
+
+	return d.Acknowledger.Nack(requeue)
+}
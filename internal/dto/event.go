@@ -4,17 +4,49 @@ import "github.com/google/uuid"
 
 // Event структура бизнес события для передачи между слоями приложения
 type Event struct {
-	EventUUID            uuid.UUID `json:"event_uuid"`                      // EventUUID связь с UUID бизнес события
-	Title                string    `json:"title"`                           // Title название бизнес события
-	Description          string    `json:"description,omitempty"`           // Description описание бизнес события
-	DefaultPriority      uint      `json:"default_priority,omitempty"`      // DefaultPriority приоритет уведомления с таким событием по умолчанию
-	NotificationChannels []string  `json:"notification_channels,omitempty"` // NotificationChannels каналы отправки для данного события
+	EventUUID            uuid.UUID        `json:"event_uuid"`                      // EventUUID связь с UUID бизнес события
+	Title                string           `json:"title"`                           // Title название бизнес события
+	Description          string           `json:"description,omitempty"`           // Description описание бизнес события
+	DefaultPriority      uint             `json:"default_priority,omitempty"`      // DefaultPriority приоритет уведомления с таким событием по умолчанию
+	NotificationChannels []string         `json:"notification_channels,omitempty"` // NotificationChannels каналы отправки для данного события
+	Category             string           `json:"category,omitempty"`              // Category категория события для предпочтений получателей, например marketing
+	Fallback             []FallbackStep   `json:"fallback,omitempty"`              // Fallback цепочка резервных каналов, сообщение уходит в следующий канал при недоставке
+	DeliveryWindows      []DeliveryWindow `json:"delivery_windows,omitempty"`      // DeliveryWindows окна доставки по времени получателя, пустой список - без ограничений
+	RoutingRules         []RoutingRule    `json:"routing_rules,omitempty"`         // RoutingRules правила приоритета и дополнительных каналов по параметрам уведомления
 }
 
 // IncomingEvent структура входящего бизнес события для анмаршаллинга json
 type IncomingEvent struct {
-	Title                string   `json:"title"`                           // Title название бизнес события
-	Description          string   `json:"description,omitempty"`           // Description описание бизнес события
-	DefaultPriority      uint     `json:"default_priority,omitempty"`      // DefaultPriority приоритет уведомления с таким событием по умолчанию
-	NotificationChannels []string `json:"notification_channels,omitempty"` // NotificationChannels каналы отправки для данного события
+	Title                string           `json:"title"`                           // Title название бизнес события
+	Description          string           `json:"description,omitempty"`           // Description описание бизнес события
+	DefaultPriority      uint             `json:"default_priority,omitempty"`      // DefaultPriority приоритет уведомления с таким событием по умолчанию
+	NotificationChannels []string         `json:"notification_channels,omitempty"` // NotificationChannels каналы отправки для данного события
+	Category             string           `json:"category,omitempty"`              // Category категория события для предпочтений получателей, например marketing
+	Fallback             []FallbackStep   `json:"fallback,omitempty"`              // Fallback цепочка резервных каналов, сообщение уходит в следующий канал при недоставке
+	DeliveryWindows      []DeliveryWindow `json:"delivery_windows,omitempty"`      // DeliveryWindows окна доставки по времени получателя, пустой список - без ограничений
+	RoutingRules         []RoutingRule    `json:"routing_rules,omitempty"`         // RoutingRules правила приоритета и дополнительных каналов по параметрам уведомления
+}
+
+// FallbackStep шаг цепочки резервных каналов. Первый шаг - основной канал.
+// Переход на следующий шаг происходит, если у получателя нет контакта или шаблона для канала,
+// попытки отправки исчерпаны или сообщение не отправлено за After секунд
+type FallbackStep struct {
+	Channel string `json:"channel"`         // Channel канал отправки
+	After   int    `json:"after,omitempty"` // After секунды ожидания отправки в предыдущий канал, 0 - до исчерпания попыток
+}
+
+// DeliveryWindow окно доставки сообщений по местному времени получателя.
+// Окно с To раньше From переходит через полночь и относится ко дню начала
+type DeliveryWindow struct {
+	Days []string `json:"days,omitempty"` // Days дни недели mon..sun, weekdays или weekends, пустой список - каждый день
+	From string   `json:"from"`           // From начало окна HH:MM
+	To   string   `json:"to"`             // To окончание окна HH:MM, не включается в окно
+}
+
+// RoutingRule правило маршрутизации уведомлений события. Срабатывает, если все параметры When
+// совпадают со значениями MessageParams уведомления
+type RoutingRule struct {
+	When     map[string]string `json:"when"`               // When ключи и значения MessageParams, например {"vip": "true"}
+	Priority uint              `json:"priority,omitempty"` // Priority приоритет уведомления при срабатывании, 0 - без изменения
+	Channels []string          `json:"channels,omitempty"` // Channels дополнительные каналы отправки при срабатывании
 }
@@ -1,11 +1,27 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Message struct {
-	NotificationUUID   uuid.UUID `json:"notification_uuid"`
-	PersonUUID         uuid.UUID `json:"person_uuid"`
-	Text               string    `json:"text"`
-	Channel            string    `json:"channel"`
-	DestinationAddress string    `json:"destination_address"`
+	MessageUUID        uuid.UUID  `json:"message_uuid"` // MessageUUID id сообщения, не меняется при повторных попытках
+	NotificationUUID   uuid.UUID  `json:"notification_uuid"`
+	PersonUUID         uuid.UUID  `json:"person_uuid"`
+	Text               string     `json:"text"`
+	ContentType        string     `json:"content_type,omitempty"` // ContentType тип содержимого Text, пустое значение - ContentTypeText
+	Channel            string     `json:"channel"`
+	DestinationAddress string     `json:"destination_address"`
+	Attempt            int        `json:"attempt"`                  // Attempt номер попытки отправки, начиная с 1
+	RetryAt            *time.Time `json:"retry_at,omitempty"`       // RetryAt время следующей попытки
+	FailedAt           *time.Time `json:"failed_at,omitempty"`      // FailedAt время последней неудачной попытки
+	LastError          string     `json:"last_error,omitempty"`     // LastError ошибка последней неудачной попытки
+	DispatchedAt       *time.Time `json:"dispatched_at,omitempty"`  // DispatchedAt время передачи сообщения в очередь на отправку
+	FallbackAfter      int        `json:"fallback_after,omitempty"` // FallbackAfter секунды ожидания отправки предыдущего сообщения цепочки
+	Fallback           []Message  `json:"fallback,omitempty"`       // Fallback подготовленные сообщения резервных каналов по порядку
+	DeferredUntil      *time.Time `json:"deferred_until,omitempty"` // DeferredUntil открытие окна доставки, до которого отправка отложена
+	Priority           uint8      `json:"priority,omitempty"`       // Priority приоритет сообщения в шине от 0 до Topology.MaxPriority
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`     // ExpiresAt срок актуальности, после него сообщение уходит в очередь недоставленных
 }
@@ -1,23 +1,67 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // Notification структура уведомления для внутренних интерфейсов
 type Notification struct {
 	Index            int            // Index индекс уведомления в очереди
-	NotificationUUID uuid.UUID      `json:"notification_uuid"`        // NotificationUUID id уведомления в системе
-	EventUUID        uuid.UUID      `json:"event_uuid"`               // EventUUID связь с UUID бизнес события
-	PersonUUIDs      []uuid.UUID    `json:"person_uuids"`             // PersonUUIDs связь с пользователями - получателями уведомления
-	MessageParams    []MessageParam `json:"message_params,omitempty"` // MessageParams key-value подстановки в шаблон уведомления
-	Priority         uint           `json:"priority,omitempty"`       // Priority опциональный приоритет уведомления
+	NotificationUUID uuid.UUID      `json:"notification_uuid"`         // NotificationUUID id уведомления в системе
+	EventUUID        uuid.UUID      `json:"event_uuid"`                // EventUUID связь с UUID бизнес события
+	PersonUUIDs      []uuid.UUID    `json:"person_uuids"`              // PersonUUIDs связь с пользователями - получателями уведомления
+	MessageParams    []MessageParam `json:"message_params,omitempty"`  // MessageParams key-value подстановки в шаблон уведомления
+	Priority         uint           `json:"priority,omitempty"`        // Priority опциональный приоритет уведомления, без приоритета - DefaultPriority события
+	SendAt           *time.Time     `json:"send_at,omitempty"`         // SendAt опциональное время отложенной отправки
+	IdempotencyKey   string         `json:"idempotency_key,omitempty"` // IdempotencyKey опциональный ключ защиты от повторной отправки
+	Locale           string         `json:"locale,omitempty"`          // Locale опциональная локаль для всех получателей, приоритетнее локали из контактов
+	ExpiresAt        *time.Time     `json:"expires_at,omitempty"`      // ExpiresAt опциональный срок актуальности, после него сообщения не отправляются
+	Channels         []string       `json:"channels,omitempty"`        // Channels дополнительные к каналам события каналы по правилам маршрутизации
+	AcceptedAt       *time.Time     `json:"accepted_at,omitempty"`     // AcceptedAt время приема уведомления сервисом, от него считается ожидание в очереди
 }
 
 // IncomingNotification структура уведомления для внешних интерфейсов
 type IncomingNotification struct {
-	EventUUID     uuid.UUID      `json:"event_uuid"`               // EventUUID связь с UUID бизнес события
-	PersonUUIDs   []uuid.UUID    `json:"person_uuids"`             // PersonUUIDs связь с пользователями - получателями уведомления
-	MessageParams []MessageParam `json:"message_params,omitempty"` // MessageParams key-value подстановки в шаблон уведомления
-	Priority      uint           `json:"priority,omitempty"`       // Priority опциональный приоритет уведомления
+	EventUUID      uuid.UUID      `json:"event_uuid"`                // EventUUID связь с UUID бизнес события
+	PersonUUIDs    []uuid.UUID    `json:"person_uuids"`              // PersonUUIDs связь с пользователями - получателями уведомления
+	MessageParams  []MessageParam `json:"message_params,omitempty"`  // MessageParams key-value подстановки в шаблон уведомления
+	Priority       uint           `json:"priority,omitempty"`        // Priority опциональный приоритет уведомления, без приоритета - DefaultPriority события
+	SendAt         *time.Time     `json:"send_at,omitempty"`         // SendAt опциональное время отложенной отправки, RFC 3339
+	IdempotencyKey string         `json:"idempotency_key,omitempty"` // IdempotencyKey опциональный ключ защиты от повторной отправки
+	Locale         string         `json:"locale,omitempty"`          // Locale опциональная локаль для всех получателей, приоритетнее локали из контактов
+	ExpiresAt      *time.Time     `json:"expires_at,omitempty"`      // ExpiresAt опциональный срок актуальности, например для кодов подтверждения, RFC 3339
+}
+
+// NotificationReceipt результат приема уведомления
+type NotificationReceipt struct {
+	NotificationUUID uuid.UUID `json:"notification_uuid"`         // NotificationUUID id уведомления в системе
+	IdempotencyKey   string    `json:"idempotency_key,omitempty"` // IdempotencyKey ключ идемпотентности уведомления
+	Duplicate        bool      `json:"duplicate,omitempty"`       // Duplicate повтор ранее принятого уведомления, повторно не отправляется
+}
+
+// QueueStats метрики очереди принятых уведомлений, ожидающих передачи диспетчеру
+type QueueStats struct {
+	Policy            string           `json:"policy"`              // Policy политика очереди: strict, aging
+	Length            int              `json:"length"`              // Length уведомлений в очереди
+	OldestWaitSeconds float64          `json:"oldest_wait_seconds"` // OldestWaitSeconds ожидание самого давнего уведомления в очереди
+	Bands             []QueueBandStats `json:"bands"`               // Bands ожидание переданных диспетчеру уведомлений по диапазонам приоритета
+}
+
+// QueueBandStats ожидание в очереди уведомлений диапазона приоритета с момента старта сервиса
+type QueueBandStats struct {
+	MinPriority    uint    `json:"min_priority"`     // MinPriority нижняя граница диапазона, верхняя - граница следующего диапазона
+	Delivered      int64   `json:"delivered"`        // Delivered уведомлений передано диспетчеру
+	WaitAvgSeconds float64 `json:"wait_avg_seconds"` // WaitAvgSeconds среднее ожидание
+	WaitMaxSeconds float64 `json:"wait_max_seconds"` // WaitMaxSeconds максимальное ожидание
+}
+
+// MissingParams обязательные параметры шаблонов бизнес события, отсутствующие в уведомлении
+type MissingParams struct {
+	Index         int       `json:"index"`          // Index позиция уведомления в пачке
+	EventUUID     uuid.UUID `json:"event_uuid"`     // EventUUID связь с UUID бизнес события
+	MissingParams []string  `json:"missing_params"` // MissingParams ключи без значений в MessageParams
 }
 
 // MessageParam key-value подстановки в шаблон уведомления
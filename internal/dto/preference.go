@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Режимы предпочтений получателя
+const (
+	PreferenceOptIn  = "opt_in"  // PreferenceOptIn получатель согласен на сообщения
+	PreferenceOptOut = "opt_out" // PreferenceOptOut получатель отказался от сообщений
+	PreferenceQuiet  = "quiet"   // PreferenceQuiet отправляются только уведомления с приоритетом не ниже MinPriority
+)
+
+// Preference предпочтение получателя для категории событий и канала.
+// Пустые Category и Channel относятся ко всем категориям и каналам, более точное правило приоритетнее
+type Preference struct {
+	PersonUUID  uuid.UUID `json:"person_uuid"`            // PersonUUID получатель
+	Category    string    `json:"category,omitempty"`     // Category категория события, пустое значение - все категории
+	Channel     string    `json:"channel,omitempty"`      // Channel канал отправки, пустое значение - все каналы
+	Mode        string    `json:"mode"`                   // Mode режим: opt_in, opt_out, quiet
+	MinPriority uint      `json:"min_priority,omitempty"` // MinPriority минимальный приоритет уведомления в режиме quiet
+	UpdatedAt   time.Time `json:"updated_at"`             // UpdatedAt время последнего изменения
+}
+
+// IncomingPreference структура входящего предпочтения для анмаршаллинга json
+type IncomingPreference struct {
+	Category    string `json:"category,omitempty"`     // Category категория события, пустое значение - все категории
+	Channel     string `json:"channel,omitempty"`      // Channel канал отправки, пустое значение - все каналы
+	Mode        string `json:"mode"`                   // Mode режим: opt_in, opt_out, quiet
+	MinPriority uint   `json:"min_priority,omitempty"` // MinPriority минимальный приоритет уведомления в режиме quiet
+}
@@ -8,15 +8,24 @@ type Stat struct {
 	NotificationUUID uuid.UUID  `json:"notification_uuid"` // NotificationUUID связь с уведомлением
 	CreatedAt        string     `json:"created_at"`        // CreatedAt дата и время отправки
 	Status           StatStatus `json:"status"`            // Status статус отправки
+	Attempt          int        `json:"attempt"`           // Attempt номер попытки отправки
+	Channel          string     `json:"channel,omitempty"` // Channel канал отправки, пустой для записей до выбора канала
+	Reason           string     `json:"reason,omitempty"`  // Reason причина неудачи, например ошибка подстановки параметров в шаблон
 }
 
 // StatStatus Статусы обработки заказа
 type StatStatus int64
 
 const (
-	Sent       StatStatus = iota + 1 // Уведомление отправлено
-	Failed                           // Ошибка отправки
-	BadChannel                       // Канал отправки не поддерживается
+	Sent         StatStatus = iota + 1 // Уведомление отправлено
+	Failed                             // Ошибка отправки
+	BadChannel                         // Канал отправки не поддерживается
+	DeadLettered                       // Попытки исчерпаны, сообщение помещено в очередь недоставленных
+	Accepted                           // Уведомление принято сервисом
+	Dispatched                         // Сообщение передано в очередь на отправку
+	OptedOut                           // Сообщение не отправлено по предпочтениям получателя
+	Rerouted                           // Сообщение не отправлено и передано в следующий канал цепочки Fallback
+	Deferred                           // Отправка отложена до открытия окна доставки получателя
 )
 
 func (s StatStatus) String() string {
@@ -27,6 +36,47 @@ func (s StatStatus) String() string {
 		return "failed"
 	case BadChannel:
 		return "bad channel"
+	case DeadLettered:
+		return "dead lettered"
+	case Accepted:
+		return "accepted"
+	case Dispatched:
+		return "dispatched"
+	case OptedOut:
+		return "opted out"
+	case Rerouted:
+		return "rerouted"
+	case Deferred:
+		return "deferred"
 	}
 	return "unknown"
 }
+
+// Состояния жизненного цикла уведомления
+const (
+	StateAccepted   = "accepted"   // Уведомление принято, сообщения еще не сформированы
+	StateScheduled  = "scheduled"  // Уведомление ожидает времени отложенной отправки
+	StateDeferred   = "deferred"   // Сообщение ожидает открытия окна доставки получателя
+	StateDispatched = "dispatched" // Сообщение передано в очередь на отправку
+	StateSent       = "sent"       // Сообщение отправлено
+	StateFailed     = "failed"     // Сообщение не отправлено
+	StateOptedOut   = "opted_out"  // Получатель отказался от сообщений в канале
+	StateRerouted   = "rerouted"   // Сообщение передано в резервный канал, итог отправки - в записи этого канала
+)
+
+// NotificationStatus сводное состояние уведомления по получателям и каналам
+type NotificationStatus struct {
+	NotificationUUID uuid.UUID         `json:"notification_uuid"` // NotificationUUID id уведомления
+	State            string            `json:"state"`             // State наименее продвинутое состояние среди получателей
+	Recipients       []RecipientStatus `json:"recipients"`        // Recipients состояние по каждому получателю и каналу
+}
+
+// RecipientStatus состояние сообщения для получателя в канале
+type RecipientStatus struct {
+	PersonUUID uuid.UUID  `json:"person_uuid"`       // PersonUUID получатель
+	Channel    string     `json:"channel,omitempty"` // Channel канал, пустой пока сообщения не сформированы
+	State      string     `json:"state"`             // State состояние жизненного цикла
+	Status     StatStatus `json:"status"`            // Status последний значимый статус статистики
+	Attempts   int        `json:"attempts"`          // Attempts количество попыток отправки
+	UpdatedAt  string     `json:"updated_at"`        // UpdatedAt время последней записи статистики
+}
@@ -1,14 +1,41 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Состояния версии шаблона
+const (
+	TemplateDraft     = "draft"     // TemplateDraft черновик, диспетчером не используется
+	TemplatePublished = "published" // TemplatePublished опубликованная версия, используется диспетчером
+	TemplateArchived  = "archived"  // TemplateArchived ранее опубликованная версия
+)
+
+// Синтаксис тела шаблона, см. пакет templating
+const (
+	SyntaxBracket  = "bracket"  // SyntaxBracket плейсхолдеры вида [param1]
+	SyntaxTemplate = "template" // SyntaxTemplate синтаксис text/template с условиями, циклами и фильтрами
+)
+
+// Тип содержимого текста сообщения, см. templating.ContentType
+const (
+	ContentTypeText = "text/plain" // ContentTypeText текст без разметки
+	ContentTypeHTML = "text/html"  // ContentTypeHTML разметка HTML, подстановки экранированы
+)
 
 type Template struct {
-	TemplateUUID uuid.UUID `json:"template_uuid"`         // TemplateUUID - id шаблона
-	EventUUID    uuid.UUID `json:"event_uuid"`            // EventUUID связь с UUID бизнес события
-	Title        string    `json:"title"`                 // Title название шаблона
-	Description  string    `json:"description,omitempty"` // Description описание шаблона
-	Body         string    `json:"body"`                  // Body тело шаблона
-	ChannelType  string    `json:"channel_type"`          // ChannelType связь с каналом отправки
+	TemplateUUID   uuid.UUID `json:"template_uuid"`             // TemplateUUID - id шаблона
+	EventUUID      uuid.UUID `json:"event_uuid"`                // EventUUID связь с UUID бизнес события
+	Title          string    `json:"title"`                     // Title название шаблона
+	Description    string    `json:"description,omitempty"`     // Description описание шаблона
+	Body           string    `json:"body"`                      // Body тело шаблона
+	ChannelType    string    `json:"channel_type"`              // ChannelType связь с каналом отправки
+	Syntax         string    `json:"syntax,omitempty"`          // Syntax синтаксис тела шаблона, пустое значение - SyntaxBracket
+	RequiredParams []string  `json:"required_params,omitempty"` // RequiredParams обязательные ключи MessageParams, заполняется при сохранении
+	Locale         string    `json:"locale,omitempty"`          // Locale локаль шаблона, пустое значение - локаль по умолчанию
+	Version        int       `json:"version,omitempty"`         // Version номер опубликованной версии
 }
 
 type IncomingTemplate struct {
@@ -17,4 +44,56 @@ type IncomingTemplate struct {
 	Description string    `json:"description,omitempty"` // Description описание шаблона
 	Body        string    `json:"body"`                  // Body тело шаблона
 	ChannelType string    `json:"channel_type"`          // ChannelType связь с каналом отправки
+	Syntax      string    `json:"syntax,omitempty"`      // Syntax синтаксис тела шаблона: bracket (по умолчанию) или template
+	Locale      string    `json:"locale,omitempty"`      // Locale локаль шаблона, например ru-RU. Пустое значение - локаль по умолчанию
+}
+
+// TemplateVersion неизменяемая версия шаблона. Меняется только состояние State
+type TemplateVersion struct {
+	TemplateUUID uuid.UUID `json:"template_uuid"` // TemplateUUID - id шаблона
+	Version      int       `json:"version"`       // Version номер версии, начиная с 1
+	State        string    `json:"state"`         // State состояние: draft, published, archived
+	CreatedAt    time.Time `json:"created_at"`    // CreatedAt время создания версии
+	Template     Template  `json:"template"`      // Template содержимое шаблона в этой версии
+}
+
+// TemplateDiff различия двух версий шаблона
+type TemplateDiff struct {
+	TemplateUUID uuid.UUID     `json:"template_uuid"`    // TemplateUUID - id шаблона
+	From         int           `json:"from"`             // From номер исходной версии
+	To           int           `json:"to"`               // To номер сравниваемой версии
+	Fields       []FieldChange `json:"fields,omitempty"` // Fields изменения полей кроме тела
+	Body         []DiffLine    `json:"body"`             // Body построчное сравнение тела шаблона
+}
+
+// FieldChange изменение поля шаблона
+type FieldChange struct {
+	Field string `json:"field"` // Field имя поля в JSON
+	From  string `json:"from"`  // From значение в исходной версии
+	To    string `json:"to"`    // To значение в сравниваемой версии
+}
+
+// DiffLine строка сравнения: Op "+" добавлена, "-" удалена, " " без изменений
+type DiffLine struct {
+	Op   string `json:"op"`   // Op операция
+	Text string `json:"text"` // Text текст строки
+}
+
+// IncomingRender параметры предпросмотра шаблона
+type IncomingRender struct {
+	MessageParams []MessageParam `json:"message_params,omitempty"` // MessageParams key-value подстановки, как в уведомлении
+}
+
+// TemplateRender результат предпросмотра шаблона, текст совпадает с отправляемым диспетчером
+type TemplateRender struct {
+	TemplateUUID  uuid.UUID `json:"template_uuid"`          // TemplateUUID - id шаблона
+	Version       int       `json:"version"`                // Version номер отрендеренной версии
+	ChannelType   string    `json:"channel_type"`           // ChannelType канал отправки
+	Text          string    `json:"text"`                   // Text текст сообщения
+	RenderError   string    `json:"render_error,omitempty"` // RenderError ошибка рендера, с ней диспетчер не отправит сообщение
+	Placeholders  []string  `json:"placeholders"`           // Placeholders все ключи параметров шаблона
+	MissingParams []string  `json:"missing_params"`         // MissingParams обязательные ключи без значений
+	Warnings      []string  `json:"warnings"`               // Warnings предупреждения канала отправки
+	SMSSegments   int       `json:"sms_segments,omitempty"` // SMSSegments число частей SMS, только для канала sms
+	SMSEncoding   string    `json:"sms_encoding,omitempty"` // SMSEncoding кодировка SMS: GSM-7 или UCS-2
 }
@@ -0,0 +1,76 @@
+package dto
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnknownChannel канала нет в топологии
+var ErrUnknownChannel = errors.New("unknown channel")
+
+// Topology топология шины сообщений на отправку: topic exchange и очереди каналов доставки.
+// Очередь канала Exchange.channel получает сообщения с ключом маршрутизации channel,
+// поэтому воркеры каналов масштабируются независимо друг от друга
+type Topology struct {
+	Exchange        string        // Exchange topic exchange сообщений на отправку
+	Channels        []string      // Channels каналы доставки, для каждого создается очередь
+	MaxPriority     uint8         // MaxPriority x-max-priority очередей каналов
+	MessageTTL      time.Duration // MessageTTL x-message-ttl очередей каналов, 0 - без ограничения
+	DeadLetterQueue string        // DeadLetterQueue очередь для сообщений с истекшим временем жизни
+}
+
+// Queue имя очереди канала доставки
+func (t Topology) Queue(channel string) string {
+	return t.Exchange + "." + channel
+}
+
+// Queues имена очередей всех каналов доставки
+func (t Topology) Queues() []string {
+	queues := make([]string, 0, len(t.Channels))
+	for _, channel := range t.Channels {
+		queues = append(queues, t.Queue(channel))
+	}
+
+	return queues
+}
+
+// ChannelQueues имена очередей каналов channels, пустой список - очереди всех каналов доставки.
+// Канал без очереди в топологии - ошибка конфигурации
+func (t Topology) ChannelQueues(channels []string) ([]string, error) {
+	if len(channels) == 0 {
+		return t.Queues(), nil
+	}
+
+	queues := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		if !t.Routes(channel) {
+			return nil, fmt.Errorf("%w: %v", ErrUnknownChannel, channel)
+		}
+		queues = append(queues, t.Queue(channel))
+	}
+
+	return queues, nil
+}
+
+// Routes для канала доставки есть очередь
+func (t Topology) Routes(channel string) bool {
+	for _, c := range t.Channels {
+		if c == channel {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Has очередь относится к каналам топологии
+func (t Topology) Has(queue string) bool {
+	for _, channel := range t.Channels {
+		if t.Queue(channel) == queue {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,31 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// Bus интерфейс шины сообщений, не зависящий от брокера.
+// Реализации: ampq.Client для RabbitMQ, bus.NatsBus для NATS JetStream и bus.MemoryBus в памяти процесса
+type Bus interface {
+	// Connect подключение к брокеру
+	Connect() error
+	// MigrateDurableQueues объявление очередей, адресуемых по имени
+	MigrateDurableQueues(queues ...string)
+	// MigrateTopology объявление очередей каналов доставки и маршрутизации сообщений в них
+	MigrateTopology(topology dto.Topology) error
+	// Qos ограничение неподтвержденных сообщений на подписчика
+	Qos(prefetchCount int) error
+	// Publish публикация в очередь по имени, nil - брокер принял сообщение
+	Publish(queue string, msgBody []byte) error
+	// PublishDelayed публикация в очередь по имени, сообщение выдается не раньше чем через delay.
+	// До выдачи сообщение хранит брокер, nil - брокер принял сообщение
+	PublishDelayed(queue string, msgBody []byte, delay time.Duration) error
+	// PublishRouted публикация в exchange топологии по ключу канала с приоритетом и временем жизни сообщения
+	PublishRouted(exchange string, routingKey string, msgBody []byte, priority uint8, expiration time.Duration) error
+	// Subscribe потребление очереди, каждое сообщение подтверждается Ack или возвращается Nack
+	Subscribe(queue string) (<-chan dto.Delivery, error)
+	// Stop закрытие подписок и подключения
+	Stop()
+}
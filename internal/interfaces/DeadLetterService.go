@@ -0,0 +1,25 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// DeadLetterService интерфейс сервиса недоставленных сообщений, исчерпавших попытки отправки
+type DeadLetterService interface {
+	// BaseService Общий сервисный интерфейс с методами Start и Stop
+	BaseService
+
+	// All FindById - просмотр недоставленных сообщений
+	All(ctx context.Context) []dto.Message
+	FindById(ctx context.Context, messageUUID uuid.UUID) (dto.Message, error)
+	// Replay ReplayAll - повторная отправка с обнуленным счетчиком попыток
+	Replay(ctx context.Context, messageUUID uuid.UUID) error
+	ReplayAll(ctx context.Context) (int, error)
+	// Purge PurgeAll - удаление без отправки
+	Purge(ctx context.Context, messageUUID uuid.UUID) error
+	PurgeAll(ctx context.Context) (int, error)
+}
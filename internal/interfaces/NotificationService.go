@@ -10,6 +10,10 @@ type NotificationService interface {
 	// BaseService Общий сервисный интерфейс с методами Start и Stop
 	BaseService
 
-	// ProcessNotification приоритезация, лимитер уведомлений
-	ProcessNotification(ctx context.Context, notification []dto.Notification) error
+	// ProcessNotification приоритезация, лимитер уведомлений, защита от повторов по ключу идемпотентности.
+	// Возвращает квитанции с UUID уведомлений в порядке входящих уведомлений
+	ProcessNotification(ctx context.Context, notification []dto.Notification) ([]dto.NotificationReceipt, error)
+
+	// QueueStats метрики очереди и времени ожидания уведомлений до передачи диспетчеру
+	QueueStats() dto.QueueStats
 }
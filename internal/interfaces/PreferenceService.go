@@ -0,0 +1,22 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// PreferenceService интерфейс сервиса предпочтений получателей по категориям событий и каналам
+type PreferenceService interface {
+	// BaseService Общий сервисный интерфейс с методами Start и Stop
+	BaseService
+
+	// FindByPersonUUID FindByPersonUUIDs - правила получателей
+	FindByPersonUUID(ctx context.Context, personUUID uuid.UUID) ([]dto.Preference, error)
+	FindByPersonUUIDs(ctx context.Context, personUUIDs []uuid.UUID) (map[uuid.UUID][]dto.Preference, error)
+	// Store Delete - изменение правил
+	Store(ctx context.Context, preference dto.Preference) (dto.Preference, error)
+	Delete(ctx context.Context, personUUID uuid.UUID, category string, channel string) error
+}
@@ -0,0 +1,22 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// SchedulerService интерфейс сервиса отложенной отправки уведомлений
+type SchedulerService interface {
+	// BaseService Общий сервисный интерфейс с методами Start и Stop
+	BaseService
+
+	// Schedule сохраняет уведомление до наступления dto.Notification.SendAt
+	Schedule(ctx context.Context, notification dto.Notification) (dto.Notification, error)
+	// All FindById Cancel - просмотр и отмена ожидающих уведомлений
+	All(ctx context.Context) []dto.Notification
+	FindById(ctx context.Context, notificationUUID uuid.UUID) (dto.Notification, error)
+	Cancel(ctx context.Context, notificationUUID uuid.UUID) error
+}
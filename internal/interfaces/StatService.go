@@ -19,4 +19,6 @@ type StatService interface {
 	Store(ctx context.Context, stat dto.Stat) error
 	FindByPersonUUID(ctx context.Context, personUUID uuid.UUID) ([]dto.Stat, error)
 	FindByNotificationId(ctx context.Context, notificationUUID uuid.UUID) ([]dto.Stat, error)
+	// FindNotificationStatus сводное состояние уведомления по получателям и каналам
+	FindNotificationStatus(ctx context.Context, notificationUUID uuid.UUID) (dto.NotificationStatus, error)
 }
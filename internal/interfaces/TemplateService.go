@@ -18,8 +18,17 @@ type TemplateService interface {
 	All(ctx context.Context) []dto.Template
 	Store(ctx context.Context, template dto.Template) (dto.Template, error)
 	StoreBatch(ctx context.Context, templates []dto.Template) ([]dto.Template, error)
-	Update(ctx context.Context, template dto.Template) (dto.Template, error)
+	Update(ctx context.Context, template dto.Template) (dto.TemplateVersion, error)
 	FindById(ctx context.Context, templateUUID uuid.UUID) (dto.Template, error)
 	FindByEventId(ctx context.Context, eventUUID uuid.UUID) ([]dto.Template, error)
 	DeleteById(ctx context.Context, templateUUID uuid.UUID) error
+
+	// Versions Diff Publish Rollback - история версий шаблона
+	Versions(ctx context.Context, templateUUID uuid.UUID) ([]dto.TemplateVersion, error)
+	Diff(ctx context.Context, templateUUID uuid.UUID, from int, to int) (dto.TemplateDiff, error)
+	Publish(ctx context.Context, templateUUID uuid.UUID, version int) (dto.Template, error)
+	Rollback(ctx context.Context, templateUUID uuid.UUID, version int) (dto.Template, error)
+
+	// Render предпросмотр шаблона без отправки
+	Render(ctx context.Context, templateUUID uuid.UUID, version int, params []dto.MessageParam) (dto.TemplateRender, error)
 }
@@ -13,8 +13,9 @@ type Worker interface {
 	// failQueue - очередь записи отризательного результата
 	Start(ctx context.Context, consumeQueue string, successQueue string, failQueue string)
 
-	// Send метод отправки сообщения через сервис провайдер на внешний сервис
-	Send(ctx context.Context, message dto.Message)
+	// Send метод отправки сообщения через сервис провайдер на внешний сервис,
+	// ошибка означает, что сообщение не передано дальше и должно быть выдано повторно
+	Send(ctx context.Context, message dto.Message) error
 
 	// Stop корректная остановка воркера
 	Stop()
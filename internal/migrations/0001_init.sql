@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS events
+(
+    event_uuid            UUID PRIMARY KEY,
+    title                 TEXT   NOT NULL,
+    description           TEXT   NOT NULL DEFAULT '',
+    default_priority      BIGINT NOT NULL DEFAULT 0,
+    notification_channels TEXT[]
+);
+
+CREATE TABLE IF NOT EXISTS templates
+(
+    template_uuid UUID PRIMARY KEY,
+    event_uuid    UUID NOT NULL,
+    title         TEXT NOT NULL,
+    description   TEXT NOT NULL DEFAULT '',
+    body          TEXT NOT NULL,
+    channel_type  TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS templates_event_uuid_idx ON templates (event_uuid);
+
+CREATE TABLE IF NOT EXISTS stats
+(
+    stat_uuid         UUID PRIMARY KEY,
+    person_uuid       UUID    NOT NULL,
+    notification_uuid UUID    NOT NULL,
+    created_at        TEXT    NOT NULL DEFAULT '',
+    status            INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS stats_person_uuid_idx ON stats (person_uuid);
+CREATE INDEX IF NOT EXISTS stats_notification_uuid_idx ON stats (notification_uuid);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS scheduled_notifications
+(
+    notification_uuid UUID PRIMARY KEY,
+    send_at           TIMESTAMPTZ NOT NULL,
+    payload           JSONB       NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_notifications_send_at_idx ON scheduled_notifications (send_at);
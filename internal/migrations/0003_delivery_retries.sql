@@ -0,0 +1,11 @@
+ALTER TABLE stats
+    ADD COLUMN IF NOT EXISTS attempt INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS dead_letters
+(
+    message_uuid UUID PRIMARY KEY,
+    failed_at    TIMESTAMPTZ NOT NULL,
+    payload      JSONB       NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS dead_letters_failed_at_idx ON dead_letters (failed_at);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys
+(
+    key               TEXT PRIMARY KEY,
+    notification_uuid UUID        NOT NULL,
+    expires_at        TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,2 @@
+ALTER TABLE stats
+    ADD COLUMN IF NOT EXISTS channel TEXT NOT NULL DEFAULT '';
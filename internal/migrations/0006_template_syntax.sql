@@ -0,0 +1,2 @@
+ALTER TABLE templates
+    ADD COLUMN IF NOT EXISTS syntax TEXT NOT NULL DEFAULT '';
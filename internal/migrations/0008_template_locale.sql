@@ -0,0 +1,2 @@
+ALTER TABLE templates
+    ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,32 @@
+ALTER TABLE templates
+    ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS template_versions
+(
+    template_uuid UUID        NOT NULL,
+    version       INTEGER     NOT NULL,
+    state         TEXT        NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL,
+    payload       JSONB       NOT NULL,
+    PRIMARY KEY (template_uuid, version)
+);
+
+-- существующие шаблоны становятся первой опубликованной версией
+INSERT INTO template_versions (template_uuid, version, state, created_at, payload)
+SELECT template_uuid,
+       1,
+       'published',
+       now(),
+       jsonb_build_object(
+               'template_uuid', template_uuid,
+               'event_uuid', event_uuid,
+               'title', title,
+               'description', description,
+               'body', body,
+               'channel_type', channel_type,
+               'syntax', syntax,
+               'required_params', COALESCE(to_jsonb(required_params), '[]'::jsonb),
+               'locale', locale,
+               'version', 1)
+FROM templates
+ON CONFLICT DO NOTHING;
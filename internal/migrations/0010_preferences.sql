@@ -0,0 +1,13 @@
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS preferences
+(
+    person_uuid  UUID        NOT NULL,
+    category     TEXT        NOT NULL DEFAULT '',
+    channel      TEXT        NOT NULL DEFAULT '',
+    mode         TEXT        NOT NULL,
+    min_priority BIGINT      NOT NULL DEFAULT 0,
+    updated_at   TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (person_uuid, category, channel)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS fallback JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,2 @@
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS delivery_windows JSONB NOT NULL DEFAULT '[]';
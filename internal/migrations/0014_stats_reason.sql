@@ -0,0 +1,2 @@
+ALTER TABLE stats
+    ADD COLUMN IF NOT EXISTS reason TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+// Package migrations SQL миграции схемы PostgreSQL хранилищ сервисов.
+// Новые миграции добавляются файлами NNNN_description.sql, примененные файлы не редактируются.
+package migrations
+
+import "embed"
+
+// FS встроенные в бинарник файлы миграций
+//
+//go:embed *.sql
+var FS embed.FS
@@ -2,33 +2,56 @@ package notify
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
+	"path/filepath"
 
 	"github.com/atrian/go-notify-customer/config"
 	"github.com/atrian/go-notify-customer/internal/dto"
 	"github.com/atrian/go-notify-customer/internal/interfaces"
+	"github.com/atrian/go-notify-customer/internal/migrations"
 	"github.com/atrian/go-notify-customer/internal/notify/handlers"
 	"github.com/atrian/go-notify-customer/internal/notify/router"
+	"github.com/atrian/go-notify-customer/internal/services/deadLetter"
 	"github.com/atrian/go-notify-customer/internal/services/event"
+	"github.com/atrian/go-notify-customer/internal/services/idempotency"
 	"github.com/atrian/go-notify-customer/internal/services/notificationDispatcher"
 	"github.com/atrian/go-notify-customer/internal/services/notify"
+	"github.com/atrian/go-notify-customer/internal/services/preference"
+	"github.com/atrian/go-notify-customer/internal/services/scheduler"
 	"github.com/atrian/go-notify-customer/internal/services/stat"
 	"github.com/atrian/go-notify-customer/internal/services/template"
 	"github.com/atrian/go-notify-customer/internal/workers"
-	"github.com/atrian/go-notify-customer/pkg/ampq"
+	"github.com/atrian/go-notify-customer/pkg/bus"
 	"github.com/atrian/go-notify-customer/pkg/logger"
+	"github.com/atrian/go-notify-customer/pkg/postgres"
 )
 
 type App struct {
 	services         services
+	db               *sql.DB
 	config           config.Config
 	notificationChan chan dto.Notification
 	statChan         chan dto.Stat
+	newBus           func() interfaces.Bus      // newBus шина сообщений для сервиса или воркера
+	rateLimiter      *notify.TokenBucketLimiter // rateLimiter ограничитель частоты приема и переходов в резервный канал
 	logger           interfaces.Logger
 }
 
+// storages хранилища CRUD сервисов
+type storages struct {
+	db          *sql.DB
+	event       event.Storager
+	template    template.Storager
+	stat        stat.Storager
+	scheduler   scheduler.Storager
+	deadLetter  deadLetter.Storager
+	idempotency idempotency.Storager
+	preference  preference.Storager
+}
+
 // services - регистр всех доступных сервисов
 type services struct {
 	notificationService    interfaces.NotificationService         // notificationService приоритезация и органичение уведомлений
@@ -36,6 +59,9 @@ type services struct {
 	eventService           interfaces.EventService                // eventService CRUD сервис для бизнес событий
 	templateService        interfaces.TemplateService             // templateService CRUD сервис для шаблонов событий
 	statisticService       interfaces.StatService                 // statisticService сервис статистики отправки
+	schedulerService       interfaces.SchedulerService            // schedulerService отложенная отправка уведомлений
+	deadLetterService      interfaces.DeadLetterService           // deadLetterService сообщения исчерпавшие попытки отправки
+	preferenceService      interfaces.PreferenceService           // preferenceService предпочтения получателей по категориям и каналам
 }
 
 func New() App {
@@ -51,18 +77,42 @@ func New() App {
 	// канал для передачи статистики отправки
 	statChan := make(chan dto.Stat)
 
+	// хранилища: PostgreSQL при заданном NC_DATABASE_DSN, иначе in-memory
+	appStorages := newStorages(appConf.GetDatabaseDSN(), appLogger)
+
 	// Подготовка зависимостей сервисов
-	ampqClient := ampq.New("", appLogger)
-	notificationService := notify.New(notificationChan, appLogger)
-	eventService := event.New(appLogger)
-	templateService := template.New(appLogger)
-	statisticService := stat.New(statChan, appLogger)
+	newBus := newBusFactory(&appConf, appLogger)
+	eventService := event.NewWithStorage(appStorages.event, appLogger)
+	rateLimiter := notify.NewTokenBucketLimiter(&appConf, eventService, appLogger)
+	schedulerService := scheduler.NewWithStorage(&appConf, appStorages.scheduler, appLogger)
+	idempotencyKeys := idempotency.NewWithStorage(&appConf, appStorages.idempotency)
+	templateService := template.NewWithStorage(appStorages.template, appLogger)
+	// журнал принятых уведомлений общий для приема и диспетчера
+	journal := newJournal(appConf.GetIngestJournalPath(), appLogger)
+	notificationService := notify.New(notificationChan, rateLimiter, schedulerService, idempotencyKeys, appLogger).
+		SetStatChan(statChan).
+		SetTemplates(templateService).
+		SetEvents(eventService).
+		SetJournal(journal).
+		SetQueuePolicy(newQueuePolicy(&appConf, appLogger))
+	// наступившие отложенные уведомления проходят через журнал и очередь приема
+	schedulerService.SetEnqueuer(notificationService)
+	// статистику отправки воркеры публикуют в очередь результатов
+	statisticService := stat.NewWithStorage(statChan, appStorages.stat, appLogger).
+		SetBus(newBus(), appConf.GetStatQueue())
+
+	preferenceService := preference.NewWithStorage(appStorages.preference, appLogger)
 
 	contactVault := notificationDispatcher.NewContactVaultClient(&appConf, appLogger)
-	serviceFacade := notificationDispatcher.NewDispatcherServiceFacade(contactVault, templateService, eventService)
-	dispatcherService := notificationDispatcher.New(notificationChan, &appConf, serviceFacade, ampqClient, appLogger)
+	serviceFacade := notificationDispatcher.NewDispatcherServiceFacade(contactVault, templateService, eventService).
+		SetPreferences(preferenceService)
+	dispatcherService := notificationDispatcher.New(notificationChan, &appConf, serviceFacade, newBus(), appLogger).
+		SetStatChan(statChan).
+		SetJournal(journal)
+	deadLetterService := deadLetter.NewWithStorage(&appConf, appStorages.deadLetter, newBus(), appLogger)
 
 	return App{
+		db:     appStorages.db,
 		config: appConf,
 		services: services{
 			notificationService:    notificationService,
@@ -70,9 +120,14 @@ func New() App {
 			eventService:           eventService,
 			templateService:        templateService,
 			statisticService:       statisticService,
+			schedulerService:       schedulerService,
+			deadLetterService:      deadLetterService,
+			preferenceService:      preferenceService,
 		},
 		notificationChan: notificationChan,
 		statChan:         statChan,
+		newBus:           newBus,
+		rateLimiter:      rateLimiter,
 		logger:           appLogger,
 	}
 }
@@ -88,9 +143,14 @@ func (a App) Run(ctx context.Context) {
 	a.services.eventService.Start(ctx)
 	a.services.templateService.Start(ctx)
 	a.services.statisticService.Start(ctx)
+	a.services.schedulerService.Start(ctx)
+	a.services.deadLetterService.Start(ctx)
+	a.services.preferenceService.Start(ctx)
 
-	// запуск фоновых воркеров
-	a.StartWorkers(ctx)
+	// запуск фоновых воркеров, если отправку не выполняют отдельные процессы cmd/worker
+	if a.config.IsEmbeddedWorkers() {
+		a.StartWorkers(ctx)
+	}
 
 	// подготовка роутера для http сервера, передаем хендлерам сервисы
 	// и логгер
@@ -100,7 +160,10 @@ func (a App) Run(ctx context.Context) {
 		a.services.notificationService,
 		a.services.statisticService,
 		a.services.templateService,
-		a.logger)
+		a.logger).
+		SetScheduler(a.services.schedulerService).
+		SetDeadLetter(a.services.deadLetterService).
+		SetPreferences(a.services.preferenceService)
 
 	routes := router.New(h, &a.config)
 
@@ -112,26 +175,157 @@ func (a App) Run(ctx context.Context) {
 }
 
 func (a App) Stop() {
+	// планировщик передает наступившие уведомления сервису приема, останавливаем до него
+	a.services.schedulerService.Stop()
 	a.services.notificationService.Stop()
 	a.services.eventService.Stop()
 	a.services.templateService.Stop()
 	a.services.statisticService.Stop()
 	a.services.notificationDispatcher.Stop()
+	a.services.deadLetterService.Stop()
+	a.services.preferenceService.Stop()
+
+	if a.db != nil {
+		if err := a.db.Close(); err != nil {
+			a.logger.Error("Database close err", err)
+		}
+	}
+
 	a.logger.Info("All services stopped")
 }
 
+// connectBus подключенная шина сообщений для воркера
+func (a App) connectBus() interfaces.Bus {
+	messageBus := a.newBus()
+	if err := messageBus.Connect(); err != nil {
+		a.logger.Error("Workers bus.Connect err", err)
+	}
+
+	return messageBus
+}
+
+// newBusFactory фабрика шины сообщений по NC_BUS, неизвестная шина - фатальная ошибка
+func newBusFactory(conf *config.Config, logger interfaces.Logger) func() interfaces.Bus {
+	newBus, err := bus.NewFactory(conf, logger)
+	if err != nil {
+		logger.Fatal("Message bus config err", err)
+	}
+
+	if conf.GetBus() == bus.Memory {
+		logger.Info("Message bus is in-memory, messages do not survive restart")
+	}
+
+	return newBus
+}
+
+// newQueuePolicy политика очереди уведомлений по конфигурации, неизвестная политика - фатальная ошибка
+func newQueuePolicy(conf *config.Config, logger interfaces.Logger) notify.QueuePolicy {
+	policy, err := notify.NewQueuePolicy(conf)
+	if err != nil {
+		logger.Fatal("Queue policy config err", err)
+	}
+
+	return policy
+}
+
+// newJournal открывает файловый журнал принятых уведомлений.
+// При пустом пути возвращает журнал в памяти
+func newJournal(path string, logger interfaces.Logger) notify.Journal {
+	if path == "" {
+		logger.Info("Ingest journal path is empty, using in-memory journal")
+		return notify.NewMemoryJournal()
+	}
+
+	// относительный путь разрешается от рабочего каталога процесса
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		logger.Fatal("Ingest journal path err", err)
+	}
+	logger.Info(fmt.Sprintf("Ingest journal path: %v", absPath))
+
+	journal, err := notify.NewFileJournal(absPath, logger)
+	if err != nil {
+		logger.Fatal("Ingest journal open err", err)
+	}
+
+	return journal
+}
+
+// newStorages подключает PostgreSQL и применяет миграции схемы.
+// При пустом dsn возвращает in-memory хранилища, данные не переживают перезапуск
+func newStorages(dsn string, logger interfaces.Logger) storages {
+	if dsn == "" {
+		logger.Info("Database DSN is empty, using in-memory storages")
+		return storages{
+			event:       event.NewMemoryStorage(),
+			template:    template.NewMemoryStorage(),
+			stat:        stat.NewMemoryStorage(),
+			scheduler:   scheduler.NewMemoryStorage(),
+			deadLetter:  deadLetter.NewMemoryStorage(),
+			idempotency: idempotency.NewMemoryStorage(),
+			preference:  preference.NewMemoryStorage(),
+		}
+	}
+
+	ctx := context.Background()
+
+	db, err := postgres.Connect(ctx, dsn)
+	if err != nil {
+		logger.Fatal("Database connection err", err)
+	}
+
+	if err = postgres.Migrate(ctx, db, migrations.FS); err != nil {
+		logger.Fatal("Database migration err", err)
+	}
+
+	logger.Info("Database connected, migrations applied")
+
+	return storages{
+		db:          db,
+		event:       event.NewPgStorage(db),
+		template:    template.NewPgStorage(db),
+		stat:        stat.NewPgStorage(db),
+		scheduler:   scheduler.NewPgStorage(db),
+		deadLetter:  deadLetter.NewPgStorage(db),
+		idempotency: idempotency.NewPgStorage(db),
+		preference:  preference.NewPgStorage(db),
+	}
+}
+
 // StartWorkers запуск фоновых воркеров непосредственной отправки сообщений
 func (a App) StartWorkers(ctx context.Context) {
 	var (
-		ampqClient    interfaces.AmpqClient
+		workerBus     interfaces.Bus
 		channelWorker interfaces.Worker
+		retryBus      interfaces.Bus
+		retryWorker   interfaces.BaseService
 	)
 
-	ampqClient = ampq.NewWithConnection(a.config.GetAmpqDSN(), a.logger)
-	channelWorker = workers.NewChannelWorker(ctx, &a.config, ampqClient, a.statChan, a.logger)
+	queues, err := a.config.GetTopology().ChannelQueues(a.config.GetWorkerChannels())
+	if err != nil {
+		a.logger.Fatal("Worker channels config err", err)
+	}
+
+	workerBus = a.connectBus()
+	channelWorker = workers.NewChannelWorker(ctx, &a.config, workerBus, a.logger).
+		SetLimiter(a.rateLimiter)
+
+	// воркер потребляет очереди выбранных каналов топологии, каждый канал своим потребителем
+	for _, queue := range queues {
+		go channelWorker.Start(ctx, queue, a.config.GetStatQueue(), a.config.GetFailedWorksQueue())
+	}
+
+	go func() {
+		<-ctx.Done()
+		channelWorker.Stop()
+	}()
+
+	// воркер повторов возвращает упавшие сообщения в очередь отправки после задержки
+	retryBus = a.connectBus()
+	retryWorker = workers.NewRetryWorker(&a.config, retryBus, a.logger)
 
 	go func() {
-		channelWorker.Start(ctx, a.config.GetNotificationQueue(), "", a.config.GetFailedWorksQueue())
-		defer channelWorker.Stop()
+		retryWorker.Start(ctx)
+		defer retryWorker.Stop()
 	}()
 }
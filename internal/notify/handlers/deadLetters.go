@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	deadLetterErrors "github.com/atrian/go-notify-customer/internal/services/deadLetter"
+)
+
+// DeadLettersResult результат массовой операции над недоставленными сообщениями
+type DeadLettersResult struct {
+	Count int `json:"count"` // Count количество обработанных сообщений
+}
+
+// GetDeadLetters запрос недоставленных сообщений GET /api/v1/dead-letters
+//
+//	@Tags DeadLetters
+//	@Summary Запрос сообщений исчерпавших попытки отправки
+//	@Produce json
+//	@Success 200 {array} dto.Message
+//	@Failure 500
+//	@Router /api/v1/dead-letters [get]
+func (h *Handler) GetDeadLetters() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		messages := h.services.deadLetter.All(r.Context())
+
+		h.writeJSON(w, messages)
+	}
+}
+
+// GetDeadLetter запрос недоставленного сообщения GET /api/v1/dead-letters/{UUID-v4}
+//
+//	@Tags DeadLetters
+//	@Summary Запрос недоставленного сообщения
+//	@Produce json
+//	@Param message_uuid path string true "ID сообщения в формате UUID v4"
+//	@Success 200 {object} dto.Message
+//	@Failure 400
+//	@Failure 404
+//	@Failure 500
+//	@Router /api/v1/dead-letters/{message_uuid} [get]
+func (h *Handler) GetDeadLetter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		messageUUID, ok := h.parseMessageUUID(w, r)
+		if !ok {
+			return
+		}
+
+		message, err := h.services.deadLetter.FindById(r.Context(), messageUUID)
+		if err != nil {
+			h.deadLetterError(w, err)
+			return
+		}
+
+		h.writeJSON(w, message)
+	}
+}
+
+// ReplayDeadLetter повторная отправка сообщения POST /api/v1/dead-letters/{UUID-v4}/replay
+//
+//	@Tags DeadLetters
+//	@Summary Повторная отправка недоставленного сообщения со сбросом счетчика попыток
+//	@Param message_uuid path string true "ID сообщения в формате UUID v4"
+//	@Success 200
+//	@Failure 400
+//	@Failure 404
+//	@Failure 409
+//	@Failure 500
+//	@Router /api/v1/dead-letters/{message_uuid}/replay [post]
+func (h *Handler) ReplayDeadLetter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		messageUUID, ok := h.parseMessageUUID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := h.services.deadLetter.Replay(r.Context(), messageUUID); err != nil {
+			h.deadLetterError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		h.logger.Debug("Request OK")
+	}
+}
+
+// ReplayDeadLetters повторная отправка всех сообщений POST /api/v1/dead-letters/replay
+//
+//	@Tags DeadLetters
+//	@Summary Повторная отправка всех недоставленных сообщений
+//	@Produce json
+//	@Success 200 {object} handlers.DeadLettersResult
+//	@Failure 500
+//	@Router /api/v1/dead-letters/replay [post]
+func (h *Handler) ReplayDeadLetters() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		count, err := h.services.deadLetter.ReplayAll(r.Context())
+		if err != nil {
+			h.logger.Error("ReplayDeadLetters err", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, DeadLettersResult{Count: count})
+	}
+}
+
+// PurgeDeadLetter удаление сообщения без отправки DELETE /api/v1/dead-letters/{UUID-v4}
+//
+//	@Tags DeadLetters
+//	@Summary Удаление недоставленного сообщения
+//	@Param message_uuid path string true "ID сообщения в формате UUID v4"
+//	@Success 200
+//	@Failure 400
+//	@Failure 404
+//	@Failure 500
+//	@Router /api/v1/dead-letters/{message_uuid} [delete]
+func (h *Handler) PurgeDeadLetter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		messageUUID, ok := h.parseMessageUUID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := h.services.deadLetter.Purge(r.Context(), messageUUID); err != nil {
+			h.deadLetterError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		h.logger.Debug("Request OK")
+	}
+}
+
+// PurgeDeadLetters удаление всех сообщений DELETE /api/v1/dead-letters
+//
+//	@Tags DeadLetters
+//	@Summary Удаление всех недоставленных сообщений
+//	@Produce json
+//	@Success 200 {object} handlers.DeadLettersResult
+//	@Failure 500
+//	@Router /api/v1/dead-letters [delete]
+func (h *Handler) PurgeDeadLetters() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		count, err := h.services.deadLetter.PurgeAll(r.Context())
+		if err != nil {
+			h.logger.Error("PurgeDeadLetters err", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, DeadLettersResult{Count: count})
+	}
+}
+
+// parseMessageUUID разбор UUID сообщения из маршрута, при ошибке отвечает 400
+func (h *Handler) parseMessageUUID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	messageUUID, err := uuid.Parse(chi.URLParam(r, "messageUUID"))
+	if err != nil {
+		h.logger.Error("Parse messageUUID err", err)
+		http.Error(w, "Bad messageUUID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	return messageUUID, true
+}
+
+// deadLetterError ответ на ошибку сервиса недоставленных сообщений
+func (h *Handler) deadLetterError(w http.ResponseWriter, err error) {
+	if errors.Is(err, deadLetterErrors.NotFound) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	// канал сообщения убран из топологии, сообщение остается в недоставленных
+	if errors.Is(err, dto.ErrUnknownChannel) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	h.logger.Error("DeadLetter service err", err)
+	http.Error(w, "Internal error", http.StatusInternalServerError)
+}
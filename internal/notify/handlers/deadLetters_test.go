@@ -0,0 +1,71 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/notify/handlers"
+	"github.com/atrian/go-notify-customer/internal/notify/router"
+	"github.com/atrian/go-notify-customer/internal/services/deadLetter"
+	"github.com/atrian/go-notify-customer/pkg/bus"
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+func ExampleHandler_ReplayDeadLetter() {
+	// Подготавливаем все зависимости, логгер, конфигурацию приложения, хранилище (In Memory) и роутер
+	appLogger := logger.NewZapLogger()
+	appConf := mockHandlerConfig{}
+
+	// сообщение исчерпавшее попытки отправки
+	storage := deadLetter.NewMemoryStorage()
+	message := dto.Message{
+		MessageUUID:      uuid.New(),
+		NotificationUUID: uuid.New(),
+		Channel:          "sms",
+		Attempt:          5,
+		LastError:        "provider unavailable",
+	}
+	_ = storage.Store(context.Background(), message)
+
+	deadLetterService := deadLetter.NewWithStorage(&appConf, storage, bus.NewMemoryBus(appLogger), appLogger)
+
+	h := handlers.New(&appConf, nil, nil, nil, nil, appLogger).SetDeadLetter(deadLetterService)
+	r := router.New(h, &appConf)
+
+	// Запускаем тестовый сервер
+	testServer := httptest.NewServer(r)
+	defer testServer.Close()
+
+	// Запрос недоставленных сообщений
+	response, err := http.Get(testServer.URL + "/api/v1/dead-letters")
+	if err != nil {
+		appLogger.Error("http.Get err", err)
+	}
+
+	var messages []dto.Message
+	_ = json.NewDecoder(response.Body).Decode(&messages)
+	_ = response.Body.Close()
+	fmt.Println(response.StatusCode, len(messages), messages[0].LastError)
+
+	// Повторная отправка, после которой сообщение удаляется из недоставленных
+	endpoint := testServer.URL + "/api/v1/dead-letters/" + message.MessageUUID.String() + "/replay"
+	for i := 0; i < 2; i++ {
+		response, err = http.Post(endpoint, "application/json", nil)
+		if err != nil {
+			appLogger.Error("http.Post err", err)
+		}
+		_ = response.Body.Close()
+		fmt.Println(response.StatusCode)
+	}
+
+	// Output:
+	// 200 1 provider unavailable
+	// 200
+	// 404
+}
@@ -26,6 +26,7 @@ import (
 //	@Success 200 {object} dto.Event
 //	@Failure 400
 //	@Failure 404
+//	@Failure 422
 //	@Failure 500
 //	@Router /api/v1/events/{event_uuid} [put]
 func (h *Handler) UpdateEvent() http.HandlerFunc {
@@ -53,6 +54,10 @@ func (h *Handler) UpdateEvent() http.HandlerFunc {
 			Description:          event.Description,
 			DefaultPriority:      event.DefaultPriority,
 			NotificationChannels: event.NotificationChannels,
+			Category:             event.Category,
+			Fallback:             event.Fallback,
+			DeliveryWindows:      event.DeliveryWindows,
+			RoutingRules:         event.RoutingRules,
 		}
 
 		result, err := h.services.event.Update(context.Background(), eventForUpdate)
@@ -63,6 +68,11 @@ func (h *Handler) UpdateEvent() http.HandlerFunc {
 				return
 			}
 
+			if invalidEvent(err) {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+
 			http.Error(w, "Bad JSON", http.StatusInternalServerError)
 			return
 		}
@@ -88,6 +98,7 @@ func (h *Handler) UpdateEvent() http.HandlerFunc {
 //	@Param event body dto.IncomingEvent true "Принимает dto события, отдает сохраненное событие с идентификатором"
 //	@Success 200 {object} dto.Event
 //	@Failure 400
+//	@Failure 422
 //	@Failure 500
 //	@Router /api/v1/events [post]
 func (h *Handler) StoreEvent() http.HandlerFunc {
@@ -102,6 +113,11 @@ func (h *Handler) StoreEvent() http.HandlerFunc {
 		result, err := h.services.event.Store(context.Background(), event)
 
 		if err != nil {
+			if invalidEvent(err) {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+
 			http.Error(w, "Bad JSON", http.StatusInternalServerError)
 			return
 		}
@@ -230,6 +246,13 @@ func (h *Handler) GetEvents() http.HandlerFunc {
 }
 
 // unmarshallEvent анмаршаллинг бизнес события
+// invalidEvent ошибка проверки настроек события: цепочки, окон доставки или правил маршрутизации
+func invalidEvent(err error) bool {
+	return errors.Is(err, eventErrors.InvalidFallback) ||
+		errors.Is(err, eventErrors.InvalidDeliveryWindow) ||
+		errors.Is(err, eventErrors.InvalidRoutingRule)
+}
+
 func (h *Handler) unmarshallEvent(r *http.Request) (dto.Event, error) {
 	var body io.Reader
 
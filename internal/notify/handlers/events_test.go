@@ -173,7 +173,7 @@ func ExampleHandler_GetEvent() {
 	fmt.Println(response.StatusCode, getResult)
 
 	// Output:
-	// 200 {00000000-0000-0000-0000-000000000000 Title Description 0 []}
+	// 200 {00000000-0000-0000-0000-000000000000 Title Description 0 []  [] [] []}
 }
 
 func ExampleHandler_GetEvents() {
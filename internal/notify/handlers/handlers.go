@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"compress/gzip"
+	"encoding/json"
 	"io"
+	"net/http"
 
 	"github.com/atrian/go-notify-customer/internal/interfaces"
 )
@@ -18,10 +20,13 @@ type handlerConfig interface {
 }
 
 type services struct {
-	event    interfaces.EventService
-	notify   interfaces.NotificationService
-	stat     interfaces.StatService
-	template interfaces.TemplateService
+	event      interfaces.EventService
+	notify     interfaces.NotificationService
+	stat       interfaces.StatService
+	template   interfaces.TemplateService
+	scheduler  interfaces.SchedulerService
+	deadLetter interfaces.DeadLetterService
+	preference interfaces.PreferenceService
 }
 
 func New(
@@ -47,6 +52,24 @@ func New(
 	return &h
 }
 
+// SetScheduler подключение сервиса отложенных уведомлений
+func (h *Handler) SetScheduler(scheduler interfaces.SchedulerService) *Handler {
+	h.services.scheduler = scheduler
+	return h
+}
+
+// SetDeadLetter подключение сервиса недоставленных сообщений
+func (h *Handler) SetDeadLetter(deadLetter interfaces.DeadLetterService) *Handler {
+	h.services.deadLetter = deadLetter
+	return h
+}
+
+// SetPreferences подключение сервиса предпочтений получателей
+func (h *Handler) SetPreferences(preference interfaces.PreferenceService) *Handler {
+	h.services.preference = preference
+	return h
+}
+
 // decodeGzipBody распаковка GZIP тела запроса
 func (h *Handler) decodeGzipBody(gzipR io.Reader) io.Reader {
 	gz, err := gzip.NewReader(gzipR)
@@ -55,3 +78,20 @@ func (h *Handler) decodeGzipBody(gzipR io.Reader) io.Reader {
 	}
 	return gz
 }
+
+// writeJSON ответ 200 с телом в json
+func (h *Handler) writeJSON(w http.ResponseWriter, body any) {
+	h.logger.Debug("Request OK")
+	h.writeJSONStatus(w, http.StatusOK, body)
+}
+
+// writeJSONStatus ответ JSON с произвольным кодом, например детали ошибки валидации
+func (h *Handler) writeJSONStatus(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("content-type", h.conf.GetDefaultResponseContentType())
+	w.WriteHeader(status)
+
+	jsonEncErr := json.NewEncoder(w).Encode(body)
+	if jsonEncErr != nil {
+		h.logger.Error("json.NewEncoder err", jsonEncErr)
+	}
+}
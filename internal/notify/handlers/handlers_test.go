@@ -5,9 +5,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/atrian/go-notify-customer/internal/dto"
 	"github.com/atrian/go-notify-customer/internal/notify/handlers"
 	"github.com/atrian/go-notify-customer/internal/notify/router"
 	"github.com/atrian/go-notify-customer/internal/services/event"
@@ -25,6 +27,30 @@ func (m *mockHandlerConfig) GetDefaultResponseContentType() string {
 	return "application/json"
 }
 
+func (m *mockHandlerConfig) GetSchedulerInterval() time.Duration {
+	return 10 * time.Millisecond
+}
+
+func (m *mockHandlerConfig) GetSchedulerBatchSize() int {
+	return 10
+}
+
+func (m *mockHandlerConfig) GetAmpqDSN() string {
+	return ""
+}
+
+func (m *mockHandlerConfig) GetTopology() dto.Topology {
+	return dto.Topology{Exchange: "notify", Channels: []string{"sms", "mail"}, MaxPriority: 10, DeadLetterQueue: "dead"}
+}
+
+func (m *mockHandlerConfig) GetDeadLetterQueue() string {
+	return "dead"
+}
+
+func (m *mockHandlerConfig) GetIdempotencyTTL() time.Duration {
+	return time.Hour
+}
+
 type subnetConf struct {
 	mockHandlerConfig
 }
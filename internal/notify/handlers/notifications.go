@@ -4,23 +4,37 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/atrian/go-notify-customer/internal/dto"
 	"github.com/atrian/go-notify-customer/internal/services/notify"
+	statErrors "github.com/atrian/go-notify-customer/internal/services/stat"
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"io"
 	"net/http"
 	"strings"
 )
 
+// idempotencyKeyHeader заголовок с ключом идемпотентности запроса
+const idempotencyKeyHeader = "Idempotency-Key"
+
 // ProcessNotifications отправка уведомлений POST /api/v1/notifications
+// Ключ идемпотентности задается полем idempotency_key уведомления или заголовком Idempotency-Key.
+// Ключ заголовка распространяется на уведомления без своего ключа в формате ключ#индекс_в_пачке.
+// Если в MessageParams нет обязательных параметров шаблонов события, пачка отклоняется с кодом 422.
+// Уведомление без приоритета получает DefaultPriority события, правила маршрутизации события
+// по MessageParams меняют приоритет и добавляют каналы отправки.
+// Код 202 возвращается после записи уведомлений в журнал, отправка выполняется в фоне
 //
 //	@Tags Notifications
 //	@Summary отправка уведомлений
 //	@Accept  json
 //	@Produce json
-//	@Param notification body []dto.IncomingNotification true "Принимает JSON dto уведомлений, возвращает код 200 при успешной постановке, 429 при привышении лимита"
-//	@Success 200
+//	@Param Idempotency-Key header string false "Ключ идемпотентности запроса, повтор в течение окна возвращает исходные уведомления"
+//	@Param notification body []dto.IncomingNotification true "Принимает JSON dto уведомлений, возвращает код 202 при успешной постановке, 429 при привышении лимита"
+//	@Success 202 {array} dto.NotificationReceipt
 //	@Failure 400
+//	@Failure 422 {array} dto.MissingParams
 //	@Failure 429
 //	@Failure 500
 //	@Router /api/v1/notifications [post]
@@ -34,31 +48,104 @@ func (h *Handler) ProcessNotifications() http.HandlerFunc {
 		}
 
 		notifications := make([]dto.Notification, 0, len(incomingNotifications))
+		requestKey := r.Header.Get(idempotencyKeyHeader)
+
+		for i, n := range incomingNotifications {
+			idempotencyKey := n.IdempotencyKey
+			if idempotencyKey == "" && requestKey != "" {
+				idempotencyKey = fmt.Sprintf("%v#%v", requestKey, i)
+			}
 
-		for _, n := range incomingNotifications {
 			notifications = append(notifications, dto.Notification{
-				EventUUID:     n.EventUUID,
-				PersonUUIDs:   n.PersonUUIDs,
-				MessageParams: n.MessageParams,
-				Priority:      n.Priority,
+				EventUUID:      n.EventUUID,
+				PersonUUIDs:    n.PersonUUIDs,
+				MessageParams:  n.MessageParams,
+				Priority:       n.Priority,
+				SendAt:         n.SendAt,
+				IdempotencyKey: idempotencyKey,
+				Locale:         n.Locale,
+				ExpiresAt:      n.ExpiresAt,
 			})
 		}
 
-		err = h.services.notify.ProcessNotification(r.Context(), notifications)
+		receipts, err := h.services.notify.ProcessNotification(r.Context(), notifications)
 		if err != nil {
 			if errors.Is(err, notify.NotificationLimitExceeded) {
 				http.Error(w, "limit exceed", http.StatusTooManyRequests)
 				return
 			}
 
+			var missingErr *notify.MissingParamsError
+			if errors.As(err, &missingErr) {
+				h.writeJSONStatus(w, http.StatusUnprocessableEntity, missingErr.Notifications)
+				return
+			}
+
 			http.Error(w, "Server side error", http.StatusInternalServerError)
 			return
 		}
 
-		w.Header().Set("content-type", h.conf.GetDefaultResponseContentType())
-		w.WriteHeader(http.StatusOK)
+		h.writeJSONStatus(w, http.StatusAccepted, receipts)
+	}
+}
+
+// GetNotificationStatus состояние уведомления GET /api/v1/notifications/{UUID-v4}
+// Состояния по получателям и каналам: accepted, dispatched, sent, failed.
+// Ожидающее отложенной отправки уведомление имеет общее состояние scheduled
+//
+//	@Tags Notifications
+//	@Summary Сводное состояние уведомления по получателям и каналам
+//	@Produce json
+//	@Param notification_uuid path string true "ID уведомления в формате UUID v4"
+//	@Success 200 {object} dto.NotificationStatus
+//	@Failure 400
+//	@Failure 404
+//	@Failure 500
+//	@Router /api/v1/notifications/{notification_uuid} [get]
+func (h *Handler) GetNotificationStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		param := chi.URLParam(r, "notificationUUID")
+
+		notificationUUID, err := uuid.Parse(param)
+		if err != nil {
+			h.logger.Error("GetNotificationStatus Parse notificationUUID err", err)
+			http.Error(w, "Bad notificationUUID", http.StatusBadRequest)
+			return
+		}
+
+		status, err := h.services.stat.FindNotificationStatus(r.Context(), notificationUUID)
+		if err != nil {
+			if errors.Is(err, statErrors.NotFound) {
+				http.Error(w, "Not found", http.StatusNotFound)
+				return
+			}
 
-		h.logger.Debug("Request OK")
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if h.services.scheduler != nil {
+			if _, schedErr := h.services.scheduler.FindById(r.Context(), notificationUUID); schedErr == nil {
+				status.State = dto.StateScheduled
+			}
+		}
+
+		h.writeJSON(w, status)
+	}
+}
+
+// GetQueueStats метрики очереди уведомлений GET /api/v1/notifications/queue
+// Длина очереди, ожидание самого давнего уведомления и время ожидания переданных диспетчеру
+// уведомлений по диапазонам приоритета. Рост ожидания в нижних диапазонах означает голодание
+//
+//	@Tags Notifications
+//	@Summary Метрики очереди уведомлений и времени ожидания по диапазонам приоритета
+//	@Produce json
+//	@Success 200 {object} dto.QueueStats
+//	@Router /api/v1/notifications/queue [get]
+func (h *Handler) GetQueueStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.writeJSON(w, h.services.notify.QueueStats())
 	}
 }
 
@@ -2,18 +2,57 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/atrian/go-notify-customer/internal/dto"
 	"github.com/atrian/go-notify-customer/internal/notify/handlers"
 	"github.com/atrian/go-notify-customer/internal/notify/router"
+	"github.com/atrian/go-notify-customer/internal/services/event"
+	"github.com/atrian/go-notify-customer/internal/services/idempotency"
 	"github.com/atrian/go-notify-customer/internal/services/notify"
+	"github.com/atrian/go-notify-customer/internal/services/scheduler"
+	"github.com/atrian/go-notify-customer/internal/services/stat"
+	"github.com/atrian/go-notify-customer/internal/services/template"
 	"github.com/atrian/go-notify-customer/pkg/logger"
 	"github.com/google/uuid"
 	"net/http"
 	"net/http/httptest"
+	"time"
 )
 
+type mockLimiterConfig struct {
+	channels []string
+}
+
+func (m *mockLimiterConfig) GetRateLimitMode() string {
+	return notify.LimitModeReject
+}
+
+func (m *mockLimiterConfig) GetRateLimitMaxDelay() time.Duration {
+	return 0
+}
+
+func (m *mockLimiterConfig) GetGlobalRateLimit() float64 {
+	return 0
+}
+
+func (m *mockLimiterConfig) GetGlobalRateBurst() int {
+	return 0
+}
+
+func (m *mockLimiterConfig) GetPersonRateLimit() float64 {
+	return 0
+}
+
+func (m *mockLimiterConfig) GetPersonRateBurst() int {
+	return 0
+}
+
+func (m *mockLimiterConfig) GetChannelRateLimits() []string {
+	return m.channels
+}
+
 func ExampleHandler_ProcessNotifications() {
 	notifications := []dto.Notification{
 		{
@@ -51,9 +90,15 @@ func ExampleHandler_ProcessNotifications() {
 		close(done)
 	}()
 
-	service := notify.New(resultChan, appLogger)
+	limiter := notify.NewTokenBucketLimiter(&mockLimiterConfig{}, nil, appLogger)
+	service := notify.New(resultChan, limiter, scheduler.New(&appConf, appLogger), idempotency.New(&appConf), appLogger)
 	getEndpoint := fmt.Sprintf("/api/v1/notifications")
 
+	// передача принятых уведомлений диспетчеру
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.Start(ctx)
+
 	h := handlers.New(&appConf, nil, service, nil, nil, appLogger)
 	r := router.New(h, &appConf)
 
@@ -71,7 +116,7 @@ func ExampleHandler_ProcessNotifications() {
 		appLogger.Error("http.DefaultClient.Do err", err)
 	}
 
-	// В случае успеха сервис отвечает кодом 200
+	// Уведомления сохранены в журнале, сервис отвечает кодом 202
 	appLogger.Debug(fmt.Sprintf("GET OK - status: %v", response.StatusCode))
 
 	// Манипуляции для отсечения изменяющегося при сохранении UUID
@@ -79,5 +124,297 @@ func ExampleHandler_ProcessNotifications() {
 	fmt.Println(response.StatusCode)
 
 	// Output:
-	// 200
+	// 202
+}
+
+func ExampleHandler_ProcessNotifications_limitExceeded() {
+	// Подготавливаем все зависимости, логгер, конфигурацию приложения, хранилище (In Memory) и роутер
+	appLogger := logger.NewZapLogger()
+	appConf := mockHandlerConfig{}
+
+	// бизнес событие с отправкой по sms, лимит sms - одно сообщение
+	eService := event.New(appLogger)
+	smsEvent, _ := eService.Store(context.Background(), dto.Event{
+		Title:                "Test",
+		NotificationChannels: []string{"sms"},
+	})
+
+	resultChan := make(chan dto.Notification, 1)
+	limiter := notify.NewTokenBucketLimiter(&mockLimiterConfig{channels: []string{"sms:0.001:1"}}, eService, appLogger)
+	service := notify.New(resultChan, limiter, scheduler.New(&appConf, appLogger), idempotency.New(&appConf), appLogger)
+
+	h := handlers.New(&appConf, eService, service, nil, nil, appLogger)
+	r := router.New(h, &appConf)
+
+	// Запускаем тестовый сервер
+	testServer := httptest.NewServer(r)
+	defer testServer.Close()
+
+	notifications := []dto.IncomingNotification{
+		{
+			EventUUID:   smsEvent.EventUUID,
+			PersonUUIDs: []uuid.UUID{uuid.New()},
+		},
+	}
+	jData, _ := json.Marshal(notifications)
+
+	// первая отправка укладывается в лимит, вторая отклоняется
+	for i := 0; i < 2; i++ {
+		request, _ := http.NewRequest(http.MethodPost, testServer.URL+"/api/v1/notifications", bytes.NewReader(jData))
+
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			appLogger.Error("http.DefaultClient.Do err", err)
+		}
+		_ = response.Body.Close()
+
+		fmt.Println(response.StatusCode)
+	}
+
+	// Output:
+	// 202
+	// 429
+}
+
+func ExampleHandler_ProcessNotifications_missingParams() {
+	// Подготавливаем все зависимости, логгер, конфигурацию приложения, хранилище (In Memory) и роутер
+	appLogger := logger.NewZapLogger()
+	appConf := mockHandlerConfig{}
+
+	// шаблон события требует параметры name и date
+	tService := template.New(appLogger)
+	smsTemplate, _ := tService.Store(context.Background(), dto.Template{
+		EventUUID:   uuid.New(),
+		Title:       "Test",
+		Body:        "Hello [name], see you at [date]",
+		ChannelType: "sms",
+	})
+
+	resultChan := make(chan dto.Notification, 1)
+	limiter := notify.NewTokenBucketLimiter(&mockLimiterConfig{}, nil, appLogger)
+	service := notify.New(resultChan, limiter, scheduler.New(&appConf, appLogger), idempotency.New(&appConf), appLogger).
+		SetTemplates(tService)
+
+	h := handlers.New(&appConf, nil, service, nil, tService, appLogger)
+	r := router.New(h, &appConf)
+
+	// Запускаем тестовый сервер
+	testServer := httptest.NewServer(r)
+	defer testServer.Close()
+
+	notifications := []dto.IncomingNotification{
+		{
+			EventUUID:     smsTemplate.EventUUID,
+			PersonUUIDs:   []uuid.UUID{uuid.New()},
+			MessageParams: []dto.MessageParam{{Key: "name", Value: "Иван"}},
+		},
+	}
+	jData, _ := json.Marshal(notifications)
+
+	request, _ := http.NewRequest(http.MethodPost, testServer.URL+"/api/v1/notifications", bytes.NewReader(jData))
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		appLogger.Error("http.DefaultClient.Do err", err)
+	}
+	defer response.Body.Close()
+
+	var missing []dto.MissingParams
+	_ = json.NewDecoder(response.Body).Decode(&missing)
+
+	// уведомление не отправлено, в ответе недостающие ключи
+	fmt.Println(response.StatusCode, missing[0].Index, missing[0].MissingParams, len(resultChan))
+
+	// Output:
+	// 422 0 [date] 0
+}
+
+func ExampleHandler_ProcessNotifications_idempotencyKey() {
+	// Подготавливаем все зависимости, логгер, конфигурацию приложения, хранилище (In Memory) и роутер
+	appLogger := logger.NewZapLogger()
+	appConf := mockHandlerConfig{}
+
+	resultChan := make(chan dto.Notification, 2)
+	limiter := notify.NewTokenBucketLimiter(&mockLimiterConfig{}, nil, appLogger)
+	service := notify.New(resultChan, limiter, scheduler.New(&appConf, appLogger), idempotency.New(&appConf), appLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.Start(ctx)
+
+	h := handlers.New(&appConf, nil, service, nil, nil, appLogger)
+	r := router.New(h, &appConf)
+
+	// Запускаем тестовый сервер
+	testServer := httptest.NewServer(r)
+	defer testServer.Close()
+
+	notifications := []dto.IncomingNotification{
+		{
+			EventUUID:   uuid.New(),
+			PersonUUIDs: []uuid.UUID{uuid.New()},
+		},
+	}
+	jData, _ := json.Marshal(notifications)
+
+	// клиент повторяет запрос после таймаута с тем же ключом
+	var receipts [2][]dto.NotificationReceipt
+	for i := 0; i < 2; i++ {
+		request, _ := http.NewRequest(http.MethodPost, testServer.URL+"/api/v1/notifications", bytes.NewReader(jData))
+		request.Header.Set("Idempotency-Key", "request-42")
+
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			appLogger.Error("http.DefaultClient.Do err", err)
+		}
+
+		_ = json.NewDecoder(response.Body).Decode(&receipts[i])
+		_ = response.Body.Close()
+
+		fmt.Println(response.StatusCode, receipts[i][0].Duplicate)
+	}
+
+	// повтор возвращает исходное уведомление и не отправляется повторно
+	sent := <-resultChan
+	fmt.Println(receipts[0][0].NotificationUUID == receipts[1][0].NotificationUUID, sent.NotificationUUID == receipts[0][0].NotificationUUID, len(resultChan))
+
+	// Output:
+	// 202 false
+	// 202 true
+	// true true 0
+}
+
+func ExampleHandler_GetNotificationStatus() {
+	// Подготавливаем все зависимости, логгер, конфигурацию приложения, хранилище (In Memory) и роутер
+	appLogger := logger.NewZapLogger()
+	appConf := mockHandlerConfig{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// сервис статистики фиксирует прием уведомления
+	statChan := make(chan dto.Stat)
+	statService := stat.New(statChan, appLogger)
+	statService.Start(ctx)
+
+	resultChan := make(chan dto.Notification, 1)
+	limiter := notify.NewTokenBucketLimiter(&mockLimiterConfig{}, nil, appLogger)
+	service := notify.New(resultChan, limiter, scheduler.New(&appConf, appLogger), idempotency.New(&appConf), appLogger).
+		SetStatChan(statChan)
+
+	h := handlers.New(&appConf, nil, service, statService, nil, appLogger)
+	r := router.New(h, &appConf)
+
+	// Запускаем тестовый сервер
+	testServer := httptest.NewServer(r)
+	defer testServer.Close()
+
+	notifications := []dto.IncomingNotification{
+		{
+			EventUUID:   uuid.New(),
+			PersonUUIDs: []uuid.UUID{uuid.New()},
+		},
+	}
+	jData, _ := json.Marshal(notifications)
+
+	response, err := http.Post(testServer.URL+"/api/v1/notifications", "application/json", bytes.NewReader(jData))
+	if err != nil {
+		appLogger.Error("http.Post err", err)
+	}
+
+	// UUID уведомления возвращается в ответе
+	var receipts []dto.NotificationReceipt
+	_ = json.NewDecoder(response.Body).Decode(&receipts)
+	_ = response.Body.Close()
+
+	response, err = http.Get(testServer.URL + "/api/v1/notifications/" + receipts[0].NotificationUUID.String())
+	if err != nil {
+		appLogger.Error("http.Get err", err)
+	}
+
+	var status dto.NotificationStatus
+	_ = json.NewDecoder(response.Body).Decode(&status)
+	_ = response.Body.Close()
+	fmt.Println(response.StatusCode, status.State, len(status.Recipients))
+
+	// Неизвестное уведомление
+	response, err = http.Get(testServer.URL + "/api/v1/notifications/" + uuid.New().String())
+	if err != nil {
+		appLogger.Error("http.Get err", err)
+	}
+	_ = response.Body.Close()
+	fmt.Println(response.StatusCode)
+
+	// Output:
+	// 200 accepted 1
+	// 404
+}
+
+func ExampleHandler_GetQueueStats() {
+	// Подготавливаем все зависимости, логгер, конфигурацию приложения, хранилище (In Memory) и роутер
+	appLogger := logger.NewZapLogger()
+	appConf := mockHandlerConfig{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resultChan := make(chan dto.Notification)
+	done := make(chan struct{})
+
+	go func() {
+		<-resultChan
+		close(done)
+	}()
+
+	limiter := notify.NewTokenBucketLimiter(&mockLimiterConfig{}, nil, appLogger)
+	service := notify.New(resultChan, limiter, scheduler.New(&appConf, appLogger), idempotency.New(&appConf), appLogger).
+		SetQueuePolicy(notify.NewAgingPolicy(1))
+	service.Start(ctx)
+
+	h := handlers.New(&appConf, nil, service, nil, nil, appLogger)
+	r := router.New(h, &appConf)
+
+	// Запускаем тестовый сервер
+	testServer := httptest.NewServer(r)
+	defer testServer.Close()
+
+	notifications := []dto.IncomingNotification{
+		{
+			EventUUID:   uuid.New(),
+			PersonUUIDs: []uuid.UUID{uuid.New()},
+			Priority:    50,
+		},
+	}
+	jData, _ := json.Marshal(notifications)
+
+	response, err := http.Post(testServer.URL+"/api/v1/notifications", "application/json", bytes.NewReader(jData))
+	if err != nil {
+		appLogger.Error("http.Post err", err)
+	}
+	_ = response.Body.Close()
+
+	// уведомление передано диспетчеру, ожидание фиксируется сразу после передачи
+	<-done
+	time.Sleep(50 * time.Millisecond)
+
+	response, err = http.Get(testServer.URL + "/api/v1/notifications/queue")
+	if err != nil {
+		appLogger.Error("http.Get err", err)
+	}
+
+	var stats dto.QueueStats
+	_ = json.NewDecoder(response.Body).Decode(&stats)
+	_ = response.Body.Close()
+
+	// уведомление с приоритетом 50 учтено в диапазоне от 10 до 100
+	fmt.Println(response.StatusCode, stats.Policy, stats.Length)
+	for _, band := range stats.Bands {
+		fmt.Println(band.MinPriority, band.Delivered)
+	}
+
+	// Output:
+	// 200 aging 0
+	// 0 0
+	// 10 1
+	// 100 0
+	// 1000 0
 }
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	preferenceErrors "github.com/atrian/go-notify-customer/internal/services/preference"
+)
+
+// GetPreferences предпочтения получателя GET /api/v1/persons/{UUID-v4}/preferences
+//
+//	@Tags Preferences
+//	@Summary Запрос предпочтений получателя по категориям событий и каналам
+//	@Produce json
+//	@Param person_uuid path string true "ID получателя в формате UUID v4"
+//	@Success 200 {array} dto.Preference
+//	@Failure 400
+//	@Failure 500
+//	@Router /api/v1/persons/{person_uuid}/preferences [get]
+func (h *Handler) GetPreferences() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		personUUID, ok := h.parsePersonUUID(w, r)
+		if !ok {
+			return
+		}
+
+		preferences, err := h.services.preference.FindByPersonUUID(r.Context(), personUUID)
+		if err != nil {
+			h.logger.Error("GetPreferences err", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, preferences)
+	}
+}
+
+// StorePreference сохранение правила получателя PUT /api/v1/persons/{UUID-v4}/preferences
+// Правило с той же категорией и каналом заменяется
+//
+//	@Tags Preferences
+//	@Summary Сохранение предпочтения получателя: opt_in, opt_out или quiet
+//	@Accept  json
+//	@Produce json
+//	@Param person_uuid path string true "ID получателя в формате UUID v4"
+//	@Param preference body dto.IncomingPreference true "Правило для категории событий и канала, пустые значения - все"
+//	@Success 200 {object} dto.Preference
+//	@Failure 400
+//	@Failure 422
+//	@Failure 500
+//	@Router /api/v1/persons/{person_uuid}/preferences [put]
+func (h *Handler) StorePreference() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		personUUID, ok := h.parsePersonUUID(w, r)
+		if !ok {
+			return
+		}
+
+		var incoming dto.IncomingPreference
+		if err := json.NewDecoder(r.Body).Decode(&incoming); err != nil {
+			h.logger.Error("StorePreference JSON decode err", err)
+			http.Error(w, "Bad JSON", http.StatusBadRequest)
+			return
+		}
+
+		result, err := h.services.preference.Store(r.Context(), dto.Preference{
+			PersonUUID:  personUUID,
+			Category:    incoming.Category,
+			Channel:     incoming.Channel,
+			Mode:        incoming.Mode,
+			MinPriority: incoming.MinPriority,
+		})
+		if err != nil {
+			if errors.Is(err, preferenceErrors.InvalidMode) {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, result)
+	}
+}
+
+// DeletePreference удаление правила получателя DELETE /api/v1/persons/{UUID-v4}/preferences?category=&channel=
+//
+//	@Tags Preferences
+//	@Summary Удаление предпочтения получателя для категории событий и канала
+//	@Param person_uuid path string true "ID получателя в формате UUID v4"
+//	@Param category query string false "Категория события, пустое значение - правило для всех категорий"
+//	@Param channel query string false "Канал отправки, пустое значение - правило для всех каналов"
+//	@Success 200
+//	@Failure 400
+//	@Failure 404
+//	@Failure 500
+//	@Router /api/v1/persons/{person_uuid}/preferences [delete]
+func (h *Handler) DeletePreference() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		personUUID, ok := h.parsePersonUUID(w, r)
+		if !ok {
+			return
+		}
+
+		query := r.URL.Query()
+		err := h.services.preference.Delete(r.Context(), personUUID, query.Get("category"), query.Get("channel"))
+		if err != nil {
+			if errors.Is(err, preferenceErrors.NotFound) {
+				http.Error(w, "Not found", http.StatusNotFound)
+				return
+			}
+
+			h.logger.Error("DeletePreference err", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		h.logger.Debug("Request OK")
+	}
+}
+
+// parsePersonUUID UUID получателя из пути, при ошибке отвечает 400
+func (h *Handler) parsePersonUUID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	personUUID, err := uuid.Parse(chi.URLParam(r, "personUUID"))
+	if err != nil {
+		h.logger.Error("Parse personUUID err", err)
+		http.Error(w, "Bad personUUID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	return personUUID, true
+}
@@ -0,0 +1,80 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/notify/handlers"
+	"github.com/atrian/go-notify-customer/internal/notify/router"
+	"github.com/atrian/go-notify-customer/internal/services/preference"
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+func ExampleHandler_StorePreference() {
+	// Подготавливаем все зависимости, логгер, конфигурацию приложения, хранилище (In Memory) и роутер
+	appLogger := logger.NewZapLogger()
+	appConf := mockHandlerConfig{}
+
+	h := handlers.New(&appConf, nil, nil, nil, nil, appLogger).
+		SetPreferences(preference.New(appLogger))
+	r := router.New(h, &appConf)
+
+	// Запускаем тестовый сервер
+	testServer := httptest.NewServer(r)
+	defer testServer.Close()
+
+	endpoint := fmt.Sprintf("%v/api/v1/persons/%v/preferences", testServer.URL, uuid.New())
+
+	// Отказ от маркетинговых рассылок по email, остальные сообщения продолжают приходить
+	for _, incoming := range []dto.IncomingPreference{
+		{Category: "marketing", Channel: "email", Mode: dto.PreferenceOptOut},
+		{Mode: "unsubscribe"},
+	} {
+		jData, _ := json.Marshal(incoming)
+		request, _ := http.NewRequest(http.MethodPut, endpoint, bytes.NewReader(jData))
+
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			appLogger.Error("http.DefaultClient.Do err", err)
+		}
+		_ = response.Body.Close()
+
+		// неизвестный режим - 422
+		fmt.Println(response.StatusCode)
+	}
+
+	response, err := http.Get(endpoint)
+	if err != nil {
+		appLogger.Error("http.Get err", err)
+	}
+
+	var preferences []dto.Preference
+	_ = json.NewDecoder(response.Body).Decode(&preferences)
+	_ = response.Body.Close()
+
+	for _, p := range preferences {
+		fmt.Println(p.Category, p.Channel, p.Mode)
+	}
+
+	// Удаление правила
+	request, _ := http.NewRequest(http.MethodDelete, endpoint+"?category=marketing&channel=email", nil)
+	response, err = http.DefaultClient.Do(request)
+	if err != nil {
+		appLogger.Error("http.DefaultClient.Do err", err)
+	}
+	_ = response.Body.Close()
+
+	fmt.Println(response.StatusCode)
+
+	// Output:
+	// 200
+	// 422
+	// marketing email opt_out
+	// 200
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	schedulerErrors "github.com/atrian/go-notify-customer/internal/services/scheduler"
+)
+
+// GetScheduledNotifications запрос ожидающих отправки уведомлений GET /api/v1/notifications/scheduled
+//
+//	@Tags Notifications
+//	@Summary Запрос ожидающих отправки отложенных уведомлений
+//	@Produce json
+//	@Success 200 {array} dto.Notification
+//	@Failure 500
+//	@Router /api/v1/notifications/scheduled [get]
+func (h *Handler) GetScheduledNotifications() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		notifications := h.services.scheduler.All(r.Context())
+
+		w.Header().Set("content-type", h.conf.GetDefaultResponseContentType())
+		w.WriteHeader(http.StatusOK)
+
+		h.logger.Debug("Request OK")
+
+		jsonEncErr := json.NewEncoder(w).Encode(notifications)
+		if jsonEncErr != nil {
+			h.logger.Error("json.NewEncoder err", jsonEncErr)
+		}
+	}
+}
+
+// GetScheduledNotification запрос отложенного уведомления GET /api/v1/notifications/scheduled/{UUID-v4}
+//
+//	@Tags Notifications
+//	@Summary Запрос отложенного уведомления
+//	@Produce json
+//	@Param notification_uuid path string true "ID уведомления в формате UUID v4"
+//	@Success 200 {object} dto.Notification
+//	@Failure 400
+//	@Failure 404
+//	@Failure 500
+//	@Router /api/v1/notifications/scheduled/{notification_uuid} [get]
+func (h *Handler) GetScheduledNotification() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		param := chi.URLParam(r, "notificationUUID")
+
+		notificationUUID, err := uuid.Parse(param)
+		if err != nil {
+			h.logger.Error("GetScheduledNotification Parse notificationUUID err", err)
+			http.Error(w, "Bad notificationUUID", http.StatusBadRequest)
+			return
+		}
+
+		notification, err := h.services.scheduler.FindById(r.Context(), notificationUUID)
+
+		if err != nil {
+			if errors.Is(err, schedulerErrors.NotFound) {
+				http.Error(w, "Not found", http.StatusNotFound)
+				return
+			}
+
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("content-type", h.conf.GetDefaultResponseContentType())
+		w.WriteHeader(http.StatusOK)
+
+		h.logger.Debug("Request OK")
+
+		jsonEncErr := json.NewEncoder(w).Encode(notification)
+		if jsonEncErr != nil {
+			h.logger.Error("json.NewEncoder err", jsonEncErr)
+		}
+	}
+}
+
+// CancelScheduledNotification отмена отложенного уведомления DELETE /api/v1/notifications/scheduled/{UUID-v4}
+//
+//	@Tags Notifications
+//	@Summary Отмена отложенного уведомления. Уже отправленное уведомление отменить нельзя
+//	@Produce json
+//	@Param notification_uuid path string true "ID уведомления в формате UUID v4"
+//	@Success 200
+//	@Failure 400
+//	@Failure 404
+//	@Failure 500
+//	@Router /api/v1/notifications/scheduled/{notification_uuid} [delete]
+func (h *Handler) CancelScheduledNotification() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		param := chi.URLParam(r, "notificationUUID")
+
+		notificationUUID, err := uuid.Parse(param)
+		if err != nil {
+			h.logger.Error("CancelScheduledNotification Parse notificationUUID err", err)
+			http.Error(w, "Bad notificationUUID", http.StatusBadRequest)
+			return
+		}
+
+		err = h.services.scheduler.Cancel(r.Context(), notificationUUID)
+
+		if err != nil {
+			if errors.Is(err, schedulerErrors.NotFound) {
+				http.Error(w, "Not found", http.StatusNotFound)
+				return
+			}
+
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("content-type", h.conf.GetDefaultResponseContentType())
+		w.WriteHeader(http.StatusOK)
+
+		h.logger.Debug("Request OK")
+	}
+}
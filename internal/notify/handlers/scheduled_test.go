@@ -0,0 +1,87 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/notify/handlers"
+	"github.com/atrian/go-notify-customer/internal/notify/router"
+	"github.com/atrian/go-notify-customer/internal/services/idempotency"
+	"github.com/atrian/go-notify-customer/internal/services/notify"
+	"github.com/atrian/go-notify-customer/internal/services/scheduler"
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+func ExampleHandler_CancelScheduledNotification() {
+	// Подготавливаем все зависимости, логгер, конфигурацию приложения, хранилище (In Memory) и роутер
+	appLogger := logger.NewZapLogger()
+	appConf := mockHandlerConfig{}
+
+	resultChan := make(chan dto.Notification)
+	schedulerService := scheduler.New(&appConf, appLogger)
+	limiter := notify.NewTokenBucketLimiter(&mockLimiterConfig{}, nil, appLogger)
+	service := notify.New(resultChan, limiter, schedulerService, idempotency.New(&appConf), appLogger)
+
+	h := handlers.New(&appConf, nil, service, nil, nil, appLogger).SetScheduler(schedulerService)
+	r := router.New(h, &appConf)
+
+	// Запускаем тестовый сервер
+	testServer := httptest.NewServer(r)
+	defer testServer.Close()
+
+	// Уведомление с отправкой завтра попадает в планировщик
+	sendAt := time.Now().Add(24 * time.Hour)
+	notifications := []dto.IncomingNotification{
+		{
+			EventUUID:   uuid.New(),
+			PersonUUIDs: []uuid.UUID{uuid.New()},
+			SendAt:      &sendAt,
+		},
+	}
+	jData, _ := json.Marshal(notifications)
+	request, _ := http.NewRequest(http.MethodPost, testServer.URL+"/api/v1/notifications", bytes.NewReader(jData))
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		appLogger.Error("http.DefaultClient.Do err", err)
+	}
+	_ = response.Body.Close()
+	fmt.Println(response.StatusCode)
+
+	// Запрос ожидающих уведомлений
+	response, err = http.Get(testServer.URL + "/api/v1/notifications/scheduled")
+	if err != nil {
+		appLogger.Error("http.Get err", err)
+	}
+
+	var scheduled []dto.Notification
+	_ = json.NewDecoder(response.Body).Decode(&scheduled)
+	_ = response.Body.Close()
+	fmt.Println(response.StatusCode, len(scheduled))
+
+	// Отмена уведомления, повторная отмена невозможна
+	endpoint := testServer.URL + "/api/v1/notifications/scheduled/" + scheduled[0].NotificationUUID.String()
+	for i := 0; i < 2; i++ {
+		request, _ = http.NewRequest(http.MethodDelete, endpoint, nil)
+
+		response, err = http.DefaultClient.Do(request)
+		if err != nil {
+			appLogger.Error("http.DefaultClient.Do err", err)
+		}
+		_ = response.Body.Close()
+		fmt.Println(response.StatusCode)
+	}
+
+	// Output:
+	// 202
+	// 200 1
+	// 200
+	// 404
+}
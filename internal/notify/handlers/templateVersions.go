@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	templateErrors "github.com/atrian/go-notify-customer/internal/services/template"
+)
+
+// GetTemplateVersions история версий шаблона GET /api/v1/templates/{UUID-v4}/versions
+//
+//	@Tags Template
+//	@Summary история версий шаблона сообщения
+//	@Produce json
+//	@Param template_uuid path string true "ID шаблона в формате UUID v4"
+//	@Success 200 {array} dto.TemplateVersion
+//	@Failure 400
+//	@Failure 404
+//	@Failure 500
+//	@Router /api/v1/templates/{template_uuid}/versions [get]
+func (h *Handler) GetTemplateVersions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		templateUUID, err := uuid.Parse(chi.URLParam(r, "templateUUID"))
+		if err != nil {
+			h.logger.Error("GetTemplateVersions Parse templateUUID", err)
+			http.Error(w, "Bad templateUUID", http.StatusBadRequest)
+			return
+		}
+
+		versions, err := h.services.template.Versions(r.Context(), templateUUID)
+		if err != nil {
+			h.templateVersionError(w, err)
+			return
+		}
+
+		h.writeJSON(w, versions)
+	}
+}
+
+// GetTemplateDiff сравнение версий шаблона GET /api/v1/templates/{UUID-v4}/versions/diff?from=1&to=2
+//
+//	@Tags Template
+//	@Summary сравнение двух версий шаблона сообщения
+//	@Produce json
+//	@Param template_uuid path string true "ID шаблона в формате UUID v4"
+//	@Param from query int true "Номер исходной версии"
+//	@Param to query int true "Номер сравниваемой версии"
+//	@Success 200 {object} dto.TemplateDiff
+//	@Failure 400
+//	@Failure 404
+//	@Failure 500
+//	@Router /api/v1/templates/{template_uuid}/versions/diff [get]
+func (h *Handler) GetTemplateDiff() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		templateUUID, err := uuid.Parse(chi.URLParam(r, "templateUUID"))
+		if err != nil {
+			h.logger.Error("GetTemplateDiff Parse templateUUID", err)
+			http.Error(w, "Bad templateUUID", http.StatusBadRequest)
+			return
+		}
+
+		from, fromErr := strconv.Atoi(r.URL.Query().Get("from"))
+		to, toErr := strconv.Atoi(r.URL.Query().Get("to"))
+		if fromErr != nil || toErr != nil {
+			http.Error(w, "Bad from or to version", http.StatusBadRequest)
+			return
+		}
+
+		diff, err := h.services.template.Diff(r.Context(), templateUUID, from, to)
+		if err != nil {
+			h.templateVersionError(w, err)
+			return
+		}
+
+		h.writeJSON(w, diff)
+	}
+}
+
+// PublishTemplateVersion публикация версии шаблона POST /api/v1/templates/{UUID-v4}/versions/{version}/publish
+// После публикации диспетчер использует содержимое этой версии
+//
+//	@Tags Template
+//	@Summary публикация версии шаблона сообщения
+//	@Produce json
+//	@Param template_uuid path string true "ID шаблона в формате UUID v4"
+//	@Param version path int true "Номер версии"
+//	@Success 200 {object} dto.Template
+//	@Failure 400
+//	@Failure 404
+//	@Failure 422
+//	@Failure 500
+//	@Router /api/v1/templates/{template_uuid}/versions/{version}/publish [post]
+func (h *Handler) PublishTemplateVersion() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		templateUUID, version, ok := h.templateVersionParams(w, r)
+		if !ok {
+			return
+		}
+
+		template, err := h.services.template.Publish(r.Context(), templateUUID, version)
+		if err != nil {
+			h.templateVersionError(w, err)
+			return
+		}
+
+		h.writeJSON(w, template)
+	}
+}
+
+// RollbackTemplate откат шаблона к версии POST /api/v1/templates/{UUID-v4}/versions/{version}/rollback
+// Содержимое версии копируется в новую опубликованную версию
+//
+//	@Tags Template
+//	@Summary откат шаблона сообщения к ранее сохраненной версии
+//	@Produce json
+//	@Param template_uuid path string true "ID шаблона в формате UUID v4"
+//	@Param version path int true "Номер версии"
+//	@Success 200 {object} dto.Template
+//	@Failure 400
+//	@Failure 404
+//	@Failure 422
+//	@Failure 500
+//	@Router /api/v1/templates/{template_uuid}/versions/{version}/rollback [post]
+func (h *Handler) RollbackTemplate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		templateUUID, version, ok := h.templateVersionParams(w, r)
+		if !ok {
+			return
+		}
+
+		template, err := h.services.template.Rollback(r.Context(), templateUUID, version)
+		if err != nil {
+			h.templateVersionError(w, err)
+			return
+		}
+
+		h.writeJSON(w, template)
+	}
+}
+
+// templateVersionParams разбор UUID шаблона и номера версии из пути, при ошибке отвечает 400
+func (h *Handler) templateVersionParams(w http.ResponseWriter, r *http.Request) (uuid.UUID, int, bool) {
+	templateUUID, err := uuid.Parse(chi.URLParam(r, "templateUUID"))
+	if err != nil {
+		h.logger.Error("Template version Parse templateUUID", err)
+		http.Error(w, "Bad templateUUID", http.StatusBadRequest)
+		return uuid.Nil, 0, false
+	}
+
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		http.Error(w, "Bad version", http.StatusBadRequest)
+		return uuid.Nil, 0, false
+	}
+
+	return templateUUID, version, true
+}
+
+// templateVersionError ответ на ошибку сервиса шаблонов
+func (h *Handler) templateVersionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, templateErrors.NotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, templateErrors.MissingDefaultLocale):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		h.logger.Error("Template version err", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+	}
+}
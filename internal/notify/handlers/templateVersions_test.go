@@ -0,0 +1,98 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/notify/handlers"
+	"github.com/atrian/go-notify-customer/internal/notify/router"
+	"github.com/atrian/go-notify-customer/internal/services/template"
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+func ExampleHandler_PublishTemplateVersion() {
+	// Подготавливаем все зависимости, логгер, конфигурацию приложения, хранилище (In Memory) и роутер
+	appLogger := logger.NewZapLogger()
+	appConf := mockHandlerConfig{}
+
+	tService := template.New(appLogger)
+	stored, _ := tService.Store(context.Background(), dto.Template{
+		EventUUID:   uuid.New(),
+		Title:       "Test",
+		Body:        "Hello",
+		ChannelType: "sms",
+	})
+
+	// Изменение шаблона сохраняется черновиком второй версии
+	stored.Body = "Hello again"
+	draft, _ := tService.Update(context.Background(), stored)
+
+	h := handlers.New(&appConf, nil, nil, nil, tService, appLogger)
+	r := router.New(h, &appConf)
+
+	// Запускаем тестовый сервер
+	testServer := httptest.NewServer(r)
+	defer testServer.Close()
+
+	versionsEndpoint := fmt.Sprintf("%v/api/v1/templates/%v/versions", testServer.URL, stored.TemplateUUID)
+
+	// Сравнение опубликованной версии с черновиком
+	response, err := http.Get(fmt.Sprintf("%v/diff?from=1&to=%d", versionsEndpoint, draft.Version))
+	if err != nil {
+		appLogger.Error("http.Get err", err)
+	}
+
+	var diff dto.TemplateDiff
+	_ = json.NewDecoder(response.Body).Decode(&diff)
+	_ = response.Body.Close()
+
+	fmt.Println(response.StatusCode, diff.Body)
+
+	// Публикация черновика
+	response, err = http.Post(fmt.Sprintf("%v/%d/publish", versionsEndpoint, draft.Version), "application/json", nil)
+	if err != nil {
+		appLogger.Error("http.Post err", err)
+	}
+
+	var published dto.Template
+	_ = json.NewDecoder(response.Body).Decode(&published)
+	_ = response.Body.Close()
+
+	fmt.Println(response.StatusCode, published.Version, published.Body)
+
+	// История версий
+	response, err = http.Get(versionsEndpoint)
+	if err != nil {
+		appLogger.Error("http.Get err", err)
+	}
+
+	var versions []dto.TemplateVersion
+	_ = json.NewDecoder(response.Body).Decode(&versions)
+	_ = response.Body.Close()
+
+	for _, version := range versions {
+		fmt.Println(version.Version, version.State)
+	}
+
+	// Публикация несуществующей версии
+	response, err = http.Post(versionsEndpoint+"/10/publish", "application/json", nil)
+	if err != nil {
+		appLogger.Error("http.Post err", err)
+	}
+	_ = response.Body.Close()
+
+	fmt.Println(response.StatusCode)
+
+	// Output:
+	// 200 [{- Hello} {+ Hello again}]
+	// 200 2 Hello again
+	// 1 archived
+	// 2 published
+	// 404
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
@@ -16,6 +17,7 @@ import (
 )
 
 // UpdateTemplate обновление шаблона сообщения PUT /api/v1/templates/{UUID-v4}
+// Изменения сохраняются новой версией-черновиком, для отправки ее нужно опубликовать
 //
 //	@Tags Template
 //	@Summary обновление шаблона сообщения
@@ -23,9 +25,10 @@ import (
 //	@Produce json
 //	@Param template_uuid path string true "ID шаблона сообщения в формате UUID v4"
 //	@Param template body dto.IncomingTemplate true "Принимает dto шаблона сообщения, возвращает JSON с обновленными данными"
-//	@Success 200 {object} dto.Template
+//	@Success 200 {object} dto.TemplateVersion
 //	@Failure 400
 //	@Failure 404
+//	@Failure 422
 //	@Failure 500
 //	@Router /api/v1/templates/{template_uuid} [put]
 func (h *Handler) UpdateTemplate() http.HandlerFunc {
@@ -52,6 +55,8 @@ func (h *Handler) UpdateTemplate() http.HandlerFunc {
 			Description:  template.Description,
 			Body:         template.Body,
 			ChannelType:  template.ChannelType,
+			Syntax:       template.Syntax,
+			Locale:       template.Locale,
 		}
 
 		result, err := h.services.template.Update(context.Background(), updateTemplate)
@@ -62,6 +67,11 @@ func (h *Handler) UpdateTemplate() http.HandlerFunc {
 				return
 			}
 
+			if errors.Is(err, templateErrors.InvalidTemplate) || errors.Is(err, templateErrors.MissingDefaultLocale) {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+
 			http.Error(w, "Bad JSON", http.StatusInternalServerError)
 			return
 		}
@@ -79,6 +89,7 @@ func (h *Handler) UpdateTemplate() http.HandlerFunc {
 }
 
 // StoreTemplate сохранение шаблона сообщения POST /api/v1/templates
+// Локализованный шаблон требует шаблон события и канала с локалью по умолчанию, иначе 422
 //
 //	@Tags Template
 //	@Summary сохранение шаблона сообщения
@@ -87,6 +98,7 @@ func (h *Handler) UpdateTemplate() http.HandlerFunc {
 //	@Param template body dto.IncomingTemplate true "Принимает dto нового шаблона сообщения, возвращает JSON сохраненными данными и идентификатором"
 //	@Success 200 {object} dto.Template
 //	@Failure 400
+//	@Failure 422
 //	@Failure 500
 //	@Router /api/v1/templates [post]
 func (h *Handler) StoreTemplate() http.HandlerFunc {
@@ -104,11 +116,18 @@ func (h *Handler) StoreTemplate() http.HandlerFunc {
 			Description: template.Description,
 			Body:        template.Body,
 			ChannelType: template.ChannelType,
+			Syntax:      template.Syntax,
+			Locale:      template.Locale,
 		}
 
 		result, err := h.services.template.Store(context.Background(), storeTemplate)
 
 		if err != nil {
+			if errors.Is(err, templateErrors.InvalidTemplate) || errors.Is(err, templateErrors.MissingDefaultLocale) {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+
 			http.Error(w, "Bad JSON", http.StatusInternalServerError)
 			return
 		}
@@ -126,6 +145,7 @@ func (h *Handler) StoreTemplate() http.HandlerFunc {
 }
 
 // DeleteTemplate удаление шаблона сообщения DELETE /api/v1/templates/{UUID-v4}
+// Шаблон с локалью по умолчанию нельзя удалить, пока у события и канала есть локализованные шаблоны - 409
 //
 //	@Tags Template
 //	@Summary удаление шаблона сообщения
@@ -134,6 +154,7 @@ func (h *Handler) StoreTemplate() http.HandlerFunc {
 //	@Success 200
 //	@Failure 400
 //	@Failure 404
+//	@Failure 409
 //	@Failure 500
 //	@Router /api/v1/templates/{template_uuid} [delete]
 func (h *Handler) DeleteTemplate() http.HandlerFunc {
@@ -155,6 +176,12 @@ func (h *Handler) DeleteTemplate() http.HandlerFunc {
 				return
 			}
 
+			// шаблон с локалью по умолчанию удаляется после локализованных
+			if errors.Is(err, templateErrors.MissingDefaultLocale) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+
 			http.Error(w, "Internal error", http.StatusInternalServerError)
 			return
 		}
@@ -291,3 +318,88 @@ func (h *Handler) unmarshallIncomingTemplate(r *http.Request) (dto.IncomingTempl
 
 	return template, nil
 }
+
+// RenderTemplate предпросмотр шаблона POST /api/v1/templates/{UUID-v4}/render
+// Возвращает текст, который диспетчер отправит с переданными параметрами, используемые и отсутствующие
+// параметры и предупреждения канала, например число частей SMS. Сообщение не отправляется.
+// Параметр version позволяет проверить черновик до публикации
+//
+//	@Tags Template
+//	@Summary предпросмотр шаблона сообщения
+//	@Accept  json
+//	@Produce json
+//	@Param template_uuid path string true "ID шаблона в формате UUID v4"
+//	@Param version query int false "Номер версии, по умолчанию опубликованная"
+//	@Param params body dto.IncomingRender true "Параметры подстановки в шаблон"
+//	@Success 200 {object} dto.TemplateRender
+//	@Failure 400
+//	@Failure 404
+//	@Failure 500
+//	@Router /api/v1/templates/{template_uuid}/render [post]
+func (h *Handler) RenderTemplate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		templateUUID, err := uuid.Parse(chi.URLParam(r, "templateUUID"))
+		if err != nil {
+			h.logger.Error("RenderTemplate cant Parse UUID", err)
+			http.Error(w, "Bad template UUID", http.StatusBadRequest)
+			return
+		}
+
+		version := 0
+		if param := r.URL.Query().Get("version"); param != "" {
+			version, err = strconv.Atoi(param)
+			if err != nil {
+				http.Error(w, "Bad version", http.StatusBadRequest)
+				return
+			}
+		}
+
+		incoming, err := h.unmarshallIncomingRender(r)
+		if err != nil {
+			h.logger.Error("RenderTemplate cant unmarshallIncomingRender", err)
+			http.Error(w, "Bad JSON", http.StatusBadRequest)
+			return
+		}
+
+		result, err := h.services.template.Render(r.Context(), templateUUID, version, incoming.MessageParams)
+		if err != nil {
+			if errors.Is(err, templateErrors.NotFound) {
+				http.Error(w, "Not found", http.StatusNotFound)
+				return
+			}
+
+			h.logger.Error("RenderTemplate err", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, result)
+	}
+}
+
+// unmarshallIncomingRender разбор параметров предпросмотра, пустое тело - без параметров
+func (h *Handler) unmarshallIncomingRender(r *http.Request) (dto.IncomingRender, error) {
+	var body io.Reader
+
+	// если в заголовках установлен Content-Encoding gzip, распаковываем тело
+	if strings.Contains(r.Header.Get("Content-Encoding"), "gzip") {
+		body = h.decodeGzipBody(r.Body)
+	} else {
+		body = r.Body
+	}
+
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			h.logger.Error("Body io.ReadCloser error", err)
+		}
+	}(r.Body)
+
+	var incoming dto.IncomingRender
+	err := json.NewDecoder(body).Decode(&incoming)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return dto.IncomingRender{}, err
+	}
+
+	return incoming, nil
+}
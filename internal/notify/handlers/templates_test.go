@@ -21,17 +21,12 @@ import (
 )
 
 func ExampleHandler_UpdateTemplate() {
-	templateUUID, _ := uuid.Parse("c10a7fa8-f162-46ce-a97e-ff8718d7eb7d")
-
-	updateEndpoint := fmt.Sprintf("/api/v1/templates/%v", templateUUID)
-
 	testTemplate := dto.Template{
-		TemplateUUID: templateUUID,
-		EventUUID:    uuid.UUID{},
-		Title:        "Test",
-		Description:  "Description",
-		Body:         "Body",
-		ChannelType:  "ChannelType",
+		EventUUID:   uuid.UUID{},
+		Title:       "Test",
+		Description: "Description",
+		Body:        "Body",
+		ChannelType: "ChannelType",
 	}
 
 	// Подготавливаем все зависимости, логгер, конфигурацию приложения, хранилище (In Memory) и роутер
@@ -39,7 +34,7 @@ func ExampleHandler_UpdateTemplate() {
 	appConf := mockHandlerConfig{}
 
 	tService := template.New(appLogger)
-	_, _ = tService.Store(context.Background(), testTemplate)
+	testTemplate, _ = tService.Store(context.Background(), testTemplate)
 
 	h := handlers.New(&appConf, nil, nil, nil, tService, appLogger)
 
@@ -49,6 +44,8 @@ func ExampleHandler_UpdateTemplate() {
 	testServer := httptest.NewServer(r)
 	defer testServer.Close()
 
+	updateEndpoint := fmt.Sprintf("/api/v1/templates/%v", testTemplate.TemplateUUID)
+
 	// Обновление данных
 	testTemplate.Title = "Updated title"
 	jData, _ := json.Marshal(testTemplate)
@@ -61,14 +58,19 @@ func ExampleHandler_UpdateTemplate() {
 		appLogger.Error("http.DefaultClient.Do err", err)
 	}
 
-	responseBody, _ := io.ReadAll(response.Body)
+	var draft dto.TemplateVersion
+	_ = json.NewDecoder(response.Body).Decode(&draft)
 	_ = response.Body.Close()
 
-	// В случае успеха сервис отвечает кодом 200 и JSON содержащим текущее значение шаблона
-	fmt.Println(response.StatusCode, string(responseBody))
+	// В случае успеха сервис отвечает кодом 200 и JSON с новой версией-черновиком,
+	// опубликованный шаблон остается прежним до публикации черновика
+	current, _ := tService.FindById(context.Background(), testTemplate.TemplateUUID)
+	fmt.Println(response.StatusCode, draft.Version, draft.State, draft.Template.Title)
+	fmt.Println(current.Version, current.Title)
 
 	// Output:
-	// 200 {"template_uuid":"c10a7fa8-f162-46ce-a97e-ff8718d7eb7d","event_uuid":"00000000-0000-0000-0000-000000000000","title":"Updated title","description":"Description","body":"Body","channel_type":"ChannelType"}
+	// 200 2 draft Updated title
+	// 1 Test
 }
 
 func ExampleHandler_DeleteTemplate() {
@@ -177,7 +179,7 @@ func ExampleHandler_GetTemplate() {
 	fmt.Println(response.StatusCode, getResult)
 
 	// Output:
-	// 200 {00000000-0000-0000-0000-000000000000 00000000-0000-0000-0000-000000000000 Test Description Body ChannelType}
+	// 200 {00000000-0000-0000-0000-000000000000 00000000-0000-0000-0000-000000000000 Test Description Body ChannelType  []  1}
 }
 
 func ExampleHandler_GetTemplates() {
@@ -306,3 +308,48 @@ func ExampleHandler_StoreTemplate() {
 	// Output:
 	// 200 TestStoreTemplateDescriptionBodyChannelType
 }
+
+func ExampleHandler_RenderTemplate() {
+	// Подготавливаем все зависимости, логгер, конфигурацию приложения, хранилище (In Memory) и роутер
+	appLogger := logger.NewZapLogger()
+	appConf := mockHandlerConfig{}
+
+	tService := template.New(appLogger)
+	stored, _ := tService.Store(context.Background(), dto.Template{
+		EventUUID:   uuid.New(),
+		Title:       "Code",
+		Body:        "Your code: [code], valid for [minutes] minutes",
+		ChannelType: "sms",
+	})
+
+	h := handlers.New(&appConf, nil, nil, nil, tService, appLogger)
+	r := router.New(h, &appConf)
+
+	// Запускаем тестовый сервер
+	testServer := httptest.NewServer(r)
+	defer testServer.Close()
+
+	renderEndpoint := fmt.Sprintf("%v/api/v1/templates/%v/render", testServer.URL, stored.TemplateUUID)
+	jData, _ := json.Marshal(dto.IncomingRender{
+		MessageParams: []dto.MessageParam{{Key: "code", Value: "1234"}},
+	})
+
+	response, err := http.Post(renderEndpoint, "application/json", bytes.NewReader(jData))
+	if err != nil {
+		appLogger.Error("http.Post err", err)
+	}
+
+	var result dto.TemplateRender
+	_ = json.NewDecoder(response.Body).Decode(&result)
+	_ = response.Body.Close()
+
+	// Сервис отвечает текстом, который будет отправлен, без отправки сообщения
+	fmt.Println(response.StatusCode, result.Text)
+	fmt.Println(result.Placeholders, result.MissingParams)
+	fmt.Println(result.SMSSegments, result.SMSEncoding)
+
+	// Output:
+	// 200 Your code: 1234, valid for minutes
+	// [code minutes] [minutes]
+	// 1 GSM-7
+}
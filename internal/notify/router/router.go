@@ -55,6 +55,19 @@ func (r *Router) RegisterRoutes(handler *handlers.Handler) *Router {
 					r.Put("/", handler.UpdateTemplate())
 					// DELETE /templates/93ebac94-cf39-4728-9bba-472ac93a4368
 					r.Delete("/", handler.DeleteTemplate())
+
+					// POST /templates/93ebac94-cf39-4728-9bba-472ac93a4368/render?version=2
+					r.Post("/render", handler.RenderTemplate())
+
+					// История версий шаблона
+					r.Route("/versions", func(r chi.Router) {
+						r.Get("/", handler.GetTemplateVersions()) // GET /templates/{uuid}/versions
+						r.Get("/diff", handler.GetTemplateDiff()) // GET /templates/{uuid}/versions/diff?from=1&to=2
+						// POST /templates/{uuid}/versions/2/publish
+						r.Post("/{version}/publish", handler.PublishTemplateVersion())
+						// POST /templates/{uuid}/versions/1/rollback
+						r.Post("/{version}/rollback", handler.RollbackTemplate())
+					})
 				})
 			})
 
@@ -87,6 +100,43 @@ func (r *Router) RegisterRoutes(handler *handlers.Handler) *Router {
 			r.Route("/notifications", func(r chi.Router) {
 				r.Post("/", handler.ProcessNotifications())
 				r.Get("/seed", handler.SeedDemoData())
+				// GET /notifications/queue
+				r.Get("/queue", handler.GetQueueStats())
+				// GET /notifications/93ebac94-cf39-4728-9bba-472ac93a4368
+				r.Get("/{notificationUUID}", handler.GetNotificationStatus())
+
+				// Отложенные уведомления
+				r.Route("/scheduled", func(r chi.Router) {
+					// GET /notifications/scheduled
+					r.Get("/", handler.GetScheduledNotifications())
+					// GET /notifications/scheduled/93ebac94-cf39-4728-9bba-472ac93a4368
+					r.Get("/{notificationUUID}", handler.GetScheduledNotification())
+					// DELETE /notifications/scheduled/93ebac94-cf39-4728-9bba-472ac93a4368
+					r.Delete("/{notificationUUID}", handler.CancelScheduledNotification())
+				})
+			})
+
+			// Предпочтения получателей по категориям и каналам
+			r.Route("/persons/{personUUID}/preferences", func(r chi.Router) {
+				r.Get("/", handler.GetPreferences())      // GET /persons/{uuid}/preferences
+				r.Put("/", handler.StorePreference())     // PUT /persons/{uuid}/preferences
+				r.Delete("/", handler.DeletePreference()) // DELETE /persons/{uuid}/preferences?category=marketing&channel=email
+			})
+
+			// Недоставленные сообщения, исчерпавшие попытки отправки
+			r.Route("/dead-letters", func(r chi.Router) {
+				r.Get("/", handler.GetDeadLetters())           // GET /dead-letters
+				r.Delete("/", handler.PurgeDeadLetters())      // DELETE /dead-letters
+				r.Post("/replay", handler.ReplayDeadLetters()) // POST /dead-letters/replay
+
+				r.Route("/{messageUUID}", func(r chi.Router) {
+					// GET /dead-letters/93ebac94-cf39-4728-9bba-472ac93a4368
+					r.Get("/", handler.GetDeadLetter())
+					// DELETE /dead-letters/93ebac94-cf39-4728-9bba-472ac93a4368
+					r.Delete("/", handler.PurgeDeadLetter())
+					// POST /dead-letters/93ebac94-cf39-4728-9bba-472ac93a4368/replay
+					r.Post("/replay", handler.ReplayDeadLetter())
+				})
 			})
 		})
 	})
@@ -0,0 +1,97 @@
+// Package routing правила маршрутизации уведомлений бизнес события.
+// Правила задаются в dto.Event.RoutingRules и по параметрам уведомления MessageParams
+// меняют приоритет и добавляют каналы отправки, например vip=true - приоритет 900 и канал sms
+package routing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// InvalidRule правило маршрутизации задано неверно
+var InvalidRule = errors.New("invalid routing rule")
+
+// Validate правило содержит условие и меняет приоритет или каналы, каналы не пустые
+func Validate(rules []dto.RoutingRule) error {
+	for i, rule := range rules {
+		if len(rule.When) == 0 {
+			return fmt.Errorf("%w: rule %d without conditions", InvalidRule, i)
+		}
+		if rule.Priority == 0 && len(rule.Channels) == 0 {
+			return fmt.Errorf("%w: rule %d changes nothing", InvalidRule, i)
+		}
+		for key := range rule.When {
+			if key == "" {
+				return fmt.Errorf("%w: rule %d empty param key", InvalidRule, i)
+			}
+		}
+		for _, channel := range rule.Channels {
+			if channel == "" {
+				return fmt.Errorf("%w: rule %d empty channel", InvalidRule, i)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Apply приоритет по умолчанию и правила события. Уведомление без приоритета получает
+// DefaultPriority события. Правила проверяются по порядку, приоритет берется из последнего
+// сработавшего правила с приоритетом, каналы сработавших правил добавляются в Channels
+func Apply(event dto.Event, notification dto.Notification) dto.Notification {
+	if notification.Priority == 0 {
+		notification.Priority = event.DefaultPriority
+	}
+
+	for _, rule := range event.RoutingRules {
+		if !matches(rule, notification.MessageParams) {
+			continue
+		}
+
+		if rule.Priority > 0 {
+			notification.Priority = rule.Priority
+		}
+		notification.Channels = Channels(notification.Channels, rule.Channels)
+	}
+
+	return notification
+}
+
+// Channels объединение списков каналов без повторов с сохранением порядка
+func Channels(channels []string, extra []string) []string {
+	if len(extra) == 0 {
+		return channels
+	}
+
+	res := make([]string, 0, len(channels)+len(extra))
+	seen := make(map[string]struct{}, len(channels)+len(extra))
+	for _, list := range [][]string{channels, extra} {
+		for _, channel := range list {
+			if _, exist := seen[channel]; exist {
+				continue
+			}
+			seen[channel] = struct{}{}
+			res = append(res, channel)
+		}
+	}
+
+	return res
+}
+
+// matches все условия правила совпадают с параметрами уведомления
+func matches(rule dto.RoutingRule, params []dto.MessageParam) bool {
+	values := make(map[string]string, len(params))
+	for _, param := range params {
+		values[param.Key] = param.Value
+	}
+
+	for key, want := range rule.When {
+		if value, exist := values[key]; !exist || value != want {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,94 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+func TestApply(t *testing.T) {
+	event := dto.Event{
+		DefaultPriority:      10,
+		NotificationChannels: []string{"mail"},
+		RoutingRules: []dto.RoutingRule{
+			{When: map[string]string{"vip": "true"}, Priority: 900, Channels: []string{"sms"}},
+			{When: map[string]string{"vip": "true", "region": "eu"}, Channels: []string{"sms", "telegram"}},
+		},
+	}
+	vip := dto.MessageParam{Key: "vip", Value: "true"}
+	eu := dto.MessageParam{Key: "region", Value: "eu"}
+
+	tests := []struct {
+		name         string
+		notification dto.Notification
+		wantPriority uint
+		wantChannels []string
+	}{
+		{
+			name:         "default priority",
+			notification: dto.Notification{},
+			wantPriority: 10,
+		}, {
+			name:         "caller priority",
+			notification: dto.Notification{Priority: 50},
+			wantPriority: 50,
+		}, {
+			name:         "rule does not match",
+			notification: dto.Notification{MessageParams: []dto.MessageParam{{Key: "vip", Value: "false"}}},
+			wantPriority: 10,
+		}, {
+			name:         "rule overrides caller priority",
+			notification: dto.Notification{Priority: 50, MessageParams: []dto.MessageParam{vip}},
+			wantPriority: 900,
+			wantChannels: []string{"sms"},
+		}, {
+			name:         "channels of all matched rules without repeats",
+			notification: dto.Notification{MessageParams: []dto.MessageParam{eu, vip}},
+			wantPriority: 900,
+			wantChannels: []string{"sms", "telegram"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Apply(event, tt.notification)
+			assert.Equal(t, tt.wantPriority, got.Priority)
+			assert.Equal(t, tt.wantChannels, got.Channels)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []dto.RoutingRule
+		wantErr bool
+	}{
+		{name: "no rules"},
+		{name: "priority", rules: []dto.RoutingRule{{When: map[string]string{"vip": "true"}, Priority: 900}}},
+		{name: "channels", rules: []dto.RoutingRule{{When: map[string]string{"vip": "true"}, Channels: []string{"sms"}}}},
+		{name: "without conditions", rules: []dto.RoutingRule{{Priority: 900}}, wantErr: true},
+		{name: "changes nothing", rules: []dto.RoutingRule{{When: map[string]string{"vip": "true"}}}, wantErr: true},
+		{name: "empty key", rules: []dto.RoutingRule{{When: map[string]string{"": "true"}, Priority: 1}}, wantErr: true},
+		{name: "empty channel", rules: []dto.RoutingRule{{When: map[string]string{"vip": "true"}, Channels: []string{""}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.rules)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, InvalidRule)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestChannels(t *testing.T) {
+	assert.Equal(t, []string{"mail", "sms"}, Channels([]string{"mail", "sms"}, nil))
+	assert.Equal(t, []string{"mail", "sms", "telegram"}, Channels([]string{"mail", "sms"}, []string{"sms", "telegram"}))
+	assert.Equal(t, []string{"sms"}, Channels(nil, []string{"sms"}))
+}
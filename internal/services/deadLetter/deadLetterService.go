@@ -0,0 +1,208 @@
+// Package deadLetter сервис недоставленных сообщений. Потребляет очередь недоставленных
+// сообщений GetDeadLetterQueue, куда воркеры помещают сообщения исчерпавшие попытки отправки,
+// а шина - сообщения с истекшим временем жизни, и сохраняет их в хранилище.
+// Сообщения можно просмотреть, повторно отправить в очередь канала или удалить.
+package deadLetter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/interfaces"
+)
+
+var _ interfaces.DeadLetterService = (*Service)(nil)
+
+// storeRetryDelay пауза перед возвратом сообщения в очередь, если хранилище недоступно
+const storeRetryDelay = time.Second
+
+// deadLetterConfig интерфейс конфигурации сервиса недоставленных сообщений
+type deadLetterConfig interface {
+	GetTopology() dto.Topology
+	GetDeadLetterQueue() string
+}
+
+// Service содержит хранилище недоставленных сообщений, шину сообщений
+// с интерфейсом interfaces.Bus и логгер с интерфейсом interfaces.Logger
+type Service struct {
+	config  deadLetterConfig
+	storage Storager
+	bus     interfaces.Bus
+	logger  interfaces.Logger
+}
+
+// New сервис с in-memory хранилищем
+func New(config deadLetterConfig, messageBus interfaces.Bus, logger interfaces.Logger) *Service {
+	return NewWithStorage(config, NewMemoryStorage(), messageBus, logger)
+}
+
+// NewWithStorage сервис с внешним хранилищем, например PgStorage
+func NewWithStorage(config deadLetterConfig, storage Storager, messageBus interfaces.Bus, logger interfaces.Logger) *Service {
+	s := Service{
+		config:  config,
+		storage: storage,
+		bus:     messageBus,
+		logger:  logger,
+	}
+
+	return &s
+}
+
+// Start подключение шины сообщений, миграция очередей и запуск потребления очереди недоставленных
+func (s *Service) Start(ctx context.Context) {
+	err := s.bus.Connect()
+	if err != nil {
+		s.logger.Error("DeadLetter bus.Connect err", err)
+	}
+
+	s.bus.MigrateDurableQueues(s.config.GetDeadLetterQueue())
+	if err = s.bus.MigrateTopology(s.config.GetTopology()); err != nil {
+		s.logger.Error("DeadLetter bus.MigrateTopology err", err)
+	}
+
+	msgs, err := s.bus.Subscribe(s.config.GetDeadLetterQueue())
+	if err != nil {
+		s.logger.Error("DeadLetter can't consume dead letter queue", err)
+		return
+	}
+
+	go func() {
+		for d := range msgs {
+			var message dto.Message
+			if jsonErr := json.Unmarshal(d.Body, &message); jsonErr != nil {
+				s.logger.Error("DeadLetter JSON unmarshall err", jsonErr)
+				s.settle(d.Nack(false))
+				continue
+			}
+
+			if message.MessageUUID == uuid.Nil {
+				message.MessageUUID = uuid.New()
+			}
+			if message.LastError == "" {
+				message.LastError = d.DeathReason
+			}
+
+			if storeErr := s.storage.Store(ctx, message); storeErr != nil {
+				s.logger.Error("DeadLetter storage.Store err", storeErr)
+				// пауза не дает зациклить возврат в очередь при недоступном хранилище
+				select {
+				case <-time.After(storeRetryDelay):
+				case <-ctx.Done():
+				}
+				s.settle(d.Nack(true))
+				continue
+			}
+			s.settle(d.Ack())
+
+			s.logger.Info(fmt.Sprintf("Dead letter stored messageUUID:%v", message.MessageUUID))
+		}
+	}()
+}
+
+// settle журналирование ошибки подтверждения сообщения
+func (s *Service) settle(err error) {
+	if err != nil {
+		s.logger.Error("DeadLetter delivery settle err", err)
+	}
+}
+
+// Stop корректное завершение работы
+func (s *Service) Stop() {
+	s.bus.Stop()
+	s.logger.Info("DeadLetter service stopped")
+}
+
+// All недоставленные сообщения, давно упавшие первыми. Без сообщений - пустой список
+func (s *Service) All(ctx context.Context) []dto.Message {
+	messages, err := s.storage.All(ctx)
+	if err != nil {
+		s.logger.Error("DeadLetter storage.All err", err)
+	}
+
+	if messages == nil {
+		return []dto.Message{}
+	}
+
+	return messages
+}
+
+func (s *Service) FindById(ctx context.Context, messageUUID uuid.UUID) (dto.Message, error) {
+	return s.storage.GetById(ctx, messageUUID)
+}
+
+// Replay возвращает сообщение в очередь канала с обнуленным счетчиком попыток.
+// Повтор оператором снимает срок актуальности сообщения.
+// Сообщение удаляется из хранилища только после успешной публикации, для канала без очереди
+// в топологии возвращается dto.ErrUnknownChannel и сообщение остается в хранилище
+func (s *Service) Replay(ctx context.Context, messageUUID uuid.UUID) error {
+	message, err := s.storage.GetById(ctx, messageUUID)
+	if err != nil {
+		return err
+	}
+
+	topology := s.config.GetTopology()
+	// exchange молча отбросит сообщение без очереди канала
+	if !topology.Routes(message.Channel) {
+		return fmt.Errorf("%w: %v", dto.ErrUnknownChannel, message.Channel)
+	}
+
+	message.Attempt = 1
+	message.RetryAt = nil
+	message.FailedAt = nil
+	message.LastError = ""
+	message.ExpiresAt = nil
+
+	jsonMessage, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	if err = s.bus.PublishRouted(topology.Exchange, message.Channel, jsonMessage, message.Priority, 0); err != nil {
+		return err
+	}
+
+	s.logger.Info(fmt.Sprintf("Dead letter replayed messageUUID:%v", messageUUID))
+
+	return s.storage.DeleteById(ctx, messageUUID)
+}
+
+// ReplayAll повторная отправка всех сообщений, возвращает количество отправленных.
+// Сообщения каналов без очереди в топологии пропускаются.
+// При ошибке публикации оставшиеся сообщения остаются в хранилище
+func (s *Service) ReplayAll(ctx context.Context) (int, error) {
+	messages, err := s.storage.All(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for _, message := range messages {
+		err = s.Replay(ctx, message.MessageUUID)
+		if errors.Is(err, dto.ErrUnknownChannel) {
+			s.logger.Warning(fmt.Sprintf("Dead letter not replayed messageUUID:%v, %v", message.MessageUUID, err))
+			continue
+		}
+		if err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+// Purge удаление сообщения без отправки
+func (s *Service) Purge(ctx context.Context, messageUUID uuid.UUID) error {
+	return s.storage.DeleteById(ctx, messageUUID)
+}
+
+// PurgeAll удаление всех сообщений, возвращает количество удаленных
+func (s *Service) PurgeAll(ctx context.Context) (int, error) {
+	return s.storage.DeleteAll(ctx)
+}
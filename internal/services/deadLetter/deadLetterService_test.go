@@ -0,0 +1,257 @@
+package deadLetter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/interfaces"
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+var (
+	_ deadLetterConfig = (*configMock)(nil)
+	_ interfaces.Bus   = (*busMock)(nil)
+)
+
+func TestService_Start(t *testing.T) {
+	client := newBusMock()
+	s := New(&configMock{}, client, logger.NewZapLogger())
+	s.Start(context.Background())
+	defer s.Stop()
+
+	message := dto.Message{MessageUUID: uuid.New(), Attempt: 5, LastError: "provider unavailable"}
+	body, _ := json.Marshal(message)
+	client.deliveries <- dto.Delivery{Body: body}
+
+	// сообщение из очереди недоставленных попадает в хранилище
+	assert.Eventually(t, func() bool {
+		_, err := s.FindById(context.TODO(), message.MessageUUID)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestService_Start_expired сообщение с истекшим временем жизни брокер переносит
+// в очередь недоставленных, причина сохраняется в LastError
+func TestService_Start_expired(t *testing.T) {
+	client := newBusMock()
+	s := New(&configMock{}, client, logger.NewZapLogger())
+	s.Start(context.Background())
+	defer s.Stop()
+
+	message := dto.Message{MessageUUID: uuid.New(), Channel: "sms", Attempt: 1}
+	body, _ := json.Marshal(message)
+	client.deliveries <- dto.Delivery{Body: body, DeathReason: "expired in queue notify.sms"}
+
+	assert.Eventually(t, func() bool {
+		stored, err := s.FindById(context.TODO(), message.MessageUUID)
+		return err == nil && stored.LastError == "expired in queue notify.sms"
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestService_Start_storeFailed при недоступном хранилище сообщение возвращается в очередь
+func TestService_Start_storeFailed(t *testing.T) {
+	client := newBusMock()
+	s := NewWithStorage(&configMock{}, failingStorage{NewMemoryStorage()}, client, logger.NewZapLogger())
+	s.Start(context.Background())
+	defer s.Stop()
+
+	ack := &ackMock{settled: make(chan string, 1)}
+	body, _ := json.Marshal(dto.Message{MessageUUID: uuid.New()})
+	client.deliveries <- dto.Delivery{Body: body, Acknowledger: ack}
+
+	select {
+	case settled := <-ack.settled:
+		assert.Equal(t, "nack requeue", settled)
+	case <-time.After(2 * storeRetryDelay):
+		t.Fatal("delivery is not settled")
+	}
+}
+
+func TestService_Replay(t *testing.T) {
+	client := newBusMock()
+	storage := NewMemoryStorage()
+	s := NewWithStorage(&configMock{}, storage, client, logger.NewZapLogger())
+
+	failedAt := time.Now()
+	message := dto.Message{
+		MessageUUID: uuid.New(),
+		Channel:     "sms",
+		Priority:    7,
+		Attempt:     5,
+		FailedAt:    &failedAt,
+		ExpiresAt:   &failedAt,
+		LastError:   "provider unavailable",
+	}
+	_ = storage.Store(context.TODO(), message)
+
+	assert.ErrorIs(t, s.Replay(context.TODO(), uuid.New()), NotFound)
+
+	// сообщение уходит в очередь канала с обнуленным счетчиком попыток и без срока актуальности
+	assert.NoError(t, s.Replay(context.TODO(), message.MessageUUID))
+
+	var replayed dto.Message
+	assert.Equal(t, "notify.sms", client.queue)
+	assert.Equal(t, uint8(7), client.priority)
+	assert.NoError(t, json.Unmarshal(client.body, &replayed))
+	assert.Equal(t, message.MessageUUID, replayed.MessageUUID)
+	assert.Equal(t, 1, replayed.Attempt)
+	assert.Nil(t, replayed.FailedAt)
+	assert.Empty(t, replayed.LastError)
+	assert.Nil(t, replayed.ExpiresAt)
+
+	_, err := s.FindById(context.TODO(), message.MessageUUID)
+	assert.ErrorIs(t, err, NotFound)
+
+	// при ошибке публикации сообщение остается в хранилище
+	_ = storage.Store(context.TODO(), message)
+	client.err = errors.New("broker unavailable")
+
+	replayedCount, err := s.ReplayAll(context.TODO())
+	assert.Error(t, err)
+	assert.Equal(t, 0, replayedCount)
+	assert.Equal(t, 1, len(s.All(context.TODO())))
+}
+
+// TestService_Replay_unknownChannel сообщение канала без очереди не публикуется и остается в хранилище
+func TestService_Replay_unknownChannel(t *testing.T) {
+	client := newBusMock()
+	s := New(&configMock{}, client, logger.NewZapLogger())
+
+	removed := dto.Message{MessageUUID: uuid.New(), Channel: "push"}
+	routed := dto.Message{MessageUUID: uuid.New(), Channel: "sms"}
+	_ = s.storage.Store(context.TODO(), removed)
+	_ = s.storage.Store(context.TODO(), routed)
+
+	assert.ErrorIs(t, s.Replay(context.TODO(), removed.MessageUUID), dto.ErrUnknownChannel)
+	assert.Empty(t, client.queue)
+
+	replayed, err := s.ReplayAll(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	assert.Equal(t, "notify.sms", client.queue)
+
+	_, err = s.FindById(context.TODO(), removed.MessageUUID)
+	assert.NoError(t, err)
+}
+
+func TestService_All_empty(t *testing.T) {
+	s := New(&configMock{}, newBusMock(), logger.NewZapLogger())
+
+	body, _ := json.Marshal(s.All(context.TODO()))
+	assert.Equal(t, "[]", string(body))
+}
+
+func TestService_Purge(t *testing.T) {
+	s := New(&configMock{}, newBusMock(), logger.NewZapLogger())
+
+	first := dto.Message{MessageUUID: uuid.New()}
+	second := dto.Message{MessageUUID: uuid.New()}
+	_ = s.storage.Store(context.TODO(), first)
+	_ = s.storage.Store(context.TODO(), second)
+
+	assert.NoError(t, s.Purge(context.TODO(), first.MessageUUID))
+	assert.ErrorIs(t, s.Purge(context.TODO(), first.MessageUUID), NotFound)
+
+	purged, err := s.PurgeAll(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	assert.Empty(t, s.All(context.TODO()))
+}
+
+// failingStorage хранилище, недоступное для записи
+type failingStorage struct {
+	*MemoryStorage
+}
+
+func (f failingStorage) Store(ctx context.Context, message dto.Message) error {
+	return errors.New("storage unavailable")
+}
+
+// ackMock подтверждение сообщения, передает результат в settled
+type ackMock struct {
+	settled chan string
+}
+
+func (a *ackMock) Ack() error {
+	a.settled <- "ack"
+	return nil
+}
+
+func (a *ackMock) Nack(requeue bool) error {
+	if requeue {
+		a.settled <- "nack requeue"
+	} else {
+		a.settled <- "nack"
+	}
+	return nil
+}
+
+type configMock struct{}
+
+func (c *configMock) GetTopology() dto.Topology {
+	return dto.Topology{Exchange: "notify", Channels: []string{"sms", "mail"}, MaxPriority: 10, DeadLetterQueue: "dead"}
+}
+
+func (c *configMock) GetDeadLetterQueue() string {
+	return "dead"
+}
+
+// busMock заглушка шины, запоминает последнюю публикацию.
+// Для публикации в exchange queue - очередь канала по ключу маршрутизации
+type busMock struct {
+	deliveries chan dto.Delivery
+	queue      string
+	priority   uint8
+	body       []byte
+	err        error
+}
+
+func newBusMock() *busMock {
+	return &busMock{deliveries: make(chan dto.Delivery)}
+}
+
+func (a *busMock) Connect() error {
+	return nil
+}
+
+func (a *busMock) Qos(prefetchCount int) error {
+	return nil
+}
+
+func (a *busMock) MigrateDurableQueues(queues ...string) {}
+
+func (a *busMock) MigrateTopology(topology dto.Topology) error {
+	return nil
+}
+
+func (a *busMock) Subscribe(queue string) (<-chan dto.Delivery, error) {
+	return a.deliveries, nil
+}
+
+func (a *busMock) Publish(queue string, msgBody []byte) error {
+	if a.err != nil {
+		return a.err
+	}
+
+	a.queue = queue
+	a.body = msgBody
+	return nil
+}
+
+func (a *busMock) PublishDelayed(queue string, msgBody []byte, delay time.Duration) error {
+	return a.Publish(queue, msgBody)
+}
+
+func (a *busMock) PublishRouted(exchange string, routingKey string, msgBody []byte, priority uint8, expiration time.Duration) error {
+	a.priority = priority
+	return a.Publish(exchange+"."+routingKey, msgBody)
+}
+
+func (a *busMock) Stop() {}
@@ -0,0 +1,96 @@
+package deadLetter
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+var NotFound = errors.New("not found")
+
+// MemoryStorage in-memory хранилище для сервиса deadLetter
+// ! is safe for concurrent use
+type MemoryStorage struct {
+	mu   sync.Mutex
+	data map[uuid.UUID]dto.Message
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	ms := MemoryStorage{
+		data: make(map[uuid.UUID]dto.Message),
+	}
+	return &ms
+}
+
+func (m *MemoryStorage) All(ctx context.Context) ([]dto.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	messages := make([]dto.Message, 0, len(m.data))
+	for _, message := range m.data {
+		messages = append(messages, message)
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		return failedAt(messages[i]).Before(failedAt(messages[j]))
+	})
+
+	return messages, nil
+}
+
+func (m *MemoryStorage) Store(ctx context.Context, message dto.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data[message.MessageUUID] = message
+
+	return nil
+}
+
+func (m *MemoryStorage) GetById(ctx context.Context, messageUUID uuid.UUID) (dto.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	message, ok := m.data[messageUUID]
+	if !ok {
+		return dto.Message{}, NotFound
+	}
+
+	return message, nil
+}
+
+func (m *MemoryStorage) DeleteById(ctx context.Context, messageUUID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.data[messageUUID]; !ok {
+		return NotFound
+	}
+
+	delete(m.data, messageUUID)
+
+	return nil
+}
+
+func (m *MemoryStorage) DeleteAll(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleted := len(m.data)
+	m.data = make(map[uuid.UUID]dto.Message)
+
+	return deleted, nil
+}
+
+func failedAt(message dto.Message) time.Time {
+	if message.FailedAt == nil {
+		return time.Time{}
+	}
+	return *message.FailedAt
+}
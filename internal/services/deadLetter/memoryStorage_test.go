@@ -0,0 +1,102 @@
+package deadLetter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// StorageTestSuite общий набор тестов для всех реализаций Storager
+type StorageTestSuite struct {
+	suite.Suite
+	newStorage func() Storager
+	storage    Storager
+	messages   []dto.Message
+}
+
+func (suite *StorageTestSuite) SetupSuite() {
+	now := time.Now().UTC().Truncate(time.Second)
+	earlier := now.Add(-time.Hour)
+
+	suite.messages = []dto.Message{
+		{
+			MessageUUID:        uuid.New(),
+			NotificationUUID:   uuid.New(),
+			PersonUUID:         uuid.New(),
+			Text:               "Hello",
+			Channel:            "sms",
+			DestinationAddress: "+79876543210",
+			Attempt:            5,
+			FailedAt:           &now,
+			LastError:          "provider unavailable",
+		}, {
+			MessageUUID:        uuid.New(),
+			NotificationUUID:   uuid.New(),
+			PersonUUID:         uuid.New(),
+			Text:               "Hello",
+			Channel:            "pigeon",
+			DestinationAddress: "roof",
+			Attempt:            1,
+			FailedAt:           &earlier,
+			LastError:          "not exist",
+		},
+	}
+}
+
+func (suite *StorageTestSuite) SetupTest() {
+	suite.storage = suite.newStorage()
+	for i := 0; i < len(suite.messages); i++ {
+		_ = suite.storage.Store(context.TODO(), suite.messages[i])
+	}
+}
+
+func (suite *StorageTestSuite) Test_All() {
+	result, err := suite.storage.All(context.TODO())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), len(suite.messages), len(result))
+
+	// давно упавшие первыми
+	assert.Equal(suite.T(), suite.messages[1].MessageUUID, result[0].MessageUUID)
+}
+
+func (suite *StorageTestSuite) Test_GetById() {
+	_, err := suite.storage.GetById(context.TODO(), uuid.New())
+	assert.ErrorIs(suite.T(), err, NotFound)
+
+	result, err := suite.storage.GetById(context.TODO(), suite.messages[0].MessageUUID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.messages[0].LastError, result.LastError)
+	assert.Equal(suite.T(), suite.messages[0].Attempt, result.Attempt)
+	assert.True(suite.T(), suite.messages[0].FailedAt.Equal(*result.FailedAt))
+}
+
+func (suite *StorageTestSuite) Test_DeleteById() {
+	err := suite.storage.DeleteById(context.TODO(), suite.messages[0].MessageUUID)
+	assert.NoError(suite.T(), err)
+
+	err = suite.storage.DeleteById(context.TODO(), suite.messages[0].MessageUUID)
+	assert.ErrorIs(suite.T(), err, NotFound)
+}
+
+func (suite *StorageTestSuite) Test_DeleteAll() {
+	deleted, err := suite.storage.DeleteAll(context.TODO())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), len(suite.messages), deleted)
+
+	result, err := suite.storage.All(context.TODO())
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), result)
+}
+
+// Для запуска через Go test
+func TestMemoryStorageSuite(t *testing.T) {
+	suite.Run(t, &StorageTestSuite{
+		newStorage: func() Storager { return NewMemoryStorage() },
+	})
+}
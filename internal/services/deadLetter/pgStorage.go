@@ -0,0 +1,114 @@
+package deadLetter
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+var _ Storager = (*PgStorage)(nil)
+
+// PgStorage PostgreSQL хранилище для сервиса deadLetter.
+// Сообщение хранится в json, время последней ошибки вынесено в отдельную колонку для сортировки.
+// Схема создается миграциями из пакета migrations
+type PgStorage struct {
+	db *sql.DB
+}
+
+func NewPgStorage(db *sql.DB) *PgStorage {
+	ps := PgStorage{db: db}
+	return &ps
+}
+
+func (p *PgStorage) All(ctx context.Context) ([]dto.Message, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT payload FROM dead_letters ORDER BY failed_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []dto.Message
+	for rows.Next() {
+		var payload []byte
+		if err = rows.Scan(&payload); err != nil {
+			return nil, err
+		}
+
+		var message dto.Message
+		if err = json.Unmarshal(payload, &message); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+func (p *PgStorage) Store(ctx context.Context, message dto.Message) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.ExecContext(ctx, `INSERT INTO dead_letters (message_uuid, failed_at, payload)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (message_uuid) DO UPDATE SET failed_at = EXCLUDED.failed_at, payload = EXCLUDED.payload`,
+		message.MessageUUID, failedAt(message), payload)
+
+	return err
+}
+
+func (p *PgStorage) GetById(ctx context.Context, messageUUID uuid.UUID) (dto.Message, error) {
+	var (
+		payload []byte
+		message dto.Message
+	)
+
+	err := p.db.QueryRowContext(ctx, `SELECT payload FROM dead_letters WHERE message_uuid = $1`,
+		messageUUID).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dto.Message{}, NotFound
+	}
+	if err != nil {
+		return dto.Message{}, err
+	}
+
+	err = json.Unmarshal(payload, &message)
+
+	return message, err
+}
+
+func (p *PgStorage) DeleteById(ctx context.Context, messageUUID uuid.UUID) error {
+	res, err := p.db.ExecContext(ctx, `DELETE FROM dead_letters WHERE message_uuid = $1`, messageUUID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return NotFound
+	}
+
+	return nil
+}
+
+func (p *PgStorage) DeleteAll(ctx context.Context) (int, error) {
+	res, err := p.db.ExecContext(ctx, `DELETE FROM dead_letters`)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return int(affected), err
+}
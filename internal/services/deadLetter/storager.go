@@ -0,0 +1,23 @@
+package deadLetter
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// Storager интерфейс хранилища недоставленных сообщений
+type Storager interface {
+	// All возвращает все недоставленные сообщения, давно упавшие первыми
+	All(ctx context.Context) ([]dto.Message, error)
+	// Store сохраняет недоставленное сообщение
+	Store(ctx context.Context, message dto.Message) error
+	// GetById возвращает сообщение по uuid
+	GetById(ctx context.Context, messageUUID uuid.UUID) (dto.Message, error)
+	// DeleteById удаляет сообщение по uuid
+	DeleteById(ctx context.Context, messageUUID uuid.UUID) error
+	// DeleteAll удаляет все сообщения, возвращает количество удаленных
+	DeleteAll(ctx context.Context) (int, error)
+}
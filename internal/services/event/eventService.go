@@ -18,14 +18,27 @@ package event
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/google/uuid"
 
+	"github.com/atrian/go-notify-customer/internal/deliveryWindow"
 	"github.com/atrian/go-notify-customer/internal/dto"
 	"github.com/atrian/go-notify-customer/internal/interfaces"
+	"github.com/atrian/go-notify-customer/internal/routing"
 )
 
-// Service структура сервиса бизнес событий содержит in-mem хранилище с интерфейсом:
+var (
+	// InvalidFallback цепочка резервных каналов задана неверно
+	InvalidFallback = errors.New("invalid fallback chain")
+	// InvalidDeliveryWindow окно доставки задано неверно
+	InvalidDeliveryWindow = deliveryWindow.InvalidWindow
+	// InvalidRoutingRule правило маршрутизации задано неверно
+	InvalidRoutingRule = routing.InvalidRule
+)
+
+// Service структура сервиса бизнес событий содержит хранилище (in-mem или PostgreSQL) с интерфейсом:
 //
 //	type Storager interface {
 //			All(ctx context.Context) ([]dto.Event, error)
@@ -42,11 +55,16 @@ type Service struct {
 }
 
 // New при создании требует логгер удовлетворяющий интерфейсу interfaces.Logger
-// В приложении используется реализация с Zap
+// В приложении используется реализация с Zap. Данные хранятся в памяти
 func New(logger interfaces.Logger) *Service {
+	return NewWithStorage(NewMemoryStorage(), logger)
+}
+
+// NewWithStorage сервис с внешним хранилищем, например PgStorage
+func NewWithStorage(storage Storager, logger interfaces.Logger) *Service {
 	s := Service{
 		logger:  logger,
-		storage: NewMemoryStorage(),
+		storage: storage,
 	}
 	return &s
 }
@@ -78,6 +96,10 @@ func (e Service) All(ctx context.Context) []dto.Event {
 
 // Store созраняет dto.Event в хранилище. Событию присваивается UUID
 func (e Service) Store(ctx context.Context, event dto.Event) (dto.Event, error) {
+	if err := validate(event); err != nil {
+		return dto.Event{}, err
+	}
+
 	event.EventUUID = uuid.New()
 
 	err := e.storage.Store(ctx, event)
@@ -107,6 +129,10 @@ func (e Service) StoreBatch(ctx context.Context, events []dto.Event) ([]dto.Even
 
 // Update обновляет бизнес событие в хранилище
 func (e Service) Update(ctx context.Context, event dto.Event) (dto.Event, error) {
+	if err := validate(event); err != nil {
+		return dto.Event{}, err
+	}
+
 	err := e.storage.Update(ctx, event)
 	if err != nil {
 		e.logger.Error("Event service storage.Update err", err)
@@ -125,3 +151,36 @@ func (e Service) FindById(ctx context.Context, eventUUID uuid.UUID) (dto.Event,
 func (e Service) DeleteById(ctx context.Context, eventUUID uuid.UUID) error {
 	return e.storage.DeleteById(ctx, eventUUID)
 }
+
+// validate проверка цепочки резервных каналов, окон доставки и правил маршрутизации события
+func validate(event dto.Event) error {
+	if err := validateFallback(event.Fallback); err != nil {
+		return err
+	}
+
+	if err := deliveryWindow.Validate(event.DeliveryWindows); err != nil {
+		return err
+	}
+
+	return routing.Validate(event.RoutingRules)
+}
+
+// validateFallback в цепочке каждый канал указан один раз, время ожидания не отрицательное
+func validateFallback(fallback []dto.FallbackStep) error {
+	channels := make(map[string]struct{}, len(fallback))
+
+	for i, step := range fallback {
+		if step.Channel == "" {
+			return fmt.Errorf("%w: step %d without channel", InvalidFallback, i)
+		}
+		if step.After < 0 {
+			return fmt.Errorf("%w: step %d negative after", InvalidFallback, i)
+		}
+		if _, exist := channels[step.Channel]; exist {
+			return fmt.Errorf("%w: channel %v repeated", InvalidFallback, step.Channel)
+		}
+		channels[step.Channel] = struct{}{}
+	}
+
+	return nil
+}
@@ -62,6 +62,64 @@ func (suite *TestSuite) TestService_Store() {
 	assert.Equal(suite.T(), storeResult, newEvent)
 }
 
+func (suite *TestSuite) TestService_Store_fallback() {
+	newEvent := dto.Event{
+		Title: "Reminder",
+		Fallback: []dto.FallbackStep{
+			{Channel: "telegram"},
+			{Channel: "sms", After: 300},
+			{Channel: "mail"},
+		},
+	}
+
+	stored, err := suite.service.Store(context.TODO(), newEvent)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), newEvent.Fallback, stored.Fallback)
+
+	// канал повторяется в цепочке
+	newEvent.Fallback = append(newEvent.Fallback, dto.FallbackStep{Channel: "sms"})
+	_, err = suite.service.Store(context.TODO(), newEvent)
+	assert.ErrorIs(suite.T(), err, InvalidFallback)
+
+	stored.Fallback = []dto.FallbackStep{{Channel: "sms", After: -1}}
+	_, err = suite.service.Update(context.TODO(), stored)
+	assert.ErrorIs(suite.T(), err, InvalidFallback)
+}
+
+func (suite *TestSuite) TestService_Store_deliveryWindows() {
+	newEvent := dto.Event{
+		Title:           "Reminder",
+		DeliveryWindows: []dto.DeliveryWindow{{Days: []string{"weekdays"}, From: "09:00", To: "21:00"}},
+	}
+
+	stored, err := suite.service.Store(context.TODO(), newEvent)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), newEvent.DeliveryWindows, stored.DeliveryWindows)
+
+	stored.DeliveryWindows = []dto.DeliveryWindow{{From: "9am", To: "21:00"}}
+	_, err = suite.service.Update(context.TODO(), stored)
+	assert.ErrorIs(suite.T(), err, InvalidDeliveryWindow)
+
+	newEvent.DeliveryWindows = []dto.DeliveryWindow{{Days: []string{"holidays"}, From: "09:00", To: "21:00"}}
+	_, err = suite.service.Store(context.TODO(), newEvent)
+	assert.ErrorIs(suite.T(), err, InvalidDeliveryWindow)
+}
+
+func (suite *TestSuite) TestService_Store_routingRules() {
+	newEvent := dto.Event{
+		Title:        "Order",
+		RoutingRules: []dto.RoutingRule{{When: map[string]string{"vip": "true"}, Priority: 900, Channels: []string{"sms"}}},
+	}
+
+	stored, err := suite.service.Store(context.TODO(), newEvent)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), newEvent.RoutingRules, stored.RoutingRules)
+
+	stored.RoutingRules = []dto.RoutingRule{{Priority: 900}}
+	_, err = suite.service.Update(context.TODO(), stored)
+	assert.ErrorIs(suite.T(), err, InvalidRoutingRule)
+}
+
 func (suite *TestSuite) TestService_StoreBatch() {
 	newEvents := []dto.Event{
 		{
@@ -12,7 +12,11 @@ import (
 )
 
 func TestNewMemoryStorage(t *testing.T) {
-	ms := NewMemoryStorage()
+	testStorage(t, NewMemoryStorage())
+}
+
+// testStorage общий сценарий проверки для всех реализаций Storager, ожидает пустое хранилище
+func testStorage(t *testing.T, ms Storager) {
 	ctx := context.TODO()
 
 	event := dto.Event{
@@ -21,6 +25,9 @@ func TestNewMemoryStorage(t *testing.T) {
 		Description:          "Test description",
 		DefaultPriority:      100,
 		NotificationChannels: []string{"sms", "email"},
+		Category:             "reminder",
+		Fallback:             []dto.FallbackStep{{Channel: "telegram"}, {Channel: "sms", After: 300}},
+		DeliveryWindows:      []dto.DeliveryWindow{{Days: []string{"weekdays"}, From: "09:00", To: "21:00"}},
 	}
 
 	event2 := dto.Event{
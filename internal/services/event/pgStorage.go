@@ -0,0 +1,195 @@
+package event
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+var _ Storager = (*PgStorage)(nil)
+
+// PgStorage PostgreSQL хранилище для сервиса event.
+// Схема создается миграциями из пакета migrations
+type PgStorage struct {
+	db *sql.DB
+}
+
+func NewPgStorage(db *sql.DB) *PgStorage {
+	ps := PgStorage{db: db}
+	return &ps
+}
+
+func (p *PgStorage) All(ctx context.Context) ([]dto.Event, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT event_uuid, title, description, default_priority, notification_channels, category, fallback, delivery_windows, routing_rules
+		FROM events ORDER BY title`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []dto.Event
+	for rows.Next() {
+		event, scanErr := scanEvent(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (p *PgStorage) Store(ctx context.Context, event dto.Event) error {
+	fallback, err := marshalArray(event.Fallback)
+	if err != nil {
+		return err
+	}
+	windows, err := marshalArray(event.DeliveryWindows)
+	if err != nil {
+		return err
+	}
+	rules, err := marshalArray(event.RoutingRules)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.ExecContext(ctx, `INSERT INTO events
+		(event_uuid, title, description, default_priority, notification_channels, category, fallback, delivery_windows, routing_rules)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (event_uuid) DO UPDATE SET
+			title = EXCLUDED.title,
+			description = EXCLUDED.description,
+			default_priority = EXCLUDED.default_priority,
+			notification_channels = EXCLUDED.notification_channels,
+			category = EXCLUDED.category,
+			fallback = EXCLUDED.fallback,
+			delivery_windows = EXCLUDED.delivery_windows,
+			routing_rules = EXCLUDED.routing_rules`,
+		event.EventUUID, event.Title, event.Description, event.DefaultPriority, pq.Array(event.NotificationChannels), event.Category, fallback, windows, rules)
+
+	return err
+}
+
+// Update обновляет существующее событие, для отсутствующего возвращает NotFound
+func (p *PgStorage) Update(ctx context.Context, event dto.Event) error {
+	fallback, err := marshalArray(event.Fallback)
+	if err != nil {
+		return err
+	}
+	windows, err := marshalArray(event.DeliveryWindows)
+	if err != nil {
+		return err
+	}
+	rules, err := marshalArray(event.RoutingRules)
+	if err != nil {
+		return err
+	}
+
+	res, err := p.db.ExecContext(ctx, `UPDATE events
+		SET title = $2, description = $3, default_priority = $4, notification_channels = $5, category = $6, fallback = $7, delivery_windows = $8,
+			routing_rules = $9
+		WHERE event_uuid = $1`,
+		event.EventUUID, event.Title, event.Description, event.DefaultPriority, pq.Array(event.NotificationChannels), event.Category, fallback, windows, rules)
+	if err != nil {
+		return err
+	}
+
+	return affectedOrNotFound(res)
+}
+
+func (p *PgStorage) GetById(ctx context.Context, eventUUID uuid.UUID) (dto.Event, error) {
+	row := p.db.QueryRowContext(ctx, `SELECT event_uuid, title, description, default_priority, notification_channels, category, fallback, delivery_windows, routing_rules
+		FROM events WHERE event_uuid = $1`, eventUUID)
+
+	event, err := scanEvent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dto.Event{}, NotFound
+	}
+
+	return event, err
+}
+
+func (p *PgStorage) DeleteById(ctx context.Context, eventUUID uuid.UUID) error {
+	res, err := p.db.ExecContext(ctx, `DELETE FROM events WHERE event_uuid = $1`, eventUUID)
+	if err != nil {
+		return err
+	}
+
+	return affectedOrNotFound(res)
+}
+
+// scanEvent чтение строки результата в dto.Event
+func scanEvent(row interface{ Scan(dest ...any) error }) (dto.Event, error) {
+	var (
+		event    dto.Event
+		fallback []byte
+		windows  []byte
+		rules    []byte
+	)
+
+	err := row.Scan(
+		&event.EventUUID,
+		&event.Title,
+		&event.Description,
+		&event.DefaultPriority,
+		pq.Array(&event.NotificationChannels),
+		&event.Category,
+		&fallback,
+		&windows,
+		&rules)
+	if err != nil {
+		return dto.Event{}, err
+	}
+
+	if err = json.Unmarshal(fallback, &event.Fallback); err != nil {
+		return dto.Event{}, err
+	}
+	if err = json.Unmarshal(windows, &event.DeliveryWindows); err != nil {
+		return dto.Event{}, err
+	}
+	if err = json.Unmarshal(rules, &event.RoutingRules); err != nil {
+		return dto.Event{}, err
+	}
+
+	// событие без цепочки, окон доставки и правил хранится с пустыми массивами
+	if len(event.Fallback) == 0 {
+		event.Fallback = nil
+	}
+	if len(event.DeliveryWindows) == 0 {
+		event.DeliveryWindows = nil
+	}
+	if len(event.RoutingRules) == 0 {
+		event.RoutingRules = nil
+	}
+
+	return event, nil
+}
+
+// marshalArray список в json для колонки JSONB, пустой список - пустой массив
+func marshalArray[T any](items []T) ([]byte, error) {
+	if items == nil {
+		items = []T{}
+	}
+
+	return json.Marshal(items)
+}
+
+// affectedOrNotFound возвращает NotFound если запрос не затронул ни одной строки
+func affectedOrNotFound(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return NotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build integration
+// +build integration
+
+package event
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/atrian/go-notify-customer/internal/migrations"
+	"github.com/atrian/go-notify-customer/pkg/postgres/pgtest"
+)
+
+func TestPgStorage(t *testing.T) {
+	pgtest.Run(t, migrations.FS, func(t *testing.T, db *sql.DB) {
+		testStorage(t, NewPgStorage(db))
+	})
+}
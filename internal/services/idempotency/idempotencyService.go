@@ -0,0 +1,55 @@
+// Package idempotency ключи идемпотентности для приема уведомлений.
+// Повторный запрос с тем же ключом в пределах окна GetIdempotencyTTL не ставит уведомление
+// в очередь повторно, а возвращает uuid уведомления из первого запроса.
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// idempotencyConfig интерфейс конфигурации ключей идемпотентности
+type idempotencyConfig interface {
+	GetIdempotencyTTL() time.Duration
+}
+
+// Keys ключи идемпотентности поверх хранилища Storager
+type Keys struct {
+	storage Storager
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// New ключи с in-memory хранилищем
+func New(conf idempotencyConfig) *Keys {
+	return NewWithStorage(conf, NewMemoryStorage())
+}
+
+// NewWithStorage ключи с внешним хранилищем, например PgStorage
+func NewWithStorage(conf idempotencyConfig, storage Storager) *Keys {
+	k := Keys{
+		storage: storage,
+		ttl:     conf.GetIdempotencyTTL(),
+		now:     time.Now,
+	}
+
+	if k.ttl <= 0 {
+		k.ttl = 24 * time.Hour
+	}
+
+	return &k
+}
+
+// Reserve закрепляет ключ за уведомлением. Для повторного ключа возвращает uuid
+// ранее принятого уведомления и false
+func (k *Keys) Reserve(ctx context.Context, key string, notificationUUID uuid.UUID) (uuid.UUID, bool, error) {
+	now := k.now()
+	return k.storage.Reserve(ctx, key, notificationUUID, now, now.Add(k.ttl))
+}
+
+// Release освобождает ключ, если уведомление не было принято, например из-за лимита
+func (k *Keys) Release(ctx context.Context, key string) error {
+	return k.storage.Delete(ctx, key)
+}
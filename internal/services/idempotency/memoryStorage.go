@@ -0,0 +1,76 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sweepInterval периодичность очистки истекших ключей
+const sweepInterval = time.Minute
+
+var _ Storager = (*MemoryStorage)(nil)
+
+// MemoryStorage in-memory хранилище ключей идемпотентности
+// ! is safe for concurrent use
+type MemoryStorage struct {
+	mu        sync.Mutex
+	data      map[string]record
+	lastSweep time.Time
+}
+
+// record закрепленный за ключом uuid уведомления
+type record struct {
+	notificationUUID uuid.UUID
+	expiresAt        time.Time
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	ms := MemoryStorage{
+		data: make(map[string]record),
+	}
+	return &ms
+}
+
+func (m *MemoryStorage) Reserve(ctx context.Context, key string, notificationUUID uuid.UUID, now time.Time, expiresAt time.Time) (uuid.UUID, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	if existing, ok := m.data[key]; ok && existing.expiresAt.After(now) {
+		return existing.notificationUUID, false, nil
+	}
+
+	m.data[key] = record{
+		notificationUUID: notificationUUID,
+		expiresAt:        expiresAt,
+	}
+
+	return notificationUUID, true, nil
+}
+
+func (m *MemoryStorage) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.data, key)
+
+	return nil
+}
+
+// sweep удаляет истекшие ключи, чтобы карта не росла бесконечно. Вызывать под m.mu
+func (m *MemoryStorage) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, r := range m.data {
+		if !r.expiresAt.After(now) {
+			delete(m.data, key)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// StorageTestSuite общий набор тестов для всех реализаций Storager
+type StorageTestSuite struct {
+	suite.Suite
+	newStorage func() Storager
+	storage    Storager
+	now        time.Time
+}
+
+func (suite *StorageTestSuite) SetupTest() {
+	suite.storage = suite.newStorage()
+	suite.now = time.Now().UTC().Truncate(time.Second)
+}
+
+func (suite *StorageTestSuite) Test_Reserve() {
+	first := uuid.New()
+	expiresAt := suite.now.Add(time.Hour)
+
+	stored, created, err := suite.storage.Reserve(context.TODO(), "key", first, suite.now, expiresAt)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), created)
+	assert.Equal(suite.T(), first, stored)
+
+	// повтор в пределах окна возвращает исходный uuid
+	stored, created, err = suite.storage.Reserve(context.TODO(), "key", uuid.New(), suite.now.Add(time.Minute), expiresAt)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), created)
+	assert.Equal(suite.T(), first, stored)
+
+	// после истечения окна ключ закрепляется заново
+	second := uuid.New()
+	later := expiresAt.Add(time.Second)
+	stored, created, err = suite.storage.Reserve(context.TODO(), "key", second, later, later.Add(time.Hour))
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), created)
+	assert.Equal(suite.T(), second, stored)
+}
+
+func (suite *StorageTestSuite) Test_Delete() {
+	_, _, err := suite.storage.Reserve(context.TODO(), "key", uuid.New(), suite.now, suite.now.Add(time.Hour))
+	assert.NoError(suite.T(), err)
+
+	assert.NoError(suite.T(), suite.storage.Delete(context.TODO(), "key"))
+
+	_, created, err := suite.storage.Reserve(context.TODO(), "key", uuid.New(), suite.now, suite.now.Add(time.Hour))
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), created)
+}
+
+// Для запуска через Go test
+func TestMemoryStorageSuite(t *testing.T) {
+	suite.Run(t, &StorageTestSuite{
+		newStorage: func() Storager { return NewMemoryStorage() },
+	})
+}
@@ -0,0 +1,73 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var _ Storager = (*PgStorage)(nil)
+
+// PgStorage PostgreSQL хранилище ключей идемпотентности.
+// Схема создается миграциями из пакета migrations
+type PgStorage struct {
+	db        *sql.DB
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewPgStorage(db *sql.DB) *PgStorage {
+	ps := PgStorage{db: db}
+	return &ps
+}
+
+// Reserve вставка ключа, истекший ключ перезаписывается. Если вставка не прошла,
+// ключ занят и возвращается закрепленный за ним uuid
+func (p *PgStorage) Reserve(ctx context.Context, key string, notificationUUID uuid.UUID, now time.Time, expiresAt time.Time) (uuid.UUID, bool, error) {
+	p.sweep(ctx, now)
+
+	var stored uuid.UUID
+
+	err := p.db.QueryRowContext(ctx, `INSERT INTO idempotency_keys (key, notification_uuid, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET notification_uuid = EXCLUDED.notification_uuid, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= $4
+		RETURNING notification_uuid`,
+		key, notificationUUID, expiresAt, now).Scan(&stored)
+
+	if err == nil {
+		return stored, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, false, err
+	}
+
+	err = p.db.QueryRowContext(ctx, `SELECT notification_uuid FROM idempotency_keys WHERE key = $1`, key).Scan(&stored)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	return stored, false, nil
+}
+
+func (p *PgStorage) Delete(ctx context.Context, key string) error {
+	_, err := p.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1`, key)
+	return err
+}
+
+// sweep удаляет истекшие ключи не чаще чем раз в sweepInterval
+func (p *PgStorage) sweep(ctx context.Context, now time.Time) {
+	p.mu.Lock()
+	if now.Sub(p.lastSweep) < sweepInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.lastSweep = now
+	p.mu.Unlock()
+
+	_, _ = p.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+}
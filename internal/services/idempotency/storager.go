@@ -0,0 +1,17 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Storager интерфейс хранилища ключей идемпотентности
+type Storager interface {
+	// Reserve атомарно закрепляет за ключом uuid уведомления до expiresAt.
+	// Если ключ уже закреплен и не истек, возвращает ранее сохраненный uuid и false
+	Reserve(ctx context.Context, key string, notificationUUID uuid.UUID, now time.Time, expiresAt time.Time) (uuid.UUID, bool, error)
+	// Delete освобождает ключ
+	Delete(ctx context.Context, key string) error
+}
@@ -1,17 +1,30 @@
 // Package notificationDispatcher диспетчер подготовки уведомлений к отправке
 // обращается к другим сервисам зя дополнительной информацией по уведомлению через фасад serviceGateway
-// результат работы отправляет во внешнюю очередь (RabbitMQ)
+// результат работы отправляет во внешнюю очередь через шину сообщений interfaces.Bus
 package notificationDispatcher
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/atrian/go-notify-customer/internal/deliveryWindow"
 	"github.com/atrian/go-notify-customer/internal/dto"
 	"github.com/atrian/go-notify-customer/internal/interfaces"
+	"github.com/atrian/go-notify-customer/internal/routing"
+	"github.com/atrian/go-notify-customer/internal/services/preference"
+	"github.com/atrian/go-notify-customer/internal/templating"
+	"github.com/atrian/go-notify-customer/pkg/bus"
+)
+
+// Задержки повтора публикации сообщений, не принятых брокером
+const (
+	dispatchRetryBaseDelay = 100 * time.Millisecond
+	dispatchRetryMaxDelay  = 5 * time.Second
 )
 
 // serviceGateway интерфейс сервисного фасада, ограничение на досупные методы автономных сервисов.
@@ -22,58 +35,88 @@ type serviceGateway interface {
 	getTemplates(ctx context.Context, eventUUID uuid.UUID) ([]dto.Template, error)
 	// getEvent запрос деталей бизнес события
 	getEvent(ctx context.Context, eventUuid uuid.UUID) (dto.Event, error)
+	// getPreferences запрос предпочтений получателей по категориям событий и каналам
+	getPreferences(ctx context.Context, personUUIDs []uuid.UUID) (map[uuid.UUID][]dto.Preference, error)
 	// prepareTemplate выполнение именованных подстановок в шаблоне сообщения
-	prepareTemplate(template string, replaces []dto.MessageParam) string
+	prepareTemplate(template dto.Template, replaces []dto.MessageParam) (string, error)
 }
 
 // dispatcherConfig интерфейс кинфигурации доступной сервису notificationDispatcher
 type dispatcherConfig interface {
-	GetAmpqDSN() string
-	GetNotificationQueue() string
+	GetTopology() dto.Topology
+	GetFailedWorksQueue() string
+	GetCriticalPriority() uint
+}
+
+// journal журнал принятых уведомлений, обработанные уведомления подтверждаются и не повторяются при старте
+type journal interface {
+	Ack(ctx context.Context, notificationUUID uuid.UUID) error
 }
 
 // Dispatcher содержит канал по котороку получает входящие уведомлений
 // конфигурацию, фасад с нужными для работы сервисами,
-// ampq клиент с интерфейсом interfaces.AmpqClient
+// шину сообщений с интерфейсом interfaces.Bus
 // и логгер с интерфейсом interfaces.Logger
 type Dispatcher struct {
 	notificationChan <-chan dto.Notification
 	config           dispatcherConfig
 	services         serviceGateway
-	ampqClient       interfaces.AmpqClient
+	bus              interfaces.Bus
+	statChan         chan<- dto.Stat
+	journal          journal
 	logger           interfaces.Logger
+	now              func() time.Time
 }
 
 func New(
 	notificationChan chan dto.Notification,
 	config dispatcherConfig,
 	serviceGateway serviceGateway,
-	ampqClient interfaces.AmpqClient,
+	messageBus interfaces.Bus,
 	logger interfaces.Logger) *Dispatcher {
 
 	d := Dispatcher{
 		notificationChan: notificationChan,
 		config:           config,
 		services:         serviceGateway,
-		ampqClient:       ampqClient,
+		bus:              messageBus,
 		logger:           logger,
+		now:              time.Now,
 	}
 
 	return &d
 }
 
-// Start стартовые операции для notificationDispatcher - ampq миграция,
+// SetStatChan подключение канала статистики, переданные в очередь сообщения фиксируются статусом dto.Dispatched,
+// отложенные до окна доставки - статусом dto.Deferred, пропущенные по предпочтениям получателя - статусом dto.OptedOut
+func (d *Dispatcher) SetStatChan(statChan chan<- dto.Stat) *Dispatcher {
+	d.statChan = statChan
+	return d
+}
+
+// SetJournal подключение журнала принятых уведомлений. Уведомление подтверждается после передачи
+// всех его сообщений в очередь. Не принятые брокером сообщения повторяются до остановки диспетчера,
+// после перезапуска не подтвержденное уведомление обрабатывается заново
+func (d *Dispatcher) SetJournal(journal journal) *Dispatcher {
+	d.journal = journal
+	return d
+}
+
+// Start стартовые операции для notificationDispatcher - миграция шины,
 // запуск прослушивания канала
 func (d Dispatcher) Start(ctx context.Context) {
 	d.logger.Info("Notification dispatcher started")
 
-	// Подключаем ampq клиент
-	err := d.ampqClient.Connect(d.config.GetAmpqDSN())
+	// Подключаем шину сообщений
+	err := d.bus.Connect()
 	if err != nil {
-		d.logger.Error("Dispatcher ampqClient.Connect err", err)
+		d.logger.Error("Dispatcher bus.Connect err", err)
+	}
+	// миграция exchange и очередей каналов, отложенные до окна доставки сообщения ждут в очереди повторов
+	if err = d.bus.MigrateTopology(d.config.GetTopology()); err != nil {
+		d.logger.Error("Dispatcher bus.MigrateTopology err", err)
 	}
-	// миграция AMPQ очередей
-	d.ampqClient.MigrateDurableQueues(d.config.GetNotificationQueue())
+	d.bus.MigrateDurableQueues(d.config.GetFailedWorksQueue())
 
 	// слушаем канал с уведомлениями, строим сообщения и отправляем на исполнение
 	go d.listenInputChannel(ctx, d.notificationChan)
@@ -81,13 +124,42 @@ func (d Dispatcher) Start(ctx context.Context) {
 
 // Stop корректное завершение работы
 func (d Dispatcher) Stop() {
-	d.ampqClient.Stop()
+	d.bus.Stop()
 	d.logger.Info("Notification dispatcher stopped")
 }
 
-// dispatch отправка готового сообщения в очередь для исполнения
-// channel worker'ом.
+// dispatch отправка готового сообщения в очередь канала через exchange топологии
+// для исполнения channel worker'ом. Приоритет и срок актуальности сообщения передаются брокеру.
+// Сообщение вне окна доставки получателя уходит в очередь повторов и возвращается
+// в очередь канала при открытии окна. Сообщение считается переданным после подтверждения брокером,
+// без подтверждения уведомление остается в журнале
 func (d Dispatcher) dispatch(message dto.Message) error {
+	topology := d.config.GetTopology()
+
+	// без очереди канала брокер молча отбросит сообщение
+	if !topology.Routes(message.Channel) {
+		d.logger.Error(fmt.Sprintf("Dispatcher no queue for channel: %v, person: %v", message.Channel, message.PersonUUID), errors.New("unroutable channel"))
+		d.sendStat(dto.Stat{
+			PersonUUID:       message.PersonUUID,
+			NotificationUUID: message.NotificationUUID,
+			Channel:          message.Channel,
+			Status:           dto.BadChannel,
+		})
+		return nil
+	}
+
+	// от времени передачи в очередь отсчитывается ожидание перед переходом в резервный канал
+	dispatchedAt := d.now()
+	status := dto.Dispatched
+
+	if message.DeferredUntil != nil && message.DeferredUntil.After(dispatchedAt) {
+		// для отложенного сообщения ожидание резервного канала отсчитывается от открытия окна
+		dispatchedAt = *message.DeferredUntil
+		message.RetryAt = message.DeferredUntil
+		status = dto.Deferred
+	}
+	message.DispatchedAt = &dispatchedAt
+
 	// Готовим json и публикуем в очередь
 	jsonMessage, err := json.Marshal(message)
 	if err != nil {
@@ -95,26 +167,58 @@ func (d Dispatcher) dispatch(message dto.Message) error {
 		return err
 	}
 
-	err = d.ampqClient.Publish(d.config.GetNotificationQueue(), jsonMessage)
+	if status == dto.Deferred {
+		err = d.bus.Publish(d.config.GetFailedWorksQueue(), jsonMessage)
+	} else {
+		err = d.bus.PublishRouted(topology.Exchange, message.Channel, jsonMessage,
+			message.Priority, bus.Expiration(message.ExpiresAt, d.now()))
+	}
 	if err != nil {
-		d.logger.Error("ampqClient.Publish error", err)
+		d.logger.Error("bus.Publish error", err)
 		return err
 	}
 
-	infoMessage := fmt.Sprintf("Message dispatched for %v", message.PersonUUID.String())
+	infoMessage := fmt.Sprintf("Message %v for %v", status, message.PersonUUID.String())
 	d.logger.Info(infoMessage)
 
+	d.sendStat(dto.Stat{
+		PersonUUID:       message.PersonUUID,
+		NotificationUUID: message.NotificationUUID,
+		Channel:          message.Channel,
+		Status:           status,
+	})
+
 	return nil
 }
 
+// ack подтверждение обработки уведомления в журнале, если журнал подключен
+func (d Dispatcher) ack(ctx context.Context, notificationUUID uuid.UUID) {
+	if d.journal == nil {
+		return
+	}
+
+	if err := d.journal.Ack(ctx, notificationUUID); err != nil {
+		d.logger.Error("Dispatcher journal Ack err", err)
+	}
+}
+
+// sendStat передача записи статистики, если подключен канал статистики
+func (d Dispatcher) sendStat(stat dto.Stat) {
+	if d.statChan != nil {
+		d.statChan <- stat
+	}
+}
+
 // listenInputChannel слушает канал с уведомлениями dto.Notification
 // и размещает Dispatch сообщения dto.Message для дальнейшей отправки
 func (d Dispatcher) listenInputChannel(ctx context.Context, input <-chan dto.Notification) {
 	for {
 		select {
 		case notification := <-input:
-			// выдаем UUID уведомлению
-			notification.NotificationUUID = uuid.New()
+			// выдаем UUID уведомлению, отложенные уведомления получают UUID в планировщике
+			if notification.NotificationUUID == uuid.Nil {
+				notification.NotificationUUID = uuid.New()
+			}
 
 			d.logger.Debug(fmt.Sprintf("Notification received: %v", notification))
 
@@ -122,12 +226,8 @@ func (d Dispatcher) listenInputChannel(ctx context.Context, input <-chan dto.Not
 			messages := d.buildMessages(ctx, notification)
 
 			// размещаем сообщения во внешнюю очередь на отправку
-			for i := 0; i < len(messages); i++ {
-				dErr := d.dispatch(messages[i])
-
-				if dErr != nil {
-					d.logger.Error("Message dispatch error", dErr)
-				}
+			if d.dispatchAll(ctx, messages) {
+				d.ack(ctx, notification.NotificationUUID)
 			}
 
 		case <-ctx.Done(): // отбой по контексту
@@ -138,6 +238,40 @@ func (d Dispatcher) listenInputChannel(ctx context.Context, input <-chan dto.Not
 	}
 }
 
+// dispatchAll передает сообщения уведомления в очередь. Не принятые брокером сообщения повторяются
+// с растущей задержкой до успеха или отмены ctx, уже переданные сообщения повторно не публикуются.
+// Возвращает false, если до отмены ctx переданы не все сообщения: уведомление остается в журнале
+func (d Dispatcher) dispatchAll(ctx context.Context, messages []dto.Message) bool {
+	delay := dispatchRetryBaseDelay
+
+	for {
+		var failed []dto.Message
+		for _, message := range messages {
+			if err := d.dispatch(message); err != nil {
+				d.logger.Error("Message dispatch error", err)
+				failed = append(failed, message)
+			}
+		}
+
+		if len(failed) == 0 {
+			return true
+		}
+		messages = failed
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+
+		if delay *= 2; delay > dispatchRetryMaxDelay {
+			delay = dispatchRetryMaxDelay
+		}
+	}
+}
+
 // buildMessages формирует клиентские сообщения из уведомления, шаблона и контактов
 func (d Dispatcher) buildMessages(ctx context.Context, notification dto.Notification) []dto.Message {
 	var messages []dto.Message
@@ -160,20 +294,43 @@ func (d Dispatcher) buildMessages(ctx context.Context, notification dto.Notifica
 		d.logger.Error("Dispatcher getTemplates err", err)
 	}
 
-	// Формируем доступные шаблоны - делаем подстановки параметров в текст
-	// структура preparedTemplates [тип_канала]текст_с_подстановками
-	preparedTemplates := make(map[string]string, len(templates))
+	// запрос предпочтений получателей, при ошибке сообщения отправляются без учета предпочтений
+	preferences, err := d.services.getPreferences(ctx, notification.PersonUUIDs)
+	if err != nil {
+		d.logger.Error("Dispatcher getPreferences err", err)
+	}
 
+	// Группируем шаблоны по каналам, в канале может быть несколько локалей
+	channelTemplates := make(map[string][]dto.Template, len(templates))
 	for _, template := range templates {
-		preparedTemplates[template.ChannelType] = d.services.prepareTemplate(template.Body, notification.MessageParams)
+		channelTemplates[template.ChannelType] = append(channelTemplates[template.ChannelType], template)
 	}
 
-	// для каждого канала в котором должно быть уведомление
-	for _, notificationChannel := range event.NotificationChannels {
+	builder := messageBuilder{
+		dispatcher:   d,
+		notification: notification,
+		event:        event,
+		templates:    channelTemplates,
+		preferences:  preferences,
+		// Подстановки выполняются один раз на шаблон, получатели с одной локалью используют общий текст.
+		// структура prepared [тип_канала|локаль_шаблона]текст_с_подстановками
+		prepared: make(map[string]string, len(templates)),
+	}
+
+	// каналы цепочки Fallback обслуживаются только цепочкой
+	chained := make(map[string]struct{}, len(event.Fallback))
+	for _, step := range event.Fallback {
+		chained[step.Channel] = struct{}{}
+	}
+
+	// для каждого канала события и дополнительного канала по правилам маршрутизации
+	for _, notificationChannel := range routing.Channels(event.NotificationChannels, notification.Channels) {
+		if _, exist := chained[notificationChannel]; exist {
+			continue
+		}
 
 		// проверяем что есть шаблон
-		template, exist := preparedTemplates[notificationChannel]
-		if !exist {
+		if _, exist := channelTemplates[notificationChannel]; !exist {
 			d.logger.Info(fmt.Sprintf("Template does not exist for channel: %v, event: %v", notificationChannel, event.EventUUID))
 			// если шаблона нет слать нечего, пропускаем канал
 			continue
@@ -181,27 +338,184 @@ func (d Dispatcher) buildMessages(ctx context.Context, notification dto.Notifica
 
 		// Для каждого пользователя берем нужный контакт
 		for _, contact := range contacts {
-			// выбор контакта для канала
-			relatedContact, cErr := contactLocator(notificationChannel, contact)
-			if cErr != nil {
-				d.logger.Error("Dispatcher contactLocator err", cErr)
-				continue
+			if message, ok := builder.build(contact, notificationChannel); ok {
+				messages = append(messages, message)
 			}
+		}
+	}
+
+	// цепочка резервных каналов: сообщение отправляется в первый доступный канал,
+	// подготовленные сообщения остальных каналов едут вместе с ним
+	if len(event.Fallback) > 0 {
+		for _, contact := range contacts {
+			if message, ok := builder.buildChain(contact); ok {
+				messages = append(messages, message)
+			}
+		}
+	}
+
+	return messages
+}
+
+// messageBuilder формирование сообщений одного уведомления
+type messageBuilder struct {
+	dispatcher   Dispatcher
+	notification dto.Notification
+	event        dto.Event
+	templates    map[string][]dto.Template
+	preferences  map[uuid.UUID][]dto.Preference
+	prepared     map[string]string
+}
 
-			d.logger.Debug("Message prepared")
+// build сообщение получателю в канал. Сообщение не формируется, если нет контакта или шаблона,
+// получатель отказался от канала или подстановка параметров завершилась ошибкой. Ошибка подстановки
+// фиксируется в статистике статусом Failed с причиной
+func (b messageBuilder) build(contact dto.PersonContacts, notificationChannel string) (dto.Message, bool) {
+	d := b.dispatcher
+
+	// выбор контакта для канала
+	relatedContact, cErr := contactLocator(notificationChannel, contact)
+	if cErr != nil {
+		d.logger.Error("Dispatcher contactLocator err", cErr)
+		return dto.Message{}, false
+	}
+
+	// получатель отказался от сообщений этой категории в канале
+	rule := preference.Resolve(b.preferences[contact.PersonUUID], b.event.Category, notificationChannel)
+	if !preference.Allows(rule, b.notification.Priority) {
+		d.logger.Info(fmt.Sprintf("Message skipped by preference %v, person: %v, channel: %v", rule.Mode, contact.PersonUUID, notificationChannel))
+		d.sendStat(dto.Stat{
+			PersonUUID:       contact.PersonUUID,
+			NotificationUUID: b.notification.NotificationUUID,
+			Channel:          notificationChannel,
+			Status:           dto.OptedOut,
+		})
+		return dto.Message{}, false
+	}
+
+	// локаль уведомления приоритетнее предпочтений получателя
+	locale := b.notification.Locale
+	if locale == "" {
+		locale = contact.Locale
+	}
+
+	// выбор шаблона по цепочке локалей ru-RU -> ru -> локаль по умолчанию
+	template, found := templating.SelectTemplate(b.templates[notificationChannel], locale)
+	if !found {
+		d.logger.Info(fmt.Sprintf("Template does not exist for channel: %v, locale: %v, event: %v", notificationChannel, locale, b.event.EventUUID))
+		return dto.Message{}, false
+	}
 
-			// и добавляем сообщение в слайс на отправку
-			messages = append(messages, dto.Message{
-				PersonUUID:         contact.PersonUUID,
-				NotificationUUID:   notification.NotificationUUID,
-				Text:               template,
-				Channel:            notificationChannel,
-				DestinationAddress: relatedContact.Destination,
+	key := notificationChannel + "|" + templating.NormalizeLocale(template.Locale)
+	text, prepared := b.prepared[key]
+	if !prepared {
+		var tErr error
+		text, tErr = d.services.prepareTemplate(template, b.notification.MessageParams)
+		if tErr != nil {
+			// сообщение с неполными подстановками не отправляем, причина видна в статистике уведомления
+			d.logger.Error(fmt.Sprintf("Dispatcher prepareTemplate err, template: %v", template.TemplateUUID), tErr)
+			d.sendStat(dto.Stat{
+				PersonUUID:       contact.PersonUUID,
+				NotificationUUID: b.notification.NotificationUUID,
+				Channel:          notificationChannel,
+				Status:           dto.Failed,
+				Reason:           tErr.Error(),
 			})
+			return dto.Message{}, false
 		}
+		b.prepared[key] = text
 	}
 
-	return messages
+	d.logger.Debug("Message prepared")
+
+	return dto.Message{
+		MessageUUID:        uuid.New(),
+		PersonUUID:         contact.PersonUUID,
+		NotificationUUID:   b.notification.NotificationUUID,
+		Text:               text,
+		ContentType:        templating.ContentType(template),
+		Channel:            notificationChannel,
+		DestinationAddress: relatedContact.Destination,
+		Attempt:            1,
+		DeferredUntil:      b.deferredUntil(contact),
+		Priority:           d.busPriority(b.notification.Priority),
+		ExpiresAt:          b.notification.ExpiresAt,
+	}, true
+}
+
+// busPriority приоритет сообщения в шине. Приоритет уведомления масштабируется к MaxPriority очередей,
+// CriticalPriority и выше соответствует максимальному. Без CriticalPriority приоритет ограничивается MaxPriority
+func (d Dispatcher) busPriority(priority uint) uint8 {
+	maxPriority := uint(d.config.GetTopology().MaxPriority)
+	critical := d.config.GetCriticalPriority()
+
+	switch {
+	case critical == 0 && priority > maxPriority, critical > 0 && priority >= critical:
+		return uint8(maxPriority)
+	case critical == 0:
+		return uint8(priority)
+	default:
+		return uint8(priority * maxPriority / critical)
+	}
+}
+
+// deferredUntil открытие ближайшего окна доставки по часовому поясу получателя,
+// nil - отправлять сразу. Уведомления с приоритетом CriticalPriority и выше окна не ждут
+func (b messageBuilder) deferredUntil(contact dto.PersonContacts) *time.Time {
+	d := b.dispatcher
+
+	if len(b.event.DeliveryWindows) == 0 {
+		return nil
+	}
+
+	critical := d.config.GetCriticalPriority()
+	if critical > 0 && b.notification.Priority >= critical {
+		return nil
+	}
+
+	location, err := deliveryWindow.Location(contact.TimeZone)
+	if err != nil {
+		// неизвестный часовой пояс не должен блокировать отправку, считаем по UTC
+		d.logger.Error(fmt.Sprintf("Dispatcher unknown time zone %q, person: %v", contact.TimeZone, contact.PersonUUID), err)
+		location = time.UTC
+	}
+
+	now := d.now().In(location)
+	open := deliveryWindow.NextOpen(b.event.DeliveryWindows, now)
+	if !open.After(now) {
+		return nil
+	}
+
+	return &open
+}
+
+// buildChain сообщение в первый доступный канал цепочки Fallback, остальные доступные
+// каналы по порядку вкладываются в dto.Message.Fallback. Недоступные каналы пропускаются,
+// время ожидания пропущенного шага не суммируется
+func (b messageBuilder) buildChain(contact dto.PersonContacts) (dto.Message, bool) {
+	var chain []dto.Message
+
+	for _, step := range b.event.Fallback {
+		message, ok := b.build(contact, step.Channel)
+		if !ok {
+			continue
+		}
+
+		message.FallbackAfter = step.After
+		chain = append(chain, message)
+	}
+
+	if len(chain) == 0 {
+		return dto.Message{}, false
+	}
+
+	first := chain[0]
+	first.FallbackAfter = 0
+	if len(chain) > 1 {
+		first.Fallback = chain[1:]
+	}
+
+	return first, true
 }
 
 // contactLocator Выбирает адрес назначения (телефон, емейл, и пр) для определенного канала
@@ -3,50 +3,57 @@ package notificationDispatcher
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/streadway/amqp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/atrian/go-notify-customer/internal/dto"
 	"github.com/atrian/go-notify-customer/internal/interfaces"
+	"github.com/atrian/go-notify-customer/internal/services/preference"
 	"github.com/atrian/go-notify-customer/pkg/logger"
 )
 
 var (
-	_ dispatcherConfig      = (*configMock)(nil)
-	_ eventService          = (*eventMock)(nil)
-	_ templateService       = (*templateMock)(nil)
-	_ contactVault          = (*contactMock)(nil)
-	_ interfaces.AmpqClient = (*ampqMock)(nil)
+	_ dispatcherConfig = (*configMock)(nil)
+	_ eventService     = (*eventMock)(nil)
+	_ templateService  = (*templateMock)(nil)
+	_ contactVault     = (*contactMock)(nil)
+	_ interfaces.Bus   = (*busMock)(nil)
 )
 
 type DispatcherServiceTestSuite struct {
 	suite.Suite
 	config     dispatcherConfig
 	dispatcher *Dispatcher
-	ampq       interfaces.AmpqClient
+	bus        interfaces.Bus
 	inputCh    chan dto.Notification
 	outChan    chan string
+	statChan   chan dto.Stat
 }
 
 func (suite *DispatcherServiceTestSuite) SetupSuite() {
 	inputCh := make(chan dto.Notification)
 	suite.inputCh = inputCh
 	outChan := make(chan string)
-	suite.ampq = newAmpqMock(outChan)
+	suite.bus = newBusMock(outChan)
 	suite.outChan = outChan
 
+	suite.statChan = make(chan dto.Stat, 1)
+
 	suite.config = &configMock{}
 	suite.dispatcher = New(
 		inputCh,
 		suite.config,
 		NewDispatcherServiceFacade(&contactMock{}, &templateMock{}, &eventMock{}),
-		suite.ampq,
-		logger.NewZapLogger())
+		suite.bus,
+		logger.NewZapLogger()).
+		SetStatChan(suite.statChan)
 
 	suite.dispatcher.Start(context.Background())
 }
@@ -65,18 +72,520 @@ func (suite *DispatcherServiceTestSuite) Test_dispatch() {
 	}
 
 	message := <-suite.outChan
-	expected := fmt.Sprintf("queue: %v, message text: test message", suite.config.GetNotificationQueue())
+	expected := fmt.Sprintf("queue: %v, message text: test message", suite.config.GetTopology().Queue("sms"))
 	assert.Equal(suite.T(), expected, message)
+
+	// переданное в очередь сообщение фиксируется в статистике
+	stat := <-suite.statChan
+	assert.Equal(suite.T(), dto.Dispatched, stat.Status)
+	assert.NotEmpty(suite.T(), stat.Channel)
+}
+
+// TestDispatcher_journalAck уведомление подтверждается в журнале после передачи сообщений в очередь
+func TestDispatcher_journalAck(t *testing.T) {
+	inputCh := make(chan dto.Notification)
+	outChan := make(chan string, 1)
+	acked := make(journalMock, 1)
+
+	dispatcher := New(inputCh, &configMock{},
+		NewDispatcherServiceFacade(&contactMock{}, &templateMock{}, &eventMock{}),
+		newBusMock(outChan), logger.NewZapLogger()).
+		SetJournal(acked)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.listenInputChannel(ctx, inputCh)
+
+	notificationUUID := uuid.New()
+	inputCh <- dto.Notification{NotificationUUID: notificationUUID, PersonUUIDs: []uuid.UUID{uuid.New()}}
+
+	<-outChan
+	assert.Equal(t, notificationUUID, <-acked)
+}
+
+// TestDispatcher_journalAck_nacked без подтверждения брокера уведомление остается в журнале
+func TestDispatcher_journalAck_nacked(t *testing.T) {
+	inputCh := make(chan dto.Notification)
+	statChan := make(chan dto.Stat, 1)
+	acked := make(journalMock, 1)
+	client := &nackBusMock{busMock: newBusMock(make(chan string)), published: make(chan struct{}, 1)}
+
+	dispatcher := New(inputCh, &configMock{},
+		NewDispatcherServiceFacade(&contactMock{}, &templateMock{}, &eventMock{}),
+		client, logger.NewZapLogger()).
+		SetStatChan(statChan).
+		SetJournal(acked)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.listenInputChannel(ctx, inputCh)
+
+	inputCh <- dto.Notification{NotificationUUID: uuid.New(), PersonUUIDs: []uuid.UUID{uuid.New()}}
+	<-client.published
+
+	select {
+	case <-acked:
+		t.Fatal("nacked notification acked in journal")
+	case stat := <-statChan:
+		t.Fatalf("unexpected stat %v", stat.Status)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// errNacked брокер не подтвердил публикацию
+var errNacked = errors.New("nacked")
+
+// TestDispatcher_journalAck_partial при отказе брокера повторяются только не принятые сообщения
+func TestDispatcher_journalAck_partial(t *testing.T) {
+	inputCh := make(chan dto.Notification)
+	acked := make(journalMock, 1)
+	client := &flakyBusMock{busMock: newBusMock(make(chan string)), out: make(chan dto.Message, 10)}
+
+	dispatcher := New(inputCh, &configMock{},
+		NewDispatcherServiceFacade(&contactMock{}, &templateMock{}, &eventMock{}),
+		client, logger.NewZapLogger()).
+		SetJournal(acked)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.listenInputChannel(ctx, inputCh)
+
+	first, second := uuid.New(), uuid.New()
+	notificationUUID := uuid.New()
+	inputCh <- dto.Notification{NotificationUUID: notificationUUID, PersonUUIDs: []uuid.UUID{first, second}}
+
+	assert.Equal(t, notificationUUID, <-acked)
+
+	// каждое сообщение принято брокером ровно один раз
+	close(client.out)
+	persons := make(map[uuid.UUID]int)
+	for message := range client.out {
+		persons[message.PersonUUID]++
+	}
+	assert.Equal(t, map[uuid.UUID]int{first: 1, second: 1}, persons)
+}
+
+// nackBusMock брокер отклоняет публикации
+type nackBusMock struct {
+	*busMock
+	published chan struct{}
+}
+
+func (a *nackBusMock) PublishRouted(exchange string, routingKey string, msgBody []byte, priority uint8, expiration time.Duration) error {
+	select {
+	case a.published <- struct{}{}:
+	default:
+	}
+	return errNacked
+}
+
+// flakyBusMock брокер отклоняет первую публикацию, принятые сообщения передаются в канал out
+type flakyBusMock struct {
+	*busMock
+	mu       sync.Mutex
+	rejected bool
+	out      chan dto.Message
+}
+
+func (f *flakyBusMock) PublishRouted(exchange string, routingKey string, msgBody []byte, priority uint8, expiration time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.rejected {
+		f.rejected = true
+		return errNacked
+	}
+
+	var message dto.Message
+	if err := json.Unmarshal(msgBody, &message); err != nil {
+		return err
+	}
+	f.out <- message
+	return nil
+}
+
+type journalMock chan uuid.UUID
+
+func (j journalMock) Ack(ctx context.Context, notificationUUID uuid.UUID) error {
+	j <- notificationUUID
+	return nil
+}
+
+// TestDispatcher_buildMessages_locale шаблон выбирается по локали получателя с откатом к локали по умолчанию
+func TestDispatcher_buildMessages_locale(t *testing.T) {
+	ruPerson, gbPerson, dePerson := uuid.New(), uuid.New(), uuid.New()
+	contacts := localeContactMock{ruPerson: "ru-RU", gbPerson: "en_gb", dePerson: "de"}
+
+	dispatcher := New(nil, &configMock{},
+		NewDispatcherServiceFacade(contacts, &localizedTemplateMock{}, &eventMock{}),
+		nil, logger.NewZapLogger())
+
+	notification := dto.Notification{PersonUUIDs: []uuid.UUID{ruPerson, gbPerson, dePerson}}
+
+	texts := func(messages []dto.Message) map[uuid.UUID]string {
+		result := make(map[uuid.UUID]string, len(messages))
+		for _, message := range messages {
+			result[message.PersonUUID] = message.Text
+		}
+		return result
+	}
+
+	assert.Equal(t, map[uuid.UUID]string{
+		ruPerson: "Привет",
+		gbPerson: "Hello, mate",
+		dePerson: "Hello",
+	}, texts(dispatcher.buildMessages(context.Background(), notification)))
+
+	// локаль уведомления приоритетнее предпочтений получателей
+	notification.Locale = "ru"
+	assert.Equal(t, map[uuid.UUID]string{
+		ruPerson: "Привет",
+		gbPerson: "Привет",
+		dePerson: "Привет",
+	}, texts(dispatcher.buildMessages(context.Background(), notification)))
+}
+
+// TestDispatcher_buildMessages_preferences сообщения не формируются для каналов, от которых отказался получатель
+func TestDispatcher_buildMessages_preferences(t *testing.T) {
+	optedOut, quiet, subscribed := uuid.New(), uuid.New(), uuid.New()
+
+	preferences := preference.New(logger.NewZapLogger())
+	_, _ = preferences.Store(context.Background(), dto.Preference{PersonUUID: optedOut, Category: "marketing", Channel: "email", Mode: dto.PreferenceOptOut})
+	_, _ = preferences.Store(context.Background(), dto.Preference{PersonUUID: quiet, Mode: dto.PreferenceQuiet, MinPriority: 100})
+	// более точное правило категории перекрывает общий отказ от канала
+	_, _ = preferences.Store(context.Background(), dto.Preference{PersonUUID: subscribed, Channel: "email", Mode: dto.PreferenceOptOut})
+	_, _ = preferences.Store(context.Background(), dto.Preference{PersonUUID: subscribed, Category: "marketing", Channel: "email", Mode: dto.PreferenceOptIn})
+
+	statChan := make(chan dto.Stat, 10)
+	dispatcher := New(nil, &configMock{},
+		NewDispatcherServiceFacade(&channelsContactMock{}, &channelsTemplateMock{}, categoryEventMock("marketing")).
+			SetPreferences(preferences),
+		nil, logger.NewZapLogger()).
+		SetStatChan(statChan)
+
+	notification := dto.Notification{
+		NotificationUUID: uuid.New(),
+		PersonUUIDs:      []uuid.UUID{optedOut, quiet, subscribed},
+		Priority:         10,
+	}
+
+	sent := make(map[string]bool)
+	for _, message := range dispatcher.buildMessages(context.Background(), notification) {
+		sent[fmt.Sprintf("%v|%v", message.PersonUUID, message.Channel)] = true
+	}
+
+	assert.Equal(t, map[string]bool{
+		fmt.Sprintf("%v|sms", optedOut):     true,
+		fmt.Sprintf("%v|sms", subscribed):   true,
+		fmt.Sprintf("%v|email", subscribed): true,
+	}, sent)
+
+	// пропущенные сообщения фиксируются статусом OptedOut
+	close(statChan)
+	skipped := make(map[string]dto.StatStatus)
+	for stat := range statChan {
+		skipped[fmt.Sprintf("%v|%v", stat.PersonUUID, stat.Channel)] = stat.Status
+	}
+
+	assert.Equal(t, map[string]dto.StatStatus{
+		fmt.Sprintf("%v|email", optedOut): dto.OptedOut,
+		fmt.Sprintf("%v|sms", quiet):      dto.OptedOut,
+		fmt.Sprintf("%v|email", quiet):    dto.OptedOut,
+	}, skipped)
+}
+
+// TestDispatcher_buildMessages_renderError ошибка подстановки фиксируется статусом Failed с причиной
+func TestDispatcher_buildMessages_renderError(t *testing.T) {
+	personUUID := uuid.New()
+
+	statChan := make(chan dto.Stat, 10)
+	dispatcher := New(nil, &configMock{},
+		NewDispatcherServiceFacade(&channelsContactMock{}, &brokenTemplateMock{}, categoryEventMock("")),
+		nil, logger.NewZapLogger()).
+		SetStatChan(statChan)
+
+	notification := dto.Notification{NotificationUUID: uuid.New(), PersonUUIDs: []uuid.UUID{personUUID}}
+	messages := dispatcher.buildMessages(context.Background(), notification)
+
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "sms", messages[0].Channel)
+
+	stat := <-statChan
+	assert.Equal(t, dto.Failed, stat.Status)
+	assert.Equal(t, "email", stat.Channel)
+	assert.Equal(t, personUUID, stat.PersonUUID)
+	assert.Equal(t, notification.NotificationUUID, stat.NotificationUUID)
+	assert.Contains(t, stat.Reason, "order")
+}
+
+// TestDispatcher_buildMessages_fallback каналы без контакта пропускаются, остальные вкладываются в цепочку
+func TestDispatcher_buildMessages_fallback(t *testing.T) {
+	personUUID := uuid.New()
+
+	dispatcher := New(nil, &configMock{},
+		NewDispatcherServiceFacade(&channelsContactMock{}, &channelsTemplateMock{}, fallbackEventMock{}),
+		nil, logger.NewZapLogger())
+
+	messages := dispatcher.buildMessages(context.Background(), dto.Notification{PersonUUIDs: []uuid.UUID{personUUID}})
+
+	// sms из NotificationChannels отправляется только в составе цепочки
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "sms", messages[0].Channel)
+	assert.Zero(t, messages[0].FallbackAfter)
+
+	assert.Len(t, messages[0].Fallback, 1)
+	assert.Equal(t, "email", messages[0].Fallback[0].Channel)
+	assert.Equal(t, "email message", messages[0].Fallback[0].Text)
+	assert.Equal(t, "person@example.com", messages[0].Fallback[0].DestinationAddress)
+	assert.Equal(t, 300, messages[0].Fallback[0].FallbackAfter)
+}
+
+// TestDispatcher_buildMessages_routingChannels дополнительные каналы правил маршрутизации
+// отправляются вместе с каналами события
+func TestDispatcher_buildMessages_routingChannels(t *testing.T) {
+	dispatcher := New(nil, &configMock{},
+		NewDispatcherServiceFacade(&channelsContactMock{}, &channelsTemplateMock{}, categoryEventMock("")),
+		nil, logger.NewZapLogger())
+
+	notification := dto.Notification{PersonUUIDs: []uuid.UUID{uuid.New()}}
+	assert.Len(t, dispatcher.buildMessages(context.Background(), notification), 2)
+
+	// канал без шаблона пропускается, повтор канала события не дублирует сообщение
+	notification.Channels = []string{"sms", "telegram"}
+	assert.Len(t, dispatcher.buildMessages(context.Background(), notification), 2)
+
+	// событие только с email, sms добавлен правилом
+	dispatcher = New(nil, &configMock{},
+		NewDispatcherServiceFacade(&channelsContactMock{}, &channelsTemplateMock{}, routedEventMock{}),
+		nil, logger.NewZapLogger())
+
+	notification.Channels = []string{"sms"}
+	var channels []string
+	for _, message := range dispatcher.buildMessages(context.Background(), notification) {
+		channels = append(channels, message.Channel)
+	}
+	assert.Equal(t, []string{"email", "sms"}, channels)
+}
+
+// TestDispatcher_busPriority приоритет уведомления масштабируется к приоритету очередей,
+// CriticalPriority соответствует максимальному
+func TestDispatcher_busPriority(t *testing.T) {
+	dispatcher := New(nil, &configMock{}, nil, nil, logger.NewZapLogger())
+
+	tests := []struct {
+		priority uint
+		want     uint8
+	}{
+		{priority: 0, want: 0},
+		{priority: 10, want: 0},
+		{priority: 100, want: 1},
+		{priority: 900, want: 9},
+		{priority: 1000, want: 10},
+		{priority: 5000, want: 10},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, dispatcher.busPriority(tt.priority), "priority %v", tt.priority)
+	}
+}
+
+// TestDispatcher_dispatch_unroutable сообщение канала без очереди не публикуется
+func TestDispatcher_dispatch_unroutable(t *testing.T) {
+	statChan := make(chan dto.Stat, 1)
+	dispatcher := New(nil, &configMock{}, nil, newBusMock(make(chan string)), logger.NewZapLogger()).
+		SetStatChan(statChan)
+
+	assert.NoError(t, dispatcher.dispatch(dto.Message{Channel: "pigeon"}))
+	assert.Equal(t, dto.BadChannel, (<-statChan).Status)
+}
+
+// TestDispatcher_deliveryWindows сообщение вне окна доставки откладывается до его открытия
+// по часовому поясу получателя, критичные уведомления окна не ждут
+func TestDispatcher_deliveryWindows(t *testing.T) {
+	moscow, vladivostok := uuid.New(), uuid.New()
+	contacts := timeZoneContactMock{moscow: "Europe/Moscow", vladivostok: "Asia/Vladivostok"}
+
+	outChan := make(chan string, 2)
+	statChan := make(chan dto.Stat, 2)
+	dispatcher := New(nil, &configMock{},
+		NewDispatcherServiceFacade(contacts, &templateMock{}, windowEventMock{}),
+		newBusMock(outChan), logger.NewZapLogger()).
+		SetStatChan(statChan)
+
+	// 03:00 UTC - 06:00 в Москве и 13:00 во Владивостоке
+	now := time.Date(2023, 3, 22, 3, 0, 0, 0, time.UTC)
+	dispatcher.now = func() time.Time { return now }
+
+	notification := dto.Notification{PersonUUIDs: []uuid.UUID{moscow, vladivostok}, Priority: 100}
+	messages := make(map[uuid.UUID]dto.Message)
+	for _, message := range dispatcher.buildMessages(context.Background(), notification) {
+		messages[message.PersonUUID] = message
+	}
+
+	assert.Nil(t, messages[vladivostok].DeferredUntil)
+	if assert.NotNil(t, messages[moscow].DeferredUntil) {
+		assert.True(t, time.Date(2023, 3, 22, 6, 0, 0, 0, time.UTC).Equal(*messages[moscow].DeferredUntil))
+	}
+
+	// отложенное сообщение ждет в очереди повторов
+	assert.NoError(t, dispatcher.dispatch(messages[moscow]))
+	assert.Equal(t, "queue: failed_queue, message text: test message", <-outChan)
+	assert.Equal(t, dto.Deferred, (<-statChan).Status)
+
+	assert.NoError(t, dispatcher.dispatch(messages[vladivostok]))
+	assert.Equal(t, "queue: notify.sms, message text: test message", <-outChan)
+	assert.Equal(t, dto.Dispatched, (<-statChan).Status)
+
+	// критичный приоритет и выше отправляются сразу, как и получают максимальный приоритет в шине
+	for _, priority := range []uint{1000, 1001} {
+		notification.Priority = priority
+		for _, message := range dispatcher.buildMessages(context.Background(), notification) {
+			assert.Nil(t, message.DeferredUntil)
+			assert.Equal(t, uint8(10), message.Priority)
+		}
+	}
+
+	// приоритет ниже критичного ждет окна
+	notification.Priority = 999
+	messages = make(map[uuid.UUID]dto.Message)
+	for _, message := range dispatcher.buildMessages(context.Background(), notification) {
+		messages[message.PersonUUID] = message
+	}
+	assert.NotNil(t, messages[moscow].DeferredUntil)
+}
+
+type windowEventMock struct{}
+
+func (e windowEventMock) FindById(ctx context.Context, eventUUID uuid.UUID) (dto.Event, error) {
+	return dto.Event{
+		EventUUID:            eventUUID,
+		Title:                "event with delivery windows",
+		NotificationChannels: []string{"sms"},
+		DeliveryWindows:      []dto.DeliveryWindow{{From: "09:00", To: "21:00"}},
+	}, nil
+}
+
+type timeZoneContactMock map[uuid.UUID]string
+
+func (c timeZoneContactMock) FindByPersonUUID(ctx context.Context, personUUID uuid.UUID) (dto.PersonContacts, error) {
+	return dto.PersonContacts{
+		PersonUUID: personUUID,
+		Contacts:   []dto.Contact{{Channel: "sms", Destination: "888"}},
+		TimeZone:   c[personUUID],
+	}, nil
+}
+
+func (c timeZoneContactMock) Stop() error {
+	return nil
+}
+
+type fallbackEventMock struct{}
+
+func (e fallbackEventMock) FindById(ctx context.Context, eventUUID uuid.UUID) (dto.Event, error) {
+	return dto.Event{
+		EventUUID:            eventUUID,
+		Title:                "event with fallback",
+		NotificationChannels: []string{"sms"},
+		Fallback: []dto.FallbackStep{
+			{Channel: "telegram"},
+			{Channel: "sms", After: 60},
+			{Channel: "email", After: 300},
+		},
+	}, nil
+}
+
+type routedEventMock struct{}
+
+func (e routedEventMock) FindById(ctx context.Context, eventUUID uuid.UUID) (dto.Event, error) {
+	return dto.Event{
+		EventUUID:            eventUUID,
+		Title:                "email only event",
+		NotificationChannels: []string{"email"},
+	}, nil
+}
+
+type categoryEventMock string
+
+func (e categoryEventMock) FindById(ctx context.Context, eventUUID uuid.UUID) (dto.Event, error) {
+	return dto.Event{
+		EventUUID:            eventUUID,
+		Title:                "categorized event",
+		NotificationChannels: []string{"sms", "email"},
+		Category:             string(e),
+	}, nil
+}
+
+type channelsContactMock struct{}
+
+func (c *channelsContactMock) FindByPersonUUID(ctx context.Context, personUUID uuid.UUID) (dto.PersonContacts, error) {
+	return dto.PersonContacts{
+		PersonUUID: personUUID,
+		Contacts: []dto.Contact{
+			{Channel: "sms", Destination: "888"},
+			{Channel: "email", Destination: "person@example.com"},
+		},
+	}, nil
+}
+
+func (c *channelsContactMock) Stop() error {
+	return nil
+}
+
+type channelsTemplateMock struct{}
+
+func (t *channelsTemplateMock) FindByEventId(ctx context.Context, eventUUID uuid.UUID) ([]dto.Template, error) {
+	return []dto.Template{
+		{Body: "sms message", ChannelType: "sms"},
+		{Body: "email message", ChannelType: "email"},
+	}, nil
+}
+
+// brokenTemplateMock шаблон email ссылается на отсутствующий параметр
+type brokenTemplateMock struct{}
+
+func (t *brokenTemplateMock) FindByEventId(ctx context.Context, eventUUID uuid.UUID) ([]dto.Template, error) {
+	return []dto.Template{
+		{Body: "sms message", ChannelType: "sms"},
+		{Body: "order {{ .order }}", ChannelType: "email", Syntax: dto.SyntaxTemplate},
+	}, nil
+}
+
+type localeContactMock map[uuid.UUID]string
+
+func (c localeContactMock) FindByPersonUUID(ctx context.Context, personUUID uuid.UUID) (dto.PersonContacts, error) {
+	return dto.PersonContacts{
+		PersonUUID: personUUID,
+		Contacts:   []dto.Contact{{Channel: "sms", Destination: "888"}},
+		Locale:     c[personUUID],
+	}, nil
+}
+
+func (c localeContactMock) Stop() error {
+	return nil
+}
+
+type localizedTemplateMock struct{}
+
+func (t *localizedTemplateMock) FindByEventId(ctx context.Context, eventUUID uuid.UUID) ([]dto.Template, error) {
+	return []dto.Template{
+		{Body: "Привет", ChannelType: "sms", Locale: "ru"},
+		{Body: "Hello", ChannelType: "sms"},
+		{Body: "Hello, mate", ChannelType: "sms", Locale: "en-GB"},
+	}, nil
 }
 
 type configMock struct{}
 
-func (c *configMock) GetAmpqDSN() string {
-	return ""
+func (c *configMock) GetTopology() dto.Topology {
+	return dto.Topology{Exchange: "notify", Channels: []string{"sms", "email"}, MaxPriority: 10, DeadLetterQueue: "dead_queue"}
+}
+
+func (c *configMock) GetFailedWorksQueue() string {
+	return "failed_queue"
 }
 
-func (c *configMock) GetNotificationQueue() string {
-	return "message_queue"
+func (c *configMock) GetCriticalPriority() uint {
+	return 1000
 }
 
 type eventMock struct{}
@@ -124,36 +633,36 @@ func (t *templateMock) FindByEventId(ctx context.Context, eventUUID uuid.UUID) (
 	}, nil
 }
 
-type ampqMock struct {
+type busMock struct {
 	outputChan chan string
 }
 
-func newAmpqMock(outputChan chan string) *ampqMock {
-	am := ampqMock{outputChan: outputChan}
+func newBusMock(outputChan chan string) *busMock {
+	am := busMock{outputChan: outputChan}
 	return &am
 }
 
-func (a *ampqMock) Connect(dsn string) error {
+func (a *busMock) Connect() error {
 	return nil
 }
 
-func (a *ampqMock) Reconnect() error {
+func (a *busMock) Qos(prefetchCount int) error {
 	return nil
 }
 
-func (a *ampqMock) MigrateDurableQueues(queues ...string) {
+func (a *busMock) MigrateDurableQueues(queues ...string) {
 	return
 }
 
-func (a *ampqMock) Channel() *amqp.Channel {
+func (a *busMock) MigrateTopology(topology dto.Topology) error {
 	return nil
 }
 
-func (a *ampqMock) Consume(queue string) (<-chan amqp.Delivery, error) {
+func (a *busMock) Subscribe(queue string) (<-chan dto.Delivery, error) {
 	return nil, nil
 }
 
-func (a *ampqMock) Publish(queue string, msgBody []byte) error {
+func (a *busMock) Publish(queue string, msgBody []byte) error {
 	var message dto.Message
 	_ = json.Unmarshal(msgBody, &message)
 
@@ -162,7 +671,15 @@ func (a *ampqMock) Publish(queue string, msgBody []byte) error {
 	return nil
 }
 
-func (a *ampqMock) Stop() {
+func (a *busMock) PublishDelayed(queue string, msgBody []byte, delay time.Duration) error {
+	return a.Publish(queue, msgBody)
+}
+
+func (a *busMock) PublishRouted(exchange string, routingKey string, msgBody []byte, priority uint8, expiration time.Duration) error {
+	return a.Publish(exchange+"."+routingKey, msgBody)
+}
+
+func (a *busMock) Stop() {
 	return
 }
 
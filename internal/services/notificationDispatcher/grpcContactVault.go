@@ -68,6 +68,8 @@ func (g GrpcContactVault) FindByPersonUUID(ctx context.Context, personUUID uuid.
 	return dto.PersonContacts{
 		PersonUUID: personUUID,
 		Contacts:   contacts,
+		Locale:     resp.GetLocale(),
+		TimeZone:   resp.GetTimeZone(),
 	}, nil
 }
 
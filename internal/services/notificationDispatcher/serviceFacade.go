@@ -2,20 +2,14 @@ package notificationDispatcher
 
 import (
 	"context"
-	"regexp"
 
 	"github.com/google/uuid"
 
 	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/templating"
 )
 
-var (
-	_ serviceGateway = (*ServiceFacade)(nil)
-	// regexp для поиска параметров [param1] [param2] в теле сообщения
-	templateRe = regexp.MustCompile(`(?m)\[([a-zA-Z]+\d*)]`)
-	// regexp для замены множественных пробелов на один
-	spaceRe = regexp.MustCompile(`\s+`)
-)
+var _ serviceGateway = (*ServiceFacade)(nil)
 
 // contactVault интерфейс клиента хранилища контакных данных
 type contactVault interface {
@@ -35,11 +29,17 @@ type eventService interface {
 	FindById(ctx context.Context, eventUUID uuid.UUID) (dto.Event, error)
 }
 
+// preferenceService контракт на сервис предпочтений получателей
+type preferenceService interface {
+	FindByPersonUUIDs(ctx context.Context, personUUIDs []uuid.UUID) (map[uuid.UUID][]dto.Preference, error)
+}
+
 // ServiceFacade сервисный фасад для нужд notificationDispatcher
 type ServiceFacade struct {
-	contact  contactVault
-	template templateService
-	event    eventService
+	contact    contactVault
+	template   templateService
+	event      eventService
+	preference preferenceService
 }
 
 func NewDispatcherServiceFacade(contact contactVault, template templateService, event eventService) *ServiceFacade {
@@ -52,6 +52,12 @@ func NewDispatcherServiceFacade(contact contactVault, template templateService,
 	return &f
 }
 
+// SetPreferences подключение сервиса предпочтений получателей, без него сообщения отправляются во все каналы
+func (f *ServiceFacade) SetPreferences(preference preferenceService) *ServiceFacade {
+	f.preference = preference
+	return f
+}
+
 func (f *ServiceFacade) getContacts(ctx context.Context, personUUIDs []uuid.UUID) ([]dto.PersonContacts, error) {
 	contacts := make([]dto.PersonContacts, 0, len(personUUIDs))
 
@@ -67,25 +73,18 @@ func (f *ServiceFacade) getTemplates(ctx context.Context, eventUuid uuid.UUID) (
 	return f.template.FindByEventId(ctx, eventUuid)
 }
 
+func (f *ServiceFacade) getPreferences(ctx context.Context, personUUIDs []uuid.UUID) (map[uuid.UUID][]dto.Preference, error) {
+	if f.preference == nil {
+		return nil, nil
+	}
+
+	return f.preference.FindByPersonUUIDs(ctx, personUUIDs)
+}
+
 func (f *ServiceFacade) getEvent(ctx context.Context, eventUuid uuid.UUID) (dto.Event, error) {
 	return f.event.FindById(ctx, eventUuid)
 }
 
-func (f *ServiceFacade) prepareTemplate(template string, replaces []dto.MessageParam) string {
-	// Собираем таблицу замен
-	replaceDict := make(map[string]string, len(replaces))
-	for _, el := range replaces {
-		replaceDict[el.Key] = el.Value
-	}
-
-	// Заменяем в строке все key1 в формате [key1] на значение replaceDict[key1]
-	result := templateRe.ReplaceAllFunc([]byte(template), func(bytes []byte) []byte {
-		key := string(bytes[1 : len(bytes)-1])
-		if val, ok := replaceDict[key]; ok {
-			return []byte(val)
-		}
-		return []byte{}
-	})
-
-	return spaceRe.ReplaceAllString(string(result), " ")
+func (f *ServiceFacade) prepareTemplate(template dto.Template, replaces []dto.MessageParam) (string, error) {
+	return templating.Render(template, replaces)
 }
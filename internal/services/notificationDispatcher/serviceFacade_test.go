@@ -60,7 +60,7 @@ func TestServiceFacade_prepareTemplate(t *testing.T) {
 				template: tt.fields.template,
 				event:    tt.fields.event,
 			}
-			if got := f.prepareTemplate(tt.args.template, tt.args.replaces); got != tt.want {
+			if got, _ := f.prepareTemplate(dto.Template{Body: tt.args.template}, tt.args.replaces); got != tt.want {
 				t.Errorf("prepareTemplate() = %v, want %v", got, tt.want)
 			}
 		})
@@ -0,0 +1,165 @@
+package notify
+
+import (
+	"sync"
+	"time"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// queueWaitBands нижние границы диапазонов приоритета для метрик ожидания
+var queueWaitBands = []uint{0, 10, 100, 1000}
+
+// deliveryQueue принятые уведомления, ожидающие передачи диспетчеру в порядке QueuePolicy.
+// Общая для копий Service, методы сервиса объявлены на значении
+type deliveryQueue struct {
+	mu      sync.Mutex
+	policy  QueuePolicy
+	waits   []waitStat    // waits ожидание переданных уведомлений по queueWaitBands
+	wake    chan struct{} // wake сигнал о новых уведомлениях, емкость 1
+	stop    chan struct{} // stop закрывается при остановке сервиса
+	done    chan struct{} // done закрывается при завершении передачи диспетчеру
+	started bool
+	now     func() time.Time
+}
+
+// waitStat накопленное время ожидания уведомлений диапазона приоритета
+type waitStat struct {
+	delivered int64
+	total     time.Duration
+	max       time.Duration
+}
+
+func newDeliveryQueue() *deliveryQueue {
+	q := deliveryQueue{
+		policy: NewStrictPolicy(),
+		waits:  make([]waitStat, len(queueWaitBands)),
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		now:    time.Now,
+	}
+
+	return &q
+}
+
+// setPolicy замена политики, уведомления в очереди переносятся в новую политику
+func (q *deliveryQueue) setPolicy(policy QueuePolicy) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for notification := q.policy.Pop(); notification != nil; notification = q.policy.Pop() {
+		policy.Push(notification)
+	}
+	q.policy = policy
+}
+
+// push добавляет уведомления и будит передачу.
+// Уведомлениям без времени приема, например из журнала до обновления, время приема - момент добавления
+func (q *deliveryQueue) push(notifications []dto.Notification) {
+	q.mu.Lock()
+	now := q.now()
+	for i := range notifications {
+		notification := notifications[i]
+		if notification.AcceptedAt == nil {
+			notification.AcceptedAt = &now
+		}
+		q.policy.Push(&notification)
+	}
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pop следующее по политике уведомление, false - очередь пуста
+func (q *deliveryQueue) pop() (dto.Notification, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	notification := q.policy.Pop()
+	if notification == nil {
+		return dto.Notification{}, false
+	}
+
+	return *notification, true
+}
+
+// delivered фиксирует время ожидания уведомления, переданного диспетчеру
+func (q *deliveryQueue) delivered(notification dto.Notification) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	wait := q.now().Sub(*notification.AcceptedAt)
+	band := 0
+	for i, minPriority := range queueWaitBands {
+		if notification.Priority >= minPriority {
+			band = i
+		}
+	}
+
+	stat := &q.waits[band]
+	stat.delivered++
+	stat.total += wait
+	if wait > stat.max {
+		stat.max = wait
+	}
+}
+
+// stats метрики очереди и ожидания переданных уведомлений
+func (q *deliveryQueue) stats() dto.QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	res := dto.QueueStats{
+		Policy: q.policy.Name(),
+		Length: q.policy.Len(),
+		Bands:  make([]dto.QueueBandStats, 0, len(queueWaitBands)),
+	}
+
+	if oldest, ok := q.policy.Oldest(); ok {
+		res.OldestWaitSeconds = q.now().Sub(oldest).Seconds()
+	}
+
+	for i, minPriority := range queueWaitBands {
+		stat := q.waits[i]
+		band := dto.QueueBandStats{
+			MinPriority:    minPriority,
+			Delivered:      stat.delivered,
+			WaitMaxSeconds: stat.max.Seconds(),
+		}
+		if stat.delivered > 0 {
+			band.WaitAvgSeconds = (stat.total / time.Duration(stat.delivered)).Seconds()
+		}
+		res.Bands = append(res.Bands, band)
+	}
+
+	return res
+}
+
+// start отмечает запуск передачи, повторный запуск не выполняется
+func (q *deliveryQueue) start() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.started {
+		return false
+	}
+	q.started = true
+
+	return true
+}
+
+// shutdown останавливает передачу и дожидается ее завершения
+func (q *deliveryQueue) shutdown() {
+	q.mu.Lock()
+	started := q.started
+	q.mu.Unlock()
+
+	close(q.stop)
+	if started {
+		<-q.done
+	}
+}
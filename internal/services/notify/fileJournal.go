@@ -0,0 +1,300 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/interfaces"
+)
+
+const (
+	// compactAfterAcks количество подтверждений, после которого журнал переписывается
+	// только с неподтвержденными уведомлениями
+	compactAfterAcks = 1000
+	// compactRetryInterval пауза перед повтором неудачного сжатия
+	compactRetryInterval = time.Minute
+)
+
+// Операции записей журнала
+const (
+	journalAppend = "append"
+	journalAck    = "ack"
+)
+
+// journalRecord строка журнала в формате json
+type journalRecord struct {
+	Op               string            `json:"op"`
+	Notification     *dto.Notification `json:"notification,omitempty"`
+	NotificationUUID uuid.UUID         `json:"notification_uuid,omitempty"`
+}
+
+// FileJournal журнал упреждающей записи в локальном файле, одна json запись на строку.
+// Принятые уведомления записываются с fsync до возврата из Append. Подтверждения пишутся без fsync:
+// потерянное при сбое подтверждение приводит к повторной обработке уведомления после перезапуска.
+// Недописанная при сбое последняя строка отбрасывается при открытии.
+// Неудачное сжатие не влияет на подтверждения и повторяется не чаще compactRetryInterval
+type FileJournal struct {
+	mu        sync.Mutex
+	path      string
+	file      *os.File
+	seq       uint64
+	entries   map[uuid.UUID]journalEntry
+	acks      int
+	compactAt time.Time
+	logger    interfaces.Logger
+	now       func() time.Time
+}
+
+// NewFileJournal открывает журнал по пути path, восстанавливает неподтвержденные уведомления
+// и сжимает файл. Каталог журнала создается при необходимости
+func NewFileJournal(path string, logger interfaces.Logger) (*FileJournal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+
+	j := FileJournal{
+		path:    path,
+		entries: make(map[uuid.UUID]journalEntry),
+		logger:  logger,
+		now:     time.Now,
+	}
+
+	if err := j.replay(); err != nil {
+		return nil, err
+	}
+
+	if err := j.compact(); err != nil {
+		return nil, err
+	}
+
+	return &j, nil
+}
+
+func (j *FileJournal) Append(ctx context.Context, notifications []dto.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	records := make([]journalRecord, 0, len(notifications))
+	for i := range notifications {
+		records = append(records, journalRecord{Op: journalAppend, Notification: &notifications[i]})
+	}
+
+	// уведомление принято только после сброса на диск
+	if err := j.write(records, true); err != nil {
+		return err
+	}
+
+	for _, notification := range notifications {
+		j.seq++
+		j.entries[notification.NotificationUUID] = journalEntry{seq: j.seq, notification: notification}
+	}
+
+	return nil
+}
+
+func (j *FileJournal) Ack(ctx context.Context, notificationUUID uuid.UUID) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// уведомление уже подтверждено, например повторно выданное после перезапуска
+	if _, exist := j.entries[notificationUUID]; !exist {
+		return nil
+	}
+
+	if err := j.write([]journalRecord{{Op: journalAck, NotificationUUID: notificationUUID}}, false); err != nil {
+		return err
+	}
+
+	delete(j.entries, notificationUUID)
+	j.acks++
+
+	// подтверждение уже записано, ошибка сжатия только откладывает его
+	if now := j.now(); j.acks >= compactAfterAcks && !now.Before(j.compactAt) {
+		if err := j.compact(); err != nil {
+			j.logger.Error(fmt.Sprintf("Journal %v compact failed, retry in %v", j.path, compactRetryInterval), err)
+			j.compactAt = now.Add(compactRetryInterval)
+		}
+	}
+
+	return nil
+}
+
+func (j *FileJournal) Pending(ctx context.Context) ([]dto.Notification, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return pendingEntries(j.entries), nil
+}
+
+func (j *FileJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+
+	err := j.file.Close()
+	j.file = nil
+
+	return err
+}
+
+// write дописывает записи в конец журнала, при sync со сбросом на диск. При ошибке файл
+// обрезается до прежнего размера: частично записанные строки иначе повторились бы при открытии
+func (j *FileJournal) write(records []journalRecord, sync bool) error {
+	if j.file == nil {
+		return os.ErrClosed
+	}
+
+	buf, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+
+	info, err := j.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if _, err = j.file.Write(buf); err == nil && sync {
+		err = j.file.Sync()
+	}
+
+	if err != nil {
+		if tErr := j.file.Truncate(info.Size()); tErr != nil {
+			return fmt.Errorf("%w, journal truncate: %v", err, tErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// encodeRecords записи журнала по одной json строке
+func encodeRecords(records []journalRecord) ([]byte, error) {
+	var buf []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+
+	return buf, nil
+}
+
+// replay восстанавливает неподтвержденные уведомления из файла журнала
+func (j *FileJournal) replay() error {
+	file, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, readErr := reader.ReadBytes('\n')
+		if errors.Is(readErr, io.EOF) {
+			// строка без перевода строки не была дописана до сбоя
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+
+		var record journalRecord
+		if err = json.Unmarshal(line, &record); err != nil {
+			return fmt.Errorf("journal %v line %d: %w", j.path, lineNumber, err)
+		}
+
+		switch record.Op {
+		case journalAppend:
+			if record.Notification != nil {
+				j.seq++
+				j.entries[record.Notification.NotificationUUID] = journalEntry{seq: j.seq, notification: *record.Notification}
+			}
+		case journalAck:
+			delete(j.entries, record.NotificationUUID)
+		}
+	}
+}
+
+// compact переписывает журнал с неподтвержденными уведомлениями через временный файл
+// и продолжает дозапись в него. При ошибке временный файл удаляется, журнал продолжает
+// дозапись в прежний файл
+func (j *FileJournal) compact() error {
+	records := make([]journalRecord, 0, len(j.entries))
+	for _, notification := range pendingEntries(j.entries) {
+		notification := notification
+		records = append(records, journalRecord{Op: journalAppend, Notification: &notification})
+	}
+
+	buf, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := j.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tmp.Write(buf); err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		// открытый дескриптор после переименования указывает на файл журнала
+		err = os.Rename(tmpPath, j.path)
+	}
+	if err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if j.file != nil {
+		// записи прежнего файла уже перенесены, ошибка закрытия на журнал не влияет
+		_ = j.file.Close()
+	}
+	j.file = tmp
+	j.acks = 0
+
+	// без сброса каталога переименование может не пережить сбой питания
+	return syncDir(filepath.Dir(j.path))
+}
+
+// syncDir сброс на диск записей каталога dir
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	if err = d.Sync(); err != nil {
+		_ = d.Close()
+		return err
+	}
+
+	return d.Close()
+}
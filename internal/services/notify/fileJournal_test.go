@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+func TestFileJournal_Replay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "journal", "ingest.journal")
+
+	journal, err := NewFileJournal(path, logger.NewZapLogger())
+	assert.NoError(t, err)
+
+	first := dto.Notification{NotificationUUID: uuid.New(), EventUUID: uuid.New(), Priority: 5}
+	second := dto.Notification{NotificationUUID: uuid.New(), EventUUID: uuid.New(), MessageParams: []dto.MessageParam{{Key: "name", Value: "Иван"}}}
+	third := dto.Notification{NotificationUUID: uuid.New(), EventUUID: uuid.New()}
+
+	assert.NoError(t, journal.Append(ctx, []dto.Notification{first, second}))
+	assert.NoError(t, journal.Append(ctx, []dto.Notification{third}))
+	assert.NoError(t, journal.Ack(ctx, second.NotificationUUID))
+	// неизвестный UUID игнорируется
+	assert.NoError(t, journal.Ack(ctx, uuid.New()))
+	assert.NoError(t, journal.Close())
+
+	// сбой во время записи оставляет недописанную строку
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	assert.NoError(t, err)
+	_, err = file.WriteString(`{"op":"append","notification":{"notifica`)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	journal, err = NewFileJournal(path, logger.NewZapLogger())
+	assert.NoError(t, err)
+	defer journal.Close()
+
+	pending, err := journal.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.Notification{first, third}, pending)
+
+	// после открытия журнал сжат до неподтвержденных уведомлений
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), second.NotificationUUID.String())
+	assert.NotContains(t, string(content), `"notifica"`)
+}
+
+func TestFileJournal_Compact(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ingest.journal")
+
+	journal, err := NewFileJournal(path, logger.NewZapLogger())
+	assert.NoError(t, err)
+	defer journal.Close()
+
+	kept := dto.Notification{NotificationUUID: uuid.New()}
+	assert.NoError(t, journal.Append(ctx, []dto.Notification{kept}))
+
+	for i := 0; i < compactAfterAcks; i++ {
+		notification := dto.Notification{NotificationUUID: uuid.New()}
+		assert.NoError(t, journal.Append(ctx, []dto.Notification{notification}))
+		assert.NoError(t, journal.Ack(ctx, notification.NotificationUUID))
+	}
+
+	// подтвержденные записи удалены, журнал продолжает принимать записи
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Less(t, info.Size(), int64(1024))
+
+	added := dto.Notification{NotificationUUID: uuid.New()}
+	assert.NoError(t, journal.Append(ctx, []dto.Notification{added}))
+
+	pending, err := journal.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.Notification{kept, added}, pending)
+}
+
+// TestFileJournal_CompactFailed неудачное сжатие не закрывает журнал
+func TestFileJournal_CompactFailed(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ingest.journal")
+
+	journal, err := NewFileJournal(path, logger.NewZapLogger())
+	assert.NoError(t, err)
+	defer journal.Close()
+
+	kept := dto.Notification{NotificationUUID: uuid.New()}
+	assert.NoError(t, journal.Append(ctx, []dto.Notification{kept}))
+
+	// временный файл сжатия создать нельзя
+	assert.NoError(t, os.Mkdir(path+".tmp", 0o750))
+	journal.mu.Lock()
+	assert.Error(t, journal.compact())
+	journal.mu.Unlock()
+
+	added := dto.Notification{NotificationUUID: uuid.New()}
+	assert.NoError(t, journal.Append(ctx, []dto.Notification{added}))
+	assert.NoError(t, journal.Close())
+
+	// записи после неудачного сжатия сохранены в прежнем файле
+	assert.NoError(t, os.Remove(path+".tmp"))
+	reopened, err := NewFileJournal(path, logger.NewZapLogger())
+	assert.NoError(t, err)
+	defer reopened.Close()
+
+	pending, err := reopened.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.Notification{kept, added}, pending)
+}
+
+// TestFileJournal_AckCompactFailed ошибка сжатия не возвращается из Ack, сжатие повторяется по расписанию
+func TestFileJournal_AckCompactFailed(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ingest.journal")
+
+	journal, err := NewFileJournal(path, logger.NewZapLogger())
+	assert.NoError(t, err)
+	defer journal.Close()
+
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	journal.now = func() time.Time { return now }
+
+	// временный файл сжатия создать нельзя
+	assert.NoError(t, os.Mkdir(path+".tmp", 0o750))
+	for i := 0; i < compactAfterAcks; i++ {
+		notification := dto.Notification{NotificationUUID: uuid.New()}
+		assert.NoError(t, journal.Append(ctx, []dto.Notification{notification}))
+		assert.NoError(t, journal.Ack(ctx, notification.NotificationUUID))
+	}
+	assert.Equal(t, compactAfterAcks, journal.acks)
+	assert.Equal(t, now.Add(compactRetryInterval), journal.compactAt)
+
+	// до истечения паузы сжатие не повторяется
+	assert.NoError(t, os.Remove(path+".tmp"))
+	ack := func() {
+		notification := dto.Notification{NotificationUUID: uuid.New()}
+		assert.NoError(t, journal.Append(ctx, []dto.Notification{notification}))
+		assert.NoError(t, journal.Ack(ctx, notification.NotificationUUID))
+	}
+	ack()
+	assert.Equal(t, compactAfterAcks+1, journal.acks)
+
+	now = now.Add(compactRetryInterval)
+	ack()
+	assert.Equal(t, 0, journal.acks)
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+}
+
+func TestFileJournal_Corrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ingest.journal")
+	assert.NoError(t, os.WriteFile(path, []byte("not json\n{\"op\":\"ack\"}\n"), 0o600))
+
+	// поврежденная запись в середине журнала не пропускается молча
+	_, err := NewFileJournal(path, logger.NewZapLogger())
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+var (
+	_ Journal = (*MemoryJournal)(nil)
+	_ Journal = (*FileJournal)(nil)
+)
+
+// Journal журнал принятых уведомлений. Уведомление записывается до ответа клиенту
+// и хранится до подтверждения диспетчером, неподтвержденные уведомления повторяются при старте
+type Journal interface {
+	// Append сохраняет уведомления, после возврата без ошибки уведомления переживают перезапуск
+	Append(ctx context.Context, notifications []dto.Notification) error
+	// Ack подтверждает обработку уведомления диспетчером, неизвестный UUID игнорируется
+	Ack(ctx context.Context, notificationUUID uuid.UUID) error
+	// Pending неподтвержденные уведомления в порядке записи
+	Pending(ctx context.Context) ([]dto.Notification, error)
+	// Close освобождение ресурсов журнала
+	Close() error
+}
+
+// journalEntry уведомление журнала с порядковым номером записи
+type journalEntry struct {
+	seq          uint64
+	notification dto.Notification
+}
+
+// pendingEntries неподтвержденные уведомления в порядке записи
+func pendingEntries(entries map[uuid.UUID]journalEntry) []dto.Notification {
+	sorted := make([]journalEntry, 0, len(entries))
+	for _, entry := range entries {
+		sorted = append(sorted, entry)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].seq < sorted[j].seq
+	})
+
+	notifications := make([]dto.Notification, 0, len(sorted))
+	for _, entry := range sorted {
+		notifications = append(notifications, entry.notification)
+	}
+
+	return notifications
+}
+
+// MemoryJournal журнал в памяти, уведомления не переживают перезапуск
+type MemoryJournal struct {
+	mu      sync.Mutex
+	seq     uint64
+	entries map[uuid.UUID]journalEntry
+}
+
+func NewMemoryJournal() *MemoryJournal {
+	j := MemoryJournal{
+		entries: make(map[uuid.UUID]journalEntry),
+	}
+
+	return &j
+}
+
+func (j *MemoryJournal) Append(ctx context.Context, notifications []dto.Notification) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, notification := range notifications {
+		j.seq++
+		j.entries[notification.NotificationUUID] = journalEntry{seq: j.seq, notification: notification}
+	}
+
+	return nil
+}
+
+func (j *MemoryJournal) Ack(ctx context.Context, notificationUUID uuid.UUID) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	delete(j.entries, notificationUUID)
+
+	return nil
+}
+
+func (j *MemoryJournal) Pending(ctx context.Context) ([]dto.Notification, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return pendingEntries(j.entries), nil
+}
+
+func (j *MemoryJournal) Close() error {
+	return nil
+}
@@ -1,61 +1,480 @@
 // Package notify фронт сервис для приема уведомлений на отправку
-// Выполняет приоритезацию уведомлений и передает далее в NotificationDispatcher
+// Применяет приоритет по умолчанию и правила маршрутизации бизнес события,
+// выполняет приоритезацию уведомлений по QueuePolicy и передает далее в NotificationDispatcher.
+// Принятые уведомления записываются в журнал Journal до ответа клиенту и передаются диспетчеру
+// в фоне, неподтвержденные диспетчером уведомления повторяются при старте сервиса
 package notify
 
 import (
-	"container/heap"
 	"context"
 	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
 
 	"github.com/atrian/go-notify-customer/internal/dto"
 	"github.com/atrian/go-notify-customer/internal/interfaces"
+	"github.com/atrian/go-notify-customer/internal/routing"
+	"github.com/atrian/go-notify-customer/internal/templating"
 )
 
 var (
 	NotificationLimitExceeded = errors.New("notification limit exceed")
 )
 
+// MissingParamsError уведомления пачки без обязательных параметров шаблонов бизнес события
+type MissingParamsError struct {
+	Notifications []dto.MissingParams
+}
+
+func (e *MissingParamsError) Error() string {
+	return fmt.Sprintf("required message params missing in %v notifications", len(e.Notifications))
+}
+
+// templateService контракт на сервис шаблонов для проверки обязательных параметров
+type templateService interface {
+	FindByEventId(ctx context.Context, eventUUID uuid.UUID) ([]dto.Template, error)
+}
+
+// eventLocator контракт на сервис бизнес событий для приоритета по умолчанию и правил маршрутизации
+type eventLocator interface {
+	FindById(ctx context.Context, eventUUID uuid.UUID) (dto.Event, error)
+}
+
+// scheduler контракт на планировщик отложенных уведомлений
+type scheduler interface {
+	Schedule(ctx context.Context, notification dto.Notification) (dto.Notification, error)
+	Cancel(ctx context.Context, notificationUUID uuid.UUID) error
+}
+
+// idempotencyKeys контракт на хранилище ключей идемпотентности
+type idempotencyKeys interface {
+	Reserve(ctx context.Context, key string, notificationUUID uuid.UUID) (uuid.UUID, bool, error)
+	Release(ctx context.Context, key string) error
+}
+
 type Service struct {
-	queue      PriorityQueue
+	queue      *deliveryQueue
+	journal    Journal
 	resultChan chan<- dto.Notification
+	limiter    RateLimiter
+	scheduler  scheduler
+	keys       idempotencyKeys
+	templates  templateService
+	events     eventLocator
+	statChan   chan<- dto.Stat
 	logger     interfaces.Logger
 }
 
 // New Конфигурация зависимостей сервиса
-func New(resultChan chan dto.Notification, logger interfaces.Logger) *Service {
+func New(resultChan chan dto.Notification, limiter RateLimiter, scheduler scheduler, keys idempotencyKeys, logger interfaces.Logger) *Service {
 	s := Service{
-		queue:      nil,        // очередь с приоритетом
-		resultChan: resultChan, // выходной канал после приоритезации сообщений
+		queue:      newDeliveryQueue(), // очередь с приоритетом
+		journal:    NewMemoryJournal(), // журнал принятых уведомлений
+		resultChan: resultChan,         // выходной канал после приоритезации сообщений
+		limiter:    limiter,            // ограничитель пропускной способности
+		scheduler:  scheduler,          // планировщик отложенных уведомлений
+		keys:       keys,               // ключи идемпотентности
 		logger:     logger,
 	}
 
-	heap.Init(&s.queue)
-
 	return &s
 }
 
-// Start стартовые процедуры - логгер?
+// SetJournal подключение журнала принятых уведомлений, например FileJournal.
+// По умолчанию используется MemoryJournal
+func (s *Service) SetJournal(journal Journal) *Service {
+	s.journal = journal
+	return s
+}
+
+// SetEvents подключение сервиса бизнес событий. Уведомления без приоритета получают
+// DefaultPriority события, правила маршрутизации события меняют приоритет и добавляют каналы
+func (s *Service) SetEvents(events eventLocator) *Service {
+	s.events = events
+	return s
+}
+
+// SetQueuePolicy подключение политики очереди передачи диспетчеру, например AgingPolicy.
+// По умолчанию используется StrictPolicy
+func (s *Service) SetQueuePolicy(policy QueuePolicy) *Service {
+	s.queue.setPolicy(policy)
+	return s
+}
+
+// SetStatChan подключение канала статистики, принятые уведомления фиксируются статусом dto.Accepted
+func (s *Service) SetStatChan(statChan chan<- dto.Stat) *Service {
+	s.statChan = statChan
+	return s
+}
+
+// SetTemplates подключение сервиса шаблонов, уведомления без обязательных параметров
+// шаблонов бизнес события отклоняются с ошибкой MissingParamsError
+func (s *Service) SetTemplates(templates templateService) *Service {
+	s.templates = templates
+	return s
+}
+
+// Start повторяет неподтвержденные уведомления журнала и запускает передачу уведомлений диспетчеру
 func (s Service) Start(ctx context.Context) {
+	if !s.queue.start() {
+		return
+	}
+
+	pending, err := s.journal.Pending(ctx)
+	if err != nil {
+		s.logger.Error("Notification journal Pending err", err)
+	}
+	if len(pending) > 0 {
+		s.logger.Info(fmt.Sprintf("Notification journal replay: %v notifications", len(pending)))
+		s.queue.push(pending)
+	}
+
+	go s.deliver(ctx)
+
 	s.logger.Info("Notification service started")
 }
 
+// Stop останавливает передачу, уведомления в очереди остаются в журнале до следующего старта
 func (s Service) Stop() {
+	s.queue.shutdown()
+
+	if err := s.journal.Close(); err != nil {
+		s.logger.Error("Notification journal Close err", err)
+	}
+
 	close(s.resultChan)
 	s.logger.Info("Notification service stopped")
 }
 
-func (s Service) ProcessNotification(ctx context.Context, notifications []dto.Notification) error {
-	// приоритизация очереди уведомлений
-	for i := 0; i < len(notifications); i++ {
-		heap.Push(&s.queue, &notifications[i])
+// deliver передает уведомления диспетчеру в порядке приоритета до остановки сервиса.
+// Запрос клиента не ждет диспетчера
+func (s Service) deliver(ctx context.Context) {
+	defer close(s.queue.done)
+
+	for {
+		notification, ok := s.queue.pop()
+		if !ok {
+			select {
+			case <-s.queue.wake:
+				continue
+			case <-s.queue.stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case s.resultChan <- notification:
+			s.queue.delivered(notification)
+		case <-s.queue.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// QueueStats метрики очереди и времени ожидания уведомлений до передачи диспетчеру
+func (s Service) QueueStats() dto.QueueStats {
+	return s.queue.stats()
+}
+
+// ProcessNotification проверка лимитов, запись в журнал и постановка уведомлений в очередь диспетчера.
+// Возврат без ошибки означает, что уведомления сохранены в журнале. Уведомления получают UUID при приеме.
+// Повтор по ключу идемпотентности не отправляется, в квитанции возвращается UUID исходного уведомления.
+// При превышении лимита возвращает NotificationLimitExceeded, без обязательных параметров - MissingParamsError,
+// в обоих случаях уведомления пачки не отправляются
+func (s Service) ProcessNotification(ctx context.Context, notifications []dto.Notification) ([]dto.NotificationReceipt, error) {
+	// приоритет и каналы по настройкам бизнес события, лимиты считаются уже с дополнительными каналами
+	notifications, events := s.route(ctx, notifications)
+
+	if err := s.validate(ctx, notifications, events); err != nil {
+		return nil, err
+	}
+
+	receipts, fresh, reserved, err := s.deduplicate(ctx, notifications)
+	if err != nil {
+		return nil, err
+	}
+
+	// ограничение пропускной способности по каналам, получателям и общему потоку
+	if err = s.limiter.Acquire(ctx, fresh); err != nil {
+		s.logger.Warning(fmt.Sprintf("Notifications rejected by rate limiter: %v", err))
+		s.release(ctx, reserved)
+		return nil, err
+	}
+
+	// уведомления с временем отправки в будущем передаем планировщику
+	immediate, scheduled, err := s.schedule(ctx, fresh)
+	if err != nil {
+		s.release(ctx, reserved)
+		return nil, err
 	}
 
-	// обрабатываем очередь в порядке приоритета и отдаем в результирующий канал
-	// TODO добавить RATE LIMITER на количество отправленных
-	for s.queue.Len() > 0 {
-		item := heap.Pop(&s.queue).(*dto.Notification)
-		s.resultChan <- *item
+	// время приема сохраняется в журнале, ожидание в очереди считается и после перезапуска
+	acceptedAt := s.queue.now()
+	for i := range immediate {
+		immediate[i].AcceptedAt = &acceptedAt
 	}
 
+	// уведомления для немедленной отправки сохраняем до ответа клиенту
+	if err = s.journal.Append(ctx, immediate); err != nil {
+		s.logger.Error("Notification journal Append err", err)
+		s.cancel(ctx, scheduled)
+		s.release(ctx, reserved)
+		return nil, err
+	}
+
+	s.accepted(fresh)
+
+	// очередь в порядке QueuePolicy, диспетчер получает уведомления в фоне
+	s.queue.push(immediate)
+
+	return receipts, nil
+}
+
+// Enqueue принимает наступившие уведомления планировщика: записывает их в журнал и ставит в очередь
+// диспетчера в порядке QueuePolicy. Лимиты, параметры и ключи идемпотентности проверены при приеме.
+// Возврат без ошибки означает, что уведомления сохранены в журнале
+func (s Service) Enqueue(ctx context.Context, notifications []dto.Notification) error {
+	// ожидание в очереди считается от наступления времени отправки
+	acceptedAt := s.queue.now()
+	for i := range notifications {
+		notifications[i].AcceptedAt = &acceptedAt
+	}
+
+	if err := s.journal.Append(ctx, notifications); err != nil {
+		s.logger.Error("Notification journal Append err", err)
+		return err
+	}
+
+	s.queue.push(notifications)
+
 	return nil
 }
+
+// route применяет приоритет по умолчанию и правила маршрутизации бизнес событий пачки.
+// Уведомления неизвестного события не меняются, диспетчер их не отправит. Возвращает также
+// найденные бизнес события пачки, для неизвестного события - пустое
+func (s Service) route(ctx context.Context, notifications []dto.Notification) ([]dto.Notification, map[uuid.UUID]dto.Event) {
+	events := make(map[uuid.UUID]dto.Event)
+
+	if s.events == nil {
+		return notifications, events
+	}
+
+	routed := make([]dto.Notification, 0, len(notifications))
+
+	for _, notification := range notifications {
+		event, ok := events[notification.EventUUID]
+		if !ok {
+			var err error
+			if event, err = s.events.FindById(ctx, notification.EventUUID); err != nil {
+				s.logger.Debug(fmt.Sprintf("Event lookup for routing %v: %v", notification.EventUUID, err))
+			}
+			events[notification.EventUUID] = event
+		}
+
+		routed = append(routed, routing.Apply(event, notification))
+	}
+
+	return routed, events
+}
+
+// validate проверяет наличие в MessageParams обязательных параметров шаблонов, которые диспетчер
+// выберет для уведомления: по каналам события, правил маршрутизации и цепочки Fallback и по цепочке локалей
+// уведомления. Для уведомления без локали шаблон выбирается по локали получателя при отправке, поэтому
+// проверяются все локали канала. Для неизвестного события проверяются все его шаблоны
+func (s Service) validate(ctx context.Context, notifications []dto.Notification, events map[uuid.UUID]dto.Event) error {
+	if s.templates == nil {
+		return nil
+	}
+
+	// шаблоны по бизнес событиям пачки
+	eventTemplates := make(map[uuid.UUID][]dto.Template)
+	var missing []dto.MissingParams
+
+	for i, notification := range notifications {
+		templates, ok := eventTemplates[notification.EventUUID]
+		if !ok {
+			var err error
+			if templates, err = s.templates.FindByEventId(ctx, notification.EventUUID); err != nil {
+				// для события без шаблонов проверять нечего, диспетчер пропустит такие каналы
+				s.logger.Debug(fmt.Sprintf("Templates lookup for event %v: %v", notification.EventUUID, err))
+			}
+			eventTemplates[notification.EventUUID] = templates
+		}
+
+		keys := requiredParams(templates, events[notification.EventUUID], notification)
+		if keysMissing := templating.MissingParams(keys, notification.MessageParams); len(keysMissing) > 0 {
+			missing = append(missing, dto.MissingParams{
+				Index:         i,
+				EventUUID:     notification.EventUUID,
+				MissingParams: keysMissing,
+			})
+		}
+	}
+
+	if len(missing) > 0 {
+		return &MissingParamsError{Notifications: missing}
+	}
+
+	return nil
+}
+
+// requiredParams обязательные параметры шаблонов события templates для каналов уведомления
+func requiredParams(templates []dto.Template, event dto.Event, notification dto.Notification) []string {
+	var keys []string
+
+	// событие неизвестно, каналы отправки определить нельзя
+	if event.EventUUID == uuid.Nil {
+		for _, template := range templates {
+			keys = append(keys, template.RequiredParams...)
+		}
+		return unique(keys)
+	}
+
+	byChannel := make(map[string][]dto.Template)
+	for _, template := range templates {
+		byChannel[template.ChannelType] = append(byChannel[template.ChannelType], template)
+	}
+
+	channels := routing.Channels(event.NotificationChannels, notification.Channels)
+	for _, step := range event.Fallback {
+		channels = routing.Channels(channels, []string{step.Channel})
+	}
+
+	for _, channel := range channels {
+		if notification.Locale != "" {
+			if template, found := templating.SelectTemplate(byChannel[channel], notification.Locale); found {
+				keys = append(keys, template.RequiredParams...)
+			}
+			continue
+		}
+
+		for _, template := range byChannel[channel] {
+			keys = append(keys, template.RequiredParams...)
+		}
+	}
+
+	return unique(keys)
+}
+
+// deduplicate выдает UUID уведомлениям и закрепляет ключи идемпотентности.
+// Возвращает квитанции в порядке входящих уведомлений, новые уведомления и закрепленные ключи
+func (s Service) deduplicate(ctx context.Context, notifications []dto.Notification) ([]dto.NotificationReceipt, []dto.Notification, []string, error) {
+	receipts := make([]dto.NotificationReceipt, 0, len(notifications))
+	fresh := make([]dto.Notification, 0, len(notifications))
+	reserved := make([]string, 0)
+
+	for _, notification := range notifications {
+		if notification.NotificationUUID == uuid.Nil {
+			notification.NotificationUUID = uuid.New()
+		}
+
+		receipt := dto.NotificationReceipt{
+			NotificationUUID: notification.NotificationUUID,
+			IdempotencyKey:   notification.IdempotencyKey,
+		}
+
+		if notification.IdempotencyKey != "" {
+			stored, created, err := s.keys.Reserve(ctx, notification.IdempotencyKey, notification.NotificationUUID)
+			if err != nil {
+				s.release(ctx, reserved)
+				return nil, nil, nil, err
+			}
+
+			if !created {
+				s.logger.Info(fmt.Sprintf("Duplicate notification by idempotency key: %v", notification.IdempotencyKey))
+				receipt.NotificationUUID = stored
+				receipt.Duplicate = true
+				receipts = append(receipts, receipt)
+				continue
+			}
+
+			reserved = append(reserved, notification.IdempotencyKey)
+		}
+
+		receipts = append(receipts, receipt)
+		fresh = append(fresh, notification)
+	}
+
+	return receipts, fresh, reserved, nil
+}
+
+// accepted фиксирует прием уведомлений в статистике по каждому получателю
+func (s Service) accepted(notifications []dto.Notification) {
+	if s.statChan == nil {
+		return
+	}
+
+	for _, notification := range notifications {
+		for _, personUUID := range notification.PersonUUIDs {
+			s.statChan <- dto.Stat{
+				PersonUUID:       personUUID,
+				NotificationUUID: notification.NotificationUUID,
+				Status:           dto.Accepted,
+			}
+		}
+	}
+}
+
+// release освобождает ключи идемпотентности не принятых уведомлений, чтобы клиент мог повторить запрос
+func (s Service) release(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.keys.Release(ctx, key); err != nil {
+			s.logger.Error("Idempotency key release err", err)
+		}
+	}
+}
+
+// schedule передает планировщику отложенные уведомления, возвращает уведомления для немедленной отправки
+// и UUID запланированных. При ошибке планировщика уже запланированные уведомления пачки отменяются
+func (s Service) schedule(ctx context.Context, notifications []dto.Notification) ([]dto.Notification, []uuid.UUID, error) {
+	now := time.Now()
+	immediate := make([]dto.Notification, 0, len(notifications))
+	scheduled := make([]uuid.UUID, 0)
+
+	for _, notification := range notifications {
+		if notification.SendAt == nil || !notification.SendAt.After(now) {
+			immediate = append(immediate, notification)
+			continue
+		}
+
+		stored, err := s.scheduler.Schedule(ctx, notification)
+		if err != nil {
+			s.cancel(ctx, scheduled)
+			return nil, nil, err
+		}
+
+		scheduled = append(scheduled, stored.NotificationUUID)
+	}
+
+	return immediate, scheduled, nil
+}
+
+// cancel отменяет запланированные уведомления не принятой пачки
+func (s Service) cancel(ctx context.Context, scheduled []uuid.UUID) {
+	for _, notificationUUID := range scheduled {
+		_ = s.scheduler.Cancel(ctx, notificationUUID)
+	}
+}
+
+// unique ключи без повторов с сохранением порядка
+func unique(keys []string) []string {
+	seen := make(map[string]struct{}, len(keys))
+	result := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		result = append(result, key)
+	}
+
+	return result
+}
@@ -2,11 +2,16 @@ package notify
 
 import (
 	"context"
+	"errors"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
 
 	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/services/idempotency"
 	"github.com/atrian/go-notify-customer/pkg/logger"
 )
 
@@ -14,6 +19,9 @@ const bufferSize = 3
 
 // TestService_ProcessNotification проверяем правильность приоритезации уведомлений
 func TestService_ProcessNotification(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	log := logger.NewZapLogger()
 
 	resultChan := make(chan dto.Notification, bufferSize)
@@ -30,9 +38,10 @@ func TestService_ProcessNotification(t *testing.T) {
 		},
 	}
 
-	s := New(resultChan, log)
+	s := New(resultChan, NewTokenBucketLimiter(&limiterConfigMock{}, nil, log), newSchedulerMock(), idempotency.New(&idempotencyConfigMock{}), log)
+	s.Start(ctx)
 
-	_ = s.ProcessNotification(context.TODO(), notifications)
+	_, _ = s.ProcessNotification(context.TODO(), notifications)
 
 	res1 := <-resultChan
 	if res1.Priority != 999 {
@@ -49,3 +58,358 @@ func TestService_ProcessNotification(t *testing.T) {
 		t.Errorf("got %v, wanted %v", res3.Priority, 1)
 	}
 }
+
+// TestService_ProcessNotification_Scheduled уведомления с send_at в будущем уходят планировщику
+func TestService_ProcessNotification_Scheduled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := logger.NewZapLogger()
+	resultChan := make(chan dto.Notification, bufferSize)
+	sched := newSchedulerMock()
+
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	notifications := []dto.Notification{
+		{EventUUID: uuid.New(), Priority: 1, SendAt: &future},
+		{EventUUID: uuid.New(), Priority: 2, SendAt: &past},
+		{EventUUID: uuid.New(), Priority: 3},
+	}
+
+	s := New(resultChan, NewTokenBucketLimiter(&limiterConfigMock{}, nil, log), sched, idempotency.New(&idempotencyConfigMock{}), log)
+	s.Start(ctx)
+
+	_, err := s.ProcessNotification(context.TODO(), notifications)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, len(sched.scheduled))
+	assert.Equal(t, uint(1), sched.scheduled[0].Priority)
+
+	assert.Equal(t, uint(3), (<-resultChan).Priority)
+	assert.Equal(t, uint(2), (<-resultChan).Priority)
+
+	// при ошибке планировщика запрос отклоняется целиком
+	sched.err = errors.New("storage is down")
+	_, err = s.ProcessNotification(context.TODO(), notifications)
+	assert.ErrorIs(t, err, sched.err)
+	assert.Equal(t, 0, len(resultChan))
+}
+
+// TestService_ProcessNotification_Idempotency повтор по ключу возвращает исходный UUID без повторной отправки
+func TestService_ProcessNotification_Idempotency(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := logger.NewZapLogger()
+	resultChan := make(chan dto.Notification, bufferSize)
+	keys := idempotency.New(&idempotencyConfigMock{})
+
+	// лимит на одно сообщение: второе новое уведомление будет отклонено
+	eventUUID := uuid.New()
+	limiter := NewTokenBucketLimiter(&limiterConfigMock{global: 0.001, globalBurst: 1}, channelLocatorMock{eventUUID: {"sms"}}, log)
+	s := New(resultChan, limiter, newSchedulerMock(), keys, log)
+	s.Start(ctx)
+
+	notification := dto.Notification{EventUUID: eventUUID, PersonUUIDs: []uuid.UUID{uuid.New()}, IdempotencyKey: "order-1"}
+
+	receipts, err := s.ProcessNotification(context.TODO(), []dto.Notification{notification})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(receipts))
+	assert.False(t, receipts[0].Duplicate)
+
+	sent := <-resultChan
+	assert.Equal(t, receipts[0].NotificationUUID, sent.NotificationUUID)
+
+	// повтор не расходует лимит и не отправляется
+	repeated, err := s.ProcessNotification(context.TODO(), []dto.Notification{notification})
+	assert.NoError(t, err)
+	assert.True(t, repeated[0].Duplicate)
+	assert.Equal(t, receipts[0].NotificationUUID, repeated[0].NotificationUUID)
+	assert.Equal(t, 0, len(resultChan))
+
+	// отклоненное лимитом уведомление освобождает ключ
+	other := dto.Notification{EventUUID: eventUUID, PersonUUIDs: []uuid.UUID{uuid.New()}, IdempotencyKey: "order-2"}
+	_, err = s.ProcessNotification(context.TODO(), []dto.Notification{other})
+	assert.ErrorIs(t, err, NotificationLimitExceeded)
+
+	_, created, err := keys.Reserve(context.TODO(), "order-2", uuid.New())
+	assert.NoError(t, err)
+	assert.True(t, created)
+}
+
+// TestService_ProcessNotification_MissingParams пачка без обязательных параметров шаблонов отклоняется целиком
+func TestService_ProcessNotification_MissingParams(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := logger.NewZapLogger()
+	resultChan := make(chan dto.Notification, bufferSize)
+
+	eventUUID := uuid.New()
+	templates := templatesMock{eventUUID: {
+		{ChannelType: "sms", RequiredParams: []string{"date", "name"}},
+		{ChannelType: "mail", RequiredParams: []string{"name", "service"}},
+	}}
+	s := New(resultChan, NewTokenBucketLimiter(&limiterConfigMock{}, nil, log), newSchedulerMock(), idempotency.New(&idempotencyConfigMock{}), log).
+		SetTemplates(templates)
+	s.Start(ctx)
+
+	complete := dto.Notification{EventUUID: eventUUID, MessageParams: []dto.MessageParam{
+		{Key: "name", Value: "Иван"}, {Key: "date", Value: "14.03"}, {Key: "service", Value: "стрижка"},
+	}}
+	incomplete := dto.Notification{EventUUID: eventUUID, MessageParams: []dto.MessageParam{{Key: "name", Value: "Иван"}}}
+	// событие без шаблонов не проверяется
+	noTemplates := dto.Notification{EventUUID: uuid.New()}
+
+	_, err := s.ProcessNotification(context.TODO(), []dto.Notification{complete, incomplete, noTemplates})
+
+	var missingErr *MissingParamsError
+	assert.ErrorAs(t, err, &missingErr)
+	assert.Equal(t, []dto.MissingParams{{Index: 1, EventUUID: eventUUID, MissingParams: []string{"date", "service"}}}, missingErr.Notifications)
+	assert.Equal(t, 0, len(resultChan))
+
+	receipts, err := s.ProcessNotification(context.TODO(), []dto.Notification{complete, noTemplates})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(receipts))
+}
+
+// TestService_ProcessNotification_MissingParamsResolved проверяются только шаблоны,
+// которые диспетчер выберет по каналам и локали уведомления
+func TestService_ProcessNotification_MissingParamsResolved(t *testing.T) {
+	log := logger.NewZapLogger()
+
+	eventUUID := uuid.New()
+	events := eventsMock{eventUUID: {
+		EventUUID:            eventUUID,
+		NotificationChannels: []string{"sms"},
+		RoutingRules:         []dto.RoutingRule{{When: map[string]string{"vip": "true"}, Channels: []string{"mail"}}},
+		Fallback:             []dto.FallbackStep{{Channel: "telegram", After: 60}},
+	}}
+	templates := templatesMock{eventUUID: {
+		{ChannelType: "sms", Locale: "ru", RequiredParams: []string{"name"}},
+		{ChannelType: "sms", RequiredParams: []string{"name", "date"}},
+		{ChannelType: "telegram", RequiredParams: []string{"chat"}},
+		{ChannelType: "mail", RequiredParams: []string{"service"}},
+	}}
+	s := New(make(chan dto.Notification, bufferSize), NewTokenBucketLimiter(&limiterConfigMock{}, events, log), newSchedulerMock(), idempotency.New(&idempotencyConfigMock{}), log).
+		SetEvents(events).
+		SetTemplates(templates)
+
+	name := dto.MessageParam{Key: "name", Value: "Иван"}
+	chat := dto.MessageParam{Key: "chat", Value: "42"}
+
+	_, err := s.ProcessNotification(context.TODO(), []dto.Notification{
+		// русский шаблон sms не требует даты, канал mail не используется
+		{EventUUID: eventUUID, Locale: "ru-RU", MessageParams: []dto.MessageParam{name, chat}},
+		// локаль получателя неизвестна до отправки, проверяются все шаблоны sms
+		{EventUUID: eventUUID, MessageParams: []dto.MessageParam{name, chat}},
+		// правило маршрутизации добавляет канал mail
+		{EventUUID: eventUUID, Locale: "ru", MessageParams: []dto.MessageParam{name, chat, {Key: "vip", Value: "true"}}},
+	})
+
+	var missingErr *MissingParamsError
+	assert.ErrorAs(t, err, &missingErr)
+	assert.Equal(t, []dto.MissingParams{
+		{Index: 1, EventUUID: eventUUID, MissingParams: []string{"date"}},
+		{Index: 2, EventUUID: eventUUID, MissingParams: []string{"service"}},
+	}, missingErr.Notifications)
+}
+
+// TestService_ProcessNotification_Journal принятые уведомления переживают перезапуск до подтверждения диспетчером
+func TestService_ProcessNotification_Journal(t *testing.T) {
+	log := logger.NewZapLogger()
+	path := filepath.Join(t.TempDir(), "ingest.journal")
+
+	journal, err := NewFileJournal(path, logger.NewZapLogger())
+	assert.NoError(t, err)
+
+	// сервис принимает уведомления, но падает до передачи диспетчеру
+	s := New(make(chan dto.Notification), NewTokenBucketLimiter(&limiterConfigMock{}, nil, log), newSchedulerMock(), idempotency.New(&idempotencyConfigMock{}), log).
+		SetJournal(journal)
+
+	receipts, err := s.ProcessNotification(context.TODO(), []dto.Notification{
+		{EventUUID: uuid.New(), Priority: 1},
+		{EventUUID: uuid.New(), Priority: 10},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, journal.Close())
+
+	// после перезапуска уведомления повторяются в порядке приоритета
+	journal, err = NewFileJournal(path, logger.NewZapLogger())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resultChan := make(chan dto.Notification, bufferSize)
+	restarted := New(resultChan, NewTokenBucketLimiter(&limiterConfigMock{}, nil, log), newSchedulerMock(), idempotency.New(&idempotencyConfigMock{}), log).
+		SetJournal(journal)
+	restarted.Start(ctx)
+
+	first, second := <-resultChan, <-resultChan
+	assert.Equal(t, receipts[1].NotificationUUID, first.NotificationUUID)
+	assert.Equal(t, receipts[0].NotificationUUID, second.NotificationUUID)
+
+	// подтвержденные диспетчером уведомления больше не повторяются
+	assert.NoError(t, journal.Ack(ctx, first.NotificationUUID))
+	assert.NoError(t, journal.Ack(ctx, second.NotificationUUID))
+	pending, err := journal.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+// TestService_ProcessNotification_Routing приоритет по умолчанию и правила маршрутизации события
+func TestService_ProcessNotification_Routing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := logger.NewZapLogger()
+	eventUUID := uuid.New()
+	events := eventsMock{eventUUID: {
+		EventUUID:            eventUUID,
+		DefaultPriority:      10,
+		NotificationChannels: []string{"mail"},
+		RoutingRules:         []dto.RoutingRule{{When: map[string]string{"vip": "true"}, Priority: 900, Channels: []string{"sms"}}},
+	}}
+	vip := []dto.MessageParam{{Key: "vip", Value: "true"}}
+
+	// лимит sms учитывает канал, добавленный правилом
+	limiter := NewTokenBucketLimiter(&limiterConfigMock{channels: []string{"sms:1:1"}}, events, log)
+	resultChan := make(chan dto.Notification, bufferSize)
+	s := New(resultChan, limiter, newSchedulerMock(), idempotency.New(&idempotencyConfigMock{}), log).
+		SetEvents(events)
+	s.Start(ctx)
+
+	_, err := s.ProcessNotification(ctx, []dto.Notification{
+		{EventUUID: eventUUID, PersonUUIDs: []uuid.UUID{uuid.New()}},
+		{EventUUID: eventUUID, PersonUUIDs: []uuid.UUID{uuid.New()}, MessageParams: vip},
+	})
+	assert.NoError(t, err)
+
+	routed := <-resultChan
+	assert.Equal(t, uint(900), routed.Priority)
+	assert.Equal(t, []string{"sms"}, routed.Channels)
+
+	routed = <-resultChan
+	assert.Equal(t, uint(10), routed.Priority)
+	assert.Empty(t, routed.Channels)
+
+	_, err = s.ProcessNotification(ctx, []dto.Notification{
+		{EventUUID: eventUUID, PersonUUIDs: []uuid.UUID{uuid.New(), uuid.New()}, MessageParams: vip},
+	})
+	assert.ErrorIs(t, err, NotificationLimitExceeded)
+}
+
+// TestService_ProcessNotification_JournalError без записи в журнал уведомления не принимаются
+func TestService_ProcessNotification_JournalError(t *testing.T) {
+	log := logger.NewZapLogger()
+	keys := idempotency.New(&idempotencyConfigMock{})
+	journalErr := errors.New("disk is full")
+
+	s := New(make(chan dto.Notification, bufferSize), NewTokenBucketLimiter(&limiterConfigMock{}, nil, log), newSchedulerMock(), keys, log).
+		SetJournal(failingJournal{MemoryJournal: NewMemoryJournal(), err: journalErr})
+
+	_, err := s.ProcessNotification(context.TODO(), []dto.Notification{{EventUUID: uuid.New(), IdempotencyKey: "order-1"}})
+	assert.ErrorIs(t, err, journalErr)
+
+	// ключ освобожден, клиент может повторить запрос
+	_, created, err := keys.Reserve(context.TODO(), "order-1", uuid.New())
+	assert.NoError(t, err)
+	assert.True(t, created)
+}
+
+// TestService_Enqueue наступившие уведомления планировщика записываются в журнал
+// и передаются диспетчеру в порядке QueuePolicy
+func TestService_Enqueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := logger.NewZapLogger()
+	journal := NewMemoryJournal()
+	resultChan := make(chan dto.Notification, bufferSize)
+	s := New(resultChan, NewTokenBucketLimiter(&limiterConfigMock{}, nil, log), newSchedulerMock(), idempotency.New(&idempotencyConfigMock{}), log).
+		SetJournal(journal)
+
+	low := dto.Notification{NotificationUUID: uuid.New(), EventUUID: uuid.New(), Priority: 1}
+	high := dto.Notification{NotificationUUID: uuid.New(), EventUUID: uuid.New(), Priority: 10}
+	s.Start(ctx)
+	assert.NoError(t, s.Enqueue(ctx, []dto.Notification{low, high}))
+
+	pending, err := journal.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	first := <-resultChan
+	assert.Equal(t, high.NotificationUUID, first.NotificationUUID)
+	assert.NotNil(t, first.AcceptedAt)
+	assert.Equal(t, low.NotificationUUID, (<-resultChan).NotificationUUID)
+
+	// без записи в журнал уведомления не принимаются и остаются у планировщика
+	journalErr := errors.New("disk is full")
+	failing := New(make(chan dto.Notification, bufferSize), NewTokenBucketLimiter(&limiterConfigMock{}, nil, log), newSchedulerMock(), idempotency.New(&idempotencyConfigMock{}), log).
+		SetJournal(failingJournal{MemoryJournal: NewMemoryJournal(), err: journalErr})
+	assert.ErrorIs(t, failing.Enqueue(ctx, []dto.Notification{low}), journalErr)
+	assert.Equal(t, 0, failing.QueueStats().Length)
+}
+
+type failingJournal struct {
+	*MemoryJournal
+	err error
+}
+
+func (f failingJournal) Append(ctx context.Context, notifications []dto.Notification) error {
+	return f.err
+}
+
+type eventsMock map[uuid.UUID]dto.Event
+
+func (e eventsMock) FindById(ctx context.Context, eventUUID uuid.UUID) (dto.Event, error) {
+	event, ok := e[eventUUID]
+	if !ok {
+		return dto.Event{}, errors.New("not found")
+	}
+
+	return event, nil
+}
+
+type templatesMock map[uuid.UUID][]dto.Template
+
+func (t templatesMock) FindByEventId(ctx context.Context, eventUUID uuid.UUID) ([]dto.Template, error) {
+	templates, ok := t[eventUUID]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+
+	return templates, nil
+}
+
+type idempotencyConfigMock struct{}
+
+func (i *idempotencyConfigMock) GetIdempotencyTTL() time.Duration {
+	return time.Hour
+}
+
+type schedulerMock struct {
+	scheduled []dto.Notification
+	err       error
+}
+
+func newSchedulerMock() *schedulerMock {
+	return &schedulerMock{}
+}
+
+func (s *schedulerMock) Schedule(ctx context.Context, notification dto.Notification) (dto.Notification, error) {
+	if s.err != nil {
+		return dto.Notification{}, s.err
+	}
+
+	notification.NotificationUUID = uuid.New()
+	s.scheduled = append(s.scheduled, notification)
+
+	return notification, nil
+}
+
+func (s *schedulerMock) Cancel(ctx context.Context, notificationUUID uuid.UUID) error {
+	return nil
+}
@@ -0,0 +1,189 @@
+package notify
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+const (
+	// QueuePolicyStrict уведомления передаются строго по убыванию Priority
+	QueuePolicyStrict = "strict"
+	// QueuePolicyAging эффективный приоритет уведомления растет со временем ожидания
+	QueuePolicyAging = "aging"
+)
+
+var (
+	_ QueuePolicy = (*StrictPolicy)(nil)
+	_ QueuePolicy = (*AgingPolicy)(nil)
+)
+
+// QueuePolicy порядок передачи принятых уведомлений диспетчеру.
+// Реализации не обязаны быть потокобезопасными, доступ синхронизирует очередь сервиса.
+// Время приема берется из dto.Notification.AcceptedAt, очередь заполняет его до Push
+type QueuePolicy interface {
+	// Name название политики для метрик
+	Name() string
+	// Push добавление уведомления
+	Push(notification *dto.Notification)
+	// Pop следующее уведомление к передаче, nil - очередь пуста
+	Pop() *dto.Notification
+	// Len количество уведомлений в очереди
+	Len() int
+	// Oldest время приема самого давнего уведомления в очереди, false - очередь пуста
+	Oldest() (time.Time, bool)
+}
+
+// queueConfig интерфейс конфигурации политики очереди
+type queueConfig interface {
+	GetQueuePolicy() string
+	GetQueueAgingRate() float64
+}
+
+// NewQueuePolicy политика очереди по конфигурации: strict или aging
+func NewQueuePolicy(conf queueConfig) (QueuePolicy, error) {
+	switch conf.GetQueuePolicy() {
+	case QueuePolicyStrict:
+		return NewStrictPolicy(), nil
+	case QueuePolicyAging:
+		if conf.GetQueueAgingRate() <= 0 {
+			return nil, fmt.Errorf("queue aging rate must be positive, got %v", conf.GetQueueAgingRate())
+		}
+		return NewAgingPolicy(conf.GetQueueAgingRate()), nil
+	default:
+		return nil, fmt.Errorf("unknown queue policy %q", conf.GetQueuePolicy())
+	}
+}
+
+// StrictPolicy строгий приоритет на PriorityQueue.
+// Пока поступают уведомления с высоким приоритетом, уведомления с низким приоритетом ждут
+type StrictPolicy struct {
+	queue PriorityQueue
+}
+
+// NewStrictPolicy политика строгого приоритета
+func NewStrictPolicy() *StrictPolicy {
+	p := StrictPolicy{}
+	heap.Init(&p.queue)
+
+	return &p
+}
+
+func (p *StrictPolicy) Name() string { return QueuePolicyStrict }
+
+func (p *StrictPolicy) Push(notification *dto.Notification) {
+	heap.Push(&p.queue, notification)
+}
+
+func (p *StrictPolicy) Pop() *dto.Notification {
+	if p.queue.Len() == 0 {
+		return nil
+	}
+
+	return heap.Pop(&p.queue).(*dto.Notification)
+}
+
+func (p *StrictPolicy) Len() int { return p.queue.Len() }
+
+func (p *StrictPolicy) Oldest() (time.Time, bool) {
+	return oldest(p.queue)
+}
+
+// AgingPolicy старение приоритета: эффективный приоритет равен Priority + rate * секунды ожидания.
+// Уведомление с низким приоритетом обгоняет свежие уведомления с более высоким приоритетом,
+// прождав разницу приоритетов / rate секунд, поэтому не голодает при постоянном потоке срочных уведомлений.
+// Прирост одинаков для всех уведомлений, порядок пары не меняется со временем и сравнивается
+// по Priority - rate * AcceptedAt, что позволяет держать уведомления в куче
+type AgingPolicy struct {
+	rate  float64
+	base  time.Time // base точка отсчета времени приема, ограничивает величину ключей
+	queue agingQueue
+}
+
+// NewAgingPolicy политика старения, rate - прирост приоритета за секунду ожидания
+func NewAgingPolicy(rate float64) *AgingPolicy {
+	p := AgingPolicy{
+		rate: rate,
+		base: time.Now(),
+	}
+	heap.Init(&p.queue)
+
+	return &p
+}
+
+func (p *AgingPolicy) Name() string { return QueuePolicyAging }
+
+func (p *AgingPolicy) Push(notification *dto.Notification) {
+	heap.Push(&p.queue, agingItem{
+		notification: notification,
+		key:          float64(notification.Priority) - p.rate*notification.AcceptedAt.Sub(p.base).Seconds(),
+	})
+}
+
+func (p *AgingPolicy) Pop() *dto.Notification {
+	if p.queue.Len() == 0 {
+		return nil
+	}
+
+	return heap.Pop(&p.queue).(agingItem).notification
+}
+
+func (p *AgingPolicy) Len() int { return p.queue.Len() }
+
+func (p *AgingPolicy) Oldest() (time.Time, bool) {
+	notifications := make([]*dto.Notification, 0, len(p.queue))
+	for _, item := range p.queue {
+		notifications = append(notifications, item.notification)
+	}
+
+	return oldest(notifications)
+}
+
+// agingItem уведомление с неизменным ключом сортировки
+type agingItem struct {
+	notification *dto.Notification
+	key          float64
+}
+
+// agingQueue куча по убыванию ключа, при равных ключах первым идет раньше принятое уведомление
+type agingQueue []agingItem
+
+func (q agingQueue) Len() int { return len(q) }
+
+func (q agingQueue) Less(i, j int) bool {
+	if q[i].key != q[j].key {
+		return q[i].key > q[j].key
+	}
+
+	return q[i].notification.AcceptedAt.Before(*q[j].notification.AcceptedAt)
+}
+
+func (q agingQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *agingQueue) Push(x any) { *q = append(*q, x.(agingItem)) }
+
+func (q *agingQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = agingItem{} // avoid memory leak
+	*q = old[0 : n-1]
+	return item
+}
+
+// oldest минимальное время приема среди уведомлений
+func oldest(notifications []*dto.Notification) (time.Time, bool) {
+	var res time.Time
+	for _, notification := range notifications {
+		if notification.AcceptedAt == nil {
+			continue
+		}
+		if res.IsZero() || notification.AcceptedAt.Before(res) {
+			res = *notification.AcceptedAt
+		}
+	}
+
+	return res, !res.IsZero()
+}
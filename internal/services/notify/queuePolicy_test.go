@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+var _ queueConfig = (*queueConfigMock)(nil)
+
+func TestNewQueuePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    queueConfigMock
+		want    string
+		wantErr bool
+	}{
+		{name: "strict", conf: queueConfigMock{policy: QueuePolicyStrict}, want: QueuePolicyStrict},
+		{name: "aging", conf: queueConfigMock{policy: QueuePolicyAging, rate: 1}, want: QueuePolicyAging},
+		{name: "aging without rate", conf: queueConfigMock{policy: QueuePolicyAging}, wantErr: true},
+		{name: "unknown", conf: queueConfigMock{policy: "fifo"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewQueuePolicy(&tt.conf)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, policy.Name())
+		})
+	}
+}
+
+func TestQueuePolicy_starvation(t *testing.T) {
+	now := time.Now()
+	waited := now.Add(-100 * time.Second)
+
+	// уведомление с низким приоритетом ждет 100 секунд, следом приходит более срочное
+	low := dto.Notification{EventUUID: uuid.New(), Priority: 1, AcceptedAt: &waited}
+	urgent := dto.Notification{EventUUID: uuid.New(), Priority: 50, AcceptedAt: &now}
+	critical := dto.Notification{EventUUID: uuid.New(), Priority: 500, AcceptedAt: &now}
+
+	tests := []struct {
+		name   string
+		policy QueuePolicy
+		want   []uuid.UUID
+	}{
+		{
+			name:   "strict",
+			policy: NewStrictPolicy(),
+			want:   []uuid.UUID{critical.EventUUID, urgent.EventUUID, low.EventUUID},
+		}, {
+			// 1 + 100 секунд ожидания обгоняет 50, но не 500
+			name:   "aging",
+			policy: NewAgingPolicy(1),
+			want:   []uuid.UUID{critical.EventUUID, low.EventUUID, urgent.EventUUID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, notification := range []dto.Notification{urgent, low, critical} {
+				notification := notification
+				tt.policy.Push(&notification)
+			}
+
+			assert.Equal(t, 3, tt.policy.Len())
+			oldest, ok := tt.policy.Oldest()
+			assert.True(t, ok)
+			assert.True(t, waited.Equal(oldest))
+
+			var got []uuid.UUID
+			for notification := tt.policy.Pop(); notification != nil; notification = tt.policy.Pop() {
+				got = append(got, notification.EventUUID)
+			}
+
+			assert.Equal(t, tt.want, got)
+			_, ok = tt.policy.Oldest()
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestAgingPolicy_samePriority(t *testing.T) {
+	policy := NewAgingPolicy(1)
+	now := time.Now()
+	earlier := now.Add(-time.Millisecond)
+
+	first := dto.Notification{EventUUID: uuid.New(), Priority: 10, AcceptedAt: &earlier}
+	second := dto.Notification{EventUUID: uuid.New(), Priority: 10, AcceptedAt: &now}
+
+	policy.Push(&second)
+	policy.Push(&first)
+
+	// при равном приоритете порядок приема
+	assert.Equal(t, first.EventUUID, policy.Pop().EventUUID)
+	assert.Equal(t, second.EventUUID, policy.Pop().EventUUID)
+	assert.Nil(t, policy.Pop())
+}
+
+func TestDeliveryQueue_stats(t *testing.T) {
+	now := time.Now()
+	queue := newDeliveryQueue()
+	queue.now = func() time.Time { return now }
+	queue.setPolicy(NewAgingPolicy(1))
+
+	acceptedAt := now.Add(-4 * time.Second)
+	queue.push([]dto.Notification{
+		{EventUUID: uuid.New(), Priority: 5, AcceptedAt: &acceptedAt},
+		{EventUUID: uuid.New(), Priority: 5},
+		{EventUUID: uuid.New(), Priority: 2000},
+	})
+
+	stats := queue.stats()
+	assert.Equal(t, QueuePolicyAging, stats.Policy)
+	assert.Equal(t, 3, stats.Length)
+	assert.Equal(t, 4.0, stats.OldestWaitSeconds)
+
+	for notification, ok := queue.pop(); ok; notification, ok = queue.pop() {
+		queue.delivered(notification)
+	}
+
+	stats = queue.stats()
+	assert.Equal(t, 0, stats.Length)
+	assert.Equal(t, 0.0, stats.OldestWaitSeconds)
+	assert.Equal(t, []dto.QueueBandStats{
+		{MinPriority: 0, Delivered: 2, WaitAvgSeconds: 2, WaitMaxSeconds: 4},
+		{MinPriority: 10},
+		{MinPriority: 100},
+		{MinPriority: 1000, Delivered: 1},
+	}, stats.Bands)
+}
+
+type queueConfigMock struct {
+	policy string
+	rate   float64
+}
+
+func (m *queueConfigMock) GetQueuePolicy() string {
+	return m.policy
+}
+
+func (m *queueConfigMock) GetQueueAgingRate() float64 {
+	return m.rate
+}
@@ -1,5 +1,387 @@
 package notify
 
-// RateLimiter TODO - реализовать ограничение пропускной шины для разных типов каналов доставки уведомлений
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/interfaces"
+	"github.com/atrian/go-notify-customer/internal/routing"
+)
+
+const (
+	// LimitModeReject уведомления сверх лимита отклоняются с ошибкой NotificationLimitExceeded
+	LimitModeReject = "reject"
+	// LimitModeDelay уведомления сверх лимита ожидают освобождения токенов не дольше MaxDelay
+	LimitModeDelay = "delay"
+
+	// personBucketsSweepInterval периодичность очистки неактивных корзин получателей
+	personBucketsSweepInterval = time.Minute
+)
+
+var _ RateLimiter = (*TokenBucketLimiter)(nil)
+
+// RateLimiter ограничение пропускной способности шины для разных типов каналов доставки уведомлений
 type RateLimiter interface {
+	// Acquire резервирует пропускную способность под пачку уведомлений.
+	// Пачка резервируется целиком: либо все уведомления проходят, либо ни одно.
+	// При превышении лимита возвращает NotificationLimitExceeded
+	Acquire(ctx context.Context, notifications []dto.Notification) error
+}
+
+// limiterConfig интерфейс конфигурации ограничителя
+type limiterConfig interface {
+	GetRateLimitMode() string
+	GetRateLimitMaxDelay() time.Duration
+	GetGlobalRateLimit() float64
+	GetGlobalRateBurst() int
+	GetPersonRateLimit() float64
+	GetPersonRateBurst() int
+	GetChannelRateLimits() []string
+}
+
+// channelLocator контракт на сервис бизнес событий, из события берутся каналы доставки
+type channelLocator interface {
+	FindById(ctx context.Context, eventUUID uuid.UUID) (dto.Event, error)
+}
+
+// TokenBucketLimiter ограничитель на алгоритме token bucket.
+// Содержит глобальную корзину, корзины по каналам доставки и корзины по получателям.
+// Токен соответствует одному сообщению - уведомлению для одного получателя в одном канале.
+// ! is safe for concurrent use
+type TokenBucketLimiter struct {
+	mu        sync.Mutex
+	mode      string
+	maxDelay  time.Duration
+	global    *tokenBucket
+	channels  map[string]*tokenBucket
+	persons   map[uuid.UUID]*tokenBucket
+	person    bucketParams
+	lastSweep time.Time
+	events    channelLocator
+	logger    interfaces.Logger
+	now       func() time.Time
+}
+
+// NewTokenBucketLimiter собирает ограничитель по конфигурации. Нулевая скорость означает отсутствие лимита.
+// Каналы указываются в формате channel:rate[:burst], например sms:10:20
+func NewTokenBucketLimiter(conf limiterConfig, events channelLocator, logger interfaces.Logger) *TokenBucketLimiter {
+	l := TokenBucketLimiter{
+		mode:     conf.GetRateLimitMode(),
+		maxDelay: conf.GetRateLimitMaxDelay(),
+		channels: make(map[string]*tokenBucket),
+		persons:  make(map[uuid.UUID]*tokenBucket),
+		person:   newBucketParams(conf.GetPersonRateLimit(), conf.GetPersonRateBurst()),
+		events:   events,
+		logger:   logger,
+		now:      time.Now,
+	}
+
+	if l.mode != LimitModeDelay {
+		l.mode = LimitModeReject
+	}
+
+	now := l.now()
+	l.lastSweep = now
+
+	if params := newBucketParams(conf.GetGlobalRateLimit(), conf.GetGlobalRateBurst()); params.enabled() {
+		l.global = newTokenBucket(params, now)
+	}
+
+	for _, raw := range conf.GetChannelRateLimits() {
+		channel, params, err := parseChannelLimit(raw)
+		if err != nil {
+			logger.Error("RateLimiter bad channel limit", err)
+			continue
+		}
+		if params.enabled() {
+			l.channels[channel] = newTokenBucket(params, now)
+		}
+	}
+
+	return &l
+}
+
+// Acquire резервирует токены под пачку уведомлений.
+// В режиме LimitModeReject при нехватке токенов сразу возвращает NotificationLimitExceeded,
+// в режиме LimitModeDelay ожидает пополнения корзин, но не дольше maxDelay
+func (l *TokenBucketLimiter) Acquire(ctx context.Context, notifications []dto.Notification) error {
+	d := l.demand(ctx, notifications)
+	deadline := l.now().Add(l.maxDelay)
+
+	for {
+		l.mu.Lock()
+		wait, err := l.tryTake(d)
+		l.mu.Unlock()
+
+		if err != nil || wait == 0 {
+			return err
+		}
+
+		if l.mode == LimitModeReject || l.now().Add(wait).After(deadline) {
+			return NotificationLimitExceeded
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Charge списывает токен за одно сообщение получателю personUUID в канале channel, например при переходе
+// в резервный канал цепочки Fallback. Сообщение уже принято, поэтому токен списывается всегда, корзина уходит
+// в долг. Возвращает ожидание до появления токена в самой "голодной" корзине, на которое стоит отложить отправку
+func (l *TokenBucketLimiter) Charge(channel string, personUUID uuid.UUID) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweepPersons(now)
+
+	buckets := []*tokenBucket{l.global, l.channels[channel]}
+	if l.person.enabled() {
+		b, ok := l.persons[personUUID]
+		if !ok {
+			b = newTokenBucket(l.person, now)
+			l.persons[personUUID] = b
+		}
+		buckets = append(buckets, b)
+	}
+
+	var wait time.Duration
+	for _, b := range buckets {
+		if b == nil {
+			continue
+		}
+
+		b.refill(now)
+		if w := b.waitFor(1); w > wait {
+			wait = w
+		}
+		b.tokens--
+	}
+
+	return wait
+}
+
+// demand подсчет потребности в токенах по корзинам
+func (l *TokenBucketLimiter) demand(ctx context.Context, notifications []dto.Notification) demand {
+	d := demand{
+		channels: make(map[string]float64),
+		persons:  make(map[uuid.UUID]float64),
+	}
+
+	for _, notification := range notifications {
+		channels := l.notificationChannels(ctx, notification)
+
+		for _, channel := range channels {
+			d.channels[channel] += float64(len(notification.PersonUUIDs))
+		}
+
+		for _, person := range notification.PersonUUIDs {
+			d.persons[person] += float64(len(channels))
+		}
+
+		d.global += float64(len(notification.PersonUUIDs) * len(channels))
+	}
+
+	return d
+}
+
+// notificationChannels каналы доставки бизнес события и дополнительные каналы уведомления.
+// Если событие не найдено уведомление не будет отправлено диспетчером и не расходует токены
+func (l *TokenBucketLimiter) notificationChannels(ctx context.Context, notification dto.Notification) []string {
+	if l.events == nil {
+		return nil
+	}
+
+	event, err := l.events.FindById(ctx, notification.EventUUID)
+	if err != nil {
+		l.logger.Debug("RateLimiter event not found", notification.EventUUID.String())
+		return nil
+	}
+
+	return routing.Channels(event.NotificationChannels, notification.Channels)
+}
+
+// tryTake списывает токены со всех корзин, если их достаточно.
+// Иначе возвращает время ожидания до пополнения самой "голодной" корзины.
+// Вызывать под l.mu
+func (l *TokenBucketLimiter) tryTake(d demand) (time.Duration, error) {
+	now := l.now()
+	l.sweepPersons(now)
+
+	var (
+		wait    time.Duration
+		buckets []*tokenBucket
+		amounts []float64
+	)
+
+	check := func(b *tokenBucket, amount float64) error {
+		if b == nil || amount == 0 {
+			return nil
+		}
+		if amount > b.params.burst {
+			return NotificationLimitExceeded
+		}
+
+		b.refill(now)
+		if w := b.waitFor(amount); w > wait {
+			wait = w
+		}
+
+		buckets = append(buckets, b)
+		amounts = append(amounts, amount)
+		return nil
+	}
+
+	if err := check(l.global, d.global); err != nil {
+		return 0, err
+	}
+
+	for channel, amount := range d.channels {
+		if err := check(l.channels[channel], amount); err != nil {
+			return 0, err
+		}
+	}
+
+	if l.person.enabled() {
+		for person, amount := range d.persons {
+			b, ok := l.persons[person]
+			if !ok {
+				b = newTokenBucket(l.person, now)
+				l.persons[person] = b
+			}
+			if err := check(b, amount); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if wait > 0 {
+		return wait, nil
+	}
+
+	for i, b := range buckets {
+		b.tokens -= amounts[i]
+	}
+
+	return 0, nil
+}
+
+// sweepPersons удаляет полностью восстановившиеся корзины получателей,
+// чтобы карта не росла бесконечно. Вызывать под l.mu
+func (l *TokenBucketLimiter) sweepPersons(now time.Time) {
+	if now.Sub(l.lastSweep) < personBucketsSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for person, b := range l.persons {
+		b.refill(now)
+		if b.tokens >= b.params.burst {
+			delete(l.persons, person)
+		}
+	}
+}
+
+// demand потребность пачки уведомлений в токенах по корзинам
+type demand struct {
+	global   float64
+	channels map[string]float64
+	persons  map[uuid.UUID]float64
+}
+
+// bucketParams скорость пополнения (токенов в секунду) и емкость корзины
+type bucketParams struct {
+	rate  float64
+	burst float64
+}
+
+// newBucketParams при незаданной емкости корзина вмещает секундный объем токенов, но не меньше одного
+func newBucketParams(rate float64, burst int) bucketParams {
+	p := bucketParams{
+		rate:  rate,
+		burst: float64(burst),
+	}
+
+	if p.burst <= 0 {
+		p.burst = math.Max(1, math.Ceil(rate))
+	}
+
+	return p
+}
+
+func (p bucketParams) enabled() bool {
+	return p.rate > 0
+}
+
+// tokenBucket корзина токенов. Не потокобезопасна, защищается мьютексом ограничителя
+type tokenBucket struct {
+	params bucketParams
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(params bucketParams, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		params: params,
+		tokens: params.burst,
+		last:   now,
+	}
+}
+
+// refill пополнение корзины за прошедшее время
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	b.tokens = math.Min(b.params.burst, b.tokens+elapsed*b.params.rate)
+	b.last = now
+}
+
+// waitFor время до накопления amount токенов
+func (b *tokenBucket) waitFor(amount float64) time.Duration {
+	if b.tokens >= amount {
+		return 0
+	}
+
+	seconds := (amount - b.tokens) / b.params.rate
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
+
+// parseChannelLimit разбор лимита канала в формате channel:rate[:burst]
+func parseChannelLimit(raw string) (string, bucketParams, error) {
+	parts := strings.Split(strings.TrimSpace(raw), ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+		return "", bucketParams{}, fmt.Errorf("bad channel limit format: %q", raw)
+	}
+
+	rate, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return "", bucketParams{}, fmt.Errorf("bad channel rate %q: %w", raw, err)
+	}
+
+	var burst int
+	if len(parts) == 3 {
+		burst, err = strconv.Atoi(parts[2])
+		if err != nil {
+			return "", bucketParams{}, fmt.Errorf("bad channel burst %q: %w", raw, err)
+		}
+	}
+
+	return parts[0], newBucketParams(rate, burst), nil
 }
@@ -0,0 +1,217 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+var (
+	_ limiterConfig  = (*limiterConfigMock)(nil)
+	_ channelLocator = (*channelLocatorMock)(nil)
+)
+
+func TestTokenBucketLimiter_Acquire(t *testing.T) {
+	smsEvent := uuid.New()
+	mailEvent := uuid.New()
+	events := channelLocatorMock{
+		smsEvent:  {"sms"},
+		mailEvent: {"mail"},
+	}
+
+	tests := []struct {
+		name          string
+		conf          limiterConfigMock
+		notifications []dto.Notification
+		wantErr       []error
+	}{
+		{
+			name: "no limits",
+			conf: limiterConfigMock{},
+			notifications: []dto.Notification{
+				{EventUUID: smsEvent, PersonUUIDs: []uuid.UUID{uuid.New(), uuid.New()}},
+			},
+			wantErr: []error{nil, nil, nil},
+		}, {
+			name: "global limit",
+			conf: limiterConfigMock{global: 0.001, globalBurst: 2},
+			notifications: []dto.Notification{
+				{EventUUID: smsEvent, PersonUUIDs: []uuid.UUID{uuid.New()}},
+			},
+			wantErr: []error{nil, nil, NotificationLimitExceeded},
+		}, {
+			name: "channel limit does not affect other channels",
+			conf: limiterConfigMock{channels: []string{"sms:0.001:1"}},
+			notifications: []dto.Notification{
+				{EventUUID: mailEvent, PersonUUIDs: []uuid.UUID{uuid.New()}},
+			},
+			wantErr: []error{nil, nil, nil},
+		}, {
+			name: "channel limit",
+			conf: limiterConfigMock{channels: []string{"sms:0.001:1"}},
+			notifications: []dto.Notification{
+				{EventUUID: smsEvent, PersonUUIDs: []uuid.UUID{uuid.New()}},
+			},
+			wantErr: []error{nil, NotificationLimitExceeded},
+		}, {
+			name: "batch bigger than burst",
+			conf: limiterConfigMock{channels: []string{"sms:100:1"}},
+			notifications: []dto.Notification{
+				{EventUUID: smsEvent, PersonUUIDs: []uuid.UUID{uuid.New(), uuid.New()}},
+			},
+			wantErr: []error{NotificationLimitExceeded},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewTokenBucketLimiter(&tt.conf, events, logger.NewZapLogger())
+			for _, wantErr := range tt.wantErr {
+				err := l.Acquire(context.TODO(), tt.notifications)
+				assert.ErrorIs(t, err, wantErr)
+			}
+		})
+	}
+}
+
+// TestTokenBucketLimiter_PersonLimit лимит получателя не затрагивает других получателей
+// и не списывает токены при отклонении пачки
+func TestTokenBucketLimiter_PersonLimit(t *testing.T) {
+	eventUUID := uuid.New()
+	first := uuid.New()
+	second := uuid.New()
+
+	l := NewTokenBucketLimiter(
+		&limiterConfigMock{person: 0.001, personBurst: 1, global: 0.001, globalBurst: 3},
+		channelLocatorMock{eventUUID: {"sms"}},
+		logger.NewZapLogger())
+
+	notify := func(persons ...uuid.UUID) error {
+		return l.Acquire(context.TODO(), []dto.Notification{{EventUUID: eventUUID, PersonUUIDs: persons}})
+	}
+
+	assert.NoError(t, notify(first))
+	assert.ErrorIs(t, notify(first, second), NotificationLimitExceeded)
+	assert.NoError(t, notify(second))
+	assert.ErrorIs(t, notify(first), NotificationLimitExceeded)
+}
+
+func TestTokenBucketLimiter_DelayMode(t *testing.T) {
+	eventUUID := uuid.New()
+	notifications := []dto.Notification{{EventUUID: eventUUID, PersonUUIDs: []uuid.UUID{uuid.New()}}}
+
+	l := NewTokenBucketLimiter(
+		&limiterConfigMock{mode: LimitModeDelay, maxDelay: time.Second, global: 20, globalBurst: 1},
+		channelLocatorMock{eventUUID: {"sms"}},
+		logger.NewZapLogger())
+
+	assert.NoError(t, l.Acquire(context.TODO(), notifications))
+
+	// вторая отправка ждет пополнения корзины ~50ms
+	start := time.Now()
+	assert.NoError(t, l.Acquire(context.TODO(), notifications))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	// ожидание дольше maxDelay отклоняется
+	slow := NewTokenBucketLimiter(
+		&limiterConfigMock{mode: LimitModeDelay, maxDelay: 10 * time.Millisecond, global: 1, globalBurst: 1},
+		channelLocatorMock{eventUUID: {"sms"}},
+		logger.NewZapLogger())
+
+	assert.NoError(t, slow.Acquire(context.TODO(), notifications))
+	assert.ErrorIs(t, slow.Acquire(context.TODO(), notifications), NotificationLimitExceeded)
+}
+
+// TestTokenBucketLimiter_Charge переход в резервный канал расходует лимит канала и получателя
+func TestTokenBucketLimiter_Charge(t *testing.T) {
+	eventUUID := uuid.New()
+	personUUID := uuid.New()
+
+	l := NewTokenBucketLimiter(
+		&limiterConfigMock{channels: []string{"sms:0.001:1"}, person: 0.001, personBurst: 1},
+		channelLocatorMock{eventUUID: {"mail"}},
+		logger.NewZapLogger())
+
+	assert.Zero(t, l.Charge("sms", personUUID))
+	// токен канала израсходован, следующее сообщение ждет пополнения
+	assert.Greater(t, l.Charge("sms", uuid.New()), time.Duration(0))
+	assert.Zero(t, l.Charge("mail", uuid.New()))
+
+	// лимит получателя общий для приема и резервных каналов
+	assert.ErrorIs(t,
+		l.Acquire(context.TODO(), []dto.Notification{{EventUUID: eventUUID, PersonUUIDs: []uuid.UUID{personUUID}}}),
+		NotificationLimitExceeded)
+}
+
+func Test_parseChannelLimit(t *testing.T) {
+	channel, params, err := parseChannelLimit("sms:10:20")
+	assert.NoError(t, err)
+	assert.Equal(t, "sms", channel)
+	assert.Equal(t, bucketParams{rate: 10, burst: 20}, params)
+
+	_, params, err = parseChannelLimit("mail:2.5")
+	assert.NoError(t, err)
+	assert.Equal(t, bucketParams{rate: 2.5, burst: 3}, params)
+
+	_, _, err = parseChannelLimit("sms")
+	assert.Error(t, err)
+
+	_, _, err = parseChannelLimit("sms:fast")
+	assert.Error(t, err)
+}
+
+type limiterConfigMock struct {
+	mode        string
+	maxDelay    time.Duration
+	global      float64
+	globalBurst int
+	person      float64
+	personBurst int
+	channels    []string
+}
+
+func (l *limiterConfigMock) GetRateLimitMode() string {
+	return l.mode
+}
+
+func (l *limiterConfigMock) GetRateLimitMaxDelay() time.Duration {
+	return l.maxDelay
+}
+
+func (l *limiterConfigMock) GetGlobalRateLimit() float64 {
+	return l.global
+}
+
+func (l *limiterConfigMock) GetGlobalRateBurst() int {
+	return l.globalBurst
+}
+
+func (l *limiterConfigMock) GetPersonRateLimit() float64 {
+	return l.person
+}
+
+func (l *limiterConfigMock) GetPersonRateBurst() int {
+	return l.personBurst
+}
+
+func (l *limiterConfigMock) GetChannelRateLimits() []string {
+	return l.channels
+}
+
+type channelLocatorMock map[uuid.UUID][]string
+
+func (c channelLocatorMock) FindById(ctx context.Context, eventUUID uuid.UUID) (dto.Event, error) {
+	channels, ok := c[eventUUID]
+	if !ok {
+		return dto.Event{}, errors.New("not found")
+	}
+
+	return dto.Event{EventUUID: eventUUID, NotificationChannels: channels}, nil
+}
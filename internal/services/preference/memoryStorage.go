@@ -0,0 +1,86 @@
+package preference
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+var NotFound = errors.New("not found")
+
+// preferenceKey правило получателя однозначно определяется категорией и каналом
+type preferenceKey struct {
+	category string
+	channel  string
+}
+
+// MemoryStorage in-memory хранилище для сервиса preference
+// ! is safe for concurrent use
+type MemoryStorage struct {
+	mu   sync.Mutex
+	data map[uuid.UUID]map[preferenceKey]dto.Preference
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	ms := MemoryStorage{
+		data: make(map[uuid.UUID]map[preferenceKey]dto.Preference),
+	}
+	return &ms
+}
+
+func (m *MemoryStorage) Store(ctx context.Context, preference dto.Preference) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.data[preference.PersonUUID]; !ok {
+		m.data[preference.PersonUUID] = make(map[preferenceKey]dto.Preference)
+	}
+
+	m.data[preference.PersonUUID][preferenceKey{category: preference.Category, channel: preference.Channel}] = preference
+
+	return nil
+}
+
+func (m *MemoryStorage) GetByPersonIds(ctx context.Context, personUUIDs []uuid.UUID) ([]dto.Preference, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var preferences []dto.Preference
+	for _, personUUID := range personUUIDs {
+		for _, preference := range m.data[personUUID] {
+			preferences = append(preferences, preference)
+		}
+	}
+
+	sort.Slice(preferences, func(i, j int) bool {
+		a, b := preferences[i], preferences[j]
+		if a.PersonUUID != b.PersonUUID {
+			return a.PersonUUID.String() < b.PersonUUID.String()
+		}
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		return a.Channel < b.Channel
+	})
+
+	return preferences, nil
+}
+
+func (m *MemoryStorage) Delete(ctx context.Context, personUUID uuid.UUID, category string, channel string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := preferenceKey{category: category, channel: channel}
+	if _, ok := m.data[personUUID][key]; !ok {
+		return NotFound
+	}
+
+	delete(m.data[personUUID], key)
+
+	return nil
+}
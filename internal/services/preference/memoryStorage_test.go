@@ -0,0 +1,101 @@
+package preference
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// StorageTestSuite общий набор тестов для всех реализаций Storager
+type StorageTestSuite struct {
+	suite.Suite
+	newStorage  func() Storager
+	storage     Storager
+	personUUID  uuid.UUID
+	preferences []dto.Preference
+}
+
+func (suite *StorageTestSuite) SetupSuite() {
+	now := time.Now().UTC().Truncate(time.Second)
+	suite.personUUID = uuid.New()
+
+	suite.preferences = []dto.Preference{
+		{
+			PersonUUID: suite.personUUID,
+			Category:   "marketing",
+			Channel:    "email",
+			Mode:       dto.PreferenceOptOut,
+			UpdatedAt:  now,
+		}, {
+			PersonUUID:  suite.personUUID,
+			Mode:        dto.PreferenceQuiet,
+			MinPriority: 100,
+			UpdatedAt:   now,
+		}, {
+			PersonUUID: uuid.New(),
+			Channel:    "sms",
+			Mode:       dto.PreferenceOptOut,
+			UpdatedAt:  now,
+		},
+	}
+}
+
+func (suite *StorageTestSuite) SetupTest() {
+	suite.storage = suite.newStorage()
+	for i := 0; i < len(suite.preferences); i++ {
+		_ = suite.storage.Store(context.TODO(), suite.preferences[i])
+	}
+}
+
+func (suite *StorageTestSuite) Test_GetByPersonIds() {
+	result, err := suite.storage.GetByPersonIds(context.TODO(), []uuid.UUID{suite.personUUID})
+	assert.NoError(suite.T(), err)
+
+	// правила отсортированы по категории и каналу, общее правило первым
+	assert.Len(suite.T(), result, 2)
+	assert.Equal(suite.T(), dto.PreferenceQuiet, result[0].Mode)
+	assert.Equal(suite.T(), uint(100), result[0].MinPriority)
+	assert.Equal(suite.T(), "marketing", result[1].Category)
+	assert.True(suite.T(), suite.preferences[0].UpdatedAt.Equal(result[1].UpdatedAt))
+
+	result, err = suite.storage.GetByPersonIds(context.TODO(), []uuid.UUID{suite.personUUID, suite.preferences[2].PersonUUID})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), result, 3)
+
+	result, err = suite.storage.GetByPersonIds(context.TODO(), []uuid.UUID{uuid.New()})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), result)
+}
+
+func (suite *StorageTestSuite) Test_Store_replace() {
+	// правило с той же категорией и каналом заменяется
+	replacement := suite.preferences[0]
+	replacement.Mode = dto.PreferenceOptIn
+	assert.NoError(suite.T(), suite.storage.Store(context.TODO(), replacement))
+
+	result, err := suite.storage.GetByPersonIds(context.TODO(), []uuid.UUID{suite.personUUID})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+	assert.Equal(suite.T(), dto.PreferenceOptIn, result[1].Mode)
+}
+
+func (suite *StorageTestSuite) Test_Delete() {
+	err := suite.storage.Delete(context.TODO(), suite.personUUID, "marketing", "email")
+	assert.NoError(suite.T(), err)
+
+	err = suite.storage.Delete(context.TODO(), suite.personUUID, "marketing", "email")
+	assert.ErrorIs(suite.T(), err, NotFound)
+}
+
+// Для запуска через Go test
+func TestMemoryStorageSuite(t *testing.T) {
+	suite.Run(t, &StorageTestSuite{
+		newStorage: func() Storager { return NewMemoryStorage() },
+	})
+}
@@ -0,0 +1,89 @@
+package preference
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+var _ Storager = (*PgStorage)(nil)
+
+// PgStorage PostgreSQL хранилище для сервиса preference.
+// Схема создается миграциями из пакета migrations
+type PgStorage struct {
+	db *sql.DB
+}
+
+func NewPgStorage(db *sql.DB) *PgStorage {
+	ps := PgStorage{db: db}
+	return &ps
+}
+
+func (p *PgStorage) Store(ctx context.Context, preference dto.Preference) error {
+	_, err := p.db.ExecContext(ctx, `INSERT INTO preferences
+		(person_uuid, category, channel, mode, min_priority, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (person_uuid, category, channel) DO UPDATE SET
+			mode = EXCLUDED.mode,
+			min_priority = EXCLUDED.min_priority,
+			updated_at = EXCLUDED.updated_at`,
+		preference.PersonUUID, preference.Category, preference.Channel,
+		preference.Mode, preference.MinPriority, preference.UpdatedAt)
+
+	return err
+}
+
+func (p *PgStorage) GetByPersonIds(ctx context.Context, personUUIDs []uuid.UUID) ([]dto.Preference, error) {
+	ids := make([]string, 0, len(personUUIDs))
+	for _, personUUID := range personUUIDs {
+		ids = append(ids, personUUID.String())
+	}
+
+	rows, err := p.db.QueryContext(ctx, `SELECT person_uuid, category, channel, mode, min_priority, updated_at
+		FROM preferences WHERE person_uuid = ANY($1::uuid[])
+		ORDER BY person_uuid, category, channel`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var preferences []dto.Preference
+	for rows.Next() {
+		var preference dto.Preference
+		if err = rows.Scan(
+			&preference.PersonUUID,
+			&preference.Category,
+			&preference.Channel,
+			&preference.Mode,
+			&preference.MinPriority,
+			&preference.UpdatedAt); err != nil {
+			return nil, err
+		}
+		preferences = append(preferences, preference)
+	}
+
+	return preferences, rows.Err()
+}
+
+func (p *PgStorage) Delete(ctx context.Context, personUUID uuid.UUID, category string, channel string) error {
+	res, err := p.db.ExecContext(ctx, `DELETE FROM preferences
+		WHERE person_uuid = $1 AND category = $2 AND channel = $3`, personUUID, category, channel)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return NotFound
+	}
+
+	return nil
+}
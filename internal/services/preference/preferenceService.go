@@ -0,0 +1,157 @@
+// Package preference сервис предпочтений получателей по категориям событий и каналам отправки.
+//
+// Правило задает режим для получателя, категории события и канала:
+//
+//	opt_in   сообщения отправляются
+//	opt_out  сообщения не отправляются
+//	quiet    отправляются только уведомления с приоритетом не ниже MinPriority
+//
+// Пустые категория или канал означают любое значение. Для сообщения выбирается наиболее точное правило:
+// категория и канал, затем только категория, затем только канал, затем общее правило получателя.
+// Без подходящего правила сообщения отправляются
+package preference
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/interfaces"
+)
+
+var _ interfaces.PreferenceService = (*Service)(nil)
+
+// InvalidMode режим предпочтения не поддерживается
+var InvalidMode = errors.New("invalid preference mode")
+
+// Service содержит хранилище предпочтений и логгер с интерфейсом interfaces.Logger
+type Service struct {
+	storage Storager
+	logger  interfaces.Logger
+}
+
+// New сервис с in-memory хранилищем
+func New(logger interfaces.Logger) *Service {
+	return NewWithStorage(NewMemoryStorage(), logger)
+}
+
+// NewWithStorage сервис с внешним хранилищем, например PgStorage
+func NewWithStorage(storage Storager, logger interfaces.Logger) *Service {
+	s := Service{
+		storage: storage,
+		logger:  logger,
+	}
+
+	return &s
+}
+
+// Start стартовые процедуры для сервиса
+func (s Service) Start(ctx context.Context) {
+	s.logger.Info("Preference service started")
+}
+
+// Stop корректное завершение работы
+func (s Service) Stop() {
+	s.logger.Info("Preference service stopped")
+}
+
+// FindByPersonUUID предпочтения получателя, для получателя без правил - пустой список
+func (s Service) FindByPersonUUID(ctx context.Context, personUUID uuid.UUID) ([]dto.Preference, error) {
+	preferences, err := s.storage.GetByPersonIds(ctx, []uuid.UUID{personUUID})
+	if err != nil {
+		return nil, err
+	}
+
+	if preferences == nil {
+		return []dto.Preference{}, nil
+	}
+
+	return preferences, nil
+}
+
+// FindByPersonUUIDs предпочтения нескольких получателей одним запросом к хранилищу
+func (s Service) FindByPersonUUIDs(ctx context.Context, personUUIDs []uuid.UUID) (map[uuid.UUID][]dto.Preference, error) {
+	preferences, err := s.storage.GetByPersonIds(ctx, personUUIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uuid.UUID][]dto.Preference, len(personUUIDs))
+	for _, preference := range preferences {
+		result[preference.PersonUUID] = append(result[preference.PersonUUID], preference)
+	}
+
+	return result, nil
+}
+
+// Store сохранение правила, правило с той же категорией и каналом заменяется
+func (s Service) Store(ctx context.Context, preference dto.Preference) (dto.Preference, error) {
+	switch preference.Mode {
+	case dto.PreferenceOptIn, dto.PreferenceOptOut:
+		preference.MinPriority = 0
+	case dto.PreferenceQuiet:
+	default:
+		return dto.Preference{}, fmt.Errorf("%w: %q", InvalidMode, preference.Mode)
+	}
+
+	preference.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	if err := s.storage.Store(ctx, preference); err != nil {
+		s.logger.Error("Preference service storage.Store err", err)
+		return dto.Preference{}, err
+	}
+
+	return preference, nil
+}
+
+// Delete удаление правила получателя для категории и канала
+func (s Service) Delete(ctx context.Context, personUUID uuid.UUID, category string, channel string) error {
+	return s.storage.Delete(ctx, personUUID, category, channel)
+}
+
+// Resolve наиболее точное правило получателя для категории события и канала.
+// Без подходящего правила возвращает opt_in
+func Resolve(preferences []dto.Preference, category string, channel string) dto.Preference {
+	resolved := dto.Preference{Category: category, Channel: channel, Mode: dto.PreferenceOptIn}
+	best := -1
+
+	for _, preference := range preferences {
+		if preference.Category != "" && preference.Category != category {
+			continue
+		}
+		if preference.Channel != "" && preference.Channel != channel {
+			continue
+		}
+
+		// точное совпадение категории весомее совпадения канала
+		weight := 0
+		if preference.Category != "" {
+			weight += 2
+		}
+		if preference.Channel != "" {
+			weight++
+		}
+
+		if weight > best {
+			resolved, best = preference, weight
+		}
+	}
+
+	return resolved
+}
+
+// Allows разрешает ли правило отправку уведомления с приоритетом priority
+func Allows(preference dto.Preference, priority uint) bool {
+	switch preference.Mode {
+	case dto.PreferenceOptOut:
+		return false
+	case dto.PreferenceQuiet:
+		return priority >= preference.MinPriority
+	default:
+		return true
+	}
+}
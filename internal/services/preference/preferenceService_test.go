@@ -0,0 +1,71 @@
+package preference
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+func TestService_Store(t *testing.T) {
+	s := New(logger.NewZapLogger())
+	personUUID := uuid.New()
+
+	_, err := s.Store(context.TODO(), dto.Preference{PersonUUID: personUUID, Mode: "unsubscribe"})
+	assert.ErrorIs(t, err, InvalidMode)
+
+	// порог приоритета имеет смысл только для режима quiet
+	stored, err := s.Store(context.TODO(), dto.Preference{PersonUUID: personUUID, Mode: dto.PreferenceOptOut, MinPriority: 10})
+	assert.NoError(t, err)
+	assert.Zero(t, stored.MinPriority)
+	assert.False(t, stored.UpdatedAt.IsZero())
+
+	result, err := s.FindByPersonUUID(context.TODO(), personUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.Preference{stored}, result)
+
+	result, err = s.FindByPersonUUID(context.TODO(), uuid.New())
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.Preference{}, result)
+}
+
+func TestResolve(t *testing.T) {
+	preferences := []dto.Preference{
+		{Mode: dto.PreferenceQuiet, MinPriority: 50},
+		{Channel: "email", Mode: dto.PreferenceOptOut},
+		{Category: "marketing", Mode: dto.PreferenceOptOut},
+		{Category: "marketing", Channel: "sms", Mode: dto.PreferenceOptIn},
+	}
+
+	tests := []struct {
+		category string
+		channel  string
+		want     string
+	}{
+		{category: "marketing", channel: "sms", want: dto.PreferenceOptIn},
+		{category: "marketing", channel: "email", want: dto.PreferenceOptOut},
+		{category: "marketing", channel: "telegram", want: dto.PreferenceOptOut},
+		{category: "reminder", channel: "email", want: dto.PreferenceOptOut},
+		{category: "reminder", channel: "sms", want: dto.PreferenceQuiet},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.category+"|"+tt.channel, func(t *testing.T) {
+			assert.Equal(t, tt.want, Resolve(preferences, tt.category, tt.channel).Mode)
+		})
+	}
+
+	// без правил сообщения отправляются
+	assert.Equal(t, dto.PreferenceOptIn, Resolve(nil, "marketing", "sms").Mode)
+}
+
+func TestAllows(t *testing.T) {
+	assert.True(t, Allows(dto.Preference{Mode: dto.PreferenceOptIn}, 0))
+	assert.False(t, Allows(dto.Preference{Mode: dto.PreferenceOptOut}, 1000))
+	assert.False(t, Allows(dto.Preference{Mode: dto.PreferenceQuiet, MinPriority: 50}, 49))
+	assert.True(t, Allows(dto.Preference{Mode: dto.PreferenceQuiet, MinPriority: 50}, 50))
+}
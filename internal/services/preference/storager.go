@@ -0,0 +1,19 @@
+package preference
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// Storager интерфейс хранилища предпочтений получателей
+type Storager interface {
+	// Store сохраняет предпочтение, правило с той же категорией и каналом заменяется
+	Store(ctx context.Context, preference dto.Preference) error
+	// GetByPersonIds возвращает предпочтения получателей, отсортированные по категории и каналу
+	GetByPersonIds(ctx context.Context, personUUIDs []uuid.UUID) ([]dto.Preference, error)
+	// Delete удаляет правило получателя для категории и канала
+	Delete(ctx context.Context, personUUID uuid.UUID, category string, channel string) error
+}
@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+var NotFound = errors.New("not found")
+
+// MemoryStorage in-memory хранилище для сервиса scheduler
+// ! is safe for concurrent use
+type MemoryStorage struct {
+	mu   sync.Mutex
+	data map[uuid.UUID]dto.Notification
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	ms := MemoryStorage{
+		data: make(map[uuid.UUID]dto.Notification),
+	}
+	return &ms
+}
+
+func (m *MemoryStorage) All(ctx context.Context) ([]dto.Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notifications := make([]dto.Notification, 0, len(m.data))
+	for _, notification := range m.data {
+		notifications = append(notifications, notification)
+	}
+
+	sortBySendAt(notifications)
+
+	return notifications, nil
+}
+
+func (m *MemoryStorage) Store(ctx context.Context, notification dto.Notification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data[notification.NotificationUUID] = notification
+
+	return nil
+}
+
+func (m *MemoryStorage) GetById(ctx context.Context, notificationUUID uuid.UUID) (dto.Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notification, ok := m.data[notificationUUID]
+	if !ok {
+		return dto.Notification{}, NotFound
+	}
+
+	return notification, nil
+}
+
+func (m *MemoryStorage) TakeDue(ctx context.Context, now time.Time, limit int, take func(due []dto.Notification) error) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []dto.Notification
+	for _, notification := range m.data {
+		if notification.SendAt == nil || !notification.SendAt.After(now) {
+			due = append(due, notification)
+		}
+	}
+
+	sortBySendAt(due)
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	if len(due) == 0 {
+		return 0, nil
+	}
+
+	if err := take(due); err != nil {
+		return 0, err
+	}
+
+	for _, notification := range due {
+		delete(m.data, notification.NotificationUUID)
+	}
+
+	return len(due), nil
+}
+
+func (m *MemoryStorage) DeleteById(ctx context.Context, notificationUUID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.data[notificationUUID]; !ok {
+		return NotFound
+	}
+
+	delete(m.data, notificationUUID)
+
+	return nil
+}
+
+// sortBySendAt упорядочивание по времени отправки, ближайшие первыми
+func sortBySendAt(notifications []dto.Notification) {
+	sort.Slice(notifications, func(i, j int) bool {
+		return sendAt(notifications[i]).Before(sendAt(notifications[j]))
+	})
+}
+
+func sendAt(notification dto.Notification) time.Time {
+	if notification.SendAt == nil {
+		return time.Time{}
+	}
+	return *notification.SendAt
+}
@@ -0,0 +1,131 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// StorageTestSuite общий набор тестов для всех реализаций Storager
+type StorageTestSuite struct {
+	suite.Suite
+	newStorage    func() Storager
+	storage       Storager
+	notifications []dto.Notification
+	now           time.Time
+}
+
+func (suite *StorageTestSuite) SetupSuite() {
+	suite.now = time.Now().UTC().Truncate(time.Second)
+	past := suite.now.Add(-time.Minute)
+	earlyPast := suite.now.Add(-time.Hour)
+	future := suite.now.Add(time.Hour)
+
+	suite.notifications = []dto.Notification{
+		{
+			NotificationUUID: uuid.New(),
+			EventUUID:        uuid.New(),
+			PersonUUIDs:      []uuid.UUID{uuid.New()},
+			Priority:         10,
+			SendAt:           &past,
+		}, {
+			NotificationUUID: uuid.New(),
+			EventUUID:        uuid.New(),
+			PersonUUIDs:      []uuid.UUID{uuid.New()},
+			MessageParams:    []dto.MessageParam{{Key: "date", Value: "tomorrow"}},
+			Priority:         20,
+			SendAt:           &earlyPast,
+		}, {
+			NotificationUUID: uuid.New(),
+			EventUUID:        uuid.New(),
+			PersonUUIDs:      []uuid.UUID{uuid.New()},
+			Priority:         30,
+			SendAt:           &future,
+		},
+	}
+}
+
+func (suite *StorageTestSuite) SetupTest() {
+	suite.storage = suite.newStorage()
+	for i := 0; i < len(suite.notifications); i++ {
+		_ = suite.storage.Store(context.TODO(), suite.notifications[i])
+	}
+}
+
+func (suite *StorageTestSuite) Test_All() {
+	result, err := suite.storage.All(context.TODO())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), len(suite.notifications), len(result))
+
+	// ближайшие уведомления первыми
+	assert.Equal(suite.T(), suite.notifications[1].NotificationUUID, result[0].NotificationUUID)
+}
+
+func (suite *StorageTestSuite) Test_GetById() {
+	_, err := suite.storage.GetById(context.TODO(), uuid.New())
+	assert.ErrorIs(suite.T(), err, NotFound)
+
+	result, err := suite.storage.GetById(context.TODO(), suite.notifications[1].NotificationUUID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.notifications[1].MessageParams, result.MessageParams)
+	assert.True(suite.T(), suite.notifications[1].SendAt.Equal(*result.SendAt))
+}
+
+func (suite *StorageTestSuite) Test_TakeDue() {
+	var due []dto.Notification
+	take := func(notifications []dto.Notification) error {
+		due = notifications
+		return nil
+	}
+
+	// не принятые take уведомления остаются в хранилище
+	failed := errors.New("journal is down")
+	taken, err := suite.storage.TakeDue(context.TODO(), suite.now, 10, func([]dto.Notification) error { return failed })
+	assert.ErrorIs(suite.T(), err, failed)
+	assert.Equal(suite.T(), 0, taken)
+
+	// по лимиту извлекается самое раннее уведомление
+	taken, err = suite.storage.TakeDue(context.TODO(), suite.now, 1, take)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, taken)
+	assert.Equal(suite.T(), 1, len(due))
+	assert.Equal(suite.T(), suite.notifications[1].NotificationUUID, due[0].NotificationUUID)
+
+	taken, err = suite.storage.TakeDue(context.TODO(), suite.now, 10, take)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, taken)
+	assert.Equal(suite.T(), suite.notifications[0].NotificationUUID, due[0].NotificationUUID)
+
+	// наступивших уведомлений не осталось, take не вызывается
+	taken, err = suite.storage.TakeDue(context.TODO(), suite.now, 10, func([]dto.Notification) error { return failed })
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, taken)
+
+	// извлеченные уведомления удалены, будущее осталось
+	rest, err := suite.storage.All(context.TODO())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(rest))
+	assert.Equal(suite.T(), suite.notifications[2].NotificationUUID, rest[0].NotificationUUID)
+}
+
+func (suite *StorageTestSuite) Test_DeleteById() {
+	err := suite.storage.DeleteById(context.TODO(), suite.notifications[0].NotificationUUID)
+	assert.NoError(suite.T(), err)
+
+	err = suite.storage.DeleteById(context.TODO(), suite.notifications[0].NotificationUUID)
+	assert.ErrorIs(suite.T(), err, NotFound)
+}
+
+// Для запуска через Go test
+func TestMemoryStorageSuite(t *testing.T) {
+	suite.Run(t, &StorageTestSuite{
+		newStorage: func() Storager { return NewMemoryStorage() },
+	})
+}
@@ -0,0 +1,155 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+var _ Storager = (*PgStorage)(nil)
+
+// PgStorage PostgreSQL хранилище для сервиса scheduler.
+// Уведомление хранится в json, время отправки вынесено в отдельную колонку для выборки.
+// Схема создается миграциями из пакета migrations
+type PgStorage struct {
+	db *sql.DB
+}
+
+func NewPgStorage(db *sql.DB) *PgStorage {
+	ps := PgStorage{db: db}
+	return &ps
+}
+
+func (p *PgStorage) All(ctx context.Context) ([]dto.Notification, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT payload FROM scheduled_notifications ORDER BY send_at`)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanNotifications(rows)
+}
+
+func (p *PgStorage) Store(ctx context.Context, notification dto.Notification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.ExecContext(ctx, `INSERT INTO scheduled_notifications (notification_uuid, send_at, payload)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (notification_uuid) DO UPDATE SET send_at = EXCLUDED.send_at, payload = EXCLUDED.payload`,
+		notification.NotificationUUID, sendAt(notification), payload)
+
+	return err
+}
+
+func (p *PgStorage) GetById(ctx context.Context, notificationUUID uuid.UUID) (dto.Notification, error) {
+	var (
+		payload      []byte
+		notification dto.Notification
+	)
+
+	err := p.db.QueryRowContext(ctx, `SELECT payload FROM scheduled_notifications WHERE notification_uuid = $1`,
+		notificationUUID).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dto.Notification{}, NotFound
+	}
+	if err != nil {
+		return dto.Notification{}, err
+	}
+
+	err = json.Unmarshal(payload, &notification)
+
+	return notification, err
+}
+
+// TakeDue передает наступившие уведомления в take и удаляет их в той же транзакции.
+// Строки заблокированы до завершения транзакции: SKIP LOCKED позволяет нескольким экземплярам
+// приложения разбирать очередь без повторной отправки, а отмена ждет ее завершения
+func (p *PgStorage) TakeDue(ctx context.Context, now time.Time, limit int, take func(due []dto.Notification) error) (int, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `SELECT payload FROM scheduled_notifications
+		WHERE send_at <= $1
+		ORDER BY send_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`, now, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	due, err := scanNotifications(rows)
+	if err != nil || len(due) == 0 {
+		return 0, err
+	}
+
+	if err = take(due); err != nil {
+		return 0, err
+	}
+
+	for _, notification := range due {
+		if _, err = tx.ExecContext(ctx, `DELETE FROM scheduled_notifications WHERE notification_uuid = $1`,
+			notification.NotificationUUID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(due), nil
+}
+
+func (p *PgStorage) DeleteById(ctx context.Context, notificationUUID uuid.UUID) error {
+	res, err := p.db.ExecContext(ctx, `DELETE FROM scheduled_notifications WHERE notification_uuid = $1`, notificationUUID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return NotFound
+	}
+
+	return nil
+}
+
+// scanNotifications чтение json уведомлений из результата запроса
+func scanNotifications(rows *sql.Rows) ([]dto.Notification, error) {
+	defer rows.Close()
+
+	var notifications []dto.Notification
+	for rows.Next() {
+		var (
+			payload      []byte
+			notification dto.Notification
+		)
+
+		if err := rows.Scan(&payload); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(payload, &notification); err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
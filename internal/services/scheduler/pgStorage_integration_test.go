@@ -0,0 +1,27 @@
+//go:build integration
+// +build integration
+
+package scheduler
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/atrian/go-notify-customer/internal/migrations"
+	"github.com/atrian/go-notify-customer/pkg/postgres/pgtest"
+)
+
+// TestPgStorageSuite общий набор тестов хранилища на PostgreSQL в docker контейнере
+func TestPgStorageSuite(t *testing.T) {
+	pgtest.Run(t, migrations.FS, func(t *testing.T, db *sql.DB) {
+		suite.Run(t, &StorageTestSuite{
+			newStorage: func() Storager {
+				// каждый тест начинается с пустой таблицы
+				pgtest.Truncate(t, db, "scheduled_notifications")
+				return NewPgStorage(db)
+			},
+		})
+	})
+}
@@ -0,0 +1,186 @@
+// Package scheduler сервис отложенной отправки уведомлений.
+// Уведомления с временем отправки dto.Notification.SendAt в будущем сохраняются в хранилище
+// и когда время отправки наступает передаются enqueuer, сервису приема уведомлений.
+// Уведомление удаляется из хранилища после того, как enqueuer записал его в журнал принятых уведомлений.
+// При использовании PgStorage ожидающие уведомления переживают перезапуск приложения.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/interfaces"
+)
+
+var _ interfaces.SchedulerService = (*Service)(nil)
+
+// schedulerConfig интерфейс конфигурации планировщика
+type schedulerConfig interface {
+	GetSchedulerInterval() time.Duration
+	GetSchedulerBatchSize() int
+}
+
+// enqueuer контракт на прием наступивших уведомлений. Возврат без ошибки означает,
+// что уведомления сохранены и будут отправлены
+type enqueuer interface {
+	Enqueue(ctx context.Context, notifications []dto.Notification) error
+}
+
+// Service планировщик. Содержит хранилище отложенных уведомлений,
+// получателя наступивших уведомлений и логгер с интерфейсом interfaces.Logger
+type Service struct {
+	storage   Storager
+	enqueuer  enqueuer
+	interval  time.Duration
+	batchSize int
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	logger    interfaces.Logger
+	now       func() time.Time
+}
+
+// New планировщик с in-memory хранилищем
+func New(conf schedulerConfig, logger interfaces.Logger) *Service {
+	return NewWithStorage(conf, NewMemoryStorage(), logger)
+}
+
+// NewWithStorage планировщик с внешним хранилищем, например PgStorage
+func NewWithStorage(conf schedulerConfig, storage Storager, logger interfaces.Logger) *Service {
+	s := Service{
+		storage:   storage,
+		interval:  conf.GetSchedulerInterval(),
+		batchSize: conf.GetSchedulerBatchSize(),
+		logger:    logger,
+		now:       time.Now,
+	}
+
+	if s.interval <= 0 {
+		s.interval = time.Second
+	}
+
+	if s.batchSize <= 0 {
+		s.batchSize = 100
+	}
+
+	return &s
+}
+
+// SetEnqueuer получатель наступивших уведомлений, например notify.Service.
+// Без получателя уведомления остаются в хранилище
+func (s *Service) SetEnqueuer(enqueuer enqueuer) *Service {
+	s.enqueuer = enqueuer
+	return s
+}
+
+// Start запуск периодической выдачи наступивших уведомлений
+func (s *Service) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			// при старте сразу выдаем накопившиеся за время простоя уведомления
+			s.release(ctx)
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	s.logger.Info("Scheduler service started")
+}
+
+// Stop остановка выдачи, дожидается завершения текущей итерации
+func (s *Service) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+
+	s.logger.Info("Scheduler service stopped")
+}
+
+// Schedule сохраняет уведомление до наступления времени отправки.
+// Уведомлению без идентификатора присваивается UUID
+func (s *Service) Schedule(ctx context.Context, notification dto.Notification) (dto.Notification, error) {
+	if notification.SendAt == nil {
+		return dto.Notification{}, fmt.Errorf("scheduler: send_at is required")
+	}
+
+	if notification.NotificationUUID == uuid.Nil {
+		notification.NotificationUUID = uuid.New()
+	}
+
+	if err := s.storage.Store(ctx, notification); err != nil {
+		s.logger.Error("Scheduler storage.Store err", err)
+		return dto.Notification{}, err
+	}
+
+	s.logger.Debug(fmt.Sprintf("Notification %v scheduled at %v", notification.NotificationUUID, notification.SendAt))
+
+	return notification, nil
+}
+
+// All возвращает ожидающие отправки уведомления
+func (s *Service) All(ctx context.Context) []dto.Notification {
+	notifications, err := s.storage.All(ctx)
+	if err != nil {
+		s.logger.Error("Scheduler storage.All err", err)
+	}
+
+	if notifications == nil {
+		return []dto.Notification{}
+	}
+
+	return notifications
+}
+
+// FindById поиск ожидающего уведомления
+func (s *Service) FindById(ctx context.Context, notificationUUID uuid.UUID) (dto.Notification, error) {
+	return s.storage.GetById(ctx, notificationUUID)
+}
+
+// Cancel отмена ожидающего уведомления. Уже переданные диспетчеру уведомления
+// отменить нельзя - возвращается NotFound
+func (s *Service) Cancel(ctx context.Context, notificationUUID uuid.UUID) error {
+	return s.storage.DeleteById(ctx, notificationUUID)
+}
+
+// release передает получателю все наступившие уведомления пачками по batchSize.
+// Пачка, которую получатель не принял, остается в хранилище до следующей итерации
+func (s *Service) release(ctx context.Context) {
+	if s.enqueuer == nil {
+		return
+	}
+
+	for ctx.Err() == nil {
+		taken, err := s.storage.TakeDue(ctx, s.now(), s.batchSize, func(due []dto.Notification) error {
+			return s.enqueuer.Enqueue(ctx, due)
+		})
+		if err != nil {
+			s.logger.Error("Scheduler release err", err)
+			return
+		}
+
+		if taken > 0 {
+			s.logger.Debug(fmt.Sprintf("Scheduler released %v notifications", taken))
+		}
+
+		if taken < s.batchSize {
+			return
+		}
+	}
+}
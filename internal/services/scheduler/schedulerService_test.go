@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+var (
+	_ schedulerConfig = (*configMock)(nil)
+	_ enqueuer        = (*enqueuerMock)(nil)
+)
+
+func TestService_Schedule(t *testing.T) {
+	target := newEnqueuerMock()
+	s := New(&configMock{}, logger.NewZapLogger()).SetEnqueuer(target)
+
+	// без времени отправки уведомление не принимается
+	_, err := s.Schedule(context.TODO(), dto.Notification{EventUUID: uuid.New()})
+	assert.Error(t, err)
+
+	soon := time.Now().Add(50 * time.Millisecond)
+	later := time.Now().Add(time.Hour)
+
+	first, err := s.Schedule(context.TODO(), dto.Notification{EventUUID: uuid.New(), SendAt: &soon})
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, first.NotificationUUID)
+
+	second, err := s.Schedule(context.TODO(), dto.Notification{EventUUID: uuid.New(), SendAt: &later})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, len(s.All(context.TODO())))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	defer s.Stop()
+
+	// уведомление выдается после наступления времени отправки
+	select {
+	case released := <-target.enqueued:
+		assert.Equal(t, first.NotificationUUID, released.NotificationUUID)
+		assert.False(t, time.Now().Before(soon))
+	case <-time.After(time.Second):
+		t.Fatal("scheduled notification was not released")
+	}
+
+	// отмена ожидающего уведомления
+	assert.NoError(t, s.Cancel(context.TODO(), second.NotificationUUID))
+	assert.ErrorIs(t, s.Cancel(context.TODO(), second.NotificationUUID), NotFound)
+	assert.Equal(t, 0, len(s.All(context.TODO())))
+}
+
+// TestService_EnqueueFailed уведомления, которые получатель не принял, остаются в хранилище
+// и выдаются на следующей итерации
+func TestService_EnqueueFailed(t *testing.T) {
+	target := newEnqueuerMock()
+	target.err = errors.New("journal is down")
+	storage := NewMemoryStorage()
+	s := NewWithStorage(&configMock{}, storage, logger.NewZapLogger()).SetEnqueuer(target)
+
+	past := time.Now().Add(-time.Minute)
+	scheduled, err := s.Schedule(context.TODO(), dto.Notification{EventUUID: uuid.New(), SendAt: &past})
+	assert.NoError(t, err)
+
+	s.Start(context.Background())
+	defer s.Stop()
+	time.Sleep(30 * time.Millisecond)
+
+	kept, err := storage.GetById(context.TODO(), scheduled.NotificationUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, scheduled.EventUUID, kept.EventUUID)
+
+	// получатель снова доступен
+	target.setErr(nil)
+	assert.Equal(t, scheduled.NotificationUUID, (<-target.enqueued).NotificationUUID)
+	assert.Eventually(t, func() bool { return len(s.All(context.TODO())) == 0 }, time.Second, 5*time.Millisecond)
+}
+
+// enqueuerMock получатель наступивших уведомлений, принятые уведомления складываются в enqueued
+type enqueuerMock struct {
+	mu       sync.Mutex
+	err      error
+	enqueued chan dto.Notification
+}
+
+func newEnqueuerMock() *enqueuerMock {
+	return &enqueuerMock{enqueued: make(chan dto.Notification, 10)}
+}
+
+func (e *enqueuerMock) Enqueue(ctx context.Context, notifications []dto.Notification) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.err != nil {
+		return e.err
+	}
+
+	for _, notification := range notifications {
+		e.enqueued <- notification
+	}
+
+	return nil
+}
+
+func (e *enqueuerMock) setErr(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.err = err
+}
+
+type configMock struct{}
+
+func (c *configMock) GetSchedulerInterval() time.Duration {
+	return 10 * time.Millisecond
+}
+
+func (c *configMock) GetSchedulerBatchSize() int {
+	return 10
+}
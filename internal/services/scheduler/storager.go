@@ -0,0 +1,25 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// Storager интерфейс хранилища отложенных уведомлений
+type Storager interface {
+	// All возвращает все ожидающие отправки уведомления
+	All(ctx context.Context) ([]dto.Notification, error)
+	// Store сохраняет отложенное уведомление
+	Store(ctx context.Context, notification dto.Notification) error
+	// GetById возвращает уведомление по uuid
+	GetById(ctx context.Context, notificationUUID uuid.UUID) (dto.Notification, error)
+	// TakeDue передает в take не более limit уведомлений со временем отправки не позже now.
+	// Уведомления удаляются из хранилища только если take вернул nil, возвращает их количество
+	TakeDue(ctx context.Context, now time.Time, limit int, take func(due []dto.Notification) error) (int, error)
+	// DeleteById удаляет уведомление по uuid
+	DeleteById(ctx context.Context, notificationUUID uuid.UUID) error
+}
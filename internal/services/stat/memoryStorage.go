@@ -38,8 +38,9 @@ func (m *MemoryStorage) All(ctx context.Context) ([]dto.Stat, error) {
 	return stats, nil
 }
 
+// Store сохраняет запись, запись с уже сохраненным StatUUID игнорируется
 func (m *MemoryStorage) Store(ctx context.Context, stat dto.Stat) error {
-	m.data.Store(stat.StatUUID, stat)
+	m.data.LoadOrStore(stat.StatUUID, stat)
 
 	return nil
 }
@@ -11,13 +11,15 @@ import (
 	"github.com/atrian/go-notify-customer/internal/dto"
 )
 
-type MemoryStorageTestSuite struct {
+// StorageTestSuite общий набор тестов для всех реализаций Storager
+type StorageTestSuite struct {
 	suite.Suite
-	storage Storager
-	stats   []dto.Stat
+	newStorage func() Storager
+	storage    Storager
+	stats      []dto.Stat
 }
 
-func (suite *MemoryStorageTestSuite) SetupSuite() {
+func (suite *StorageTestSuite) SetupSuite() {
 	personOneUUID := uuid.New()
 	personTwoUUID := uuid.New()
 
@@ -46,14 +48,26 @@ func (suite *MemoryStorageTestSuite) SetupSuite() {
 	}
 }
 
-func (suite *MemoryStorageTestSuite) SetupTest() {
-	suite.storage = NewMemoryStorage()
+func (suite *StorageTestSuite) SetupTest() {
+	suite.storage = suite.newStorage()
 	for i := 0; i < len(suite.stats); i++ {
 		_ = suite.storage.Store(context.TODO(), suite.stats[i])
 	}
 }
 
-func (suite *MemoryStorageTestSuite) Test_GetByNotificationId() {
+func (suite *StorageTestSuite) Test_StoreDuplicate() {
+	// повторная запись с тем же StatUUID не создает дубликата и не меняет сохраненную запись
+	duplicate := suite.stats[0]
+	duplicate.Status = dto.Failed
+	assert.NoError(suite.T(), suite.storage.Store(context.TODO(), duplicate))
+
+	result, err := suite.storage.GetByNotificationId(context.TODO(), duplicate.NotificationUUID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(result))
+	assert.Equal(suite.T(), dto.Sent, result[0].Status)
+}
+
+func (suite *StorageTestSuite) Test_GetByNotificationId() {
 	// Запрос несуществующего объекта
 	_, err := suite.storage.GetByNotificationId(context.TODO(), uuid.New())
 	assert.ErrorIs(suite.T(), err, NotFound)
@@ -64,7 +78,7 @@ func (suite *MemoryStorageTestSuite) Test_GetByNotificationId() {
 	assert.Equal(suite.T(), 1, len(result))
 }
 
-func (suite *MemoryStorageTestSuite) Test_GetByPersonId() {
+func (suite *StorageTestSuite) Test_GetByPersonId() {
 	// Запрос несуществующего объекта
 	_, err := suite.storage.GetByPersonId(context.TODO(), uuid.New())
 	assert.ErrorIs(suite.T(), err, NotFound)
@@ -76,7 +90,7 @@ func (suite *MemoryStorageTestSuite) Test_GetByPersonId() {
 	assert.Equal(suite.T(), 2, len(result))
 }
 
-func (suite *MemoryStorageTestSuite) Test_All() {
+func (suite *StorageTestSuite) Test_All() {
 	result, err := suite.storage.All(context.TODO())
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), len(suite.stats), len(result))
@@ -84,5 +98,7 @@ func (suite *MemoryStorageTestSuite) Test_All() {
 
 // Для запуска через Go test
 func TestMemoryStorageSuite(t *testing.T) {
-	suite.Run(t, new(MemoryStorageTestSuite))
+	suite.Run(t, &StorageTestSuite{
+		newStorage: func() Storager { return NewMemoryStorage() },
+	})
 }
@@ -0,0 +1,119 @@
+package stat
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// statusRank продвижение статуса по жизненному циклу. Успешная отправка после
+// неудачных попыток или повтора из очереди недоставленных перекрывает ошибку
+var statusRank = map[dto.StatStatus]int{
+	dto.Accepted:     1,
+	dto.Dispatched:   2,
+	dto.Deferred:     2,
+	dto.Failed:       3,
+	dto.BadChannel:   3,
+	dto.DeadLettered: 4,
+	dto.Sent:         5,
+	dto.OptedOut:     5,
+	dto.Rerouted:     5,
+}
+
+// stateRank порядок состояний для выбора наименее продвинутого. Ранги не повторяются,
+// иначе общее состояние зависело бы от порядка обхода получателей
+var stateRank = map[string]int{
+	dto.StateAccepted:   1,
+	dto.StateDeferred:   2,
+	dto.StateDispatched: 3,
+	dto.StateFailed:     4,
+	dto.StateSent:       5,
+	dto.StateRerouted:   6,
+	dto.StateOptedOut:   7,
+}
+
+// recipientKey получатель и канал
+type recipientKey struct {
+	person  uuid.UUID
+	channel string
+}
+
+// aggregateStatus сворачивает записи статистики уведомления в состояния по получателям и каналам.
+// Запись о приеме без канала показывается, только пока для получателя нет сообщений в каналах
+func aggregateStatus(notificationUUID uuid.UUID, stats []dto.Stat) dto.NotificationStatus {
+	recipients := make(map[recipientKey]dto.RecipientStatus)
+	withChannels := make(map[uuid.UUID]bool)
+
+	for _, stat := range stats {
+		if stat.Channel != "" {
+			withChannels[stat.PersonUUID] = true
+		}
+
+		key := recipientKey{person: stat.PersonUUID, channel: stat.Channel}
+		current, exist := recipients[key]
+
+		if stat.Attempt > current.Attempts {
+			current.Attempts = stat.Attempt
+		}
+		if stat.CreatedAt > current.UpdatedAt {
+			current.UpdatedAt = stat.CreatedAt
+		}
+		if !exist || statusRank[stat.Status] >= statusRank[current.Status] {
+			current.Status = stat.Status
+		}
+
+		current.PersonUUID = stat.PersonUUID
+		current.Channel = stat.Channel
+		current.State = stateOf(current.Status)
+		recipients[key] = current
+	}
+
+	status := dto.NotificationStatus{
+		NotificationUUID: notificationUUID,
+		Recipients:       make([]dto.RecipientStatus, 0, len(recipients)),
+	}
+
+	for key, recipient := range recipients {
+		if key.channel == "" && withChannels[key.person] {
+			continue
+		}
+
+		status.Recipients = append(status.Recipients, recipient)
+
+		if status.State == "" || stateRank[recipient.State] < stateRank[status.State] {
+			status.State = recipient.State
+		}
+	}
+
+	sort.Slice(status.Recipients, func(i, j int) bool {
+		a, b := status.Recipients[i], status.Recipients[j]
+		if a.PersonUUID != b.PersonUUID {
+			return a.PersonUUID.String() < b.PersonUUID.String()
+		}
+		return a.Channel < b.Channel
+	})
+
+	return status
+}
+
+// stateOf состояние жизненного цикла по статусу статистики
+func stateOf(status dto.StatStatus) string {
+	switch status {
+	case dto.Accepted:
+		return dto.StateAccepted
+	case dto.Dispatched:
+		return dto.StateDispatched
+	case dto.Deferred:
+		return dto.StateDeferred
+	case dto.Sent:
+		return dto.StateSent
+	case dto.OptedOut:
+		return dto.StateOptedOut
+	case dto.Rerouted:
+		return dto.StateRerouted
+	default:
+		return dto.StateFailed
+	}
+}
@@ -0,0 +1,90 @@
+package stat
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+func Test_aggregateStatus(t *testing.T) {
+	notificationUUID := uuid.New()
+	first := uuid.New()
+	second := uuid.New()
+
+	stats := []dto.Stat{
+		{PersonUUID: first, Status: dto.Accepted, CreatedAt: "2023-03-21 10:00:00"},
+		{PersonUUID: second, Status: dto.Accepted, CreatedAt: "2023-03-21 10:00:00"},
+		// первый получатель: sms отправлено со второй попытки, mail в очереди
+		{PersonUUID: first, Channel: "sms", Status: dto.Dispatched, CreatedAt: "2023-03-21 10:00:01"},
+		{PersonUUID: first, Channel: "sms", Status: dto.Sent, Attempt: 2, CreatedAt: "2023-03-21 10:00:03"},
+		{PersonUUID: first, Channel: "sms", Status: dto.Failed, Attempt: 1, CreatedAt: "2023-03-21 10:00:03"},
+		{PersonUUID: first, Channel: "mail", Status: dto.Dispatched, CreatedAt: "2023-03-21 10:00:01"},
+	}
+
+	status := aggregateStatus(notificationUUID, stats)
+
+	assert.Equal(t, notificationUUID, status.NotificationUUID)
+	// второй получатель еще без сообщений
+	assert.Equal(t, dto.StateAccepted, status.State)
+	assert.Equal(t, 3, len(status.Recipients))
+
+	byKey := make(map[recipientKey]dto.RecipientStatus)
+	for _, recipient := range status.Recipients {
+		byKey[recipientKey{person: recipient.PersonUUID, channel: recipient.Channel}] = recipient
+	}
+
+	sms := byKey[recipientKey{person: first, channel: "sms"}]
+	assert.Equal(t, dto.StateSent, sms.State)
+	assert.Equal(t, 2, sms.Attempts)
+	assert.Equal(t, "2023-03-21 10:00:03", sms.UpdatedAt)
+
+	assert.Equal(t, dto.StateDispatched, byKey[recipientKey{person: first, channel: "mail"}].State)
+	assert.Equal(t, dto.StateAccepted, byKey[recipientKey{person: second}].State)
+
+	// запись о приеме скрывается после появления сообщений в каналах
+	_, hasAccepted := byKey[recipientKey{person: first}]
+	assert.False(t, hasAccepted)
+
+	// после исчерпания попыток общее состояние - ошибка
+	stats = append(stats,
+		dto.Stat{PersonUUID: first, Channel: "mail", Status: dto.DeadLettered, Attempt: 5},
+		dto.Stat{PersonUUID: second, Channel: "sms", Status: dto.Sent, Attempt: 1},
+	)
+
+	status = aggregateStatus(notificationUUID, stats)
+	assert.Equal(t, dto.StateFailed, status.State)
+
+	// отказ получателя от канала - завершенное состояние, не ошибка
+	status = aggregateStatus(notificationUUID, []dto.Stat{
+		{PersonUUID: first, Channel: "sms", Status: dto.Sent, Attempt: 1},
+		{PersonUUID: first, Channel: "mail", Status: dto.OptedOut},
+	})
+	assert.Equal(t, dto.StateSent, status.State)
+	assert.Equal(t, dto.StateOptedOut, status.Recipients[0].State)
+
+	// отложенное до окна доставки сообщение ждет, после отправки - отправлено
+	status = aggregateStatus(notificationUUID, []dto.Stat{
+		{PersonUUID: first, Channel: "sms", Status: dto.Deferred},
+	})
+	assert.Equal(t, dto.StateDeferred, status.State)
+
+	status = aggregateStatus(notificationUUID, []dto.Stat{
+		{PersonUUID: first, Channel: "sms", Status: dto.Deferred},
+		{PersonUUID: first, Channel: "sms", Status: dto.Sent, Attempt: 1},
+	})
+	assert.Equal(t, dto.StateSent, status.State)
+
+	// общее состояние не зависит от порядка получателей: отложенное ждет дольше переданного в очередь
+	for i := 0; i < 20; i++ {
+		status = aggregateStatus(notificationUUID, []dto.Stat{
+			{PersonUUID: first, Channel: "sms", Status: dto.Dispatched},
+			{PersonUUID: first, Channel: "mail", Status: dto.Deferred},
+			{PersonUUID: second, Channel: "sms", Status: dto.Rerouted},
+			{PersonUUID: second, Channel: "mail", Status: dto.Sent, Attempt: 1},
+		})
+		assert.Equal(t, dto.StateDeferred, status.State)
+	}
+}
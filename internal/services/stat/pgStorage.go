@@ -0,0 +1,85 @@
+package stat
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+var _ Storager = (*PgStorage)(nil)
+
+// PgStorage PostgreSQL хранилище для сервиса stat.
+// Схема создается миграциями из пакета migrations
+type PgStorage struct {
+	db *sql.DB
+}
+
+func NewPgStorage(db *sql.DB) *PgStorage {
+	ps := PgStorage{db: db}
+	return &ps
+}
+
+const statColumns = `stat_uuid, person_uuid, notification_uuid, created_at, status, attempt, channel, reason`
+
+func (p *PgStorage) All(ctx context.Context) ([]dto.Stat, error) {
+	return p.query(ctx, `SELECT `+statColumns+` FROM stats ORDER BY created_at`)
+}
+
+func (p *PgStorage) Store(ctx context.Context, stat dto.Stat) error {
+	_, err := p.db.ExecContext(ctx, `INSERT INTO stats (`+statColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (stat_uuid) DO NOTHING`,
+		stat.StatUUID, stat.PersonUUID, stat.NotificationUUID, stat.CreatedAt, stat.Status, stat.Attempt, stat.Channel, stat.Reason)
+
+	return err
+}
+
+func (p *PgStorage) GetByNotificationId(ctx context.Context, notificationUUID uuid.UUID) ([]dto.Stat, error) {
+	return p.queryNotEmpty(ctx, `SELECT `+statColumns+` FROM stats
+		WHERE notification_uuid = $1 ORDER BY created_at`, notificationUUID)
+}
+
+func (p *PgStorage) GetByPersonId(ctx context.Context, personUUID uuid.UUID) ([]dto.Stat, error) {
+	return p.queryNotEmpty(ctx, `SELECT `+statColumns+` FROM stats
+		WHERE person_uuid = $1 ORDER BY created_at`, personUUID)
+}
+
+// queryNotEmpty выборка статистики, пустой результат - NotFound
+func (p *PgStorage) queryNotEmpty(ctx context.Context, query string, args ...any) ([]dto.Stat, error) {
+	stats, err := p.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stats) == 0 {
+		return nil, NotFound
+	}
+
+	return stats, nil
+}
+
+// query выборка списка записей статистики
+func (p *PgStorage) query(ctx context.Context, query string, args ...any) ([]dto.Stat, error) {
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []dto.Stat
+	for rows.Next() {
+		var stat dto.Stat
+
+		scanErr := rows.Scan(&stat.StatUUID, &stat.PersonUUID, &stat.NotificationUUID, &stat.CreatedAt, &stat.Status, &stat.Attempt, &stat.Channel, &stat.Reason)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
@@ -9,17 +9,32 @@
 //		NotificationUUID uuid.UUID  `json:"notification_uuid"` // NotificationUUID связь с уведомлением
 //		CreatedAt        string     `json:"created_at"`        // CreatedAt дата и время отправки
 //		Status           StatStatus `json:"status"`            // Status статус отправки
+//		Attempt          int        `json:"attempt"`           // Attempt номер попытки отправки
+//		Channel          string     `json:"channel,omitempty"` // Channel канал отправки, пустой для записей до выбора канала
 //	}
 //
 // Возможные статусы dto.Stat
 //
-//	Sent       StatStatus = iota + 1 // Уведомление отправлено
-//	Failed                           // Ошибка отправки
-//	BadChannel                       // Канал отправки не поддерживается
+//	Sent         StatStatus = iota + 1 // Уведомление отправлено
+//	Failed                             // Ошибка отправки
+//	BadChannel                         // Канал отправки не поддерживается
+//	DeadLettered                       // Попытки исчерпаны, сообщение помещено в очередь недоставленных
+//	Accepted                           // Уведомление принято сервисом
+//	Dispatched                         // Сообщение передано в очередь на отправку
+//	OptedOut                           // Сообщение не отправлено по предпочтениям получателя
+//	Rerouted                           // Сообщение не отправлено и передано в следующий канал цепочки Fallback
+//	Deferred                           // Отправка отложена до открытия окна доставки получателя
+//
+// Каждая попытка отправки сообщения фиксируется отдельной записью с номером попытки Attempt.
+//
+// Воркеры отправки публикуют статистику в очередь результатов шины, сервис подключается к ней через SetBus.
+// Запись подтверждается после сохранения, повторно выданная брокером запись с тем же StatUUID
+// не создает дубликата
 package stat
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -30,26 +45,52 @@ import (
 
 var _ interfaces.StatService = (*Service)(nil)
 
-// Service структура содержит канал для получения статистикт отправок
-// хранилище и логгер с интерфейсом interfaces.Logger
+const (
+	// storeRetryBaseDelay начальная пауза перед повторной выдачей записи, которую не удалось сохранить
+	storeRetryBaseDelay = 100 * time.Millisecond
+	// storeRetryMaxDelay предельная пауза перед повторной выдачей записи
+	storeRetryMaxDelay = 5 * time.Second
+)
+
+// Service структура содержит канал для получения статистикт отправок, шину с очередью
+// результатов воркеров, хранилище и логгер с интерфейсом interfaces.Logger
 type Service struct {
-	statChan <-chan dto.Stat
-	storage  Storager
-	logger   interfaces.Logger
+	statChan    <-chan dto.Stat
+	bus         interfaces.Bus
+	resultQueue string
+	storage     Storager
+	logger      interfaces.Logger
 }
 
+// New сервис с in-memory хранилищем
 func New(statChan chan dto.Stat, logger interfaces.Logger) *Service {
+	return NewWithStorage(statChan, NewMemoryStorage(), logger)
+}
+
+// NewWithStorage сервис с внешним хранилищем, например PgStorage
+func NewWithStorage(statChan chan dto.Stat, storage Storager, logger interfaces.Logger) *Service {
 	s := Service{
 		statChan: statChan,
-		storage:  NewMemoryStorage(),
+		storage:  storage,
 		logger:   logger,
 	}
 
 	return &s
 }
 
+// SetBus шина, из очереди resultQueue которой сервис получает статистику воркеров отправки
+func (s *Service) SetBus(messageBus interfaces.Bus, resultQueue string) *Service {
+	s.bus = messageBus
+	s.resultQueue = resultQueue
+	return s
+}
+
 // Start стартовые процедуры для сервиса
 func (s Service) Start(ctx context.Context) {
+	if s.bus != nil {
+		s.consumeResults(ctx)
+	}
+
 	// слушаем канал statChan в который другие сервисы передают данные об отправках
 	go func(ctx context.Context, statChan <-chan dto.Stat) {
 		s.logger.Info("Stat listener is UP")
@@ -67,8 +108,62 @@ func (s Service) Start(ctx context.Context) {
 	}(ctx, s.statChan)
 }
 
+// consumeResults сохраняет статистику из очереди результатов. Запись подтверждается после сохранения,
+// при ошибке хранилища возвращается в очередь после паузы, растущей до storeRetryMaxDelay
+func (s Service) consumeResults(ctx context.Context) {
+	if err := s.bus.Connect(); err != nil {
+		s.logger.Error("Stat bus.Connect err", err)
+	}
+
+	s.bus.MigrateDurableQueues(s.resultQueue)
+
+	msgs, err := s.bus.Subscribe(s.resultQueue)
+	if err != nil {
+		s.logger.Error("Stat can't consume result queue", err)
+		return
+	}
+
+	go func() {
+		delay := storeRetryBaseDelay
+		for d := range msgs {
+			var stat dto.Stat
+			if jsonErr := json.Unmarshal(d.Body, &stat); jsonErr != nil {
+				s.logger.Error("Stat JSON unmarshall err", jsonErr)
+				s.settle(d.Nack(false))
+				continue
+			}
+
+			if storeErr := s.Store(ctx, stat); storeErr != nil {
+				s.logger.Error("Stat storage.Store err", storeErr)
+				// пауза не дает повторной выдаче нагружать недоступное хранилище
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+				}
+				if delay *= 2; delay > storeRetryMaxDelay {
+					delay = storeRetryMaxDelay
+				}
+				s.settle(d.Nack(true))
+				continue
+			}
+			delay = storeRetryBaseDelay
+			s.settle(d.Ack())
+		}
+	}()
+}
+
+// settle журналирование ошибки подтверждения записи
+func (s Service) settle(err error) {
+	if err != nil {
+		s.logger.Error("Stat delivery settle err", err)
+	}
+}
+
 // Stop корректное завершение работы
 func (s Service) Stop() {
+	if s.bus != nil {
+		s.bus.Stop()
+	}
 	s.logger.Info("Stat service stopped")
 }
 
@@ -86,19 +181,47 @@ func (s Service) All(ctx context.Context) []dto.Stat {
 	return res
 }
 
-// Store сохранение шаблона в харнилище
+// Store сохранение шаблона в харнилище. StatUUID и время CreatedAt, назначенные источником записи,
+// сохраняются, повторная запись с тем же StatUUID игнорируется хранилищем
 func (s Service) Store(ctx context.Context, stat dto.Stat) error {
-	stat.StatUUID = uuid.New()
-	stat.CreatedAt = time.Now().Format(dateTimeFormat) // сохраняем время записи
+	if stat.StatUUID == uuid.Nil {
+		stat.StatUUID = uuid.New()
+	}
+	stat.CreatedAt = createdAt(stat.CreatedAt, time.Now())
 
 	return s.storage.Store(ctx, stat)
 }
 
+// createdAt приводит время записи к формату хранилища dateTimeFormat. Воркеры передают время
+// в RFC3339, запись без времени или с неразборчивым временем получает время сохранения now
+func createdAt(value string, now time.Time) string {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(now.Location()).Format(dateTimeFormat)
+	}
+	if _, err := time.Parse(dateTimeFormat, value); err == nil {
+		return value
+	}
+
+	return now.Format(dateTimeFormat)
+}
+
 // FindByPersonUUID возвращает статистику по получателю уведомления
 func (s Service) FindByPersonUUID(ctx context.Context, personUUID uuid.UUID) ([]dto.Stat, error) {
 	return s.storage.GetByPersonId(ctx, personUUID)
 }
 
+// FindNotificationStatus сводное состояние уведомления по получателям и каналам.
+// Записи одного получателя и канала сворачиваются в наиболее продвинутое состояние,
+// поэтому порядок записей с одинаковым временем не важен
+func (s Service) FindNotificationStatus(ctx context.Context, notificationUUID uuid.UUID) (dto.NotificationStatus, error) {
+	stats, err := s.storage.GetByNotificationId(ctx, notificationUUID)
+	if err != nil {
+		return dto.NotificationStatus{}, err
+	}
+
+	return aggregateStatus(notificationUUID, stats), nil
+}
+
 // FindByNotificationId возвращает статистику по конкретному уведомлению
 func (s Service) FindByNotificationId(ctx context.Context, notificationUUID uuid.UUID) ([]dto.Stat, error) {
 	return s.storage.GetByNotificationId(ctx, notificationUUID)
@@ -2,13 +2,18 @@ package stat
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/pkg/bus"
 	"github.com/atrian/go-notify-customer/pkg/logger"
 )
 
@@ -109,6 +114,106 @@ func (suite *StatTestSuite) Test_FindByNotificationId() {
 	assert.Equal(suite.T(), []dto.Stat{suite.stats[0]}, result)
 }
 
+// TestService_resultQueue статистика из очереди результатов сохраняется один раз на StatUUID,
+// все записи подтверждаются
+func TestService_resultQueue(t *testing.T) {
+	log := logger.NewZapLogger()
+	memoryBus := bus.NewMemoryBus(log)
+
+	service := New(make(chan dto.Stat), log).SetBus(memoryBus, "stats")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.Start(ctx)
+	defer service.Stop()
+
+	stat := dto.Stat{StatUUID: uuid.New(), NotificationUUID: uuid.New(), Status: dto.Sent, Channel: "sms", Attempt: 1}
+	body, err := json.Marshal(stat)
+	assert.NoError(t, err)
+
+	// брокер повторно выдал запись, подтверждение которой не дошло
+	assert.NoError(t, memoryBus.Publish("stats", body))
+	assert.NoError(t, memoryBus.Publish("stats", body))
+	assert.NoError(t, memoryBus.Publish("stats", []byte("{")))
+
+	assert.Eventually(t, func() bool {
+		return memoryBus.Len("stats") == 0 && memoryBus.Unacked() == 0
+	}, time.Second, 5*time.Millisecond)
+
+	result, err := service.FindByNotificationId(ctx, stat.NotificationUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, stat.StatUUID, result[0].StatUUID)
+	assert.Equal(t, dto.Sent, result[0].Status)
+}
+
+// TestService_resultQueue_storeFailed запись, которую не удалось сохранить, возвращается в очередь
+// и сохраняется с временем, назначенным воркером
+func TestService_resultQueue_storeFailed(t *testing.T) {
+	log := logger.NewZapLogger()
+	memoryBus := bus.NewMemoryBus(log)
+	storage := &flakyStorage{Storager: NewMemoryStorage(), failures: 2}
+
+	service := NewWithStorage(make(chan dto.Stat), storage, log).SetBus(memoryBus, "stats")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.Start(ctx)
+	defer service.Stop()
+
+	sentAt := time.Date(2023, 1, 1, 12, 0, 0, 0, time.Local)
+	stat := dto.Stat{StatUUID: uuid.New(), NotificationUUID: uuid.New(), Status: dto.Sent, Channel: "sms", Attempt: 1,
+		CreatedAt: sentAt.Format(time.RFC3339)}
+	body, err := json.Marshal(stat)
+	assert.NoError(t, err)
+	assert.NoError(t, memoryBus.Publish("stats", body))
+
+	assert.Eventually(t, func() bool {
+		return storage.attempts() == 3 && memoryBus.Len("stats") == 0 && memoryBus.Unacked() == 0
+	}, 2*time.Second, 5*time.Millisecond)
+
+	result, err := service.FindByNotificationId(ctx, stat.NotificationUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, sentAt.Format(dateTimeFormat), result[0].CreatedAt)
+}
+
+func TestCreatedAt(t *testing.T) {
+	now := time.Date(2023, 2, 1, 10, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "2023-01-01 12:00:00", createdAt("2023-01-01T15:00:00+03:00", now))
+	assert.Equal(t, "2023-01-01 12:00:00", createdAt("2023-01-01 12:00:00", now))
+	assert.Equal(t, "2023-02-01 10:00:00", createdAt("", now))
+	assert.Equal(t, "2023-02-01 10:00:00", createdAt("yesterday", now))
+}
+
+// flakyStorage хранилище, первые failures сохранений завершаются ошибкой
+type flakyStorage struct {
+	Storager
+	mu       sync.Mutex
+	failures int
+	calls    int
+}
+
+func (f *flakyStorage) Store(ctx context.Context, stat dto.Stat) error {
+	f.mu.Lock()
+	f.calls++
+	fail := f.calls <= f.failures
+	f.mu.Unlock()
+
+	if fail {
+		return errors.New("storage unavailable")
+	}
+
+	return f.Storager.Store(ctx, stat)
+}
+
+// attempts количество сохранений
+func (f *flakyStorage) attempts() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls
+}
+
 // Для запуска через Go test
 func TestStatServiceTestSuite(t *testing.T) {
 	suite.Run(t, new(StatTestSuite))
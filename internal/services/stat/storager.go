@@ -12,7 +12,7 @@ import (
 type Storager interface {
 	// All возвращает все записи
 	All(ctx context.Context) ([]dto.Stat, error)
-	// Store созраняет запись в хранилище
+	// Store созраняет запись в хранилище, запись с уже сохраненным StatUUID игнорируется
 	Store(ctx context.Context, stat dto.Stat) error
 	// GetByNotificationId возвращает записи по uuid уведомления
 	GetByNotificationId(ctx context.Context, notificationUUID uuid.UUID) ([]dto.Stat, error)
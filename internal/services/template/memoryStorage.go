@@ -13,10 +13,12 @@ import (
 var NotFound = errors.New("not found")
 
 // MemoryStorage in-memory хранилище для сервиса template
-// ! потокобезопасно, работает на sync.Map
+// ! потокобезопасно, работает на sync.Map, история версий под sync.Mutex
 // ! is safe for concurrent use
 type MemoryStorage struct {
-	data sync.Map
+	data     sync.Map
+	mu       sync.Mutex
+	versions map[uuid.UUID][]dto.TemplateVersion
 }
 
 func NewMemoryStorage() *MemoryStorage {
@@ -89,5 +91,73 @@ func (m *MemoryStorage) DeleteById(ctx context.Context, templateUUID uuid.UUID)
 
 	m.data.Delete(templateUUID.String())
 
+	m.mu.Lock()
+	delete(m.versions, templateUUID)
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *MemoryStorage) AddVersion(ctx context.Context, version dto.TemplateVersion) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.versions == nil {
+		m.versions = make(map[uuid.UUID][]dto.TemplateVersion)
+	}
+
+	versions := m.versions[version.TemplateUUID]
+	version.Version = len(versions) + 1
+	version.Template.Version = version.Version
+	m.versions[version.TemplateUUID] = append(versions, version)
+
+	return version.Version, nil
+}
+
+func (m *MemoryStorage) GetVersions(ctx context.Context, templateUUID uuid.UUID) ([]dto.TemplateVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	versions, ok := m.versions[templateUUID]
+	if !ok {
+		return nil, NotFound
+	}
+
+	result := make([]dto.TemplateVersion, len(versions))
+	copy(result, versions)
+
+	return result, nil
+}
+
+func (m *MemoryStorage) GetVersion(ctx context.Context, templateUUID uuid.UUID, version int) (dto.TemplateVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	versions := m.versions[templateUUID]
+	if version < 1 || version > len(versions) {
+		return dto.TemplateVersion{}, NotFound
+	}
+
+	return versions[version-1], nil
+}
+
+func (m *MemoryStorage) PublishVersion(ctx context.Context, template dto.Template) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	versions := m.versions[template.TemplateUUID]
+	if template.Version < 1 || template.Version > len(versions) {
+		return NotFound
+	}
+
+	for i := range versions {
+		if versions[i].State == dto.TemplatePublished {
+			versions[i].State = dto.TemplateArchived
+		}
+	}
+	versions[template.Version-1].State = dto.TemplatePublished
+
+	m.data.Store(template.TemplateUUID.String(), template)
+
 	return nil
 }
@@ -11,13 +11,15 @@ import (
 	"github.com/atrian/go-notify-customer/internal/dto"
 )
 
-type MemoryStorageTestSuite struct {
+// StorageTestSuite общий набор тестов для всех реализаций Storager
+type StorageTestSuite struct {
 	suite.Suite
-	storage   Storager
-	templates []dto.Template
+	newStorage func() Storager
+	storage    Storager
+	templates  []dto.Template
 }
 
-func (suite *MemoryStorageTestSuite) SetupSuite() {
+func (suite *StorageTestSuite) SetupSuite() {
 	persistentEventUUID := uuid.New()
 
 	suite.templates = []dto.Template{
@@ -53,14 +55,14 @@ func (suite *MemoryStorageTestSuite) SetupSuite() {
 	}
 }
 
-func (suite *MemoryStorageTestSuite) SetupTest() {
-	suite.storage = NewMemoryStorage()
+func (suite *StorageTestSuite) SetupTest() {
+	suite.storage = suite.newStorage()
 	for i := 0; i < len(suite.templates); i++ {
 		_ = suite.storage.Store(context.TODO(), suite.templates[i])
 	}
 }
 
-func (suite *MemoryStorageTestSuite) Test_GetById() {
+func (suite *StorageTestSuite) Test_GetById() {
 	// Запрос несуществующего объекта
 	_, err := suite.storage.GetById(context.TODO(), uuid.New())
 	assert.ErrorIs(suite.T(), err, NotFound)
@@ -71,7 +73,7 @@ func (suite *MemoryStorageTestSuite) Test_GetById() {
 	assert.Equal(suite.T(), result, suite.templates[0])
 }
 
-func (suite *MemoryStorageTestSuite) Test_GetByEventId() {
+func (suite *StorageTestSuite) Test_GetByEventId() {
 	// Запрос несуществующего объекта
 	_, err := suite.storage.GetByEventId(context.TODO(), uuid.New())
 	assert.ErrorIs(suite.T(), err, NotFound)
@@ -84,13 +86,13 @@ func (suite *MemoryStorageTestSuite) Test_GetByEventId() {
 	assert.Equal(suite.T(), suite.templates[0].EventUUID, result[1].EventUUID)
 }
 
-func (suite *MemoryStorageTestSuite) Test_All() {
+func (suite *StorageTestSuite) Test_All() {
 	result, err := suite.storage.All(context.TODO())
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), len(result), len(suite.templates))
 }
 
-func (suite *MemoryStorageTestSuite) Test_DeleteById() {
+func (suite *StorageTestSuite) Test_DeleteById() {
 	err := suite.storage.DeleteById(context.TODO(), suite.templates[0].TemplateUUID)
 	assert.NoError(suite.T(), err)
 
@@ -98,7 +100,7 @@ func (suite *MemoryStorageTestSuite) Test_DeleteById() {
 	assert.ErrorIs(suite.T(), err, NotFound)
 }
 
-func (suite *MemoryStorageTestSuite) Test_Update() {
+func (suite *StorageTestSuite) Test_Update() {
 	template := suite.templates[0]
 	template.Title = "Updated field"
 
@@ -112,5 +114,7 @@ func (suite *MemoryStorageTestSuite) Test_Update() {
 
 // Для запуска через Go test
 func TestMemoryStorageSuite(t *testing.T) {
-	suite.Run(t, new(MemoryStorageTestSuite))
+	suite.Run(t, &StorageTestSuite{
+		newStorage: func() Storager { return NewMemoryStorage() },
+	})
 }
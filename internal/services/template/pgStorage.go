@@ -0,0 +1,280 @@
+package template
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+var _ Storager = (*PgStorage)(nil)
+
+// PgStorage PostgreSQL хранилище для сервиса template.
+// Схема создается миграциями из пакета migrations
+type PgStorage struct {
+	db *sql.DB
+}
+
+func NewPgStorage(db *sql.DB) *PgStorage {
+	ps := PgStorage{db: db}
+	return &ps
+}
+
+const templateColumns = `template_uuid, event_uuid, title, description, body, channel_type, syntax, required_params, locale, version`
+
+func (p *PgStorage) All(ctx context.Context) ([]dto.Template, error) {
+	return p.query(ctx, `SELECT `+templateColumns+` FROM templates ORDER BY title`)
+}
+
+// Update обновляет существующий шаблон, для отсутствующего возвращает NotFound
+func (p *PgStorage) Update(ctx context.Context, template dto.Template) error {
+	res, err := p.db.ExecContext(ctx, `UPDATE templates
+		SET event_uuid = $2, title = $3, description = $4, body = $5, channel_type = $6, syntax = $7, required_params = $8, locale = $9, version = $10
+		WHERE template_uuid = $1`,
+		template.TemplateUUID, template.EventUUID, template.Title, template.Description, template.Body, template.ChannelType, template.Syntax, pq.Array(template.RequiredParams), template.Locale, versionNumber(template))
+	if err != nil {
+		return err
+	}
+
+	return affectedOrNotFound(res)
+}
+
+func (p *PgStorage) Store(ctx context.Context, template dto.Template) error {
+	return store(ctx, p.db, template)
+}
+
+// store сохранение шаблона вне или внутри транзакции
+func store(ctx context.Context, db interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}, template dto.Template) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO templates (`+templateColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (template_uuid) DO UPDATE SET
+			event_uuid = EXCLUDED.event_uuid,
+			title = EXCLUDED.title,
+			description = EXCLUDED.description,
+			body = EXCLUDED.body,
+			channel_type = EXCLUDED.channel_type,
+			syntax = EXCLUDED.syntax,
+			required_params = EXCLUDED.required_params,
+			locale = EXCLUDED.locale,
+			version = EXCLUDED.version`,
+		template.TemplateUUID, template.EventUUID, template.Title, template.Description, template.Body, template.ChannelType, template.Syntax, pq.Array(template.RequiredParams), template.Locale, versionNumber(template))
+
+	return err
+}
+
+func (p *PgStorage) GetById(ctx context.Context, templateUUID uuid.UUID) (dto.Template, error) {
+	row := p.db.QueryRowContext(ctx, `SELECT `+templateColumns+` FROM templates WHERE template_uuid = $1`, templateUUID)
+
+	template, err := scanTemplate(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dto.Template{}, NotFound
+	}
+
+	return template, err
+}
+
+func (p *PgStorage) GetByEventId(ctx context.Context, eventUUID uuid.UUID) ([]dto.Template, error) {
+	templates, err := p.query(ctx, `SELECT `+templateColumns+` FROM templates WHERE event_uuid = $1 ORDER BY title`, eventUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(templates) == 0 {
+		return nil, NotFound
+	}
+
+	return templates, nil
+}
+
+func (p *PgStorage) DeleteById(ctx context.Context, templateUUID uuid.UUID) error {
+	res, err := p.db.ExecContext(ctx, `DELETE FROM templates WHERE template_uuid = $1`, templateUUID)
+	if err != nil {
+		return err
+	}
+
+	if err = affectedOrNotFound(res); err != nil {
+		return err
+	}
+
+	_, err = p.db.ExecContext(ctx, `DELETE FROM template_versions WHERE template_uuid = $1`, templateUUID)
+
+	return err
+}
+
+const versionColumns = `template_uuid, version, state, created_at, payload`
+
+// AddVersion номер версии выдается в запросе, при гонке двух вставок вторая нарушит первичный ключ
+func (p *PgStorage) AddVersion(ctx context.Context, version dto.TemplateVersion) (int, error) {
+	payload, err := json.Marshal(version.Template)
+	if err != nil {
+		return 0, err
+	}
+
+	var number int
+	err = p.db.QueryRowContext(ctx, `INSERT INTO template_versions (`+versionColumns+`)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4 FROM template_versions WHERE template_uuid = $1
+		RETURNING version`,
+		version.TemplateUUID, version.State, version.CreatedAt, payload).Scan(&number)
+
+	return number, err
+}
+
+func (p *PgStorage) GetVersions(ctx context.Context, templateUUID uuid.UUID) ([]dto.TemplateVersion, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT `+versionColumns+` FROM template_versions
+		WHERE template_uuid = $1 ORDER BY version`, templateUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []dto.TemplateVersion
+	for rows.Next() {
+		version, scanErr := scanVersion(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		versions = append(versions, version)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(versions) == 0 {
+		return nil, NotFound
+	}
+
+	return versions, nil
+}
+
+func (p *PgStorage) GetVersion(ctx context.Context, templateUUID uuid.UUID, version int) (dto.TemplateVersion, error) {
+	row := p.db.QueryRowContext(ctx, `SELECT `+versionColumns+` FROM template_versions
+		WHERE template_uuid = $1 AND version = $2`, templateUUID, version)
+
+	result, err := scanVersion(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dto.TemplateVersion{}, NotFound
+	}
+
+	return result, err
+}
+
+// PublishVersion смена состояний версий и обновление текущего шаблона в одной транзакции
+func (p *PgStorage) PublishVersion(ctx context.Context, template dto.Template) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `UPDATE template_versions SET state = $2
+		WHERE template_uuid = $1 AND state = $3`,
+		template.TemplateUUID, dto.TemplateArchived, dto.TemplatePublished)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE template_versions SET state = $3
+		WHERE template_uuid = $1 AND version = $2`,
+		template.TemplateUUID, template.Version, dto.TemplatePublished)
+	if err != nil {
+		return err
+	}
+
+	if err = affectedOrNotFound(res); err != nil {
+		return err
+	}
+
+	if err = store(ctx, tx, template); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// query выборка списка шаблонов
+func (p *PgStorage) query(ctx context.Context, query string, args ...any) ([]dto.Template, error) {
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []dto.Template
+	for rows.Next() {
+		template, scanErr := scanTemplate(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+// scanTemplate чтение строки результата в dto.Template
+func scanTemplate(row interface{ Scan(dest ...any) error }) (dto.Template, error) {
+	var template dto.Template
+
+	err := row.Scan(
+		&template.TemplateUUID,
+		&template.EventUUID,
+		&template.Title,
+		&template.Description,
+		&template.Body,
+		&template.ChannelType,
+		&template.Syntax,
+		pq.Array(&template.RequiredParams),
+		&template.Locale,
+		&template.Version)
+
+	return template, err
+}
+
+// scanVersion чтение строки результата в dto.TemplateVersion
+func scanVersion(row interface{ Scan(dest ...any) error }) (dto.TemplateVersion, error) {
+	var (
+		version dto.TemplateVersion
+		payload []byte
+	)
+
+	err := row.Scan(&version.TemplateUUID, &version.Version, &version.State, &version.CreatedAt, &payload)
+	if err != nil {
+		return dto.TemplateVersion{}, err
+	}
+
+	err = json.Unmarshal(payload, &version.Template)
+	version.Template.Version = version.Version
+
+	return version, err
+}
+
+// versionNumber номер версии для колонки templates.version, шаблоны без истории считаются первой версией
+func versionNumber(template dto.Template) int {
+	if template.Version == 0 {
+		return 1
+	}
+
+	return template.Version
+}
+
+// affectedOrNotFound возвращает NotFound если запрос не затронул ни одной строки
+func affectedOrNotFound(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return NotFound
+	}
+
+	return nil
+}
@@ -20,6 +20,16 @@ type Storager interface {
 	GetById(ctx context.Context, templateUUID uuid.UUID) (dto.Template, error)
 	// GetByEventId возвращает записи по uuid бизнес события
 	GetByEventId(ctx context.Context, eventUUID uuid.UUID) ([]dto.Template, error)
-	// DeleteById удаляет запись по uuid сущности
+	// DeleteById удаляет запись по uuid сущности вместе с историей версий
 	DeleteById(ctx context.Context, templateUUID uuid.UUID) error
+
+	// AddVersion сохраняет новую версию шаблона, возвращает присвоенный номер версии
+	AddVersion(ctx context.Context, version dto.TemplateVersion) (int, error)
+	// GetVersions возвращает версии шаблона по возрастанию номера
+	GetVersions(ctx context.Context, templateUUID uuid.UUID) ([]dto.TemplateVersion, error)
+	// GetVersion возвращает версию шаблона по номеру
+	GetVersion(ctx context.Context, templateUUID uuid.UUID, version int) (dto.TemplateVersion, error)
+	// PublishVersion публикует версию template.Version: содержимое template становится текущим шаблоном,
+	// версия переходит в состояние published, ранее опубликованная - в archived
+	PublishVersion(ctx context.Context, template dto.Template) error
 }
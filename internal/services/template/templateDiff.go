@@ -0,0 +1,85 @@
+package template
+
+import (
+	"strings"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// diffVersions сравнение полей и построчное сравнение тела двух версий шаблона
+func diffVersions(from dto.TemplateVersion, to dto.TemplateVersion) dto.TemplateDiff {
+	diff := dto.TemplateDiff{
+		TemplateUUID: to.TemplateUUID,
+		From:         from.Version,
+		To:           to.Version,
+		Body:         diffLines(strings.Split(from.Template.Body, "\n"), strings.Split(to.Template.Body, "\n")),
+	}
+
+	fields := []struct {
+		name     string
+		from, to string
+	}{
+		{"event_uuid", from.Template.EventUUID.String(), to.Template.EventUUID.String()},
+		{"title", from.Template.Title, to.Template.Title},
+		{"description", from.Template.Description, to.Template.Description},
+		{"channel_type", from.Template.ChannelType, to.Template.ChannelType},
+		{"syntax", from.Template.Syntax, to.Template.Syntax},
+		{"locale", from.Template.Locale, to.Template.Locale},
+	}
+
+	for _, field := range fields {
+		if field.from != field.to {
+			diff.Fields = append(diff.Fields, dto.FieldChange{Field: field.name, From: field.from, To: field.to})
+		}
+	}
+
+	return diff
+}
+
+// diffLines построчное сравнение по наибольшей общей подпоследовательности
+func diffLines(from []string, to []string) []dto.DiffLine {
+	// lcs[i][j] длина общей подпоследовательности from[i:] и to[j:]
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := make([]dto.DiffLine, 0, len(from)+len(to))
+	i, j := 0, 0
+
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			lines = append(lines, dto.DiffLine{Op: " ", Text: from[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, dto.DiffLine{Op: "-", Text: from[i]})
+			i++
+		default:
+			lines = append(lines, dto.DiffLine{Op: "+", Text: to[j]})
+			j++
+		}
+	}
+
+	for ; i < len(from); i++ {
+		lines = append(lines, dto.DiffLine{Op: "-", Text: from[i]})
+	}
+	for ; j < len(to); j++ {
+		lines = append(lines, dto.DiffLine{Op: "+", Text: to[j]})
+	}
+
+	return lines
+}
@@ -13,8 +13,18 @@
 //		Description  string    `json:"description,omitempty"` // Description описание шаблона
 //		Body         string    `json:"body"`                  // Body тело шаблона
 //		ChannelType  string    `json:"channel_type"`          // ChannelType связь с каналом отправки
+//		Syntax       string    `json:"syntax,omitempty"`      // Syntax синтаксис тела шаблона, пустое значение - SyntaxBracket
+//		RequiredParams []string `json:"required_params,omitempty"` // RequiredParams обязательные ключи MessageParams
+//		Locale       string    `json:"locale,omitempty"`      // Locale локаль шаблона, пустое значение - локаль по умолчанию
 //	}
 //
+// Для события и канала можно хранить несколько шаблонов в разных локалях, например ru, ru-RU, en-GB.
+// Диспетчер выбирает шаблон по локали получателя по цепочке ru-RU -> ru -> локаль по умолчанию,
+// поэтому у локализованных шаблонов всегда должен быть шаблон с локалью по умолчанию.
+//
+// Синтаксис dto.SyntaxTemplate поддерживает условия, циклы и фильтры, подробнее см. пакет templating.
+// Ниже описан режим совместимости dto.SyntaxBracket, используемый по умолчанию.
+//
 // В поле Body (тело шаблона) можно указывать места для подстановки.
 // Пример "Ваша запись на [date] подтверждена. [company]"
 // Плейсхолдер должен начинаться с квадратной скобки [ и заканчиваться закрывающейся квадратной скобкой ]
@@ -39,11 +49,22 @@ package template
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/atrian/go-notify-customer/internal/dto"
 	"github.com/atrian/go-notify-customer/internal/interfaces"
+	"github.com/atrian/go-notify-customer/internal/templating"
+)
+
+var (
+	// InvalidTemplate тело шаблона не разбирается: некорректный плейсхолдер, синтаксическая ошибка или неизвестный синтаксис
+	InvalidTemplate = errors.New("invalid template")
+	// MissingDefaultLocale у локализованных шаблонов события и канала должен быть шаблон с локалью по умолчанию
+	MissingDefaultLocale = errors.New("default locale template required")
 )
 
 // Service содержит хранилище данных и логгер удовлетворяющий интерфейсу interfaces.Logger
@@ -52,9 +73,15 @@ type Service struct {
 	logger  interfaces.Logger
 }
 
+// New сервис с in-memory хранилищем
 func New(logger interfaces.Logger) *Service {
+	return NewWithStorage(NewMemoryStorage(), logger)
+}
+
+// NewWithStorage сервис с внешним хранилищем, например PgStorage
+func NewWithStorage(storage Storager, logger interfaces.Logger) *Service {
 	s := Service{
-		storage: NewMemoryStorage(),
+		storage: storage,
 		logger:  logger,
 	}
 	return &s
@@ -85,44 +112,191 @@ func (s Service) All(ctx context.Context) []dto.Template {
 	return templates
 }
 
-// Store сохранение шаблона в харнилище
+// Store сохранение шаблона в харнилище. Тело шаблона проверяется,
+// обязательные ключи параметров сохраняются в RequiredParams. Ошибка разбора - InvalidTemplate.
+// Локализованный шаблон сохраняется только при наличии шаблона события и канала
+// с локалью по умолчанию, иначе MissingDefaultLocale.
+// Новый шаблон сохраняется первой опубликованной версией
 func (s Service) Store(ctx context.Context, template dto.Template) (dto.Template, error) {
+	template, err := prepare(template)
+	if err != nil {
+		return dto.Template{}, err
+	}
+
+	if err = s.checkDefaultLocale(ctx, template); err != nil {
+		return dto.Template{}, err
+	}
+
 	template.TemplateUUID = uuid.New()
 
-	err := s.storage.Store(ctx, template)
+	version, err := s.addVersion(ctx, template)
 	if err != nil {
-		s.logger.Error("Template service storage.Store err", err)
+		s.logger.Error("Template service storage.AddVersion err", err)
 		return dto.Template{}, err
 	}
 
-	return template, nil
+	err = s.storage.PublishVersion(ctx, version.Template)
+	if err != nil {
+		s.logger.Error("Template service storage.PublishVersion err", err)
+		return dto.Template{}, err
+	}
+
+	return version.Template, nil
 }
 
 // StoreBatch массовое сохранение шаблонов.
 // В данной версии не используется хендлерами, задел на будущее.
 func (s Service) StoreBatch(ctx context.Context, templates []dto.Template) ([]dto.Template, error) {
 	for i := 0; i < len(templates); i++ {
-		templates[i].TemplateUUID = uuid.New()
-		err := s.storage.Store(ctx, templates[i])
+		template, err := s.Store(ctx, templates[i])
 		if err != nil {
-			s.logger.Error("Template service storage.Store err", err)
+			s.logger.Error("Template service Store err (StoreBatch)", err)
+			continue
 		}
+
+		templates[i] = template
 	}
 
 	return templates, nil
 }
 
-// Update обновление шаблона
-func (s Service) Update(ctx context.Context, template dto.Template) (dto.Template, error) {
-	err := s.storage.Store(ctx, template)
+// Update сохраняет изменения шаблона новой версией-черновиком, опубликованная версия не меняется.
+// Тело проверяется как в Store, проверки локалей выполняются при публикации
+func (s Service) Update(ctx context.Context, template dto.Template) (dto.TemplateVersion, error) {
+	if _, err := s.storage.GetById(ctx, template.TemplateUUID); err != nil {
+		return dto.TemplateVersion{}, err
+	}
+
+	template, err := prepare(template)
+	if err != nil {
+		return dto.TemplateVersion{}, err
+	}
+
+	version, err := s.addVersion(ctx, template)
+	if err != nil {
+		s.logger.Error("Template service storage.AddVersion err (Update)", err)
+		return dto.TemplateVersion{}, err
+	}
+
+	return version, nil
+}
+
+// Versions история версий шаблона
+func (s Service) Versions(ctx context.Context, templateUUID uuid.UUID) ([]dto.TemplateVersion, error) {
+	return s.storage.GetVersions(ctx, templateUUID)
+}
+
+// Diff различия версий from и to шаблона
+func (s Service) Diff(ctx context.Context, templateUUID uuid.UUID, from int, to int) (dto.TemplateDiff, error) {
+	fromVersion, err := s.storage.GetVersion(ctx, templateUUID, from)
+	if err != nil {
+		return dto.TemplateDiff{}, err
+	}
+
+	toVersion, err := s.storage.GetVersion(ctx, templateUUID, to)
 	if err != nil {
-		s.logger.Error("Template service storage.Store err (Update)", err)
+		return dto.TemplateDiff{}, err
+	}
+
+	return diffVersions(fromVersion, toVersion), nil
+}
+
+// Publish публикует версию шаблона, диспетчер начинает использовать ее содержимое.
+// Проверки локалей как в Store и Update, повторная публикация текущей версии ничего не меняет
+func (s Service) Publish(ctx context.Context, templateUUID uuid.UUID, version int) (dto.Template, error) {
+	stored, err := s.storage.GetVersion(ctx, templateUUID, version)
+	if err != nil {
+		return dto.Template{}, err
+	}
+
+	template := stored.Template
+	if stored.State == dto.TemplatePublished {
+		return template, nil
+	}
+
+	if err = s.checkDefaultLocale(ctx, template); err != nil {
+		return dto.Template{}, err
+	}
+
+	// шаблон с локалью по умолчанию нельзя перевести в другую локаль, пока есть локализованные
+	previous, err := s.storage.GetById(ctx, templateUUID)
+	if err == nil && previous.Locale == "" && !sameGroup(previous, template) {
+		if err = s.checkDefaultRemoval(ctx, previous); err != nil {
+			return dto.Template{}, err
+		}
+	}
+
+	if err = s.storage.PublishVersion(ctx, template); err != nil {
+		s.logger.Error("Template service storage.PublishVersion err", err)
 		return dto.Template{}, err
 	}
 
 	return template, nil
 }
 
+// Rollback возврат к содержимому ранее сохраненной версии.
+// История не переписывается: содержимое копируется в новую версию, которая сразу публикуется
+func (s Service) Rollback(ctx context.Context, templateUUID uuid.UUID, version int) (dto.Template, error) {
+	stored, err := s.storage.GetVersion(ctx, templateUUID, version)
+	if err != nil {
+		return dto.Template{}, err
+	}
+
+	if stored.State == dto.TemplatePublished {
+		return stored.Template, nil
+	}
+
+	restored, err := s.addVersion(ctx, stored.Template)
+	if err != nil {
+		s.logger.Error("Template service storage.AddVersion err (Rollback)", err)
+		return dto.Template{}, err
+	}
+
+	return s.Publish(ctx, templateUUID, restored.Version)
+}
+
+// Render предпросмотр шаблона с параметрами уведомления без отправки.
+// version 0 - опубликованная версия, иначе версия из истории, например черновик
+func (s Service) Render(ctx context.Context, templateUUID uuid.UUID, version int, params []dto.MessageParam) (dto.TemplateRender, error) {
+	var template dto.Template
+
+	if version == 0 {
+		published, err := s.storage.GetById(ctx, templateUUID)
+		if err != nil {
+			return dto.TemplateRender{}, err
+		}
+		template = published
+	} else {
+		stored, err := s.storage.GetVersion(ctx, templateUUID, version)
+		if err != nil {
+			return dto.TemplateRender{}, err
+		}
+		template = stored.Template
+	}
+
+	return templating.Preview(template, params), nil
+}
+
+// addVersion сохраняет содержимое шаблона новой версией-черновиком
+func (s Service) addVersion(ctx context.Context, template dto.Template) (dto.TemplateVersion, error) {
+	version := dto.TemplateVersion{
+		TemplateUUID: template.TemplateUUID,
+		State:        dto.TemplateDraft,
+		CreatedAt:    time.Now(),
+		Template:     template,
+	}
+
+	number, err := s.storage.AddVersion(ctx, version)
+	if err != nil {
+		return dto.TemplateVersion{}, err
+	}
+
+	version.Version = number
+	version.Template.Version = number
+
+	return version, nil
+}
+
 // FindById поиск шаблона по его uuid
 func (s Service) FindById(ctx context.Context, templateUUID uuid.UUID) (dto.Template, error) {
 	return s.storage.GetById(ctx, templateUUID)
@@ -134,6 +308,87 @@ func (s Service) FindByEventId(ctx context.Context, eventUUID uuid.UUID) ([]dto.
 }
 
 // DeleteById удаление шаблона из хранилища. Hard delete!
+// Шаблон с локалью по умолчанию удаляется после локализованных шаблонов события и канала
 func (s Service) DeleteById(ctx context.Context, templateUUID uuid.UUID) error {
+	template, err := s.storage.GetById(ctx, templateUUID)
+	if err != nil {
+		return err
+	}
+
+	if template.Locale == "" {
+		if err = s.checkDefaultRemoval(ctx, template); err != nil {
+			return err
+		}
+	}
+
 	return s.storage.DeleteById(ctx, templateUUID)
 }
+
+// checkDefaultLocale для локализованного шаблона проверяет наличие шаблона события и канала с локалью по умолчанию
+func (s Service) checkDefaultLocale(ctx context.Context, template dto.Template) error {
+	if template.Locale == "" {
+		return nil
+	}
+
+	for _, stored := range s.group(ctx, template) {
+		if stored.Locale == "" && stored.TemplateUUID != template.TemplateUUID {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: event %v, channel %v", MissingDefaultLocale, template.EventUUID, template.ChannelType)
+}
+
+// checkDefaultRemoval проверяет что без шаблона defaultTemplate не останется локализованных шаблонов без локали по умолчанию
+func (s Service) checkDefaultRemoval(ctx context.Context, defaultTemplate dto.Template) error {
+	localized := false
+
+	for _, stored := range s.group(ctx, defaultTemplate) {
+		if stored.TemplateUUID == defaultTemplate.TemplateUUID {
+			continue
+		}
+		if stored.Locale == "" {
+			return nil
+		}
+		localized = true
+	}
+
+	if localized {
+		return fmt.Errorf("%w: event %v, channel %v has localized templates", MissingDefaultLocale, defaultTemplate.EventUUID, defaultTemplate.ChannelType)
+	}
+
+	return nil
+}
+
+// group сохраненные шаблоны того же события и канала
+func (s Service) group(ctx context.Context, template dto.Template) []dto.Template {
+	// для события без шаблонов хранилище возвращает NotFound
+	templates, _ := s.storage.GetByEventId(ctx, template.EventUUID)
+
+	group := make([]dto.Template, 0, len(templates))
+	for _, stored := range templates {
+		if stored.ChannelType == template.ChannelType {
+			group = append(group, stored)
+		}
+	}
+
+	return group
+}
+
+// sameGroup шаблоны относятся к одному событию, каналу и локали
+func sameGroup(a, b dto.Template) bool {
+	return a.EventUUID == b.EventUUID && a.ChannelType == b.ChannelType && a.Locale == b.Locale
+}
+
+// prepare проверка тела шаблона и заполнение обязательных ключей параметров
+func prepare(template dto.Template) (dto.Template, error) {
+	required, err := templating.RequiredParams(template)
+	if err != nil {
+		return dto.Template{}, fmt.Errorf("%w: %v", InvalidTemplate, err)
+	}
+
+	template.RequiredParams = required
+	template.Locale = templating.NormalizeLocale(template.Locale)
+
+	return template, nil
+}
@@ -60,11 +60,70 @@ func (suite *TemplateTestSuite) TestService_Store() {
 	storeResult, err := suite.service.Store(context.TODO(), newEvent)
 	assert.NoError(suite.T(), err)
 
-	// При сохранении шаблону выдается UUID, сбрасываем его для сравнения в тесте
+	// Новый шаблон сразу публикуется первой версией
+	assert.Equal(suite.T(), 1, storeResult.Version)
+
+	// При сохранении шаблону выдается UUID и номер версии, сбрасываем их для сравнения в тесте
 	storeResult.TemplateUUID = uuid.UUID{}
+	storeResult.Version = 0
 	assert.Equal(suite.T(), newEvent, storeResult)
 }
 
+func (suite *TemplateTestSuite) TestService_Store_requiredParams() {
+	result, err := suite.service.Store(context.TODO(), dto.Template{
+		Title:       "Required params",
+		Body:        "Hello [name], your visit is at [date]",
+		ChannelType: "sms",
+	})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"date", "name"}, result.RequiredParams)
+
+	// некорректный плейсхолдер не сохраняется
+	_, err = suite.service.Store(context.TODO(), dto.Template{
+		Title:       "Malformed",
+		Body:        "Hello [first name]",
+		ChannelType: "sms",
+	})
+	assert.ErrorIs(suite.T(), err, InvalidTemplate)
+
+	_, err = suite.service.Update(context.TODO(), dto.Template{
+		TemplateUUID: result.TemplateUUID,
+		Title:        "Broken",
+		Body:         "{{ if .vip }}",
+		ChannelType:  "sms",
+		Syntax:       dto.SyntaxTemplate,
+	})
+	assert.ErrorIs(suite.T(), err, InvalidTemplate)
+}
+
+func (suite *TemplateTestSuite) TestService_Store_defaultLocale() {
+	eventUUID := uuid.New()
+	localized := dto.Template{EventUUID: eventUUID, Title: "ru", Body: "Привет", ChannelType: "sms", Locale: "ru_ru"}
+
+	// без шаблона с локалью по умолчанию локализованный не сохраняется
+	_, err := suite.service.Store(context.TODO(), localized)
+	assert.ErrorIs(suite.T(), err, MissingDefaultLocale)
+
+	defaultTemplate, err := suite.service.Store(context.TODO(), dto.Template{EventUUID: eventUUID, Title: "default", Body: "Hello", ChannelType: "sms"})
+	assert.NoError(suite.T(), err)
+
+	stored, err := suite.service.Store(context.TODO(), localized)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "ru-RU", stored.Locale)
+
+	// шаблон по умолчанию нельзя удалить или перевести в другую локаль, пока есть локализованные
+	assert.ErrorIs(suite.T(), suite.service.DeleteById(context.TODO(), defaultTemplate.TemplateUUID), MissingDefaultLocale)
+
+	defaultTemplate.Locale = "en"
+	draft, err := suite.service.Update(context.TODO(), defaultTemplate)
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.Publish(context.TODO(), draft.TemplateUUID, draft.Version)
+	assert.ErrorIs(suite.T(), err, MissingDefaultLocale)
+
+	assert.NoError(suite.T(), suite.service.DeleteById(context.TODO(), stored.TemplateUUID))
+	assert.NoError(suite.T(), suite.service.DeleteById(context.TODO(), defaultTemplate.TemplateUUID))
+}
+
 func (suite *TemplateTestSuite) TestService_StoreBatch() {
 	newTemplates := []dto.Template{
 		{
@@ -96,9 +155,64 @@ func (suite *TemplateTestSuite) TestService_Update() {
 	itemForUpdate.Title = "Updated Title"
 
 	result, err := suite.service.Update(context.TODO(), itemForUpdate)
+	assert.NoError(suite.T(), err)
+
+	// изменения сохраняются черновиком следующей версии
+	assert.Equal(suite.T(), dto.TemplateDraft, result.State)
+	assert.Equal(suite.T(), 2, result.Version)
+	assert.Equal(suite.T(), "Updated Title", result.Template.Title)
 
+	// опубликованный шаблон не меняется до публикации черновика
+	published, err := suite.service.FindById(context.TODO(), itemForUpdate.TemplateUUID)
 	assert.NoError(suite.T(), err)
-	assert.Equal(suite.T(), result, itemForUpdate)
+	assert.Equal(suite.T(), suite.templates[0], published)
+
+	// обновление несуществующего шаблона
+	_, err = suite.service.Update(context.TODO(), dto.Template{TemplateUUID: uuid.New(), Title: "Unknown", ChannelType: "sms"})
+	assert.ErrorIs(suite.T(), err, NotFound)
+}
+
+func (suite *TemplateTestSuite) TestService_Versions() {
+	templateUUID := suite.templates[0].TemplateUUID
+	itemForUpdate := suite.templates[0]
+	itemForUpdate.Body = "Body [param] 1\nSecond line"
+
+	draft, err := suite.service.Update(context.TODO(), itemForUpdate)
+	assert.NoError(suite.T(), err)
+
+	// публикация черновика архивирует предыдущую версию
+	published, err := suite.service.Publish(context.TODO(), templateUUID, draft.Version)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, published.Version)
+	assert.Equal(suite.T(), itemForUpdate.Body, published.Body)
+
+	versions, err := suite.service.Versions(context.TODO(), templateUUID)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), versions, 2)
+	assert.Equal(suite.T(), dto.TemplateArchived, versions[0].State)
+	assert.Equal(suite.T(), dto.TemplatePublished, versions[1].State)
+
+	diff, err := suite.service.Diff(context.TODO(), templateUUID, 1, 2)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), diff.Fields)
+	assert.Equal(suite.T(), []dto.DiffLine{
+		{Op: " ", Text: "Body [param] 1"},
+		{Op: "+", Text: "Second line"},
+	}, diff.Body)
+
+	// откат копирует содержимое первой версии в новую опубликованную версию
+	rolledBack, err := suite.service.Rollback(context.TODO(), templateUUID, 1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 3, rolledBack.Version)
+	assert.Equal(suite.T(), suite.templates[0].Body, rolledBack.Body)
+
+	current, err := suite.service.FindById(context.TODO(), templateUUID)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), rolledBack, current)
+
+	// несуществующая версия
+	_, err = suite.service.Publish(context.TODO(), templateUUID, 10)
+	assert.ErrorIs(suite.T(), err, NotFound)
 }
 
 func (suite *TemplateTestSuite) TestService_DeleteById() {
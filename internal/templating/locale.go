@@ -0,0 +1,62 @@
+package templating
+
+import (
+	"strings"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// NormalizeLocale приводит тег локали к виду ru-RU: разделитель дефис, язык в нижнем регистре, регион в верхнем.
+// Пустое значение - локаль по умолчанию
+func NormalizeLocale(locale string) string {
+	parts := strings.FieldsFunc(strings.TrimSpace(locale), func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+
+	for i := range parts {
+		if i == 0 {
+			parts[i] = strings.ToLower(parts[i])
+		} else if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		}
+	}
+
+	return strings.Join(parts, "-")
+}
+
+// LocaleChain цепочка поиска шаблона от точной локали к локали по умолчанию: ru-RU -> ru -> ""
+func LocaleChain(locale string) []string {
+	locale = NormalizeLocale(locale)
+	chain := make([]string, 0, 3)
+
+	for locale != "" {
+		chain = append(chain, locale)
+
+		cut := strings.LastIndex(locale, "-")
+		if cut < 0 {
+			break
+		}
+		locale = locale[:cut]
+	}
+
+	return append(chain, "")
+}
+
+// SelectTemplate выбирает из шаблонов одного канала наиболее подходящий локали по цепочке LocaleChain
+func SelectTemplate(templates []dto.Template, locale string) (dto.Template, bool) {
+	byLocale := make(map[string]dto.Template, len(templates))
+	for _, template := range templates {
+		normalized := NormalizeLocale(template.Locale)
+		if _, exist := byLocale[normalized]; !exist {
+			byLocale[normalized] = template
+		}
+	}
+
+	for _, candidate := range LocaleChain(locale) {
+		if template, ok := byLocale[candidate]; ok {
+			return template, true
+		}
+	}
+
+	return dto.Template{}, false
+}
@@ -0,0 +1,45 @@
+package templating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+func TestLocaleChain(t *testing.T) {
+	assert.Equal(t, []string{"ru-RU", "ru", ""}, LocaleChain("ru_ru"))
+	assert.Equal(t, []string{"zh-Hant-TW", "zh-Hant", "zh", ""}, LocaleChain("zh-Hant-TW"))
+	assert.Equal(t, []string{""}, LocaleChain(""))
+}
+
+func TestSelectTemplate(t *testing.T) {
+	templates := []dto.Template{
+		{Body: "default"},
+		{Body: "ru", Locale: "ru"},
+		{Body: "en-GB", Locale: "en-GB"},
+	}
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{locale: "ru-RU", want: "ru"},
+		{locale: "en-gb", want: "en-GB"},
+		{locale: "en-US", want: "default"},
+		{locale: "", want: "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			template, ok := SelectTemplate(templates, tt.locale)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, template.Body)
+		})
+	}
+
+	// без шаблона по умолчанию неподходящая локаль не выбирается
+	_, ok := SelectTemplate(templates[1:], "de")
+	assert.False(t, ok)
+}
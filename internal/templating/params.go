@@ -0,0 +1,214 @@
+package templating
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+var (
+	// ErrMalformedPlaceholder плейсхолдер [..] не соответствует формату [param1]
+	ErrMalformedPlaceholder = errors.New("malformed placeholder")
+
+	// regexp для поиска похожих на плейсхолдер конструкций: ключ с лишним пробелом, разделителем
+	// или $ - [param 1], [param_1], [first-name], [$name], [ name ]. Обычный текст в скобках
+	// вида [1], [см. стр. 2] или [ИНН] плейсхолдером не считается
+	placeholderLikeRe = regexp.MustCompile(`\[( *\$?[a-zA-Z]+(?:[ _-]?[a-zA-Z\d]+)? *)]`)
+	// regexp корректного ключа плейсхолдера
+	placeholderKeyRe = regexp.MustCompile(`^[a-zA-Z]+\d*$`)
+)
+
+// RequiredParams разбирает тело шаблона и возвращает отсортированный список обязательных ключей параметров.
+// Для шаблона без подстановок возвращает nil.
+//
+// В режиме совместимости обязательны все плейсхолдеры, некорректный плейсхолдер - ErrMalformedPlaceholder.
+// В синтаксисе text/template обязательны ключи корневого контекста: .key и $.key,
+// ключи, прочитанные через index, необязательны
+func RequiredParams(tmpl dto.Template) ([]string, error) {
+	required, _, err := usedKeys(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	return sortedKeys(required), nil
+}
+
+// Placeholders отсортированный список всех ключей параметров шаблона, включая необязательные
+func Placeholders(tmpl dto.Template) ([]string, error) {
+	required, optional, err := usedKeys(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	for key := range optional {
+		required[key] = struct{}{}
+	}
+
+	return sortedKeys(required), nil
+}
+
+// usedKeys обязательные и необязательные ключи параметров в теле шаблона
+func usedKeys(tmpl dto.Template) (map[string]struct{}, map[string]struct{}, error) {
+	required := make(map[string]struct{})
+	optional := make(map[string]struct{})
+
+	switch tmpl.Syntax {
+	case "", dto.SyntaxBracket:
+		for _, match := range placeholderLikeRe.FindAllStringSubmatch(tmpl.Body, -1) {
+			if !placeholderKeyRe.MatchString(match[1]) {
+				return nil, nil, fmt.Errorf("%w: %v", ErrMalformedPlaceholder, match[0])
+			}
+			required[match[1]] = struct{}{}
+		}
+
+	case dto.SyntaxTemplate:
+		compiled, err := compile(tmpl)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, t := range compiled.Templates() {
+			if t.Tree != nil {
+				collectKeys(t.Tree.Root, true, required, optional)
+			}
+		}
+
+	default:
+		return nil, nil, fmt.Errorf("%w: %v", ErrUnknownSyntax, tmpl.Syntax)
+	}
+
+	return required, optional, nil
+}
+
+// sortedKeys отсортированные ключи, для пустого набора nil
+func sortedKeys(keys map[string]struct{}) []string {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+// MissingParams ключи required, отсутствующие или пустые в params
+func MissingParams(required []string, params []dto.MessageParam) []string {
+	present := make(map[string]struct{}, len(params))
+	for _, param := range params {
+		if strings.TrimSpace(param.Value) != "" {
+			present[param.Key] = struct{}{}
+		}
+	}
+
+	var missing []string
+	for _, key := range required {
+		if _, ok := present[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	return missing
+}
+
+// collectKeys обход дерева шаблона. rootDot - точка указывает на параметры уведомления,
+// внутри range и with точка переопределяется и поля относятся к другому значению.
+// Ключи, прочитанные через index, попадают в optional
+func collectKeys(node parse.Node, rootDot bool, keys map[string]struct{}, optional map[string]struct{}) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectKeys(child, rootDot, keys, optional)
+		}
+
+	case *parse.ActionNode:
+		collectKeys(n.Pipe, rootDot, keys, optional)
+
+	case *parse.TemplateNode:
+		collectKeys(n.Pipe, rootDot, keys, optional)
+
+	case *parse.IfNode:
+		collectKeys(n.Pipe, rootDot, keys, optional)
+		collectKeys(n.List, rootDot, keys, optional)
+		collectKeys(n.ElseList, rootDot, keys, optional)
+
+	case *parse.RangeNode:
+		collectKeys(n.Pipe, rootDot, keys, optional)
+		collectKeys(n.List, false, keys, optional)
+		collectKeys(n.ElseList, rootDot, keys, optional)
+
+	case *parse.WithNode:
+		collectKeys(n.Pipe, rootDot, keys, optional)
+		collectKeys(n.List, false, keys, optional)
+		collectKeys(n.ElseList, rootDot, keys, optional)
+
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			if key, ok := indexKey(cmd, rootDot); ok {
+				optional[key] = struct{}{}
+			}
+			for _, arg := range cmd.Args {
+				collectKeys(arg, rootDot, keys, optional)
+			}
+		}
+
+	case *parse.ChainNode:
+		collectKeys(n.Node, rootDot, keys, optional)
+
+	case *parse.FieldNode:
+		if rootDot {
+			keys[n.Ident[0]] = struct{}{}
+		}
+
+	case *parse.VariableNode:
+		// $ всегда указывает на параметры уведомления
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			keys[n.Ident[1]] = struct{}{}
+		}
+	}
+}
+
+// indexKey ключ из вызова index . "key" или index $ "key"
+func indexKey(cmd *parse.CommandNode, rootDot bool) (string, bool) {
+	if len(cmd.Args) < 3 {
+		return "", false
+	}
+
+	if ident, ok := cmd.Args[0].(*parse.IdentifierNode); !ok || ident.Ident != "index" {
+		return "", false
+	}
+
+	switch target := cmd.Args[1].(type) {
+	case *parse.DotNode:
+		if !rootDot {
+			return "", false
+		}
+	case *parse.VariableNode:
+		if len(target.Ident) != 1 || target.Ident[0] != "$" {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+
+	key, ok := cmd.Args[2].(*parse.StringNode)
+	if !ok {
+		return "", false
+	}
+
+	return key.Text, true
+}
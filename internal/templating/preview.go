@@ -0,0 +1,74 @@
+package templating
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+// Ограничения длины сообщения по каналам
+const (
+	telegramMaxLength = 4096  // telegramMaxLength максимум символов в сообщении Telegram Bot API
+	slackMaxLength    = 40000 // slackMaxLength текст длиннее обрезается Slack chat.postMessage
+)
+
+// Preview рендер шаблона без отправки: текст, используемые и отсутствующие параметры,
+// предупреждения канала отправки. Ошибка рендера возвращается в поле RenderError
+func Preview(tmpl dto.Template, params []dto.MessageParam) dto.TemplateRender {
+	result := dto.TemplateRender{
+		TemplateUUID:  tmpl.TemplateUUID,
+		Version:       tmpl.Version,
+		ChannelType:   tmpl.ChannelType,
+		Placeholders:  []string{},
+		MissingParams: []string{},
+		Warnings:      []string{},
+	}
+
+	placeholders, err := Placeholders(tmpl)
+	if err != nil {
+		result.RenderError = err.Error()
+		return result
+	}
+	if placeholders != nil {
+		result.Placeholders = placeholders
+	}
+
+	required, _ := RequiredParams(tmpl)
+	if missing := MissingParams(required, params); missing != nil {
+		result.MissingParams = missing
+	}
+
+	result.Text, err = Render(tmpl, params)
+	if err != nil {
+		result.RenderError = err.Error()
+		return result
+	}
+
+	if result.Text == "" {
+		result.Warnings = append(result.Warnings, "rendered text is empty")
+	}
+
+	switch tmpl.ChannelType {
+	case "sms":
+		segments, encoding := SMSSegments(result.Text)
+		result.SMSSegments = segments
+		result.SMSEncoding = encoding
+
+		if segments > 1 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("sms is split into %d segments (%v)", segments, encoding))
+		}
+
+	case "telegram":
+		if length := utf8.RuneCountInString(result.Text); length > telegramMaxLength {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("text length %d exceeds telegram limit %d", length, telegramMaxLength))
+		}
+
+	case "slack":
+		if length := utf8.RuneCountInString(result.Text); length > slackMaxLength {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("text length %d exceeds slack limit %d and will be truncated", length, slackMaxLength))
+		}
+	}
+
+	return result
+}
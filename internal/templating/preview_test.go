@@ -0,0 +1,69 @@
+package templating
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+func TestSMSSegments(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		segments int
+		encoding string
+	}{
+		{name: "empty", text: "", segments: 0, encoding: SMSEncodingGSM7},
+		{name: "gsm single", text: strings.Repeat("a", 160), segments: 1, encoding: SMSEncodingGSM7},
+		{name: "gsm multipart", text: strings.Repeat("a", 161), segments: 2, encoding: SMSEncodingGSM7},
+		{name: "gsm extension counts twice", text: strings.Repeat("€", 80) + "a", segments: 2, encoding: SMSEncodingGSM7},
+		{name: "ucs2 single", text: strings.Repeat("я", 70), segments: 1, encoding: SMSEncodingUCS2},
+		{name: "ucs2 multipart", text: strings.Repeat("я", 71), segments: 2, encoding: SMSEncodingUCS2},
+		{name: "ucs2 surrogate pairs", text: strings.Repeat("😀", 35) + "a", segments: 2, encoding: SMSEncodingUCS2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments, encoding := SMSSegments(tt.text)
+			assert.Equal(t, tt.segments, segments)
+			assert.Equal(t, tt.encoding, encoding)
+		})
+	}
+}
+
+func TestPreview(t *testing.T) {
+	tmpl := dto.Template{
+		Body:        `{{ .name }}, {{ index . "greeting" | default "привет" }} {{ .code }}`,
+		ChannelType: "sms",
+		Syntax:      dto.SyntaxTemplate,
+	}
+
+	// text/template: отсутствующий обязательный параметр - ошибка рендера
+	result := Preview(tmpl, []dto.MessageParam{{Key: "name", Value: "Иван"}})
+	assert.Equal(t, []string{"code", "greeting", "name"}, result.Placeholders)
+	assert.Equal(t, []string{"code"}, result.MissingParams)
+	assert.NotEmpty(t, result.RenderError)
+
+	result = Preview(tmpl, []dto.MessageParam{{Key: "name", Value: "Иван"}, {Key: "code", Value: "1234"}})
+	assert.Empty(t, result.RenderError)
+	assert.Equal(t, "Иван, привет 1234", result.Text)
+	assert.Equal(t, []string{}, result.MissingParams)
+	assert.Equal(t, 1, result.SMSSegments)
+	assert.Equal(t, SMSEncodingUCS2, result.SMSEncoding)
+	assert.Equal(t, []string{}, result.Warnings)
+
+	// режим совместимости: отсутствующий параметр заменяется пустой строкой
+	long := dto.Template{Body: strings.Repeat("Текст ", 20) + "[code]", ChannelType: "sms"}
+	result = Preview(long, nil)
+	assert.Equal(t, []string{"code"}, result.MissingParams)
+	assert.Equal(t, 2, result.SMSSegments)
+	assert.Equal(t, []string{"sms is split into 2 segments (UCS-2)"}, result.Warnings)
+
+	// для Telegram проверяется лимит длины Bot API, части SMS не считаются
+	result = Preview(dto.Template{Body: strings.Repeat("a", 5000), ChannelType: "telegram"}, nil)
+	assert.Zero(t, result.SMSSegments)
+	assert.Equal(t, []string{"text length 5000 exceeds telegram limit 4096"}, result.Warnings)
+}
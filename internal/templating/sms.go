@@ -0,0 +1,58 @@
+package templating
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// Кодировки SMS
+const (
+	SMSEncodingGSM7 = "GSM-7" // SMSEncodingGSM7 базовый алфавит GSM 03.38
+	SMSEncodingUCS2 = "UCS-2" // SMSEncodingUCS2 любой символ вне алфавита GSM 03.38 переводит сообщение в UCS-2
+)
+
+// Длина одной части SMS. В составном сообщении часть заголовка UDH занимает место текста
+const (
+	gsm7Single = 160
+	gsm7Part   = 153
+	ucs2Single = 70
+	ucs2Part   = 67
+)
+
+const (
+	// gsm7Basic основная таблица GSM 03.38, символ занимает один септет
+	gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+		"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	// gsm7Extension таблица расширения, символ занимает два септета
+	gsm7Extension = "^{}\\[~]|€\f"
+)
+
+// SMSSegments число частей SMS и кодировка текста
+func SMSSegments(text string) (int, string) {
+	if text == "" {
+		return 0, SMSEncodingGSM7
+	}
+
+	septets := 0
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune(gsm7Basic, r):
+			septets++
+		case strings.ContainsRune(gsm7Extension, r):
+			septets += 2
+		default:
+			return segments(len(utf16.Encode([]rune(text))), ucs2Single, ucs2Part), SMSEncodingUCS2
+		}
+	}
+
+	return segments(septets, gsm7Single, gsm7Part), SMSEncodingGSM7
+}
+
+// segments число частей для длины length при лимитах одиночного и составного сообщения
+func segments(length int, single int, part int) int {
+	if length <= single {
+		return 1
+	}
+
+	return (length + part - 1) / part
+}
@@ -0,0 +1,262 @@
+// Package templating движок шаблонов сообщений.
+//
+// Синтаксис шаблона задается полем dto.Template.Syntax:
+//
+//   - dto.SyntaxBracket (или пустое значение) - режим совместимости с плейсхолдерами [param1].
+//     Неизвестные ключи заменяются пустой строкой, повторные пробелы и табы схлопываются, переводы строк сохраняются
+//   - dto.SyntaxTemplate - синтаксис text/template с условиями, циклами и фильтрами.
+//     Параметры уведомления доступны как .key, обращение к отсутствующему ключу - ошибка рендера.
+//     Необязательные параметры читаются через index: {{ index . "name" | default "клиент" }}
+//
+// Фильтры шаблонов:
+//
+//	default "значение"  подстановка значения для пустого параметра
+//	upper, lower, trim  регистр и пробелы
+//	date "02.01.2006"   форматирование даты из RFC 3339 или 2006-01-02
+//	currency "RUB"      форматирование суммы: 1 234.50 ₽
+//	split ","           разбиение строки на список для {{ range }}
+//
+// Подставляемые значения экранируются по правилам канала отправки, см. channelEscapers.
+// Письма отправляются как text/html только для dto.SyntaxTemplate, шаблоны [param1] остаются текстом, см. ContentType
+package templating
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+var (
+	// ErrUnknownSyntax синтаксис шаблона не поддерживается
+	ErrUnknownSyntax = errors.New("unknown template syntax")
+
+	// regexp для поиска параметров [param1] [param2] в теле сообщения
+	bracketRe = regexp.MustCompile(`(?m)\[([a-zA-Z]+\d*)]`)
+	// regexp для замены повторных пробелов на один без затрагивания переводов строк
+	spaceRe = regexp.MustCompile(`[ \t]+`)
+
+	// channelEscapers экранирование подставляемых значений по каналам отправки.
+	// Для каналов без записи значения подставляются как есть
+	channelEscapers = map[string]func(string) string{
+		// письмо с синтаксисом text/template отправляется как text/html, см. ContentType
+		mailChannel: html.EscapeString,
+		// в тексте Slack управляющими являются только &, < и >
+		"slack": strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace,
+		// текст webhook экранирует json.Marshal при сборке тела запроса, повторное экранирование исказит его
+		"webhook": identity,
+		// sms и Telegram без parse_mode передают текст без разметки
+		"sms":      identity,
+		"telegram": identity,
+	}
+
+	currencySymbols = map[string]string{
+		"RUB": "₽",
+		"USD": "$",
+		"EUR": "€",
+	}
+)
+
+const (
+	// escapeFunc имя функции экранирования, добавляемой в конец каждого вывода шаблона
+	escapeFunc = "_escape"
+	// mailChannel канал писем, тип содержимого которых зависит от синтаксиса шаблона
+	mailChannel = "mail"
+)
+
+// Render выполняет подстановку параметров уведомления в тело шаблона
+func Render(tmpl dto.Template, params []dto.MessageParam) (string, error) {
+	data := make(map[string]string, len(params))
+	for _, param := range params {
+		data[param.Key] = param.Value
+	}
+
+	switch tmpl.Syntax {
+	case "", dto.SyntaxBracket:
+		return renderBracket(tmpl.Body, data, escaper(tmpl)), nil
+
+	case dto.SyntaxTemplate:
+		compiled, err := compile(tmpl)
+		if err != nil {
+			return "", err
+		}
+
+		var result strings.Builder
+		if err = compiled.Execute(&result, data); err != nil {
+			return "", err
+		}
+
+		return result.String(), nil
+	}
+
+	return "", fmt.Errorf("%w: %v", ErrUnknownSyntax, tmpl.Syntax)
+}
+
+// renderBracket режим совместимости: заменяет все key1 в формате [key1] на значение data[key1]
+func renderBracket(body string, data map[string]string, escape func(string) string) string {
+	result := bracketRe.ReplaceAllStringFunc(body, func(placeholder string) string {
+		return escape(data[placeholder[1:len(placeholder)-1]])
+	})
+
+	return spaceRe.ReplaceAllString(result, " ")
+}
+
+// compile разбор шаблона с синтаксисом text/template
+func compile(tmpl dto.Template) (*template.Template, error) {
+	compiled, err := template.New(tmpl.Title).
+		Option("missingkey=error").
+		Funcs(funcs(escaper(tmpl))).
+		Parse(tmpl.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// экранирование добавляется в конец каждого конвейера вывода,
+	// то есть применяется после пользовательских фильтров
+	for _, t := range compiled.Templates() {
+		if t.Tree != nil {
+			appendEscape(t.Tree, t.Tree.Root)
+		}
+	}
+
+	return compiled, nil
+}
+
+// appendEscape рекурсивно дописывает escapeFunc в конвейеры вывода {{ ... }}
+func appendEscape(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			appendEscape(tree, child)
+		}
+
+	case *parse.ActionNode:
+		// {{ $x := ... }} ничего не выводит
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier(escapeFunc).SetTree(tree).SetPos(n.Pos)},
+		})
+
+	case *parse.IfNode:
+		appendEscape(tree, n.List)
+		appendEscape(tree, n.ElseList)
+
+	case *parse.RangeNode:
+		appendEscape(tree, n.List)
+		appendEscape(tree, n.ElseList)
+
+	case *parse.WithNode:
+		appendEscape(tree, n.List)
+		appendEscape(tree, n.ElseList)
+	}
+}
+
+// ContentType тип содержимого отрисованного шаблона. Разметкой HTML считаются только письма
+// с синтаксисом text/template, остальные шаблоны отправляются как текст
+func ContentType(tmpl dto.Template) string {
+	if tmpl.ChannelType == mailChannel && tmpl.Syntax == dto.SyntaxTemplate {
+		return dto.ContentTypeHTML
+	}
+
+	return dto.ContentTypeText
+}
+
+// escaper правило экранирования для канала шаблона
+func escaper(tmpl dto.Template) func(string) string {
+	// письмо без разметки экранировать не нужно
+	if tmpl.ChannelType == mailChannel && ContentType(tmpl) == dto.ContentTypeText {
+		return identity
+	}
+
+	if escape, ok := channelEscapers[tmpl.ChannelType]; ok {
+		return escape
+	}
+
+	return identity
+}
+
+// identity подстановка значения без экранирования
+func identity(value string) string {
+	return value
+}
+
+// funcs фильтры доступные в шаблонах
+func funcs(escape func(string) string) template.FuncMap {
+	return template.FuncMap{
+		"default":  defaultValue,
+		"upper":    strings.ToUpper,
+		"lower":    strings.ToLower,
+		"trim":     strings.TrimSpace,
+		"date":     formatDate,
+		"currency": formatCurrency,
+		"split":    strings.Split,
+		escapeFunc: func(value any) string {
+			return escape(fmt.Sprint(value))
+		},
+	}
+}
+
+func defaultValue(fallback string, value string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+// formatDate форматирует дату из RFC 3339 или 2006-01-02 по шаблону layout в формате пакета time
+func formatDate(layout string, value string) (string, error) {
+	for _, inputLayout := range []string{time.RFC3339, "2006-01-02"} {
+		if parsed, err := time.Parse(inputLayout, value); err == nil {
+			return parsed.Format(layout), nil
+		}
+	}
+
+	return "", fmt.Errorf("date: bad value %q", value)
+}
+
+// formatCurrency форматирует сумму с двумя знаками после точки, разделителем разрядов и символом валюты
+func formatCurrency(code string, value string) (string, error) {
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(value), ",", "."), 64)
+	if err != nil {
+		return "", fmt.Errorf("currency: bad value %q", value)
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+
+	cents := int64(math.Round(math.Abs(amount) * 100))
+	integer := strconv.FormatInt(cents/100, 10)
+
+	var grouped strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteByte(' ')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	symbol, ok := currencySymbols[strings.ToUpper(code)]
+	if !ok {
+		symbol = strings.ToUpper(code)
+	}
+
+	return fmt.Sprintf("%v%v.%02d %v", sign, grouped.String(), cents%100, symbol), nil
+}
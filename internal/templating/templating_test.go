@@ -0,0 +1,184 @@
+package templating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+)
+
+func TestRender(t *testing.T) {
+	params := []dto.MessageParam{
+		{Key: "name", Value: "Иван"},
+		{Key: "date", Value: "2023-03-14T10:30:00Z"},
+		{Key: "amount", Value: "1234567.5"},
+		{Key: "items", Value: "стрижка,укладка"},
+		{Key: "vip", Value: ""},
+		{Key: "company", Value: "R&D <Lab>"},
+	}
+
+	tests := []struct {
+		name     string
+		template dto.Template
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "bracket compatibility mode keeps line breaks",
+			template: dto.Template{Body: "Здравствуйте, [name]!\n\nВаш  заказ [unknown] готов"},
+			want:     "Здравствуйте, Иван!\n\nВаш заказ готов",
+		}, {
+			name:     "bracket mode escapes values for slack",
+			template: dto.Template{Body: "[company]", ChannelType: "slack"},
+			want:     "R&amp;D &lt;Lab&gt;",
+		}, {
+			name:     "filters",
+			template: dto.Template{Syntax: dto.SyntaxTemplate, Body: `{{ .name | upper }}, {{ .date | date "02.01.2006 15:04" }}, {{ .amount | currency "RUB" }}`},
+			want:     "ИВАН, 14.03.2023 10:30, 1 234 567.50 ₽",
+		}, {
+			name:     "conditionals, defaults and loops",
+			template: dto.Template{Syntax: dto.SyntaxTemplate, Body: "{{ if .vip }}VIP{{ else }}{{ index . \"title\" | default \"Клиент\" }}{{ end }}:\n{{ range split .items \",\" }}- {{ . }}\n{{ end }}"},
+			want:     "Клиент:\n- стрижка\n- укладка\n",
+		}, {
+			name:     "template escapes output for slack after filters",
+			template: dto.Template{Syntax: dto.SyntaxTemplate, ChannelType: "slack", Body: "<b>{{ .company | upper }}</b>"},
+			want:     "<b>R&amp;D &lt;LAB&gt;</b>",
+		}, {
+			name:     "template does not escape for other channels",
+			template: dto.Template{Syntax: dto.SyntaxTemplate, ChannelType: "sms", Body: "{{ .company }}"},
+			want:     "R&D <Lab>",
+		}, {
+			name:     "template escapes html for mail",
+			template: dto.Template{Syntax: dto.SyntaxTemplate, ChannelType: "mail", Body: "<p>{{ .company }}</p>"},
+			want:     "<p>R&amp;D &lt;Lab&gt;</p>",
+		}, {
+			name:     "bracket mode keeps multi-line mail as plain text",
+			template: dto.Template{Syntax: dto.SyntaxBracket, ChannelType: "mail", Body: "Здравствуйте, [name]!\n\nКомпания [company] < партнеры & друзья\n-- \nподпись"},
+			want:     "Здравствуйте, Иван!\n\nКомпания R&D <Lab> < партнеры & друзья\n-- \nподпись",
+		}, {
+			name:     "bracket mode does not escape webhook text encoded as json",
+			template: dto.Template{Body: "[company]", ChannelType: "webhook"},
+			want:     "R&D <Lab>",
+		}, {
+			name:     "missing key is an error",
+			template: dto.Template{Syntax: dto.SyntaxTemplate, Body: "{{ .unknown }}"},
+			wantErr:  true,
+		}, {
+			name:     "bad filter value is an error",
+			template: dto.Template{Syntax: dto.SyntaxTemplate, Body: `{{ .name | date "02.01.2006" }}`},
+			wantErr:  true,
+		}, {
+			name:     "unknown syntax",
+			template: dto.Template{Syntax: "mustache", Body: "{{name}}"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.template, params)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestContentType(t *testing.T) {
+	assert.Equal(t, dto.ContentTypeHTML, ContentType(dto.Template{Syntax: dto.SyntaxTemplate, ChannelType: "mail"}))
+	assert.Equal(t, dto.ContentTypeText, ContentType(dto.Template{Syntax: dto.SyntaxBracket, ChannelType: "mail"}))
+	assert.Equal(t, dto.ContentTypeText, ContentType(dto.Template{ChannelType: "mail"}))
+	assert.Equal(t, dto.ContentTypeText, ContentType(dto.Template{Syntax: dto.SyntaxTemplate, ChannelType: "slack"}))
+}
+
+func Test_formatCurrency(t *testing.T) {
+	got, err := formatCurrency("usd", "-999,999")
+	assert.NoError(t, err)
+	assert.Equal(t, "-1 000.00 $", got)
+
+	got, err = formatCurrency("KZT", "12")
+	assert.NoError(t, err)
+	assert.Equal(t, "12.00 KZT", got)
+}
+
+func TestRequiredParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		template dto.Template
+		want     []string
+		wantErr  bool
+		errIs    error
+	}{
+		{
+			name:     "bracket placeholders",
+			template: dto.Template{Body: "Hello [name], see you at [date]. [name]"},
+			want:     []string{"date", "name"},
+		}, {
+			name:     "no placeholders",
+			template: dto.Template{Body: "Hello"},
+			want:     nil,
+		}, {
+			name:     "malformed placeholder",
+			template: dto.Template{Body: "Hello [param 1]"},
+			wantErr:  true,
+			errIs:    ErrMalformedPlaceholder,
+		}, {
+			name:     "placeholder with stray characters",
+			template: dto.Template{Body: "Hello [$name]"},
+			wantErr:  true,
+			errIs:    ErrMalformedPlaceholder,
+		}, {
+			name:     "placeholder with dash",
+			template: dto.Template{Body: "Hello [first-name]"},
+			wantErr:  true,
+			errIs:    ErrMalformedPlaceholder,
+		}, {
+			name:     "footnotes and references are not placeholders",
+			template: dto.Template{Body: "Hello [name][1], see [see page 2] and [ИНН] [2023-03-14]"},
+			want:     []string{"name"},
+		}, {
+			name:     "text in non latin brackets is not a placeholder",
+			template: dto.Template{Body: "[срочно] Hello [name]"},
+			want:     []string{"name"},
+		}, {
+			name: "template root keys only",
+			template: dto.Template{Syntax: dto.SyntaxTemplate, Body: `{{ if .vip }}{{ .name | upper }}{{ end }}` +
+				`{{ range split .items "," }}{{ .ignored }}{{ $.company }}{{ end }}{{ index . "optional" }}`},
+			want: []string{"company", "items", "name", "vip"},
+		}, {
+			name:     "template syntax error",
+			template: dto.Template{Syntax: dto.SyntaxTemplate, Body: `{{ if .vip }}`},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RequiredParams(tt.template)
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.errIs != nil {
+					assert.ErrorIs(t, err, tt.errIs)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMissingParams(t *testing.T) {
+	missing := MissingParams([]string{"date", "name", "service"}, []dto.MessageParam{
+		{Key: "name", Value: "Иван"},
+		{Key: "service", Value: " "},
+	})
+
+	assert.Equal(t, []string{"date", "service"}, missing)
+}
@@ -0,0 +1,116 @@
+// Package worker отдельный процесс отправки сообщений cmd/worker. Потребляет очереди каналов
+// NC_WORKER_CHANNELS и очередь повторов, несколько процессов делят сообщения очередей между собой.
+// Статистика отправки публикуется в очередь результатов NC_STAT_QUEUE, ее принимает сервис статистики API
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/atrian/go-notify-customer/config"
+	"github.com/atrian/go-notify-customer/internal/interfaces"
+	"github.com/atrian/go-notify-customer/internal/services/notify"
+	"github.com/atrian/go-notify-customer/internal/workers"
+	"github.com/atrian/go-notify-customer/pkg/bus"
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+// ErrMemoryBus шина в памяти недоступна другим процессам
+var ErrMemoryBus = errors.New("in-memory bus is not shared between processes")
+
+type App struct {
+	config  config.Config
+	newBus  func() interfaces.Bus      // newBus шина сообщений для воркера
+	limiter *notify.TokenBucketLimiter // limiter ограничитель частоты переходов в резервный канал, свой у каждого процесса
+	logger  interfaces.Logger
+}
+
+func New() *App {
+	// логгер приложения
+	appLogger := logger.NewZapLogger()
+
+	// общий конфиг приложения
+	appConf := config.NewConfig(appLogger)
+
+	if appConf.GetBus() == bus.Memory {
+		appLogger.Fatal("Message bus config err", ErrMemoryBus)
+	}
+
+	newBus, err := bus.NewFactory(&appConf, appLogger)
+	if err != nil {
+		appLogger.Fatal("Message bus config err", err)
+	}
+
+	a := App{
+		config:  appConf,
+		newBus:  newBus,
+		limiter: notify.NewTokenBucketLimiter(&appConf, nil, appLogger),
+		logger:  appLogger,
+	}
+
+	return &a
+}
+
+// Run запуск воркеров отправки до сигнала SIGINT или SIGTERM. При остановке воркеры перестают
+// брать новые сообщения и дожидаются начатых отправок в пределах NC_WORKER_DRAIN_TIMEOUT
+func (a *App) Run(ctx context.Context) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	queues, err := a.config.GetTopology().ChannelQueues(a.config.GetWorkerChannels())
+	if err != nil {
+		a.logger.Fatal("Worker channels config err", err)
+	}
+
+	var wg sync.WaitGroup
+
+	// воркер потребляет очереди выбранных каналов, каждый канал своим потребителем
+	workerBus := a.connectBus()
+	channelWorker := workers.NewChannelWorker(ctx, &a.config, workerBus, a.logger).
+		SetLimiter(a.limiter)
+	for _, queue := range queues {
+		wg.Add(1)
+		go func(queue string) {
+			defer wg.Done()
+			channelWorker.Start(ctx, queue, a.config.GetStatQueue(), a.config.GetFailedWorksQueue())
+		}(queue)
+	}
+
+	// воркер повторов возвращает упавшие сообщения в очередь отправки после задержки
+	retryBus := a.connectBus()
+	retryWorker := workers.NewRetryWorker(&a.config, retryBus, a.logger)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		retryWorker.Start(ctx)
+	}()
+
+	a.logger.Info(fmt.Sprintf("Worker started, queues: %v", queues))
+
+	<-ctx.Done()
+	a.logger.Info("Worker stopping, draining in-flight sends")
+
+	wg.Wait()
+	channelWorker.Stop()
+	retryWorker.Stop()
+
+	workerBus.Stop()
+	retryBus.Stop()
+
+	a.logger.Info("Worker stopped")
+}
+
+// connectBus подключенная шина сообщений
+func (a *App) connectBus() interfaces.Bus {
+	messageBus := a.newBus()
+	if err := messageBus.Connect(); err != nil {
+		a.logger.Error("Worker bus.Connect err", err)
+	}
+
+	return messageBus
+}
@@ -0,0 +1,50 @@
+package workers
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff экспоненциальная задержка между попытками отправки со случайным разбросом.
+// Разброс не дает одновременно упавшим сообщениям повторяться одной волной
+type Backoff struct {
+	base   time.Duration
+	max    time.Duration
+	jitter float64
+	random func() float64
+}
+
+// NewBackoff задержка перед попыткой n равна base * 2^(n-2), но не больше max,
+// jitter - доля случайного отклонения в обе стороны, от 0 до 1
+func NewBackoff(base time.Duration, max time.Duration, jitter float64) *Backoff {
+	b := Backoff{
+		base:   base,
+		max:    max,
+		jitter: math.Min(math.Max(jitter, 0), 1),
+		random: rand.Float64,
+	}
+
+	if b.max < b.base {
+		b.max = b.base
+	}
+
+	return &b
+}
+
+// Delay задержка перед следующей попыткой после неудачной попытки attempt
+func (b *Backoff) Delay(attempt int) time.Duration {
+	delay := b.base
+	for i := 1; i < attempt && delay < b.max; i++ {
+		delay *= 2
+	}
+
+	if delay > b.max {
+		delay = b.max
+	}
+
+	// равномерное отклонение в диапазоне [-jitter; +jitter]
+	deviation := float64(delay) * b.jitter * (2*b.random() - 1)
+
+	return time.Duration(math.Max(0, float64(delay)+deviation))
+}
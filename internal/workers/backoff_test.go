@@ -0,0 +1,41 @@
+package workers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	b := NewBackoff(time.Second, 10*time.Second, 0)
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 3, want: 4 * time.Second},
+		{attempt: 4, want: 8 * time.Second},
+		{attempt: 5, want: 10 * time.Second},
+		{attempt: 100, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, b.Delay(tt.attempt))
+	}
+}
+
+func TestBackoff_DelayJitter(t *testing.T) {
+	b := NewBackoff(time.Second, time.Minute, 0.5)
+
+	b.random = func() float64 { return 0 }
+	assert.Equal(t, 2*time.Second, b.Delay(3))
+
+	b.random = func() float64 { return 1 }
+	assert.Equal(t, 6*time.Second, b.Delay(3))
+
+	b.random = func() float64 { return 0.5 }
+	assert.Equal(t, 4*time.Second, b.Delay(3))
+}
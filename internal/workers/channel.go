@@ -8,9 +8,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/atrian/go-notify-customer/internal/dto"
 	"github.com/atrian/go-notify-customer/internal/interfaces"
 	"github.com/atrian/go-notify-customer/internal/workers/channelServices"
+	"github.com/atrian/go-notify-customer/pkg/bus"
 )
 
 var (
@@ -18,12 +21,15 @@ var (
 )
 
 type ChannelWorker struct {
-	mu           sync.Locker
-	config       config
-	services     map[string]channelService
-	sendStatChan chan<- dto.Stat
-	client       interfaces.AmpqClient
-	logger       interfaces.Logger
+	mu          sync.Locker
+	config      config
+	services    map[string]channelService
+	resultQueue string         // resultQueue очередь статистики отправки, ее потребляет stat.Service
+	limiter     rerouteLimiter // limiter ограничитель частоты отправки, учитывает переходы в резервный канал
+	client      interfaces.Bus
+	backoff     *Backoff
+	logger      interfaces.Logger
+	now         func() time.Time
 }
 
 // loadServices загрузки стандартных сервисов отправки.
@@ -33,8 +39,11 @@ func (c *ChannelWorker) loadServices(ctx context.Context) {
 	defer c.mu.Unlock()
 
 	c.services = map[string]channelService{
-		"sms":  channelServices.NewTwilio(c.config, c.logger),
-		"mail": channelServices.NewMail(c.config, c.logger),
+		"sms":      channelServices.NewTwilio(c.config, c.logger),
+		"mail":     channelServices.NewMail(c.config, c.logger),
+		"webhook":  channelServices.NewWebhook(c.config, c.logger),
+		"telegram": channelServices.NewTelegram(c.config, c.logger),
+		"slack":    channelServices.NewSlack(c.config, c.logger),
 	}
 }
 
@@ -51,13 +60,14 @@ func (c *ChannelWorker) ReloadService(overwrite string, service channelService)
 	c.services[overwrite] = service
 }
 
-func NewChannelWorker(ctx context.Context, conf config, client interfaces.AmpqClient, sendStatChan chan<- dto.Stat, logger interfaces.Logger) *ChannelWorker {
+func NewChannelWorker(ctx context.Context, conf config, client interfaces.Bus, logger interfaces.Logger) *ChannelWorker {
 	w := ChannelWorker{
-		mu:           &sync.Mutex{},
-		config:       conf,
-		client:       client,
-		sendStatChan: sendStatChan,
-		logger:       logger,
+		mu:      &sync.Mutex{},
+		config:  conf,
+		client:  client,
+		backoff: NewBackoff(conf.GetRetryBaseDelay(), conf.GetRetryMaxDelay(), conf.GetRetryJitter()),
+		logger:  logger,
+		now:     time.Now,
 	}
 
 	w.loadServices(ctx)
@@ -65,16 +75,70 @@ func NewChannelWorker(ctx context.Context, conf config, client interfaces.AmpqCl
 	return &w
 }
 
+// SetResultQueue очередь статистики отправки. Start назначает очередь из successQueue.
+// Защищено через sync.Locker
+func (c *ChannelWorker) SetResultQueue(queue string) *ChannelWorker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.resultQueue = queue
+	return c
+}
+
+// SetLimiter ограничитель частоты отправки. Переход в резервный канал списывает токен канала
+// и получателя, при исчерпании лимита сообщение откладывается через очередь повторов.
+// Защищено через sync.Locker
+func (c *ChannelWorker) SetLimiter(limiter rerouteLimiter) *ChannelWorker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.limiter = limiter
+	return c
+}
+
+// service сервис отправки канала. Защищено через sync.Locker
+func (c *ChannelWorker) service(channel string) (channelService, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	service, exist := c.services[channel]
+	return service, exist
+}
+
 type channelService interface {
 	SendMessage(ctx context.Context, message string, destination string) error
 }
 
+// rerouteLimiter ограничитель частоты отправки для сообщений, перешедших в резервный канал
+type rerouteLimiter interface {
+	Charge(channel string, personUUID uuid.UUID) time.Duration
+}
+
+// messageSender сервис отправки, которому нужно сообщение целиком, а не только текст и адрес
+type messageSender interface {
+	Send(ctx context.Context, message dto.Message) error
+}
+
 type config interface {
-	GetAmpqDSN() string
-	GetNotificationQueue() string
+	GetTopology() dto.Topology
 	GetFailedWorksQueue() string
+	GetDeadLetterQueue() string
+	GetWorkerConcurrency() int
+	GetWorkerPrefetch() int
+	GetWorkerDrainTimeout() time.Duration
+	retryConfig
 	mailConfig
 	twilioConfig
+	webhookConfig
+	telegramConfig
+	slackConfig
+}
+
+type retryConfig interface {
+	GetRetryMaxAttempts() int
+	GetRetryBaseDelay() time.Duration
+	GetRetryMaxDelay() time.Duration
+	GetRetryJitter() float64
 }
 
 type mailConfig interface {
@@ -86,76 +150,371 @@ type mailConfig interface {
 	IsMailTLSRequired() bool
 }
 
+type webhookConfig interface {
+	GetWebhookSecret() string
+	GetWebhookTimeout() time.Duration
+}
+
+type telegramConfig interface {
+	GetTelegramBotToken() string
+	GetTelegramBaseURL() string
+}
+
+type slackConfig interface {
+	GetSlackBotToken() string
+	GetSlackBaseURL() string
+}
+
 type twilioConfig interface {
 	GetTwilioAccountSid() string
 	GetTwilioAuthToken() string
 	GetTwilioSenderPhone() string
 }
 
-// Start потребляет очередь consumeQueue, восстанавливает объект dto.Message из json
-// и отправляет его в ChannelWorker.Send
+// Start потребляет очередь consumeQueue, обычно очередь канала топологии GetTopology,
+// пулом из GetWorkerConcurrency отправителей. Статистика отправки публикуется в successQueue. Брокер выдает не больше GetWorkerPrefetch
+// неподтвержденных сообщений. Возвращает управление после отмены контекста и завершения начатых отправок,
+// на которые отводится GetWorkerDrainTimeout
 func (c *ChannelWorker) Start(ctx context.Context, consumeQueue string, successQueue string, failQueue string) {
-	c.client.MigrateDurableQueues(consumeQueue, successQueue, failQueue)
+	if successQueue != "" {
+		c.SetResultQueue(successQueue)
+	}
+
+	topology := c.config.GetTopology()
+	if err := c.client.MigrateTopology(topology); err != nil {
+		c.logger.Error("ChannelWorker can't migrate topology", err)
+	}
 
-	msgs, err := c.client.Consume(consumeQueue)
+	// очереди каналов объявлены с аргументами топологии, повторное объявление без них брокер отклонит
+	for _, queue := range []string{consumeQueue, successQueue, failQueue, c.config.GetDeadLetterQueue()} {
+		if queue != "" && !topology.Has(queue) {
+			c.client.MigrateDurableQueues(queue)
+		}
+	}
+
+	if err := c.client.Qos(c.config.GetWorkerPrefetch()); err != nil {
+		c.logger.Error("ChannelWorker can't set Qos", err)
+	}
+
+	msgs, err := c.client.Subscribe(consumeQueue)
 	if err != nil {
 		c.logger.Error("Can't consume message queue", err)
 	}
 
+	concurrency := c.config.GetWorkerConcurrency()
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// начатые отправки не прерываются отменой ctx, пока не истечет время на завершение
+	sendCtx, cancel := drainContext(ctx, c.config.GetWorkerDrainTimeout())
+	defer cancel()
+
+	// пул отправителей очереди, каждый подтверждает свое сообщение после обработки
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.consume(ctx, sendCtx, msgs)
+		}()
+	}
+
+	wg.Wait()
+	c.logger.Info(fmt.Sprintf("ChannelWorker consumer of %v stopped", consumeQueue))
+}
+
+// drainContext контекст отправки, который отменяется через drain после отмены ctx
+func drainContext(ctx context.Context, drain time.Duration) (context.Context, context.CancelFunc) {
+	sendCtx, cancel := context.WithCancel(context.Background())
+
 	go func() {
-		for d := range msgs {
-			var message dto.Message
-			jsonErr := json.Unmarshal(d.Body, &message)
+		select {
+		case <-ctx.Done():
+		case <-sendCtx.Done():
+			return
+		}
 
-			if jsonErr != nil {
-				c.logger.Error("ChannelWorker start JSON unmarshall err", jsonErr)
+		timer := time.NewTimer(drain)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			cancel()
+		case <-sendCtx.Done():
+		}
+	}()
+
+	return sendCtx, cancel
+}
+
+// consume обработка сообщений очереди до отмены контекста или закрытия канала потребителя.
+// Отправка выполняется с контекстом sendCtx. Полученные, но не взятые в обработку сообщения
+// остаются неподтвержденными, брокер выдаст их повторно
+func (c *ChannelWorker) consume(ctx context.Context, sendCtx context.Context, msgs <-chan dto.Delivery) {
+	for {
+		select {
+		case d, ok := <-msgs:
+			if !ok {
+				// клиент возобновляет потребителя после переподключения, канал закрывается только при остановке клиента
+				return
 			}
+			c.handle(ctx, sendCtx, d)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
 
-			c.logger.Info(fmt.Sprintf("Received a message from BUS notificationUUID:%v personUUID: %v, text: %v", message.NotificationUUID, message.PersonUUID, message.Text))
+// handle восстанавливает объект dto.Message из json и отправляет его в ChannelWorker.Send.
+// Сообщение подтверждается после отправки или передачи в очередь повторов, резервный канал
+// или очередь недоставленных, при ошибке передачи возвращается в очередь. Битое сообщение отклоняется без возврата в очередь.
+// После отмены ctx новые сообщения возвращаются в очередь
+func (c *ChannelWorker) handle(ctx context.Context, sendCtx context.Context, d dto.Delivery) {
+	var message dto.Message
+	if jsonErr := json.Unmarshal(d.Body, &message); jsonErr != nil {
+		c.logger.Error("ChannelWorker start JSON unmarshall err", jsonErr)
+		if err := d.Nack(false); err != nil {
+			c.logger.Error("ChannelWorker Reject err", err)
+		}
+		return
+	}
 
-			c.Send(ctx, message)
+	// воркер останавливается, сообщение достанется следующему потребителю
+	if ctx.Err() != nil {
+		if err := d.Nack(true); err != nil {
+			c.logger.Error("ChannelWorker Nack err", err)
 		}
-	}()
+		return
+	}
+
+	c.logger.Info(fmt.Sprintf("Received a message from BUS notificationUUID:%v personUUID: %v, text: %v", message.NotificationUUID, message.PersonUUID, message.Text))
+
+	// сообщение не передано дальше, брокер выдаст его повторно
+	if err := c.Send(sendCtx, message); err != nil {
+		c.logger.Error(fmt.Sprintf("ChannelWorker hand-off failed messageUUID:%v", message.MessageUUID), err)
+		if err = d.Nack(true); err != nil {
+			c.logger.Error("ChannelWorker Nack err", err)
+		}
+		return
+	}
 
-	<-ctx.Done()
+	if err := d.Ack(); err != nil {
+		// соединение оборвано, брокер выдаст сообщение повторно
+		c.logger.Error("ChannelWorker Ack err", err)
+	}
 }
 
-// Send принимает сообщение в формате dto.Message и отправляет его в нужный сервис
-// в случае ошибки пишет в канал статистики через ChannelWorker.sendStat
-func (c *ChannelWorker) Send(ctx context.Context, message dto.Message) {
-	service, exist := c.services[message.Channel]
+// Send принимает сообщение в формате dto.Message и отправляет его в нужный сервис.
+// Сообщение, срок ожидания резервного канала которого истек, сразу уходит в резервный канал. Каждая попытка фиксируется в канале статистики через ChannelWorker.sendStat,
+// в случае ошибки сообщение уходит на повтор через ChannelWorker.retry.
+// Возвращает ошибку, если сообщение не удалось передать в очередь повторов, резервный канал, очередь недоставленных
+// или не удалось опубликовать статистику попытки
+func (c *ChannelWorker) Send(ctx context.Context, message dto.Message) error {
+	// сообщения без идентификатора и счетчика попыток
+	if message.MessageUUID == uuid.Nil {
+		message.MessageUUID = uuid.New()
+	}
+	if message.Attempt < 1 {
+		message.Attempt = 1
+	}
+
+	// срок ожидания резервного канала истек, пока сообщение ждало в очереди,
+	// отправка в текущий канал уже не нужна
+	if deadline, ok := fallbackDeadline(message); ok && !c.now().Before(deadline) {
+		c.logger.Info(fmt.Sprintf("Fallback deadline passed before send messageUUID:%v channel:%v", message.MessageUUID, message.Channel))
+		if rerouted, err := c.reroute(message, deadline); rerouted || err != nil {
+			return err
+		}
+	}
+
+	service, exist := c.service(message.Channel)
 
 	if !exist {
-		c.logger.Error(fmt.Sprintf("Bad channel: %v for notificationUUID:%v", message.Channel, message.NotificationUUID), errors.New("not exist"))
-		c.sendStat(message, dto.BadChannel)
-		c.storeFailedJobs(message)
-		return
+		err := errors.New("not exist")
+		c.logger.Error(fmt.Sprintf("Bad channel: %v for notificationUUID:%v", message.Channel, message.NotificationUUID), err)
+		if statErr := c.sendStat(message, dto.BadChannel); statErr != nil {
+			return statErr
+		}
+		// повтор не поможет, сервис отправки не появится без перезапуска воркера
+		message = c.markFailed(message, err)
+		if rerouted, err := c.reroute(message, c.now()); rerouted || err != nil {
+			return err
+		}
+		return c.deadLetter(message)
 	}
 
-	err := service.SendMessage(ctx, message.Text, message.DestinationAddress)
+	var err error
+	if sender, ok := service.(messageSender); ok {
+		err = sender.Send(ctx, message)
+	} else {
+		err = service.SendMessage(ctx, message.Text, message.DestinationAddress)
+	}
 
 	if err != nil {
-		c.logger.Error(fmt.Sprintf("External sender error for notificationUUID:%v", message.NotificationUUID), err)
-		c.sendStat(message, dto.Failed)
-		c.storeFailedJobs(message)
-		return
+		c.logger.Error(fmt.Sprintf("External sender error for notificationUUID:%v attempt:%v", message.NotificationUUID, message.Attempt), err)
+		if statErr := c.sendStat(message, dto.Failed); statErr != nil {
+			return statErr
+		}
+		return c.retry(c.markFailed(message, err))
 	}
 
 	c.logger.Info(fmt.Sprintf("Notification SENT notificationUUID:%v", message.NotificationUUID))
-	c.sendStat(message, dto.Sent)
+
+	return c.sendStat(message, dto.Sent)
+}
+
+// markFailed фиксирует в сообщении время и причину неудачной попытки
+func (c *ChannelWorker) markFailed(message dto.Message, err error) dto.Message {
+	failedAt := c.now()
+	message.FailedAt = &failedAt
+	message.LastError = err.Error()
+
+	return message
 }
 
-func (c *ChannelWorker) storeFailedJobs(message dto.Message) {
+// retry публикует сообщение в очередь повторов со временем следующей попытки
+// по экспоненциальной задержке. После исчерпания попыток сообщение уходит в резервный канал
+// цепочки Fallback, а без него - в очередь недоставленных
+func (c *ChannelWorker) retry(message dto.Message) error {
+	now := c.now()
+
+	if message.Attempt >= c.config.GetRetryMaxAttempts() {
+		if rerouted, err := c.reroute(message, now); rerouted || err != nil {
+			return err
+		}
+		return c.deadLetter(message)
+	}
+
+	retryAt := now.Add(c.backoff.Delay(message.Attempt))
+
+	// следующая попытка не успевает до срока ожидания резервного канала,
+	// сообщение уходит в резервный канал к этому сроку. Если резервного канала нет в топологии,
+	// сообщение остается на повторах в текущем канале
+	if deadline, ok := fallbackDeadline(message); ok && !retryAt.Before(deadline) {
+		if rerouted, err := c.reroute(message, deadline); rerouted || err != nil {
+			return err
+		}
+	}
+
+	message.RetryAt = &retryAt
+	message.Attempt++
+
+	return c.publish(c.config.GetFailedWorksQueue(), message)
+}
+
+// reroute передает сообщение в следующий канал цепочки Fallback не раньше at и не раньше,
+// чем позволит ограничитель частоты. Отложенное сообщение ждет своего времени в очереди повторов. Возвращает false, если цепочка закончилась,
+// и ошибку публикации сообщения резервного канала
+func (c *ChannelWorker) reroute(message dto.Message, at time.Time) (bool, error) {
+	if len(message.Fallback) == 0 {
+		return false, nil
+	}
+
+	next := message.Fallback[0]
+	if len(message.Fallback) > 1 {
+		next.Fallback = message.Fallback[1:]
+	}
+
+	// без очереди следующего канала брокер отбросит сообщение, цепочка прерывается
+	if !c.config.GetTopology().Routes(next.Channel) {
+		c.logger.Warning(fmt.Sprintf("Message reroute messageUUID:%v: no queue for channel %v", message.MessageUUID, next.Channel))
+		return false, nil
+	}
+
+	now := c.now()
+	if at.Before(now) {
+		at = now
+	}
+
+	// резервный канал расходует лимит так же, как исходная отправка
+	c.mu.Lock()
+	limiter := c.limiter
+	c.mu.Unlock()
+	if limiter != nil {
+		if limitAt := now.Add(limiter.Charge(next.Channel, next.PersonUUID)); limitAt.After(at) {
+			at = limitAt
+		}
+	}
+	next.DispatchedAt = &at
+
+	var err error
+	if at.After(now) {
+		next.RetryAt = &at
+		err = c.publish(c.config.GetFailedWorksQueue(), next)
+	} else {
+		err = c.route(next)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	c.logger.Info(fmt.Sprintf("Message rerouted messageUUID:%v from %v to %v", message.MessageUUID, message.Channel, next.Channel))
+	if err = c.sendStat(message, dto.Rerouted); err != nil {
+		return true, err
+	}
+
+	return true, c.sendStat(next, dto.Dispatched)
+}
+
+// fallbackDeadline срок, после которого сообщение уходит в следующий канал цепочки,
+// отсчитывается от передачи сообщения в очередь. Для шага без ожидания срока нет
+func fallbackDeadline(message dto.Message) (time.Time, bool) {
+	if len(message.Fallback) == 0 || message.Fallback[0].FallbackAfter <= 0 {
+		return time.Time{}, false
+	}
+
+	start := message.DispatchedAt
+	if start == nil {
+		start = message.FailedAt
+	}
+	if start == nil {
+		return time.Time{}, false
+	}
+
+	return start.Add(time.Duration(message.Fallback[0].FallbackAfter) * time.Second), true
+}
+
+// deadLetter помещает сообщение в очередь недоставленных, откуда его можно
+// просмотреть, повторить или удалить через сервис deadLetter
+func (c *ChannelWorker) deadLetter(message dto.Message) error {
+	message.RetryAt = nil
+	if err := c.publish(c.config.GetDeadLetterQueue(), message); err != nil {
+		return err
+	}
+
+	c.logger.Warning(fmt.Sprintf("Message dead lettered messageUUID:%v after %v attempts", message.MessageUUID, message.Attempt))
+
+	return c.sendStat(message, dto.DeadLettered)
+}
+
+func (c *ChannelWorker) publish(queue string, message dto.Message) error {
 	jsonMessage, err := json.Marshal(message)
 	if err != nil {
-		c.logger.Error("storeFailedJobs Message JSON marshal failed", err)
-		return
+		return fmt.Errorf("message marshal: %w", err)
+	}
+
+	if err = c.client.Publish(queue, jsonMessage); err != nil {
+		return fmt.Errorf("publish to %v: %w", queue, err)
 	}
 
-	err = c.client.Publish(c.config.GetFailedWorksQueue(), jsonMessage)
+	return nil
+}
+
+// route публикует сообщение в очередь его канала с приоритетом и сроком актуальности
+func (c *ChannelWorker) route(message dto.Message) error {
+	jsonMessage, err := json.Marshal(message)
 	if err != nil {
-		c.logger.Error("storeFailedJobs client.Publish to queue failed", err)
+		return fmt.Errorf("message marshal: %w", err)
 	}
+
+	err = c.client.PublishRouted(c.config.GetTopology().Exchange, message.Channel, jsonMessage,
+		message.Priority, bus.Expiration(message.ExpiresAt, c.now()))
+	if err != nil {
+		return fmt.Errorf("publish routed %v: %w", message.Channel, err)
+	}
+
+	return nil
 }
 
 // Stop остановка воркера
@@ -163,14 +522,45 @@ func (c *ChannelWorker) Stop() {
 	c.logger.Info("Channel worker stopped")
 }
 
-// sendStat отправка статистики в формате dto.Stat в канал sendStatChan
-// канал слушает stat.Service
-func (c *ChannelWorker) sendStat(message dto.Message, status dto.StatStatus) {
-	// отправляем статистику
-	c.sendStatChan <- dto.Stat{
+// statNamespace пространство имен идентификаторов статистики отправок
+var statNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("go-notify-customer/stat"))
+
+// statUUID идентификатор записи статистики, одинаковый для одной попытки сообщения в одном статусе.
+// Повторная обработка выданного брокером сообщения дает те же идентификаторы, stat.Service сохраняет их один раз
+func statUUID(message dto.Message, status dto.StatStatus) uuid.UUID {
+	return uuid.NewSHA1(statNamespace, []byte(fmt.Sprintf("%v/%v/%v/%v/%v/%v",
+		message.NotificationUUID, message.PersonUUID, message.MessageUUID, message.Channel, message.Attempt, status)))
+}
+
+// sendStat публикация статистики в формате dto.Stat в очередь результатов resultQueue,
+// очередь слушает stat.Service. Ошибка публикации возвращает сообщение в очередь,
+// StatUUID записи защищает от дублей при повторной выдаче брокером
+func (c *ChannelWorker) sendStat(message dto.Message, status dto.StatStatus) error {
+	c.mu.Lock()
+	resultQueue := c.resultQueue
+	c.mu.Unlock()
+
+	if resultQueue == "" {
+		c.logger.Warning(fmt.Sprintf("ChannelWorker no result queue, stat %v for notificationUUID:%v dropped", status, message.NotificationUUID))
+		return nil
+	}
+
+	jsonStat, err := json.Marshal(dto.Stat{
+		StatUUID:         statUUID(message, status),
 		PersonUUID:       message.PersonUUID,
 		NotificationUUID: message.NotificationUUID,
-		CreatedAt:        time.Now().Format(time.RFC3339),
+		CreatedAt:        c.now().Format(time.RFC3339),
 		Status:           status,
+		Attempt:          message.Attempt,
+		Channel:          message.Channel,
+	})
+	if err != nil {
+		return fmt.Errorf("stat marshal: %w", err)
+	}
+
+	if err = c.client.Publish(resultQueue, jsonStat); err != nil {
+		return fmt.Errorf("publish stat to %v: %w", resultQueue, err)
 	}
+
+	return nil
 }
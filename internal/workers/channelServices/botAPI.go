@@ -0,0 +1,78 @@
+package channelServices
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// botAPITimeout таймаут запросов к API мессенджеров
+const botAPITimeout = 10 * time.Second
+
+// botAPIResponse общая часть ответов Telegram и Slack API: HTTP 200 не гарантирует успех,
+// результат передается полем ok, причина ошибки в description (Telegram) или error (Slack)
+type botAPIResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// postBotAPI POST запрос с JSON телом к API мессенджера с разбором поля ok.
+// Адрес запроса может содержать токен бота, поэтому в ошибках его нет
+func postBotAPI(ctx context.Context, client *http.Client, endpoint string, token string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return withoutURL(err)
+	}
+
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return withoutURL(err)
+	}
+	defer func(Body io.ReadCloser) {
+		_, _ = io.Copy(io.Discard, Body)
+		_ = Body.Close()
+	}(response.Body)
+
+	var result botAPIResponse
+	if err = json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return fmt.Errorf("bot api status %v, bad response: %w", response.StatusCode, err)
+	}
+
+	if !result.OK {
+		reason := result.Description
+		if reason == "" {
+			reason = result.Error
+		}
+		return fmt.Errorf("bot api status %v: %v", response.StatusCode, reason)
+	}
+
+	return nil
+}
+
+// withoutURL убирает адрес запроса из ошибки *url.Error, ошибка попадает в логи,
+// LastError сообщения, очередь недоставленных и API администратора
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("bot api %v: %w", urlErr.Op, urlErr.Err)
+	}
+
+	return err
+}
@@ -0,0 +1,101 @@
+package channelServices
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+var (
+	_ configTelegram = (*botConfigMock)(nil)
+	_ configSlack    = (*botConfigMock)(nil)
+)
+
+func TestTelegram_SendMessage(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/bottg-token/sendMessage", r.URL.Path)
+
+		var message telegramMessage
+		_ = json.NewDecoder(r.Body).Decode(&message)
+
+		// неизвестный чат - ошибка в теле ответа
+		if message.ChatID != "123456" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+			return
+		}
+
+		assert.Equal(t, "Hello", message.Text)
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer api.Close()
+
+	telegram := NewTelegram(&botConfigMock{token: "tg-token", baseURL: api.URL + "/"}, logger.NewZapLogger())
+
+	assert.NoError(t, telegram.SendMessage(context.Background(), "Hello", "123456"))
+	assert.ErrorContains(t, telegram.SendMessage(context.Background(), "Hello", "42"), "chat not found")
+}
+
+// TestTelegram_SendMessageHidesToken токен бота в пути запроса не попадает в ошибку
+func TestTelegram_SendMessageHidesToken(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	api.Close()
+
+	telegram := NewTelegram(&botConfigMock{token: "tg-secret-token", baseURL: api.URL}, logger.NewZapLogger())
+
+	err := telegram.SendMessage(context.Background(), "Hello", "123456")
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "tg-secret-token")
+}
+
+func TestSlack_SendMessage(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat.postMessage", r.URL.Path)
+		assert.Equal(t, "Bearer xoxb-token", r.Header.Get("Authorization"))
+
+		var message slackMessage
+		_ = json.NewDecoder(r.Body).Decode(&message)
+
+		// Slack отвечает 200 и на ошибки
+		if message.Channel != "C0123" {
+			_, _ = w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+			return
+		}
+
+		assert.Equal(t, "Hello", message.Text)
+		_, _ = w.Write([]byte(`{"ok":true,"channel":"C0123","ts":"1503435956.000247"}`))
+	}))
+	defer api.Close()
+
+	slack := NewSlack(&botConfigMock{token: "xoxb-token", baseURL: api.URL}, logger.NewZapLogger())
+
+	assert.NoError(t, slack.SendMessage(context.Background(), "Hello", "C0123"))
+	assert.ErrorContains(t, slack.SendMessage(context.Background(), "Hello", "C404"), "channel_not_found")
+}
+
+type botConfigMock struct {
+	token   string
+	baseURL string
+}
+
+func (b *botConfigMock) GetTelegramBotToken() string {
+	return b.token
+}
+
+func (b *botConfigMock) GetTelegramBaseURL() string {
+	return b.baseURL
+}
+
+func (b *botConfigMock) GetSlackBotToken() string {
+	return b.token
+}
+
+func (b *botConfigMock) GetSlackBaseURL() string {
+	return b.baseURL
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/atrian/go-notify-customer/internal/dto"
 	"github.com/atrian/go-notify-customer/internal/interfaces"
 )
 
@@ -35,12 +36,34 @@ func NewMail(conf configMail, logger interfaces.Logger) *Mail {
 	}
 }
 
+// Send отправка письма с типом содержимого сообщения, см. dto.Message.ContentType
+func (s *Mail) Send(ctx context.Context, message dto.Message) error {
+	return s.send(message.Text, message.DestinationAddress, contentType(message.ContentType))
+}
+
+// SendMessage отправка письма без разметки
 func (s *Mail) SendMessage(ctx context.Context, message string, destination string) error {
+	return s.send(message, destination, dto.ContentTypeText)
+}
+
+// contentType заголовок Content-Type письма. HTML отправляется только для шаблонов с разметкой,
+// текст шаблонов [param1] не экранируется и должен остаться text/plain
+func contentType(messageType string) string {
+	if messageType == dto.ContentTypeHTML {
+		return dto.ContentTypeHTML + "; charset=UTF-8"
+	}
+
+	return dto.ContentTypeText + "; charset=UTF-8"
+}
+
+func (s *Mail) send(message string, destination string, contentType string) error {
 	headers := make(map[string]string)
 	headers["Message-ID"] = s.generateMessageId()
 	headers["From"] = s.conf.GetMailSenderAddress()
 	headers["To"] = destination
 	headers["Subject"] = s.conf.GetMailMessageTheme()
+	headers["MIME-Version"] = "1.0"
+	headers["Content-Type"] = contentType
 
 	mail := strings.Builder{}
 	mail.Grow(len(headers) * 2)
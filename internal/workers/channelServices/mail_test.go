@@ -3,6 +3,8 @@ package channelServices
 import (
 	"github.com/stretchr/testify/assert"
 	"testing"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
 )
 
 var _ configMail = (*mailConfigMock)(nil)
@@ -12,6 +14,12 @@ func TestMail_SendMessage(t *testing.T) {
 	assert.NoError(t, nil)
 }
 
+func Test_contentType(t *testing.T) {
+	assert.Equal(t, "text/html; charset=UTF-8", contentType(dto.ContentTypeHTML))
+	assert.Equal(t, "text/plain; charset=UTF-8", contentType(dto.ContentTypeText))
+	assert.Equal(t, "text/plain; charset=UTF-8", contentType(""))
+}
+
 type mailConfigMock struct{}
 
 func (m mailConfigMock) IsMailTLSRequired() bool {
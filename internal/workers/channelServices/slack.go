@@ -0,0 +1,49 @@
+package channelServices
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/atrian/go-notify-customer/internal/interfaces"
+)
+
+const defaultSlackBaseURL = "https://slack.com/api"
+
+// Slack отправка сообщения через метод chat.postMessage Slack Web API,
+// адрес назначения - ID канала или пользователя
+type Slack struct {
+	cfg    configSlack
+	client *http.Client
+	logger interfaces.Logger
+}
+
+type configSlack interface {
+	GetSlackBotToken() string
+	GetSlackBaseURL() string
+}
+
+type slackMessage struct {
+	Channel string `json:"channel"`
+	Text    string `json:"text"`
+}
+
+func NewSlack(cfg configSlack, logger interfaces.Logger) *Slack {
+	return &Slack{
+		cfg:    cfg,
+		client: &http.Client{Timeout: botAPITimeout},
+		logger: logger,
+	}
+}
+
+func (s *Slack) SendMessage(ctx context.Context, message string, destination string) error {
+	baseURL := s.cfg.GetSlackBaseURL()
+	if baseURL == "" {
+		baseURL = defaultSlackBaseURL
+	}
+
+	return postBotAPI(ctx, s.client, strings.TrimRight(baseURL, "/")+"/chat.postMessage", s.cfg.GetSlackBotToken(), slackMessage{
+		Channel: destination,
+		Text:    message,
+	})
+}
@@ -0,0 +1,52 @@
+package channelServices
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/atrian/go-notify-customer/internal/interfaces"
+)
+
+const defaultTelegramBaseURL = "https://api.telegram.org"
+
+// Telegram отправка сообщения через Telegram Bot API, адрес назначения - chat_id получателя
+type Telegram struct {
+	cfg    configTelegram
+	client *http.Client
+	logger interfaces.Logger
+}
+
+type configTelegram interface {
+	GetTelegramBotToken() string
+	GetTelegramBaseURL() string
+}
+
+type telegramMessage struct {
+	ChatID string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+func NewTelegram(cfg configTelegram, logger interfaces.Logger) *Telegram {
+	return &Telegram{
+		cfg:    cfg,
+		client: &http.Client{Timeout: botAPITimeout},
+		logger: logger,
+	}
+}
+
+func (s *Telegram) SendMessage(ctx context.Context, message string, destination string) error {
+	baseURL := s.cfg.GetTelegramBaseURL()
+	if baseURL == "" {
+		baseURL = defaultTelegramBaseURL
+	}
+
+	// токен Telegram передается в пути запроса, а не в заголовке
+	endpoint := fmt.Sprintf("%v/bot%v/sendMessage", strings.TrimRight(baseURL, "/"), s.cfg.GetTelegramBotToken())
+
+	return postBotAPI(ctx, s.client, endpoint, "", telegramMessage{
+		ChatID: destination,
+		Text:   message,
+	})
+}
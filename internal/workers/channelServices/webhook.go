@@ -0,0 +1,147 @@
+package channelServices
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/interfaces"
+)
+
+const (
+	// WebhookSignatureHeader подпись запроса HMAC-SHA256 в формате sha256=<hex>
+	WebhookSignatureHeader = "X-Notify-Signature"
+	// WebhookTimestampHeader unix время подписи, входит в подписываемые данные для защиты от повтора
+	WebhookTimestampHeader = "X-Notify-Timestamp"
+
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+// ErrWebhookSecretMissing без секрета получатель не может проверить подлинность запроса
+var ErrWebhookSecretMissing = errors.New("webhook secret is not configured")
+
+// Webhook отправка сообщения POST запросом с JSON телом на адрес из контакта получателя.
+// Запрос подписывается HMAC-SHA256 от строки "<timestamp>.<body>", без секрета сообщения не отправляются.
+// Ответ вне диапазона 2xx считается ошибкой отправки
+type Webhook struct {
+	cfg    configWebhook
+	client *http.Client
+	logger interfaces.Logger
+	now    func() time.Time
+}
+
+type configWebhook interface {
+	GetWebhookSecret() string
+	GetWebhookTimeout() time.Duration
+}
+
+// WebhookPayload тело запроса webhook
+type WebhookPayload struct {
+	MessageUUID      uuid.UUID `json:"message_uuid,omitempty"`
+	NotificationUUID uuid.UUID `json:"notification_uuid,omitempty"`
+	PersonUUID       uuid.UUID `json:"person_uuid,omitempty"`
+	Text             string    `json:"text"`
+	Attempt          int       `json:"attempt,omitempty"`
+	SentAt           time.Time `json:"sent_at"`
+}
+
+func NewWebhook(cfg configWebhook, logger interfaces.Logger) *Webhook {
+	timeout := cfg.GetWebhookTimeout()
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+
+	if cfg.GetWebhookSecret() == "" {
+		logger.Warning("Webhook secret NC_WEBHOOK_SECRET is empty, webhook messages will not be sent")
+	}
+
+	return &Webhook{
+		cfg:    cfg,
+		client: &http.Client{Timeout: timeout},
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// SendMessage отправка только текста сообщения
+func (s *Webhook) SendMessage(ctx context.Context, message string, destination string) error {
+	return s.Send(ctx, dto.Message{Text: message, DestinationAddress: destination})
+}
+
+// Send отправка сообщения с идентификаторами уведомления, получателя и номером попытки
+func (s *Webhook) Send(ctx context.Context, message dto.Message) error {
+	secret := s.cfg.GetWebhookSecret()
+	if secret == "" {
+		return ErrWebhookSecretMissing
+	}
+
+	endpoint, err := url.Parse(message.DestinationAddress)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("bad webhook url: %q", message.DestinationAddress)
+	}
+
+	now := s.now()
+	body, err := json.Marshal(WebhookPayload{
+		MessageUUID:      message.MessageUUID,
+		NotificationUUID: message.NotificationUUID,
+		PersonUUID:       message.PersonUUID,
+		Text:             message.Text,
+		Attempt:          message.Attempt,
+		SentAt:           now.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookTimestampHeader, timestamp)
+	request.Header.Set(WebhookSignatureHeader, SignWebhook(secret, timestamp, body))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		// вычитываем тело для переиспользования соединения
+		_, _ = io.Copy(io.Discard, Body)
+		if cErr := Body.Close(); cErr != nil {
+			s.logger.Error("Webhook response body close err", cErr)
+		}
+	}(response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %v", response.StatusCode)
+	}
+
+	return nil
+}
+
+// SignWebhook подпись webhook, получатель проверяет ее тем же секретом
+func SignWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,109 @@
+package channelServices
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+var _ configWebhook = (*webhookConfigMock)(nil)
+
+func TestWebhook_Send(t *testing.T) {
+	conf := webhookConfigMock{secret: "top-secret", timeout: time.Second}
+	message := dto.Message{
+		MessageUUID:      uuid.New(),
+		NotificationUUID: uuid.New(),
+		PersonUUID:       uuid.New(),
+		Text:             "Hello",
+		Attempt:          2,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		// получатель проверяет подпись тем же секретом
+		expected := SignWebhook(conf.secret, r.Header.Get(WebhookTimestampHeader), body)
+		assert.True(t, hmac.Equal([]byte(expected), []byte(r.Header.Get(WebhookSignatureHeader))))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var payload WebhookPayload
+		assert.NoError(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, message.NotificationUUID, payload.NotificationUUID)
+		assert.Equal(t, message.Text, payload.Text)
+		assert.Equal(t, 2, payload.Attempt)
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	message.DestinationAddress = server.URL
+	err := NewWebhook(&conf, logger.NewZapLogger()).Send(context.Background(), message)
+	assert.NoError(t, err)
+}
+
+// TestWebhook_SendNoSecret без секрета неподписанный запрос не отправляется
+func TestWebhook_SendNoSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unsigned webhook request sent")
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(&webhookConfigMock{timeout: time.Second}, logger.NewZapLogger())
+	err := webhook.SendMessage(context.Background(), "Hello", server.URL)
+	assert.ErrorIs(t, err, ErrWebhookSecretMissing)
+}
+
+func TestWebhook_SendMessageFailures(t *testing.T) {
+	conf := webhookConfigMock{secret: "top-secret", timeout: 50 * time.Millisecond}
+	webhook := NewWebhook(&conf, logger.NewZapLogger())
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	tests := []struct {
+		name        string
+		destination string
+	}{
+		{name: "non 2xx response", destination: failing.URL},
+		{name: "timeout", destination: slow.URL},
+		{name: "bad url", destination: "not a url"},
+		{name: "bad scheme", destination: "ftp://example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, webhook.SendMessage(context.Background(), "Hello", tt.destination))
+		})
+	}
+}
+
+type webhookConfigMock struct {
+	secret  string
+	timeout time.Duration
+}
+
+func (w *webhookConfigMock) GetWebhookSecret() string {
+	return w.secret
+}
+
+func (w *webhookConfigMock) GetWebhookTimeout() time.Duration {
+	return w.timeout
+}
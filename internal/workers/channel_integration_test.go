@@ -36,6 +36,9 @@ var (
 
 	image = "rabbitmq:3-management-alpine"
 
+	// testQueue очередь без аргументов топологии для прямой публикации
+	testQueue = "planned_notifications"
+
 	_ config         = (*configMock)(nil)
 	_ channelService = (*smsServiceMock)(nil)
 )
@@ -53,23 +56,107 @@ func TestRabbitAlive(t *testing.T) {
 	})
 }
 
+func TestRabbitTopology(t *testing.T) {
+	dktest.Run(t, image, opts, func(t *testing.T, c dktest.ContainerInfo) {
+		client := ampq.NewWithConnection(dsn, logger.NewZapLogger())
+		topology := configMock{}.GetTopology()
+		assert.NoError(t, client.MigrateTopology(topology))
+
+		// повторное объявление с теми же аргументами не ломает канал
+		assert.NoError(t, client.MigrateTopology(topology))
+
+		// сообщения с большим приоритетом выдаются первыми
+		assert.NoError(t, client.PublishRouted(topology.Exchange, "sms", []byte("low"), 1, 0))
+		assert.NoError(t, client.PublishRouted(topology.Exchange, "sms", []byte("high"), 9, time.Minute))
+		assert.NoError(t, client.PublishRouted(topology.Exchange, "mail", []byte("mail"), 5, 0))
+
+		first, ok, err := client.Channel().Get(topology.Queue("sms"), true)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "high", string(first.Body))
+
+		second, ok, err := client.Channel().Get(topology.Queue("sms"), true)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "low", string(second.Body))
+
+		// сообщение канала mail не попадает в очередь sms
+		_, ok, err = client.Channel().Get(topology.Queue("sms"), true)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+// TestRabbitResume после ошибки канала клиент переподключается, объявляет очереди заново
+// и возобновляет потребителя, публикация снова подтверждается брокером
+func TestRabbitResume(t *testing.T) {
+	dktest.Run(t, image, opts, func(t *testing.T, c dktest.ContainerInfo) {
+		client := ampq.New(dsn, logger.NewZapLogger()).
+			SetReconnectBackoff(10*time.Millisecond, 100*time.Millisecond)
+		assert.NoError(t, client.Connect())
+		defer client.Stop()
+
+		client.MigrateDurableQueues("resume")
+		msgs, err := client.Consume("resume")
+		assert.NoError(t, err)
+
+		// пассивное объявление несуществующей очереди закрывает канал ошибкой брокера
+		_, err = client.Channel().QueueDeclarePassive("missing", true, false, false, false, nil)
+		assert.Error(t, err)
+
+		assert.Eventually(t, func() bool {
+			return client.Publish("resume", []byte("after reconnect")) == nil
+		}, 5*time.Second, 50*time.Millisecond)
+
+		select {
+		case d := <-msgs:
+			assert.Equal(t, "after reconnect", string(d.Body))
+		case <-time.After(5 * time.Second):
+			t.Fatal("consumer not resumed")
+		}
+	})
+}
+
+// TestRabbitPublishDelayed отложенное сообщение ждет в очереди ожидания и переводится брокером в очередь назначения
+func TestRabbitPublishDelayed(t *testing.T) {
+	dktest.Run(t, image, opts, func(t *testing.T, c dktest.ContainerInfo) {
+		client := ampq.NewWithConnection(dsn, logger.NewZapLogger())
+		defer client.Stop()
+
+		client.MigrateDurableQueues("failed")
+		msgs, err := client.Consume("failed")
+		assert.NoError(t, err)
+
+		publishedAt := time.Now()
+		assert.NoError(t, client.PublishDelayed("failed", []byte("later"), 1500*time.Millisecond))
+
+		select {
+		case d := <-msgs:
+			assert.Equal(t, "later", string(d.Body))
+			assert.GreaterOrEqual(t, time.Since(publishedAt), time.Second)
+			assert.NoError(t, d.Ack(false))
+		case <-time.After(5 * time.Second):
+			t.Fatal("delayed message not delivered")
+		}
+	})
+}
+
 func TestChannelWorker_Start(t *testing.T) {
 	dktest.Run(t, image, opts, func(t *testing.T, c dktest.ContainerInfo) {
 		client := ampq.NewWithConnection(dsn, logger.NewZapLogger())
 		conf := configMock{}
-		client.MigrateDurableQueues(conf.GetNotificationQueue(), conf.GetFailedWorksQueue())
+		client.MigrateDurableQueues(testQueue, conf.GetFailedWorksQueue())
 		zapLogger := logger.NewZapLogger()
-		sendStatChan := make(chan dto.Stat)
 		outputChan := make(chan string)
 		done := make(chan struct{})
 
 		// Создаем нового воркера
-		worker := NewChannelWorker(context.Background(), conf, client, sendStatChan, zapLogger)
+		worker := NewChannelWorker(context.Background(), conf, client, zapLogger)
 		worker.ReloadService("sms", newSmsMock(outputChan))
 
 		// Run worker
 		go func() {
-			worker.Start(conf.GetNotificationQueue(), "", "")
+			worker.Start(context.Background(), testQueue, statQueue, "")
 			defer worker.Stop()
 		}()
 
@@ -86,18 +173,6 @@ func TestChannelWorker_Start(t *testing.T) {
 			done <- struct{}{}
 		}()
 
-		// Stat receiver stub
-		go func() {
-			for {
-				select {
-				case <-sendStatChan:
-					// do something with stat data
-				default:
-					// do nothing
-				}
-			}
-		}()
-
 		// отправляем в очередь тестовое сообщение
 		err := client.Publish("test", []byte("message"))
 		notificationUUID := uuid.New()
@@ -124,10 +199,10 @@ func TestChannelWorker_Start(t *testing.T) {
 		assert.NoError(t, err)
 
 		// отправляем первое сообщение
-		err = client.Publish(conf.GetNotificationQueue(), jsonMessage)
+		err = client.Publish(testQueue, jsonMessage)
 		assert.NoError(t, err)
 		// отправляем второе сообщение
-		err = client.Publish(conf.GetNotificationQueue(), jsonMessage2)
+		err = client.Publish(testQueue, jsonMessage2)
 		assert.NoError(t, err)
 
 		<-done
@@ -136,7 +211,7 @@ func TestChannelWorker_Start(t *testing.T) {
 
 func isReady(ctx context.Context, c dktest.ContainerInfo) bool {
 	client := ampq.New(dsn, logger.NewFatalZapLogger())
-	err := client.Connect(dsn)
+	err := client.Connect()
 
 	if err != nil {
 		return false
@@ -145,52 +220,6 @@ func isReady(ctx context.Context, c dktest.ContainerInfo) bool {
 	return true
 }
 
-type configMock struct{}
-
-func (c configMock) GetMailSenderAddress() string {
-	return "test@mail.com"
-}
-
-func (c configMock) GetMailSMTPHost() string {
-	return "localhost:465"
-}
-
-func (c configMock) GetMailLogin() string {
-	return "test@mail.com"
-}
-
-func (c configMock) GetMailPassword() string {
-	return "test@mail.com"
-}
-
-func (c configMock) GetMailMessageTheme() string {
-	return "test@mail.com"
-}
-
-func (c configMock) GetTwilioAccountSid() string {
-	return "test@mail.com"
-}
-
-func (c configMock) GetTwilioAuthToken() string {
-	return "test@mail.com"
-}
-
-func (c configMock) GetTwilioSenderPhone() string {
-	return "test@mail.com"
-}
-
-func (c configMock) GetAmpqDSN() string {
-	return dsn
-}
-
-func (c configMock) GetNotificationQueue() string {
-	return "notifications"
-}
-
-func (c configMock) GetFailedWorksQueue() string {
-	return "failed"
-}
-
 type smsServiceMock struct {
 	output chan<- string
 }
@@ -200,7 +229,7 @@ func newSmsMock(output chan string) *smsServiceMock {
 	return &s
 }
 
-func (s *smsServiceMock) SendMessage(message string, destination string) error {
+func (s *smsServiceMock) SendMessage(ctx context.Context, message string, destination string) error {
 	s.output <- fmt.Sprintf("%v:%v", message, destination)
 	return nil
 }
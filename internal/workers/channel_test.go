@@ -0,0 +1,829 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/interfaces"
+	"github.com/atrian/go-notify-customer/internal/workers/channelServices"
+	"github.com/atrian/go-notify-customer/pkg/bus"
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+// statQueue очередь результатов, в которую воркер публикует статистику
+const statQueue = "stats"
+
+var (
+	_ config         = (*configMock)(nil)
+	_ interfaces.Bus = (*busMock)(nil)
+	_ channelService = (*failingServiceMock)(nil)
+	_ channelService = (*blockingServiceMock)(nil)
+	_ rerouteLimiter = (*limiterMock)(nil)
+)
+
+func TestChannelWorker_SendRetry(t *testing.T) {
+	conf := configMock{}
+	client := newBusMock()
+	worker := NewChannelWorker(context.Background(), conf, client, logger.NewZapLogger()).
+		SetResultQueue(statQueue)
+	worker.ReloadService("sms", &failingServiceMock{})
+
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	worker.now = func() time.Time { return now }
+
+	message := dto.Message{
+		MessageUUID:        uuid.New(),
+		NotificationUUID:   uuid.New(),
+		PersonUUID:         uuid.New(),
+		Text:               "Test",
+		Channel:            "sms",
+		DestinationAddress: "+79876543210",
+		Attempt:            1,
+	}
+
+	// первая неудачная попытка - сообщение уходит на повтор
+	worker.Send(context.Background(), message)
+
+	stat := <-client.stats
+	assert.NotEqual(t, uuid.Nil, stat.StatUUID)
+	assert.Equal(t, dto.Failed, stat.Status)
+	assert.Equal(t, 1, stat.Attempt)
+	assert.Equal(t, "sms", stat.Channel)
+	assert.Equal(t, now.Format(time.RFC3339), stat.CreatedAt)
+
+	published := <-client.published
+	assert.Equal(t, conf.GetFailedWorksQueue(), published.queue)
+	assert.Equal(t, message.MessageUUID, published.message.MessageUUID)
+	assert.Equal(t, 2, published.message.Attempt)
+	assert.Equal(t, now.Add(conf.GetRetryBaseDelay()), *published.message.RetryAt)
+	assert.Equal(t, "provider unavailable", published.message.LastError)
+
+	// повторная выдача той же попытки дает запись статистики с тем же идентификатором
+	worker.Send(context.Background(), message)
+	assert.Equal(t, stat.StatUUID, (<-client.stats).StatUUID)
+	<-client.published
+
+	// последняя попытка - сообщение помещается в очередь недоставленных
+	worker.Send(context.Background(), published.message)
+	published = <-client.published
+
+	worker.Send(context.Background(), published.message)
+
+	stat = <-client.stats
+	assert.Equal(t, dto.Failed, stat.Status)
+	stat = <-client.stats
+	assert.Equal(t, dto.Failed, stat.Status)
+	assert.Equal(t, conf.GetRetryMaxAttempts(), stat.Attempt)
+	stat = <-client.stats
+	assert.Equal(t, dto.DeadLettered, stat.Status)
+
+	published = <-client.published
+	assert.Equal(t, conf.GetDeadLetterQueue(), published.queue)
+	assert.Equal(t, conf.GetRetryMaxAttempts(), published.message.Attempt)
+	assert.Nil(t, published.message.RetryAt)
+}
+
+func TestChannelWorker_SendBadChannel(t *testing.T) {
+	conf := configMock{}
+	client := newBusMock()
+	worker := NewChannelWorker(context.Background(), conf, client, logger.NewZapLogger()).
+		SetResultQueue(statQueue)
+
+	// повторять отправку в неизвестный канал бессмысленно
+	worker.Send(context.Background(), dto.Message{Channel: "pigeon"})
+
+	assert.Equal(t, dto.BadChannel, (<-client.stats).Status)
+	assert.Equal(t, dto.DeadLettered, (<-client.stats).Status)
+
+	published := <-client.published
+	assert.Equal(t, conf.GetDeadLetterQueue(), published.queue)
+	assert.NotEqual(t, uuid.Nil, published.message.MessageUUID)
+	assert.Equal(t, 1, published.message.Attempt)
+}
+
+func TestChannelWorker_SendFallback(t *testing.T) {
+	conf := configMock{}
+	client := newBusMock()
+	worker := NewChannelWorker(context.Background(), conf, client, logger.NewZapLogger()).
+		SetResultQueue(statQueue)
+	worker.ReloadService("sms", &failingServiceMock{})
+
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	worker.now = func() time.Time { return now }
+
+	mail := dto.Message{MessageUUID: uuid.New(), Channel: "mail", DestinationAddress: "person@example.com", Attempt: 1}
+	telegram := dto.Message{MessageUUID: uuid.New(), Channel: "telegram", DestinationAddress: "42", Attempt: 1, FallbackAfter: 600}
+
+	// попытки sms исчерпаны - сообщение сразу уходит в следующий канал цепочки
+	worker.Send(context.Background(), dto.Message{
+		MessageUUID: uuid.New(),
+		Channel:     "sms",
+		Attempt:     conf.GetRetryMaxAttempts(),
+		Fallback:    []dto.Message{mail, telegram},
+	})
+
+	assert.Equal(t, dto.Failed, (<-client.stats).Status)
+	stat := <-client.stats
+	assert.Equal(t, dto.Rerouted, stat.Status)
+	assert.Equal(t, "sms", stat.Channel)
+	stat = <-client.stats
+	assert.Equal(t, dto.Dispatched, stat.Status)
+	assert.Equal(t, "mail", stat.Channel)
+
+	published := <-client.published
+	assert.Equal(t, "notify.mail", published.queue)
+	assert.Equal(t, mail.MessageUUID, published.message.MessageUUID)
+	assert.Equal(t, []dto.Message{telegram}, published.message.Fallback)
+	assert.Equal(t, now, *published.message.DispatchedAt)
+
+	// следующая попытка sms позже срока ожидания - резервный канал ждет срока в очереди повторов
+	dispatchedAt := now.Add(-500 * time.Millisecond)
+	mail.FallbackAfter = 1
+	worker.Send(context.Background(), dto.Message{
+		MessageUUID:  uuid.New(),
+		Channel:      "sms",
+		Attempt:      1,
+		DispatchedAt: &dispatchedAt,
+		Fallback:     []dto.Message{mail},
+	})
+
+	assert.Equal(t, dto.Failed, (<-client.stats).Status)
+	assert.Equal(t, dto.Rerouted, (<-client.stats).Status)
+	assert.Equal(t, dto.Dispatched, (<-client.stats).Status)
+
+	published = <-client.published
+	assert.Equal(t, conf.GetFailedWorksQueue(), published.queue)
+	assert.Equal(t, "mail", published.message.Channel)
+	assert.Equal(t, dispatchedAt.Add(time.Second), *published.message.RetryAt)
+	assert.Nil(t, published.message.Fallback)
+}
+
+// TestChannelWorker_SendFallbackNotRouted резервный канал без очереди в топологии не прерывает повторы,
+// после исчерпания попыток сообщение уходит в очередь недоставленных
+func TestChannelWorker_SendFallbackNotRouted(t *testing.T) {
+	conf := configMock{}
+	client := newBusMock()
+	worker := NewChannelWorker(context.Background(), conf, client, logger.NewZapLogger()).
+		SetResultQueue(statQueue)
+	worker.ReloadService("sms", &failingServiceMock{})
+
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	worker.now = func() time.Time { return now }
+
+	// срок ожидания резервного канала наступает раньше следующей попытки
+	push := dto.Message{MessageUUID: uuid.New(), Channel: "push", Attempt: 1, FallbackAfter: 1}
+	message := dto.Message{
+		MessageUUID:  uuid.New(),
+		Channel:      "sms",
+		Attempt:      1,
+		DispatchedAt: &now,
+		Fallback:     []dto.Message{push},
+	}
+
+	assert.NoError(t, worker.Send(context.Background(), message))
+	assert.Equal(t, dto.Failed, (<-client.stats).Status)
+
+	published := <-client.published
+	assert.Equal(t, conf.GetFailedWorksQueue(), published.queue)
+	assert.Equal(t, "sms", published.message.Channel)
+	assert.Equal(t, 2, published.message.Attempt)
+	assert.Equal(t, now.Add(conf.GetRetryBaseDelay()), *published.message.RetryAt)
+
+	// последняя попытка
+	published.message.Attempt = conf.GetRetryMaxAttempts()
+	assert.NoError(t, worker.Send(context.Background(), published.message))
+	assert.Equal(t, dto.Failed, (<-client.stats).Status)
+	assert.Equal(t, dto.DeadLettered, (<-client.stats).Status)
+
+	published = <-client.published
+	assert.Equal(t, conf.GetDeadLetterQueue(), published.queue)
+	assert.Equal(t, message.MessageUUID, published.message.MessageUUID)
+}
+
+// TestChannelWorker_SendFallbackDeadline сообщение, пролежавшее в очереди дольше срока
+// ожидания резервного канала, не отправляется в текущий канал
+func TestChannelWorker_SendFallbackDeadline(t *testing.T) {
+	conf := configMock{}
+	client := newBusMock()
+	service := newBlockingServiceMock()
+	close(service.release)
+	worker := NewChannelWorker(context.Background(), conf, client, logger.NewZapLogger()).
+		SetResultQueue(statQueue)
+	worker.ReloadService("sms", service)
+
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	worker.now = func() time.Time { return now }
+
+	dispatchedAt := now.Add(-2 * time.Minute)
+	mail := dto.Message{MessageUUID: uuid.New(), Channel: "mail", Attempt: 1, FallbackAfter: 60}
+
+	worker.Send(context.Background(), dto.Message{
+		MessageUUID:  uuid.New(),
+		Channel:      "sms",
+		Attempt:      1,
+		DispatchedAt: &dispatchedAt,
+		Fallback:     []dto.Message{mail},
+	})
+
+	assert.Equal(t, dto.Rerouted, (<-client.stats).Status)
+	assert.Equal(t, dto.Dispatched, (<-client.stats).Status)
+
+	published := <-client.published
+	assert.Equal(t, "notify.mail", published.queue)
+	assert.Equal(t, now, *published.message.DispatchedAt)
+
+	select {
+	case <-service.started:
+		t.Error("message sent after fallback deadline")
+	default:
+	}
+}
+
+// TestChannelWorker_SendFallbackLimited переход в резервный канал списывает лимит канала,
+// при исчерпании лимита сообщение ждет в очереди повторов
+func TestChannelWorker_SendFallbackLimited(t *testing.T) {
+	conf := configMock{}
+	client := newBusMock()
+	limiter := &limiterMock{wait: time.Minute}
+	worker := NewChannelWorker(context.Background(), conf, client, logger.NewZapLogger()).
+		SetResultQueue(statQueue).
+		SetLimiter(limiter)
+	worker.ReloadService("sms", &failingServiceMock{})
+
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	worker.now = func() time.Time { return now }
+
+	personUUID := uuid.New()
+	mail := dto.Message{MessageUUID: uuid.New(), PersonUUID: personUUID, Channel: "mail", Attempt: 1}
+
+	worker.Send(context.Background(), dto.Message{
+		MessageUUID: uuid.New(),
+		PersonUUID:  personUUID,
+		Channel:     "sms",
+		Attempt:     conf.GetRetryMaxAttempts(),
+		Fallback:    []dto.Message{mail},
+	})
+
+	assert.Equal(t, []string{"mail"}, limiter.channels)
+	assert.Equal(t, []uuid.UUID{personUUID}, limiter.persons)
+
+	published := <-client.published
+	assert.Equal(t, conf.GetFailedWorksQueue(), published.queue)
+	assert.Equal(t, "mail", published.message.Channel)
+	assert.Equal(t, now.Add(time.Minute), *published.message.RetryAt)
+}
+
+func TestChannelWorker_SendWebhook(t *testing.T) {
+	received := make(chan channelServices.WebhookPayload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload channelServices.WebhookPayload
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+	}))
+	defer server.Close()
+
+	client := newBusMock()
+	worker := NewChannelWorker(context.Background(), configMock{}, client, logger.NewZapLogger()).
+		SetResultQueue(statQueue)
+
+	message := dto.Message{
+		MessageUUID:        uuid.New(),
+		NotificationUUID:   uuid.New(),
+		Text:               "Hello",
+		Channel:            "webhook",
+		DestinationAddress: server.URL,
+		Attempt:            1,
+	}
+	worker.Send(context.Background(), message)
+
+	// webhook получает идентификаторы уведомления вместе с текстом
+	payload := <-received
+	assert.Equal(t, message.NotificationUUID, payload.NotificationUUID)
+	assert.Equal(t, "Hello", payload.Text)
+	assert.Equal(t, dto.Sent, (<-client.stats).Status)
+}
+
+func TestRetryWorker_Start(t *testing.T) {
+	conf := configMock{}
+	client := bus.NewMemoryBus(logger.NewZapLogger())
+	defer client.Stop()
+	worker := NewRetryWorker(conf, client, logger.NewZapLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go worker.Start(ctx)
+
+	past := time.Now().Add(-time.Second)
+	soon := time.Now().Add(50 * time.Millisecond)
+	later := time.Now().Add(time.Hour)
+	due := dto.Message{MessageUUID: uuid.New(), Channel: "sms", Priority: 5, Attempt: 2, RetryAt: &past, ExpiresAt: &later}
+	delayed := dto.Message{MessageUUID: uuid.New(), Channel: "sms", Attempt: 3, RetryAt: &soon}
+	waiting := dto.Message{MessageUUID: uuid.New(), Channel: "sms", Attempt: 3, RetryAt: &later}
+
+	for _, message := range []dto.Message{due, delayed, waiting} {
+		body, err := json.Marshal(message)
+		assert.NoError(t, err)
+		assert.NoError(t, client.Publish(conf.GetFailedWorksQueue(), body))
+	}
+
+	// наступивший повтор возвращается в очередь канала, остальные ждут у брокера подтвержденными
+	smsQueue := conf.GetTopology().Queue("sms")
+	assert.Eventually(t, func() bool {
+		return client.Len(smsQueue) == 1 && client.Delayed() == 2 && client.Unacked() == 0
+	}, time.Second, time.Millisecond)
+
+	msgs, err := client.Subscribe(smsQueue)
+	assert.NoError(t, err)
+	d := <-msgs
+	assert.NoError(t, d.Ack())
+	assert.Equal(t, uint8(5), d.Priority)
+
+	var published dto.Message
+	assert.NoError(t, json.Unmarshal(d.Body, &published))
+	assert.Equal(t, due.MessageUUID, published.MessageUUID)
+	assert.Equal(t, 2, published.Attempt)
+	assert.Nil(t, published.RetryAt)
+
+	// по истечении задержки сообщение возвращается воркеру и уходит в очередь канала
+	d = <-msgs
+	assert.NoError(t, d.Ack())
+	assert.NoError(t, json.Unmarshal(d.Body, &published))
+	assert.Equal(t, delayed.MessageUUID, published.MessageUUID)
+	assert.Nil(t, published.RetryAt)
+
+	assert.Equal(t, 1, client.Delayed())
+	assert.Equal(t, 0, client.Len(conf.GetFailedWorksQueue()))
+}
+
+// TestRetryWorker_Start_publishFailed сообщение, которое не принял брокер, возвращается
+// в очередь неудачных отправок и публикуется при следующей выдаче
+func TestRetryWorker_Start_publishFailed(t *testing.T) {
+	conf := configMock{}
+	memory := bus.NewMemoryBus(logger.NewZapLogger())
+	defer memory.Stop()
+	client := &flakyPublishBus{MemoryBus: memory, queue: conf.GetDeadLetterQueue(), failures: 1}
+	worker := NewRetryWorker(conf, client, logger.NewZapLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go worker.Start(ctx)
+
+	// сообщение канала без очереди уходит в очередь недоставленных
+	body, err := json.Marshal(dto.Message{MessageUUID: uuid.New(), Channel: "pigeon"})
+	assert.NoError(t, err)
+	assert.NoError(t, memory.Publish(conf.GetFailedWorksQueue(), body))
+
+	assert.Eventually(t, func() bool {
+		return memory.Len(conf.GetDeadLetterQueue()) == 1 && memory.Unacked() == 0
+	}, 3*time.Second, 5*time.Millisecond)
+	assert.Equal(t, 2, client.attempts())
+}
+
+// TestChannelWorker_Start_pool сообщения отправляются пулом в пределах prefetch и подтверждаются после отправки
+func TestChannelWorker_Start_pool(t *testing.T) {
+	conf := configMock{}
+	client := bus.NewMemoryBus(logger.NewZapLogger())
+	defer client.Stop()
+	sms := newBlockingServiceMock()
+
+	worker := NewChannelWorker(context.Background(), conf, client, logger.NewZapLogger())
+	worker.ReloadService("sms", sms)
+
+	assert.NoError(t, client.MigrateTopology(conf.GetTopology()))
+	for _, phone := range []string{"+1", "+2", "+3"} {
+		routeMessage(t, client, dto.Message{Channel: "sms", DestinationAddress: phone})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		worker.Start(ctx, conf.GetTopology().Queue("sms"), statQueue, conf.GetFailedWorksQueue())
+		close(stopped)
+	}()
+
+	// два отправителя заняты, третье сообщение ждет подтверждения одного из них
+	assert.ElementsMatch(t, []string{"+1", "+2"}, []string{<-sms.started, <-sms.started})
+	select {
+	case phone := <-sms.started:
+		t.Fatalf("concurrency exceeded by %v", phone)
+	case <-time.After(20 * time.Millisecond):
+	}
+	assert.Equal(t, 2, client.Unacked())
+
+	sms.release <- struct{}{}
+	assert.Equal(t, "+3", <-sms.started)
+	sms.release <- struct{}{}
+	sms.release <- struct{}{}
+
+	assert.Eventually(t, func() bool { return client.Unacked() == 0 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 0, client.Len(conf.GetTopology().Queue("sms")))
+
+	cancel()
+	<-stopped
+}
+
+// TestChannelWorker_Start_redelivery неподтвержденное при обрыве соединения сообщение выдается повторно,
+// битое сообщение отклоняется в очередь недоставленных
+func TestChannelWorker_Start_redelivery(t *testing.T) {
+	conf := configMock{}
+	client := bus.NewMemoryBus(logger.NewZapLogger())
+	defer client.Stop()
+	sms := newBlockingServiceMock()
+
+	worker := NewChannelWorker(context.Background(), conf, client, logger.NewZapLogger())
+	worker.ReloadService("sms", sms)
+
+	queue := conf.GetTopology().Queue("sms")
+	assert.NoError(t, client.MigrateTopology(conf.GetTopology()))
+	routeMessage(t, client, dto.Message{Channel: "sms", DestinationAddress: "+1"})
+	assert.NoError(t, client.PublishRouted("notify", "sms", []byte("{"), 0, 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		worker.Start(ctx, queue, statQueue, conf.GetFailedWorksQueue())
+		close(stopped)
+	}()
+
+	assert.Equal(t, "+1", <-sms.started)
+	assert.Eventually(t, func() bool { return client.Len(conf.GetDeadLetterQueue()) == 1 }, time.Second, 5*time.Millisecond)
+
+	// соединение обрывается во время отправки, подтверждение не доходит до брокера
+	client.Recover()
+	sms.release <- struct{}{}
+	<-stopped
+	assert.Equal(t, 1, client.Len(queue))
+
+	go worker.Start(ctx, queue, statQueue, conf.GetFailedWorksQueue())
+
+	assert.Equal(t, "+1", <-sms.started)
+	sms.release <- struct{}{}
+	assert.Eventually(t, func() bool { return client.Unacked() == 0 && client.Len(queue) == 0 }, time.Second, 5*time.Millisecond)
+}
+
+// TestChannelWorker_Start_handoff сообщение, которое не удалось передать в очередь повторов,
+// возвращается в очередь канала и подтверждается после успешной передачи
+func TestChannelWorker_Start_handoff(t *testing.T) {
+	conf := configMock{}
+	memory := bus.NewMemoryBus(logger.NewZapLogger())
+	defer memory.Stop()
+	client := &flakyPublishBus{MemoryBus: memory, queue: conf.GetFailedWorksQueue(), failures: 1}
+
+	worker := NewChannelWorker(context.Background(), conf, client, logger.NewZapLogger())
+	worker.ReloadService("sms", &failingServiceMock{})
+
+	queue := conf.GetTopology().Queue("sms")
+	assert.NoError(t, client.MigrateTopology(conf.GetTopology()))
+	routeMessage(t, memory, dto.Message{Channel: "sms", DestinationAddress: "+1"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go worker.Start(ctx, queue, statQueue, conf.GetFailedWorksQueue())
+
+	assert.Eventually(t, func() bool {
+		return memory.Len(conf.GetFailedWorksQueue()) == 1 && memory.Len(queue) == 0 && memory.Unacked() == 0
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 2, client.attempts())
+}
+
+// TestChannelWorker_Start_statFailed при ошибке публикации статистики сообщение возвращается в очередь,
+// повторная обработка публикует ту же запись статистики
+func TestChannelWorker_Start_statFailed(t *testing.T) {
+	conf := configMock{}
+	memory := bus.NewMemoryBus(logger.NewZapLogger())
+	defer memory.Stop()
+	client := &flakyPublishBus{MemoryBus: memory, queue: statQueue, failures: 1}
+
+	worker := NewChannelWorker(context.Background(), conf, client, logger.NewZapLogger())
+	worker.ReloadService("sms", &failingServiceMock{})
+
+	queue := conf.GetTopology().Queue("sms")
+	assert.NoError(t, client.MigrateTopology(conf.GetTopology()))
+	routeMessage(t, memory, dto.Message{Channel: "sms", DestinationAddress: "+1"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go worker.Start(ctx, queue, statQueue, conf.GetFailedWorksQueue())
+
+	assert.Eventually(t, func() bool {
+		return memory.Len(statQueue) == 1 && memory.Len(conf.GetFailedWorksQueue()) == 1 &&
+			memory.Len(queue) == 0 && memory.Unacked() == 0
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 2, client.attempts())
+}
+
+// TestChannelWorker_Start_drain начатая при остановке отправка завершается с действующим контекстом
+// и подтверждается до возврата из Start
+func TestChannelWorker_Start_drain(t *testing.T) {
+	conf := configMock{}
+	client := bus.NewMemoryBus(logger.NewZapLogger())
+	defer client.Stop()
+	sms := newBlockingServiceMock()
+
+	worker := NewChannelWorker(context.Background(), conf, client, logger.NewZapLogger())
+	worker.ReloadService("sms", sms)
+
+	queue := conf.GetTopology().Queue("sms")
+	assert.NoError(t, client.MigrateTopology(conf.GetTopology()))
+	routeMessage(t, client, dto.Message{Channel: "sms", DestinationAddress: "+1"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		worker.Start(ctx, queue, statQueue, conf.GetFailedWorksQueue())
+		close(stopped)
+	}()
+
+	assert.Equal(t, "+1", <-sms.started)
+	cancel()
+
+	select {
+	case <-stopped:
+		t.Fatal("worker stopped before in-flight send finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	sms.release <- struct{}{}
+	assert.NoError(t, <-sms.errs)
+	<-stopped
+
+	assert.Equal(t, 0, client.Unacked())
+	assert.Equal(t, 0, client.Len(queue))
+}
+
+// routeMessage публикация сообщения в очередь его канала
+func routeMessage(t *testing.T, client *bus.MemoryBus, message dto.Message) {
+	jsonMessage, err := json.Marshal(message)
+	assert.NoError(t, err)
+	assert.NoError(t, client.PublishRouted("notify", message.Channel, jsonMessage, message.Priority, 0))
+}
+
+// blockingServiceMock сервис отправки, каждая отправка ждет разрешения теста.
+// Состояние контекста после разрешения складывается в errs
+type blockingServiceMock struct {
+	started chan string
+	release chan struct{}
+	errs    chan error
+}
+
+func newBlockingServiceMock() *blockingServiceMock {
+	return &blockingServiceMock{started: make(chan string, 10), release: make(chan struct{}), errs: make(chan error, 10)}
+}
+
+func (b *blockingServiceMock) SendMessage(ctx context.Context, message string, destination string) error {
+	b.started <- destination
+	<-b.release
+	b.errs <- ctx.Err()
+	return nil
+}
+
+type configMock struct{}
+
+func (c configMock) GetMailSenderAddress() string {
+	return "test@mail.com"
+}
+
+func (c configMock) GetMailSMTPHost() string {
+	return "localhost:465"
+}
+
+func (c configMock) GetMailLogin() string {
+	return "test@mail.com"
+}
+
+func (c configMock) GetMailPassword() string {
+	return "test@mail.com"
+}
+
+func (c configMock) GetMailMessageTheme() string {
+	return "test@mail.com"
+}
+
+func (c configMock) IsMailTLSRequired() bool {
+	return false
+}
+
+func (c configMock) GetTwilioAccountSid() string {
+	return "test@mail.com"
+}
+
+func (c configMock) GetTwilioAuthToken() string {
+	return "test@mail.com"
+}
+
+func (c configMock) GetTwilioSenderPhone() string {
+	return "test@mail.com"
+}
+
+func (c configMock) GetTopology() dto.Topology {
+	return dto.Topology{Exchange: "notify", Channels: []string{"sms", "mail", "telegram"}, MaxPriority: 10, DeadLetterQueue: "dead"}
+}
+
+func (c configMock) GetFailedWorksQueue() string {
+	return "failed"
+}
+
+func (c configMock) GetDeadLetterQueue() string {
+	return "dead"
+}
+
+func (c configMock) GetWorkerConcurrency() int {
+	return 2
+}
+
+func (c configMock) GetWorkerPrefetch() int {
+	return 2
+}
+
+func (c configMock) GetWorkerDrainTimeout() time.Duration {
+	return time.Second
+}
+
+func (c configMock) GetRetryMaxAttempts() int {
+	return 3
+}
+
+func (c configMock) GetRetryBaseDelay() time.Duration {
+	return time.Second
+}
+
+func (c configMock) GetRetryMaxDelay() time.Duration {
+	return time.Minute
+}
+
+func (c configMock) GetRetryJitter() float64 {
+	return 0
+}
+
+func (c configMock) GetWebhookSecret() string {
+	return "secret"
+}
+
+func (c configMock) GetWebhookTimeout() time.Duration {
+	return time.Second
+}
+
+func (c configMock) GetTelegramBotToken() string {
+	return ""
+}
+
+func (c configMock) GetTelegramBaseURL() string {
+	return ""
+}
+
+func (c configMock) GetSlackBotToken() string {
+	return ""
+}
+
+func (c configMock) GetSlackBaseURL() string {
+	return ""
+}
+
+// limiterMock ограничитель частоты, запоминает списания и возвращает фиксированное ожидание
+type limiterMock struct {
+	wait     time.Duration
+	channels []string
+	persons  []uuid.UUID
+}
+
+func (l *limiterMock) Charge(channel string, personUUID uuid.UUID) time.Duration {
+	l.channels = append(l.channels, channel)
+	l.persons = append(l.persons, personUUID)
+	return l.wait
+}
+
+// flakyPublishBus шина в памяти, первые failures публикаций в очередь queue завершаются ошибкой
+type flakyPublishBus struct {
+	*bus.MemoryBus
+	mu       sync.Mutex
+	queue    string
+	failures int
+	calls    int
+}
+
+func (b *flakyPublishBus) Publish(queue string, msgBody []byte) error {
+	if queue == b.queue {
+		b.mu.Lock()
+		b.calls++
+		fail := b.calls <= b.failures
+		b.mu.Unlock()
+
+		if fail {
+			return errors.New("broker unavailable")
+		}
+	}
+
+	return b.MemoryBus.Publish(queue, msgBody)
+}
+
+// attempts количество публикаций в очередь queue
+func (b *flakyPublishBus) attempts() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.calls
+}
+
+type failingServiceMock struct{}
+
+func (f *failingServiceMock) SendMessage(ctx context.Context, message string, destination string) error {
+	return errors.New("provider unavailable")
+}
+
+// busMock заглушка шины сообщений, публикации складываются в канал published,
+// статистика из очереди statQueue - в канал stats, потребителю сообщения передаются через deliver
+type busMock struct {
+	published  chan publishedMessage
+	stats      chan dto.Stat
+	deliveries chan dto.Delivery
+}
+
+// publishedMessage публикация, для публикации в exchange queue - очередь канала по ключу маршрутизации
+type publishedMessage struct {
+	queue      string
+	priority   uint8
+	expiration time.Duration
+	message    dto.Message
+}
+
+func newBusMock() *busMock {
+	bm := busMock{
+		published:  make(chan publishedMessage, 10),
+		stats:      make(chan dto.Stat, 10),
+		deliveries: make(chan dto.Delivery),
+	}
+	return &bm
+}
+
+func (b *busMock) deliver(t *testing.T, message dto.Message) {
+	body, err := json.Marshal(message)
+	assert.NoError(t, err)
+
+	b.deliveries <- dto.Delivery{Body: body}
+}
+
+func (b *busMock) Connect() error {
+	return nil
+}
+
+func (b *busMock) Qos(prefetchCount int) error {
+	return nil
+}
+
+func (b *busMock) MigrateDurableQueues(queues ...string) {}
+
+func (b *busMock) MigrateTopology(topology dto.Topology) error {
+	return nil
+}
+
+func (b *busMock) Subscribe(queue string) (<-chan dto.Delivery, error) {
+	return b.deliveries, nil
+}
+
+func (b *busMock) Publish(queue string, msgBody []byte) error {
+	if queue == statQueue {
+		var stat dto.Stat
+		if err := json.Unmarshal(msgBody, &stat); err != nil {
+			return err
+		}
+
+		b.stats <- stat
+		return nil
+	}
+
+	var message dto.Message
+	if err := json.Unmarshal(msgBody, &message); err != nil {
+		return err
+	}
+
+	b.published <- publishedMessage{queue: queue, message: message}
+	return nil
+}
+
+func (b *busMock) PublishDelayed(queue string, msgBody []byte, delay time.Duration) error {
+	return b.Publish(queue, msgBody)
+}
+
+func (b *busMock) PublishRouted(exchange string, routingKey string, msgBody []byte, priority uint8, expiration time.Duration) error {
+	var message dto.Message
+	if err := json.Unmarshal(msgBody, &message); err != nil {
+		return err
+	}
+
+	b.published <- publishedMessage{queue: exchange + "." + routingKey, priority: priority, expiration: expiration, message: message}
+	return nil
+}
+
+func (b *busMock) Stop() {}
@@ -0,0 +1,159 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/interfaces"
+	"github.com/atrian/go-notify-customer/pkg/bus"
+)
+
+var (
+	_ interfaces.BaseService = (*RetryWorker)(nil)
+)
+
+// retryWorkerConfig интерфейс конфигурации воркера повторов
+type retryWorkerConfig interface {
+	GetTopology() dto.Topology
+	GetFailedWorksQueue() string
+}
+
+// retryPublishDelay пауза перед возвратом в очередь сообщения, которое не принял брокер
+const retryPublishDelay = time.Second
+
+// RetryWorker потребляет очередь неудачных отправок GetFailedWorksQueue и по наступлении
+// dto.Message.RetryAt возвращает сообщение в очередь его канала топологии GetTopology.
+// Ожидание до RetryAt выполняет брокер: не дождавшееся повтора сообщение публикуется обратно
+// в очередь неудачных отправок с задержкой PublishDelayed и подтверждается. Воркер не хранит
+// ожидающие сообщения, остановка и сбой процесса их не теряют
+type RetryWorker struct {
+	config retryWorkerConfig
+	client interfaces.Bus
+	logger interfaces.Logger
+	now    func() time.Time
+}
+
+func NewRetryWorker(conf retryWorkerConfig, client interfaces.Bus, logger interfaces.Logger) *RetryWorker {
+	w := RetryWorker{
+		config: conf,
+		client: client,
+		logger: logger,
+		now:    time.Now,
+	}
+
+	return &w
+}
+
+// Start потребляет очередь неудачных отправок до отмены контекста
+func (r *RetryWorker) Start(ctx context.Context) {
+	r.client.MigrateDurableQueues(r.config.GetFailedWorksQueue())
+	if err := r.client.MigrateTopology(r.config.GetTopology()); err != nil {
+		r.logger.Error("RetryWorker can't migrate topology", err)
+	}
+
+	msgs, err := r.client.Subscribe(r.config.GetFailedWorksQueue())
+	if err != nil {
+		r.logger.Error("RetryWorker can't consume failed queue", err)
+	}
+
+	for {
+		select {
+		case d, ok := <-msgs:
+			if !ok {
+				return
+			}
+			r.handle(ctx, d)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// handle возвращает наступивший повтор в очередь канала, остальные сообщения откладывает до RetryAt.
+// Сообщение подтверждается после публикации, при ошибке брокера возвращается в очередь после паузы
+func (r *RetryWorker) handle(ctx context.Context, d dto.Delivery) {
+	var message dto.Message
+	if jsonErr := json.Unmarshal(d.Body, &message); jsonErr != nil {
+		r.logger.Error("RetryWorker JSON unmarshall err", jsonErr)
+		r.settle(d.Nack(false))
+		return
+	}
+
+	var err error
+	if wait := r.wait(message); wait > 0 {
+		err = r.client.PublishDelayed(r.config.GetFailedWorksQueue(), d.Body, wait)
+	} else {
+		message.RetryAt = nil
+		r.logger.Info(fmt.Sprintf("Retry messageUUID:%v attempt:%v", message.MessageUUID, message.Attempt))
+		err = r.route(message)
+	}
+
+	if err != nil {
+		r.logger.Error(fmt.Sprintf("RetryWorker publish failed messageUUID:%v", message.MessageUUID), err)
+		// пауза не дает повторной выдаче нагружать недоступного брокера
+		select {
+		case <-time.After(retryPublishDelay):
+		case <-ctx.Done():
+		}
+		r.settle(d.Nack(true))
+		return
+	}
+
+	r.settle(d.Ack())
+}
+
+// wait время до следующей попытки, 0 - попытка наступила
+func (r *RetryWorker) wait(message dto.Message) time.Duration {
+	if message.RetryAt == nil {
+		return 0
+	}
+
+	if wait := message.RetryAt.Sub(r.now()); wait > 0 {
+		return wait
+	}
+
+	return 0
+}
+
+// settle журналирование ошибки подтверждения сообщения
+func (r *RetryWorker) settle(err error) {
+	if err != nil {
+		r.logger.Error("RetryWorker delivery settle err", err)
+	}
+}
+
+// Stop остановка воркера
+func (r *RetryWorker) Stop() {
+	r.logger.Info("Retry worker stopped")
+}
+
+func (r *RetryWorker) publish(queue string, message dto.Message) error {
+	jsonMessage, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Publish(queue, jsonMessage)
+}
+
+// route публикует сообщение в очередь его канала с приоритетом и сроком актуальности.
+// Сообщение канала без очереди уходит в очередь недоставленных
+func (r *RetryWorker) route(message dto.Message) error {
+	topology := r.config.GetTopology()
+	if !topology.Routes(message.Channel) {
+		r.logger.Warning(fmt.Sprintf("RetryWorker no queue for channel %v, messageUUID:%v", message.Channel, message.MessageUUID))
+		message.LastError = fmt.Sprintf("no queue for channel %v", message.Channel)
+		return r.publish(topology.DeadLetterQueue, message)
+	}
+
+	jsonMessage, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	return r.client.PublishRouted(topology.Exchange, message.Channel, jsonMessage,
+		message.Priority, bus.Expiration(message.ExpiresAt, r.now()))
+}
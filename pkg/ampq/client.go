@@ -1,133 +1,683 @@
 package ampq
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
 	"github.com/streadway/amqp"
 
+	"github.com/atrian/go-notify-customer/internal/dto"
 	"github.com/atrian/go-notify-customer/internal/interfaces"
 )
 
+var (
+	_ interfaces.Bus = (*Client)(nil)
+
+	// ErrNotConnected нет соединения с брокером, клиент ожидает переподключения
+	ErrNotConnected = errors.New("ampq: not connected")
+	// ErrNacked брокер отказался принять опубликованное сообщение
+	ErrNacked = errors.New("ampq: message nacked by broker")
+	// ErrConfirmTimeout брокер не подтвердил публикацию за время ожидания
+	ErrConfirmTimeout = errors.New("ampq: publish confirm timeout")
+)
+
+const (
+	defaultReconnectBaseDelay = time.Second
+	defaultReconnectMaxDelay  = 30 * time.Second
+	defaultConfirmTimeout     = 5 * time.Second
+
+	// minDelayStep, maxDelayStep ступени задержки PublishDelayed, степени двойки секунд
+	minDelayStep = time.Second
+	maxDelayStep = 1 << 16 * time.Second
+)
+
+// Client клиент RabbitMQ с подтверждением публикаций и автоматическим переподключением.
+// Supervisor следит за NotifyClose канала, после обрыва переподключается с экспоненциальной задержкой,
+// заново объявляет очереди и топологии, объявленные через клиент, и возобновляет потребителей Consume
 type Client struct {
-	connection *amqp.Connection
-	channel    *amqp.Channel
-	logger     interfaces.Logger
-	dsn        string
+	mu          sync.RWMutex
+	reconnectMu sync.Mutex // reconnectMu одно переподключение одновременно
+	publishMu   sync.Mutex // publishMu публикация и ожидание подтверждения идут по одной, подтверждения приходят по порядку
+	connection  *amqp.Connection
+	channel     *amqp.Channel
+	confirms    chan amqp.Confirmation
+	deliveryTag uint64           // deliveryTag номер последней публикации в текущем канале
+	closed      chan *amqp.Error // closed уведомление о закрытии текущего канала
+	connected   chan struct{}    // connected закрывается при каждом успешном подключении
+	done        chan struct{}    // done закрывается в Stop
+	stopOnce    sync.Once
+	supervised  bool
+
+	queues     []string
+	topologies []dto.Topology
+	prefetch   int
+
+	reconnectBaseDelay time.Duration
+	reconnectMaxDelay  time.Duration
+	confirmTimeout     time.Duration
+
+	logger interfaces.Logger
+	dsn    string
 }
 
 func New(dsn string, logger interfaces.Logger) *Client {
 	client := Client{
-		logger: logger,
-		dsn:    dsn,
+		connected:          make(chan struct{}),
+		done:               make(chan struct{}),
+		reconnectBaseDelay: defaultReconnectBaseDelay,
+		reconnectMaxDelay:  defaultReconnectMaxDelay,
+		confirmTimeout:     defaultConfirmTimeout,
+		logger:             logger,
+		dsn:                dsn,
 	}
 
 	return &client
 }
 
 func NewWithConnection(dsn string, logger interfaces.Logger) *Client {
-	client := Client{
-		logger: logger,
-		dsn:    dsn,
-	}
+	client := New(dsn, logger)
 
-	err := client.Connect(dsn)
+	err := client.Connect()
 	if err != nil {
 		logger.Error("Can't connect AMPQ", err)
 	}
 
-	return &client
+	return client
 }
 
-func (c *Client) Connect(dsn string) error {
-	conn, err := amqp.Dial(dsn)
-	if err != nil {
-		c.logger.Error("Can't connect AMPQ", err)
+// SetReconnectBackoff задержки между попытками переподключения, задержка удваивается до max
+func (c *Client) SetReconnectBackoff(base time.Duration, max time.Duration) *Client {
+	c.reconnectBaseDelay = base
+	c.reconnectMaxDelay = max
+	return c
+}
+
+// SetConfirmTimeout время ожидания подтверждения публикации брокером
+func (c *Client) SetConfirmTimeout(timeout time.Duration) *Client {
+	c.confirmTimeout = timeout
+	return c
+}
+
+// Connect подключение к RabbitMQ и запуск supervisor соединения
+func (c *Client) Connect() error {
+	if err := c.dial(); err != nil {
 		return err
 	}
-	c.connection = conn
 
-	channel, err := conn.Channel()
-	if err != nil {
-		c.logger.Error("Can't create AMPQ channel", err)
-		return err
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.supervised {
+		c.supervised = true
+		go c.supervise()
 	}
-	c.channel = channel
 
 	return nil
 }
 
-// Reconnect переподключение к RabbitMQ
+// Reconnect переподключение к RabbitMQ с повторным объявлением очередей и топологий
 func (c *Client) Reconnect() error {
-	conn, err := amqp.Dial(c.dsn)
+	_, err := c.reconnect()
+	return err
+}
+
+// reconnect закрывает старое соединение, подключается заново и восстанавливает объявления.
+// dialed сообщает, что соединение установлено, даже если объявления восстановить не удалось
+func (c *Client) reconnect() (dialed bool, err error) {
+	c.reconnectMu.Lock()
+	defer c.reconnectMu.Unlock()
+
+	c.mu.RLock()
+	connection := c.connection
+	c.mu.RUnlock()
+
+	if connection != nil && !connection.IsClosed() {
+		_ = connection.Close()
+	}
+
+	if err = c.dial(); err != nil {
+		return false, err
+	}
+
+	c.mu.RLock()
+	queues := append([]string(nil), c.queues...)
+	topologies := append([]dto.Topology(nil), c.topologies...)
+	c.mu.RUnlock()
+
+	c.declareQueues(queues...)
+	for _, topology := range topologies {
+		if err = c.declareTopology(topology); err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// dial открывает соединение и канал в режиме подтверждения публикаций.
+// Закрытие соединения закрывает и канал, поэтому supervisor следит только за каналом
+func (c *Client) dial() error {
+	c.mu.RLock()
+	dsn := c.dsn
+	c.mu.RUnlock()
+
+	conn, err := amqp.Dial(dsn)
 	if err != nil {
 		c.logger.Error("Can't connect AMPQ", err)
 		return err
 	}
-	c.connection = conn
 
 	channel, err := conn.Channel()
 	if err != nil {
 		c.logger.Error("Can't create AMPQ channel", err)
+		_ = conn.Close()
+		return err
+	}
+
+	if err = channel.Confirm(false); err != nil {
+		c.logger.Error("Can't enable AMPQ publisher confirms", err)
+		_ = conn.Close()
+		return err
+	}
+
+	c.mu.RLock()
+	prefetch := c.prefetch
+	c.mu.RUnlock()
+
+	if err = channel.Qos(prefetch, 0, false); err != nil {
+		c.logger.Error("Can't set AMPQ Qos", err)
+		_ = conn.Close()
 		return err
 	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-c.done:
+		// клиент остановлен во время подключения
+		_ = conn.Close()
+		return ErrNotConnected
+	default:
+	}
+
+	c.connection = conn
 	c.channel = channel
+	// буфер на одно подтверждение, публикации идут по одной под publishMu
+	c.confirms = channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	c.deliveryTag = 0
+	c.closed = channel.NotifyClose(make(chan *amqp.Error, 1))
+	close(c.connected)
+	c.connected = make(chan struct{})
 
 	return nil
 }
 
+// supervise ждет обрыва канала и переподключается с экспоненциальной задержкой.
+// Штатное закрытие в Stop завершает supervisor, замена соединения через Reconnect - нет
+func (c *Client) supervise() {
+	for {
+		c.mu.RLock()
+		closed := c.closed
+		c.mu.RUnlock()
+
+		select {
+		case <-c.done:
+			return
+		case amqpErr := <-closed:
+			select {
+			case <-c.done:
+				return
+			default:
+			}
+
+			if c.replaced(closed) {
+				continue
+			}
+
+			if amqpErr != nil {
+				c.logger.Error("AMPQ connection lost, reconnecting", amqpErr)
+			} else {
+				c.logger.Error("AMPQ channel closed, reconnecting", ErrNotConnected)
+			}
+		}
+
+		c.reconnectWithBackoff()
+	}
+}
+
+// replaced канал closed уже заменен новым подключением через Reconnect
+func (c *Client) replaced(closed <-chan *amqp.Error) bool {
+	// Reconnect держит reconnectMu до конца подключения
+	c.reconnectMu.Lock()
+	defer c.reconnectMu.Unlock()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.closed != closed
+}
+
+// reconnectWithBackoff попытки переподключения с удвоением задержки до reconnectMaxDelay
+func (c *Client) reconnectWithBackoff() {
+	delay := c.reconnectBaseDelay
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-time.After(delay):
+		}
+
+		dialed, err := c.reconnect()
+		if err == nil {
+			c.logger.Info("AMPQ reconnected")
+			return
+		}
+		if dialed {
+			// соединение есть, но объявления не восстановлены - ждем следующего обрыва
+			c.logger.Error("AMPQ redeclare after reconnect failed", err)
+			return
+		}
+
+		delay *= 2
+		if delay > c.reconnectMaxDelay {
+			delay = c.reconnectMaxDelay
+		}
+	}
+}
+
 func (c *Client) Channel() *amqp.Channel {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	return c.channel
 }
 
-// MigrateDurableQueues создает Durable очереди в RabbitMQ
+// Qos количество неподтвержденных сообщений, которое брокер выдает каждому потребителю, 0 - без ограничения.
+// Действует на потребителей, созданных после вызова, и сохраняется после переподключения
+func (c *Client) Qos(prefetchCount int) error {
+	c.mu.Lock()
+	c.prefetch = prefetchCount
+	channel := c.channel
+	c.mu.Unlock()
+
+	if channel == nil {
+		return nil
+	}
+
+	if err := channel.Qos(prefetchCount, 0, false); err != nil {
+		c.logger.Error("Can't set AMPQ Qos", err)
+		return err
+	}
+
+	return nil
+}
+
+// MigrateDurableQueues создает Durable очереди в RabbitMQ.
+// Очереди объявляются заново после переподключения
 func (c *Client) MigrateDurableQueues(queues ...string) {
+	c.mu.Lock()
+	for _, queue := range queues {
+		if queue != "" && !contains(c.queues, queue) {
+			c.queues = append(c.queues, queue)
+		}
+	}
+	c.mu.Unlock()
+
+	c.declareQueues(queues...)
+}
+
+// declareQueues объявление durable очередей без запоминания
+func (c *Client) declareQueues(queues ...string) {
+	channel := c.Channel()
+	if channel == nil {
+		c.logger.Error("Can't declare queue", ErrNotConnected)
+		return
+	}
+
 	for _, queue := range queues {
 		if queue == "" {
 			continue
 		}
 
-		_, err := c.Channel().QueueDeclare(queue, true, false, false, false, nil)
+		_, err := channel.QueueDeclare(queue, true, false, false, false, nil)
 		if err != nil {
 			c.logger.Error("Can't declare queue", err)
 		}
 	}
 }
 
+// MigrateTopology создает durable topic exchange и очереди каналов доставки с приоритетом
+// и временем жизни сообщений. Сообщения с истекшим временем жизни попадают в DeadLetterQueue.
+// Аргументы очередей должны совпадать у всех участников шины, иначе брокер отклонит объявление.
+// Топология объявляется заново после переподключения
+func (c *Client) MigrateTopology(topology dto.Topology) error {
+	c.mu.Lock()
+	known := false
+	for _, t := range c.topologies {
+		if t.Exchange == topology.Exchange {
+			known = true
+			break
+		}
+	}
+	if !known {
+		c.topologies = append(c.topologies, topology)
+	}
+	c.mu.Unlock()
+
+	return c.declareTopology(topology)
+}
+
+// declareTopology объявление exchange и очередей каналов без запоминания
+func (c *Client) declareTopology(topology dto.Topology) error {
+	ch := c.Channel()
+	if ch == nil {
+		c.logger.Error("Can't declare exchange", ErrNotConnected)
+		return ErrNotConnected
+	}
+
+	err := ch.ExchangeDeclare(topology.Exchange, amqp.ExchangeTopic, true, false, false, false, nil)
+	if err != nil {
+		c.logger.Error("Can't declare exchange", err)
+		return err
+	}
+
+	args := amqp.Table{
+		"x-max-priority": topology.MaxPriority,
+	}
+	if topology.MessageTTL > 0 {
+		args["x-message-ttl"] = topology.MessageTTL.Milliseconds()
+	}
+	if topology.DeadLetterQueue != "" {
+		c.declareQueues(topology.DeadLetterQueue)
+		args["x-dead-letter-exchange"] = ""
+		args["x-dead-letter-routing-key"] = topology.DeadLetterQueue
+	}
+
+	for _, channel := range topology.Channels {
+		queue := topology.Queue(channel)
+
+		if _, err = ch.QueueDeclare(queue, true, false, false, false, args); err != nil {
+			c.logger.Error("Can't declare queue", err)
+			return err
+		}
+
+		if err = ch.QueueBind(queue, channel, topology.Exchange, false, nil); err != nil {
+			c.logger.Error("Can't bind queue", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Consume потребление очереди с ручным подтверждением: каждое сообщение нужно подтвердить
+// через Ack или вернуть через Nack/Reject, неподтвержденные сообщения брокер выдаст повторно.
+// Возвращаемый канал переживает переподключения: после обрыва потребитель возобновляется
+// на новом соединении, канал закрывается только в Stop. Подтверждения сообщений,
+// полученных до обрыва, завершаются ошибкой, брокер уже вернул их в очередь
 func (c *Client) Consume(queue string) (<-chan amqp.Delivery, error) {
-	consume, err := c.Channel().Consume(queue, "", true, false, false, false, nil)
+	deliveries, err := c.consume(queue)
 	if err != nil {
 		c.logger.Error("AMPQ consume error", err)
+		return nil, err
+	}
+
+	out := make(chan amqp.Delivery)
+	go c.resume(queue, deliveries, out)
+
+	return out, nil
+}
+
+// consume потребитель очереди на текущем канале
+func (c *Client) consume(queue string) (<-chan amqp.Delivery, error) {
+	channel := c.Channel()
+	if channel == nil {
+		return nil, ErrNotConnected
+	}
+
+	return channel.Consume(queue, "", false, false, false, false, nil)
+}
+
+// resume пересылает сообщения потребителя в out и заново подписывается на очередь после переподключения
+func (c *Client) resume(queue string, deliveries <-chan amqp.Delivery, out chan<- amqp.Delivery) {
+	defer close(out)
+
+	for {
+		for d := range deliveries {
+			select {
+			case out <- d:
+			case <-c.done:
+				return
+			}
+		}
+
+		// канал потребителя закрыт - соединение оборвано или клиент остановлен
+		for {
+			c.mu.RLock()
+			connected := c.connected
+			c.mu.RUnlock()
+
+			var err error
+			if deliveries, err = c.consume(queue); err == nil {
+				c.logger.Info(fmt.Sprintf("AMPQ consumer resumed for queue %v", queue))
+				break
+			}
+
+			select {
+			case <-c.done:
+				return
+			case <-connected:
+			}
+		}
+	}
+}
+
+// Subscribe потребление очереди в формате шины dto.Delivery, см. Consume
+func (c *Client) Subscribe(queue string) (<-chan dto.Delivery, error) {
+	deliveries, err := c.Consume(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan dto.Delivery)
+	go func() {
+		defer close(out)
+		for d := range deliveries {
+			select {
+			case out <- toDelivery(d):
+			case <-c.done:
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// toDelivery сообщение RabbitMQ в формате шины
+func toDelivery(d amqp.Delivery) dto.Delivery {
+	return dto.Delivery{
+		Body:         d.Body,
+		Priority:     d.Priority,
+		Redelivered:  d.Redelivered,
+		DeathReason:  deathReason(d),
+		Acknowledger: acknowledger{delivery: d},
+	}
+}
+
+// acknowledger подтверждение сообщения в канале, из которого оно получено
+type acknowledger struct {
+	delivery amqp.Delivery
+}
+
+func (a acknowledger) Ack() error { return a.delivery.Ack(false) }
+
+func (a acknowledger) Nack(requeue bool) error { return a.delivery.Nack(false, requeue) }
+
+// deathReason причина, по которой брокер переместил сообщение в очередь недоставленных,
+// например истекло время жизни в очереди канала. Пустая строка для сообщений воркеров
+func deathReason(d amqp.Delivery) string {
+	deaths, ok := d.Headers["x-death"].([]interface{})
+	if !ok || len(deaths) == 0 {
+		return ""
+	}
+
+	death, ok := deaths[0].(amqp.Table)
+	if !ok {
+		return ""
 	}
 
-	return consume, nil
+	return fmt.Sprintf("%v in queue %v", death["reason"], death["queue"])
 }
 
+// Publish публикация в очередь через exchange по умолчанию. Сообщение сохраняется брокером на диск
+// и переживает его перезапуск. Возвращает nil только после подтверждения брокером
 func (c *Client) Publish(queue string, msgBody []byte) error {
-	err := c.Channel().Publish("", queue, false, false,
-		amqp.Publishing{
-			Headers:     nil,
-			ContentType: "text/json",
-			Body:        msgBody,
-		})
+	return c.publish("", queue, amqp.Publishing{
+		Headers:      nil,
+		ContentType:  "text/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         msgBody,
+	})
+}
+
+// PublishDelayed публикация в очередь queue через очередь ожидания queue.delay.<секунды>.
+// Очередь ожидания не читается, по истечении времени жизни сообщения брокер переводит его в queue.
+// Задержка округляется вниз до ступени DelayStep, потребитель откладывает остаток повторно.
+// Возвращает nil только после подтверждения брокером
+func (c *Client) PublishDelayed(queue string, msgBody []byte, delay time.Duration) error {
+	step := DelayStep(delay)
+	delayQueue := fmt.Sprintf("%v.delay.%d", queue, step/time.Second)
 
+	channel := c.Channel()
+	if channel == nil {
+		c.logger.Error("AMPQ publish error", ErrNotConnected)
+		return ErrNotConnected
+	}
+
+	_, err := channel.QueueDeclare(delayQueue, true, false, false, false, amqp.Table{
+		"x-message-ttl":             step.Milliseconds(),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": queue,
+	})
 	if err != nil {
+		c.logger.Error("Can't declare delay queue", err)
+		return err
+	}
+
+	return c.Publish(delayQueue, msgBody)
+}
+
+// DelayStep ступень очереди ожидания для задержки delay: наибольшая степень двойки секунд,
+// не превышающая delay, в пределах от minDelayStep до maxDelayStep
+func DelayStep(delay time.Duration) time.Duration {
+	step := minDelayStep
+	for step*2 <= delay && step < maxDelayStep {
+		step *= 2
+	}
+
+	return step
+}
+
+// PublishRouted публикация в exchange по ключу маршрутизации с приоритетом сообщения.
+// Expiration задает время жизни сообщения, 0 - время жизни очереди.
+// Возвращает nil только после подтверждения брокером
+func (c *Client) PublishRouted(exchange string, routingKey string, msgBody []byte, priority uint8, expiration time.Duration) error {
+	publishing := amqp.Publishing{
+		ContentType:  "text/json",
+		DeliveryMode: amqp.Persistent,
+		Priority:     priority,
+		Body:         msgBody,
+	}
+	if expiration > 0 {
+		publishing.Expiration = strconv.FormatInt(expiration.Milliseconds(), 10)
+	}
+
+	return c.publish(exchange, routingKey, publishing)
+}
+
+// publish публикация и ожидание подтверждения брокера
+func (c *Client) publish(exchange string, key string, publishing amqp.Publishing) error {
+	c.publishMu.Lock()
+	defer c.publishMu.Unlock()
+
+	c.mu.Lock()
+	channel, confirms := c.channel, c.confirms
+	c.deliveryTag++
+	tag := c.deliveryTag
+	c.mu.Unlock()
+
+	if channel == nil {
+		c.logger.Error("AMPQ publish error", ErrNotConnected)
+		return ErrNotConnected
+	}
+
+	if err := channel.Publish(exchange, key, false, false, publishing); err != nil {
 		c.logger.Error("AMPQ publish error", err)
 		return err
 	}
 
-	return nil
+	timeout := time.NewTimer(c.confirmTimeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case confirm, ok := <-confirms:
+			if !ok {
+				// канал закрыт до подтверждения, судьба сообщения неизвестна
+				c.logger.Error("AMPQ publish error", ErrNotConnected)
+				return ErrNotConnected
+			}
+			if confirm.DeliveryTag < tag {
+				// запоздавшее подтверждение публикации, не дождавшейся его по таймауту
+				continue
+			}
+			if !confirm.Ack {
+				c.logger.Error("AMPQ publish error", ErrNacked)
+				return ErrNacked
+			}
+
+			return nil
+		case <-timeout.C:
+			c.logger.Error("AMPQ publish error", ErrConfirmTimeout)
+			return ErrConfirmTimeout
+		}
+	}
 }
 
+// Stop остановка supervisor, потребителей и закрытие соединения
 func (c *Client) Stop() {
-	if c.channel != nil {
-		chErr := c.channel.Close()
+	c.stopOnce.Do(func() { close(c.done) })
+
+	c.mu.RLock()
+	channel, connection := c.channel, c.connection
+	c.mu.RUnlock()
+
+	if channel != nil {
+		chErr := channel.Close()
 		if chErr != nil {
 			c.logger.Error("Channel close error", chErr)
 		}
 	}
 
-	if c.connection != nil {
-		cErr := c.connection.Close()
+	if connection != nil {
+		cErr := connection.Close()
 		if cErr != nil {
 			c.logger.Error("Connection close error", cErr)
 		}
 	}
 }
+
+// contains проверка наличия строки в срезе
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+
+	return false
+}
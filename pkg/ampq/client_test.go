@@ -0,0 +1,54 @@
+package ampq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+// TestDeathReason причина перевода в очередь недоставленных берется из заголовка x-death
+func TestDeathReason(t *testing.T) {
+	delivery := toDelivery(amqp.Delivery{
+		Headers: amqp.Table{"x-death": []interface{}{amqp.Table{"reason": "expired", "queue": "notify.sms"}}},
+		Body:    []byte("message"),
+	})
+
+	assert.Equal(t, "expired in queue notify.sms", delivery.DeathReason)
+	assert.Equal(t, "", toDelivery(amqp.Delivery{}).DeathReason)
+}
+
+// TestClient_notConnected без соединения публикация не считается успешной, потребитель не создается
+func TestClient_notConnected(t *testing.T) {
+	client := New("", logger.NewZapLogger())
+
+	assert.ErrorIs(t, client.Publish("queue", []byte("message")), ErrNotConnected)
+	assert.ErrorIs(t, client.PublishRouted("notify", "sms", []byte("message"), 1, 0), ErrNotConnected)
+	assert.ErrorIs(t, client.PublishDelayed("queue", []byte("message"), time.Minute), ErrNotConnected)
+	assert.ErrorIs(t, client.MigrateTopology(dto.Topology{Exchange: "notify", Channels: []string{"sms"}, MaxPriority: 10}), ErrNotConnected)
+
+	msgs, err := client.Subscribe("queue")
+	assert.ErrorIs(t, err, ErrNotConnected)
+	assert.Nil(t, msgs)
+
+	// очереди и топология запоминаются для объявления после подключения
+	client.MigrateDurableQueues("queue", "queue", "")
+	assert.Equal(t, []string{"queue"}, client.queues)
+	assert.Len(t, client.topologies, 1)
+
+	client.Stop()
+}
+
+// TestDelayStep задержка округляется вниз до степени двойки секунд в пределах ступеней
+func TestDelayStep(t *testing.T) {
+	assert.Equal(t, time.Second, DelayStep(0))
+	assert.Equal(t, time.Second, DelayStep(1500*time.Millisecond))
+	assert.Equal(t, 2*time.Second, DelayStep(2*time.Second))
+	assert.Equal(t, 32*time.Second, DelayStep(time.Minute))
+	assert.Equal(t, 2048*time.Second, DelayStep(time.Hour))
+	assert.Equal(t, maxDelayStep, DelayStep(100*time.Hour))
+}
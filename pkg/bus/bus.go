@@ -0,0 +1,30 @@
+// Package bus реализации шины сообщений interfaces.Bus, не требующие RabbitMQ:
+// MemoryBus в памяти процесса для тестов и запуска одним бинарником, NatsBus для NATS JetStream.
+// Реализация для RabbitMQ - ampq.Client, реализацию по конфигурации выбирает NewFactory
+package bus
+
+import "time"
+
+const (
+	// Memory шина в памяти процесса
+	Memory = "memory"
+	// RabbitMQ шина на RabbitMQ, ampq.Client
+	RabbitMQ = "rabbitmq"
+	// NATS шина на NATS JetStream
+	NATS = "nats"
+)
+
+// Expiration время жизни сообщения со сроком актуальности expiresAt.
+// Без срока возвращает 0, для просроченного - минимальное время жизни, шина сразу переведет
+// сообщение в очередь недоставленных
+func Expiration(expiresAt *time.Time, now time.Time) time.Duration {
+	if expiresAt == nil {
+		return 0
+	}
+
+	if ttl := expiresAt.Sub(now); ttl >= time.Millisecond {
+		return ttl
+	}
+
+	return time.Millisecond
+}
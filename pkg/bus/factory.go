@@ -0,0 +1,36 @@
+package bus
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/atrian/go-notify-customer/internal/interfaces"
+	"github.com/atrian/go-notify-customer/pkg/ampq"
+)
+
+// ErrUnknownBus шина не поддерживается
+var ErrUnknownBus = errors.New("bus: unknown bus")
+
+// factoryConfig интерфейс конфигурации шины сообщений
+type factoryConfig interface {
+	GetBus() string
+	GetAmpqDSN() string
+	GetNatsURL() string
+	GetNatsStream() string
+}
+
+// NewFactory фабрика шины сообщений по GetBus. RabbitMQ и NATS подключаются отдельно
+// для каждого вызова, шина в памяти общая для всего процесса, каждый вызов получает свое подключение MemorySession
+func NewFactory(conf factoryConfig, logger interfaces.Logger) (func() interfaces.Bus, error) {
+	switch conf.GetBus() {
+	case RabbitMQ:
+		return func() interfaces.Bus { return ampq.New(conf.GetAmpqDSN(), logger) }, nil
+	case NATS:
+		return func() interfaces.Bus { return NewNatsBus(conf.GetNatsURL(), conf.GetNatsStream(), logger) }, nil
+	case Memory:
+		memoryBus := NewMemoryBus(logger)
+		return func() interfaces.Bus { return memoryBus.Session() }, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownBus, conf.GetBus())
+	}
+}
@@ -0,0 +1,421 @@
+package bus
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/interfaces"
+)
+
+var _ interfaces.Bus = (*MemoryBus)(nil)
+
+var (
+	// ErrStopped шина остановлена
+	ErrStopped = errors.New("bus: stopped")
+	// ErrUnknownDelivery подтверждение сообщения, которое не выдавалось или уже подтверждено
+	ErrUnknownDelivery = errors.New("bus: unknown delivery")
+)
+
+// MemoryBus шина в памяти процесса для тестов и запуска одним бинарником без брокера.
+// Поддерживает ручное подтверждение, повторную выдачу неподтвержденных сообщений, ограничение Qos,
+// приоритеты и маршрутизацию по топологии. Отклоненное без возврата сообщение очереди канала
+// уходит в DeadLetterQueue топологии. Время жизни сообщений не поддерживается.
+// Публикация в необъявленную очередь создает ее, публикация без привязки ключа отбрасывается, как в RabbitMQ.
+// Все сервисы процесса должны использовать один экземпляр
+type MemoryBus struct {
+	mu         sync.Mutex
+	cond       *sync.Cond
+	queues     map[string][]memoryMessage
+	bindings   map[string]string // bindings exchange/routingKey - очередь
+	deadLetter map[string]string // deadLetter очередь недоставленных для очереди
+	unacked    map[uint64]memoryDelivery
+	consumers  map[*memoryConsumer]struct{}
+	tag        uint64
+	delayed    int
+	prefetch   int
+	stopped    bool
+	done       chan struct{}
+	logger     interfaces.Logger
+}
+
+// memoryMessage сообщение в очереди
+type memoryMessage struct {
+	body        []byte
+	priority    uint8
+	redelivered bool
+	deathReason string
+}
+
+// memoryDelivery выданное и еще не подтвержденное сообщение
+type memoryDelivery struct {
+	queue    string
+	consumer *memoryConsumer
+	message  memoryMessage
+}
+
+// memoryConsumer подписчик очереди
+type memoryConsumer struct {
+	inflight int
+	prefetch int
+	closed   chan struct{}
+}
+
+// memoryAcknowledger подтверждение сообщения по номеру выдачи
+type memoryAcknowledger struct {
+	bus *MemoryBus
+	tag uint64
+}
+
+func (a memoryAcknowledger) Ack() error { return a.bus.settle(a.tag, false, false) }
+
+func (a memoryAcknowledger) Nack(requeue bool) error { return a.bus.settle(a.tag, true, requeue) }
+
+// NewMemoryBus шина в памяти
+func NewMemoryBus(logger interfaces.Logger) *MemoryBus {
+	b := MemoryBus{
+		queues:     make(map[string][]memoryMessage),
+		bindings:   make(map[string]string),
+		deadLetter: make(map[string]string),
+		unacked:    make(map[uint64]memoryDelivery),
+		consumers:  make(map[*memoryConsumer]struct{}),
+		done:       make(chan struct{}),
+		logger:     logger,
+	}
+	b.cond = sync.NewCond(&b.mu)
+
+	return &b
+}
+
+func (b *MemoryBus) Connect() error { return nil }
+
+func (b *MemoryBus) MigrateDurableQueues(queues ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, queue := range queues {
+		b.declare(queue)
+	}
+}
+
+func (b *MemoryBus) MigrateTopology(topology dto.Topology) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.declare(topology.DeadLetterQueue)
+	for _, channel := range topology.Channels {
+		queue := topology.Queue(channel)
+		b.declare(queue)
+		b.bindings[topology.Exchange+"/"+channel] = queue
+		if topology.DeadLetterQueue != "" {
+			b.deadLetter[queue] = topology.DeadLetterQueue
+		}
+	}
+
+	return nil
+}
+
+// declare создание очереди, вызывается под mu
+func (b *MemoryBus) declare(queue string) {
+	if queue == "" {
+		return
+	}
+	if _, ok := b.queues[queue]; !ok {
+		b.queues[queue] = nil
+	}
+}
+
+// Session подключение к шине со своим ограничением Qos, как отдельный канал RabbitMQ.
+// Очереди и сообщения у подключений общие
+func (b *MemoryBus) Session() *MemorySession {
+	return &MemorySession{MemoryBus: b}
+}
+
+// Qos ограничение неподтвержденных сообщений для подписчиков, созданных после вызова
+func (b *MemoryBus) Qos(prefetchCount int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.prefetch = prefetchCount
+
+	return nil
+}
+
+// Subscribe потребление очереди с ручным подтверждением. Канал закрывается в Stop и Recover
+func (b *MemoryBus) Subscribe(queue string) (<-chan dto.Delivery, error) {
+	b.mu.Lock()
+	prefetch := b.prefetch
+	b.mu.Unlock()
+
+	return b.subscribe(queue, prefetch)
+}
+
+// subscribe подписчик очереди с ограничением prefetch неподтвержденных сообщений
+func (b *MemoryBus) subscribe(queue string, prefetch int) (<-chan dto.Delivery, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped {
+		return nil, ErrStopped
+	}
+
+	b.declare(queue)
+	consumer := &memoryConsumer{prefetch: prefetch, closed: make(chan struct{})}
+	b.consumers[consumer] = struct{}{}
+
+	out := make(chan dto.Delivery)
+	go b.deliver(queue, consumer, out)
+
+	return out, nil
+}
+
+// deliver выдает сообщения очереди подписчику, пока у него есть место в пределах Qos
+func (b *MemoryBus) deliver(queue string, consumer *memoryConsumer, out chan<- dto.Delivery) {
+	defer close(out)
+
+	for {
+		b.mu.Lock()
+		for !b.stopped && !isClosed(consumer.closed) &&
+			(len(b.queues[queue]) == 0 || (consumer.prefetch > 0 && consumer.inflight >= consumer.prefetch)) {
+			b.cond.Wait()
+		}
+		if b.stopped || isClosed(consumer.closed) {
+			b.mu.Unlock()
+			return
+		}
+
+		message := b.queues[queue][0]
+		b.queues[queue] = b.queues[queue][1:]
+		b.tag++
+		consumer.inflight++
+		b.unacked[b.tag] = memoryDelivery{queue: queue, consumer: consumer, message: message}
+		delivery := dto.Delivery{
+			Body:         message.body,
+			Priority:     message.priority,
+			Redelivered:  message.redelivered,
+			DeathReason:  message.deathReason,
+			Acknowledger: memoryAcknowledger{bus: b, tag: b.tag},
+		}
+		b.mu.Unlock()
+
+		select {
+		case out <- delivery:
+		case <-consumer.closed:
+			// сообщение вернул в очередь Recover
+			return
+		case <-b.done:
+			return
+		}
+	}
+}
+
+func (b *MemoryBus) Publish(queue string, msgBody []byte) error {
+	return b.publish(queue, memoryMessage{body: msgBody})
+}
+
+// PublishDelayed публикация в очередь по истечении delay. Ожидающие сообщения хранятся в памяти
+// и теряются при остановке шины
+func (b *MemoryBus) PublishDelayed(queue string, msgBody []byte, delay time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped {
+		return ErrStopped
+	}
+
+	b.declare(queue)
+	b.delayed++
+	time.AfterFunc(delay, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		b.delayed--
+		if !b.stopped {
+			b.enqueue(queue, memoryMessage{body: msgBody}, false)
+		}
+	})
+
+	return nil
+}
+
+// PublishRouted публикация по привязке топологии, время жизни сообщения не учитывается
+func (b *MemoryBus) PublishRouted(exchange string, routingKey string, msgBody []byte, priority uint8, expiration time.Duration) error {
+	b.mu.Lock()
+	queue, ok := b.bindings[exchange+"/"+routingKey]
+	b.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	return b.publish(queue, memoryMessage{body: msgBody, priority: priority})
+}
+
+func (b *MemoryBus) publish(queue string, message memoryMessage) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped {
+		return ErrStopped
+	}
+
+	b.declare(queue)
+	b.enqueue(queue, message, false)
+
+	return nil
+}
+
+// enqueue добавляет сообщение за сообщениями с тем же приоритетом,
+// возвращенное сообщение встает перед ними. Вызывается под mu
+func (b *MemoryBus) enqueue(queue string, message memoryMessage, front bool) {
+	messages := b.queues[queue]
+
+	i := 0
+	for ; i < len(messages); i++ {
+		p := messages[i].priority
+		if p < message.priority || (front && p == message.priority) {
+			break
+		}
+	}
+
+	messages = append(messages, memoryMessage{})
+	copy(messages[i+1:], messages[i:])
+	messages[i] = message
+	b.queues[queue] = messages
+
+	b.cond.Broadcast()
+}
+
+// settle снимает сообщение с учета неподтвержденных и освобождает место у подписчика.
+// Отвергнутое сообщение возвращается в очередь или уходит в очередь недоставленных
+func (b *MemoryBus) settle(tag uint64, rejected bool, requeue bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delivery, ok := b.unacked[tag]
+	if !ok {
+		return ErrUnknownDelivery
+	}
+
+	delete(b.unacked, tag)
+	delivery.consumer.inflight--
+	b.cond.Broadcast()
+
+	if !rejected {
+		return nil
+	}
+
+	message := delivery.message
+	if requeue {
+		message.redelivered = true
+		b.enqueue(delivery.queue, message, true)
+		return nil
+	}
+
+	if dlq, ok := b.deadLetter[delivery.queue]; ok {
+		message.redelivered = false
+		message.deathReason = fmt.Sprintf("rejected in queue %v", delivery.queue)
+		b.enqueue(dlq, message, false)
+	}
+
+	return nil
+}
+
+// Recover имитирует обрыв соединения: подписки закрываются, неподтвержденные сообщения
+// возвращаются в очереди и будут выданы повторно. Подтверждения выданных ранее сообщений завершаются ошибкой
+func (b *MemoryBus) Recover() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for consumer := range b.consumers {
+		close(consumer.closed)
+		delete(b.consumers, consumer)
+	}
+
+	for tag, delivery := range b.unacked {
+		delete(b.unacked, tag)
+		delivery.message.redelivered = true
+		b.enqueue(delivery.queue, delivery.message, true)
+	}
+
+	b.cond.Broadcast()
+}
+
+// Len количество ожидающих выдачи сообщений в очереди
+func (b *MemoryBus) Len(queue string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.queues[queue])
+}
+
+// Delayed количество сообщений, ожидающих публикации PublishDelayed
+func (b *MemoryBus) Delayed() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.delayed
+}
+
+// Unacked количество выданных и неподтвержденных сообщений
+func (b *MemoryBus) Unacked() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.unacked)
+}
+
+func (b *MemoryBus) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped {
+		return
+	}
+
+	b.stopped = true
+	close(b.done)
+	b.cond.Broadcast()
+}
+
+// isClosed проверка закрытия канала без блокировки
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// MemorySession подключение к общей шине в памяти. Qos подключения не влияет на подписчиков
+// других подключений, как у отдельных соединений RabbitMQ и NATS: воркер повторов не ограничен
+// prefetch воркера каналов
+type MemorySession struct {
+	*MemoryBus
+	mu       sync.Mutex
+	prefetch int
+}
+
+var _ interfaces.Bus = (*MemorySession)(nil)
+
+// Qos ограничение неподтвержденных сообщений для подписчиков подключения, созданных после вызова
+func (s *MemorySession) Qos(prefetchCount int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prefetch = prefetchCount
+
+	return nil
+}
+
+// Subscribe потребление очереди с ограничением Qos подключения
+func (s *MemorySession) Subscribe(queue string) (<-chan dto.Delivery, error) {
+	s.mu.Lock()
+	prefetch := s.prefetch
+	s.mu.Unlock()
+
+	return s.MemoryBus.subscribe(queue, prefetch)
+}
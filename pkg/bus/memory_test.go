@@ -0,0 +1,158 @@
+package bus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+func TestExpiration(t *testing.T) {
+	now := time.Date(2023, 3, 21, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	assert.Equal(t, time.Duration(0), Expiration(nil, now))
+	assert.Equal(t, time.Hour, Expiration(&later, now))
+	// просроченное сообщение шина сразу переводит в очередь недоставленных
+	assert.Equal(t, time.Millisecond, Expiration(&earlier, now))
+}
+
+func TestMemoryBus_ack(t *testing.T) {
+	b := NewMemoryBus(logger.NewZapLogger())
+	defer b.Stop()
+
+	assert.NoError(t, b.MigrateTopology(dto.Topology{Exchange: "notify", Channels: []string{"sms"}, MaxPriority: 10, DeadLetterQueue: "dead"}))
+	assert.NoError(t, b.PublishRouted("notify", "sms", []byte("low"), 1, 0))
+	assert.NoError(t, b.PublishRouted("notify", "sms", []byte("high"), 9, 0))
+	// без привязки ключа сообщение отбрасывается
+	assert.NoError(t, b.PublishRouted("notify", "pigeon", []byte("lost"), 1, 0))
+
+	msgs, err := b.Subscribe("notify.sms")
+	assert.NoError(t, err)
+
+	// первым выдается сообщение с большим приоритетом
+	high := receive(t, msgs)
+	assert.Equal(t, "high", string(high.Body))
+	assert.Equal(t, uint8(9), high.Priority)
+	assert.NoError(t, high.Ack())
+	assert.ErrorIs(t, high.Ack(), ErrUnknownDelivery)
+
+	// возвращенное сообщение выдается повторно
+	low := receive(t, msgs)
+	assert.NoError(t, low.Nack(true))
+	low = receive(t, msgs)
+	assert.Equal(t, "low", string(low.Body))
+	assert.True(t, low.Redelivered)
+
+	// отклоненное без возврата сообщение уходит в очередь недоставленных
+	assert.NoError(t, low.Nack(false))
+	assert.Equal(t, 0, b.Len("notify.sms"))
+	assert.Equal(t, 0, b.Unacked())
+
+	dead, err := b.Subscribe("dead")
+	assert.NoError(t, err)
+	assert.Equal(t, "rejected in queue notify.sms", receive(t, dead).DeathReason)
+}
+
+// TestMemoryBus_prefetch подписчик получает не больше prefetch неподтвержденных сообщений
+func TestMemoryBus_prefetch(t *testing.T) {
+	b := NewMemoryBus(logger.NewZapLogger())
+	defer b.Stop()
+
+	assert.NoError(t, b.Qos(2))
+	for _, body := range []string{"1", "2", "3"} {
+		assert.NoError(t, b.Publish("queue", []byte(body)))
+	}
+
+	msgs, err := b.Subscribe("queue")
+	assert.NoError(t, err)
+
+	first := receive(t, msgs)
+	receive(t, msgs)
+
+	select {
+	case d := <-msgs:
+		t.Fatalf("prefetch exceeded by %v", string(d.Body))
+	case <-time.After(20 * time.Millisecond):
+	}
+	assert.Equal(t, 2, b.Unacked())
+
+	assert.NoError(t, first.Ack())
+	assert.Equal(t, "3", string(receive(t, msgs).Body))
+}
+
+// TestMemoryBus_Session Qos подключения не ограничивает подписчиков других подключений общей шины
+func TestMemoryBus_Session(t *testing.T) {
+	b := NewMemoryBus(logger.NewZapLogger())
+	defer b.Stop()
+
+	limited, unlimited := b.Session(), b.Session()
+	assert.NoError(t, limited.Qos(1))
+
+	for _, body := range []string{"1", "2", "3"} {
+		assert.NoError(t, unlimited.Publish("queue", []byte(body)))
+	}
+
+	msgs, err := unlimited.Subscribe("queue")
+	assert.NoError(t, err)
+	for _, body := range []string{"1", "2", "3"} {
+		assert.Equal(t, body, string(receive(t, msgs).Body))
+	}
+	assert.Equal(t, 3, b.Unacked())
+}
+
+// TestMemoryBus_PublishDelayed отложенное сообщение попадает в очередь по истечении задержки
+func TestMemoryBus_PublishDelayed(t *testing.T) {
+	b := NewMemoryBus(logger.NewZapLogger())
+	defer b.Stop()
+
+	assert.NoError(t, b.PublishDelayed("queue", []byte("later"), 20*time.Millisecond))
+	assert.Equal(t, 0, b.Len("queue"))
+	assert.Equal(t, 1, b.Delayed())
+
+	assert.Eventually(t, func() bool { return b.Len("queue") == 1 && b.Delayed() == 0 }, time.Second, time.Millisecond)
+
+	msgs, err := b.Subscribe("queue")
+	assert.NoError(t, err)
+	assert.Equal(t, "later", string(receive(t, msgs).Body))
+}
+
+// TestMemoryBus_Recover неподтвержденные сообщения возвращаются в очередь при обрыве соединения
+func TestMemoryBus_Recover(t *testing.T) {
+	b := NewMemoryBus(logger.NewZapLogger())
+	defer b.Stop()
+
+	assert.NoError(t, b.Publish("queue", []byte("message")))
+
+	msgs, err := b.Subscribe("queue")
+	assert.NoError(t, err)
+	d := receive(t, msgs)
+
+	b.Recover()
+
+	_, ok := <-msgs
+	assert.False(t, ok)
+	assert.ErrorIs(t, d.Ack(), ErrUnknownDelivery)
+
+	msgs, err = b.Subscribe("queue")
+	assert.NoError(t, err)
+	d = receive(t, msgs)
+	assert.Equal(t, "message", string(d.Body))
+	assert.True(t, d.Redelivered)
+}
+
+func receive(t *testing.T, msgs <-chan dto.Delivery) dto.Delivery {
+	t.Helper()
+
+	select {
+	case d := <-msgs:
+		return d
+	case <-time.After(time.Second):
+		t.Fatal("no delivery")
+		return dto.Delivery{}
+	}
+}
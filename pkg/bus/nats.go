@@ -0,0 +1,428 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/internal/interfaces"
+)
+
+var _ interfaces.Bus = (*NatsBus)(nil)
+
+// ErrNotConnected шина не подключена к брокеру
+var ErrNotConnected = errors.New("bus: not connected")
+
+const (
+	// заголовки сообщений, заменяющие свойства и аргументы очередей RabbitMQ
+	headerPriority    = "Nc-Priority"
+	headerExpiresAt   = "Nc-Expires-At"
+	headerDeathReason = "Nc-Death-Reason"
+	headerNotBefore   = "Nc-Not-Before"
+
+	natsFetchWait  = time.Second     // natsFetchWait ожидание сообщений одним запросом Fetch
+	natsRetryDelay = 1 * time.Second // natsRetryDelay пауза после ошибки Fetch, например на время переподключения
+	// natsProgressInterval продление неподтвержденного сообщения, меньше AckWait consumer'а по умолчанию (30s)
+	natsProgressInterval = 10 * time.Second
+)
+
+// NatsBus шина на NATS JetStream. Все очереди - subject'ы stream.queue одного stream с политикой
+// WorkQueue, сообщение удаляется из stream после подтверждения. Каждую очередь читает durable
+// pull consumer с именем очереди, поэтому несколько экземпляров сервиса делят сообщения между собой.
+// JetStream не поддерживает приоритеты, сообщения выдаются в порядке публикации.
+// Время жизни сообщения и перевод в очередь недоставленных выполняет шина по заголовкам сообщения.
+// Переподключение выполняет клиент NATS, публикация возвращает nil после подтверждения JetStream
+type NatsBus struct {
+	mu         sync.RWMutex
+	url        string
+	stream     string
+	conn       *nats.Conn
+	js         nats.JetStreamContext
+	bindings   map[string]string // bindings exchange/routingKey - очередь
+	deadLetter map[string]string // deadLetter очередь недоставленных для очереди
+	ttl        map[string]time.Duration
+	prefetch   int
+	done       chan struct{}
+	stopOnce   sync.Once
+	logger     interfaces.Logger
+	now        func() time.Time
+}
+
+// NewNatsBus шина NATS JetStream с сервером url и stream для очередей
+func NewNatsBus(url string, stream string, logger interfaces.Logger) *NatsBus {
+	b := NatsBus{
+		url:        url,
+		stream:     stream,
+		bindings:   make(map[string]string),
+		deadLetter: make(map[string]string),
+		ttl:        make(map[string]time.Duration),
+		done:       make(chan struct{}),
+		logger:     logger,
+		now:        time.Now,
+	}
+
+	return &b
+}
+
+// Connect подключение к NATS и создание stream очередей, если его нет
+func (b *NatsBus) Connect() error {
+	conn, err := nats.Connect(b.url,
+		nats.Name("go-notify-customer"),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(time.Second),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				b.logger.Error("NATS connection lost, reconnecting", err)
+			}
+		}),
+		nats.ReconnectHandler(func(_ *nats.Conn) {
+			b.logger.Info("NATS reconnected")
+		}),
+	)
+	if err != nil {
+		b.logger.Error("Can't connect NATS", err)
+		return err
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		b.logger.Error("Can't create JetStream context", err)
+		conn.Close()
+		return err
+	}
+
+	if _, err = js.StreamInfo(b.stream); errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:      b.stream,
+			Subjects:  []string{b.stream + ".>"},
+			Retention: nats.WorkQueuePolicy,
+			Storage:   nats.FileStorage,
+		})
+	}
+	if err != nil {
+		b.logger.Error("Can't create JetStream stream", err)
+		conn.Close()
+		return err
+	}
+
+	b.mu.Lock()
+	b.conn = conn
+	b.js = js
+	b.mu.Unlock()
+
+	return nil
+}
+
+// MigrateDurableQueues очереди - subject'ы stream, созданного в Connect, объявлять их не нужно
+func (b *NatsBus) MigrateDurableQueues(queues ...string) {}
+
+// MigrateTopology привязка ключей каналов к их очередям, время жизни сообщений и очередь недоставленных
+func (b *NatsBus) MigrateTopology(topology dto.Topology) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, channel := range topology.Channels {
+		queue := topology.Queue(channel)
+		b.bindings[topology.Exchange+"/"+channel] = queue
+		b.ttl[queue] = topology.MessageTTL
+		if topology.DeadLetterQueue != "" {
+			b.deadLetter[queue] = topology.DeadLetterQueue
+		}
+	}
+
+	return nil
+}
+
+// Qos ограничение неподтвержденных сообщений для подписчиков, созданных после вызова.
+// Выполняется на стороне подписчика, следующий Fetch ждет подтверждения
+func (b *NatsBus) Qos(prefetchCount int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.prefetch = prefetchCount
+
+	return nil
+}
+
+func (b *NatsBus) Publish(queue string, msgBody []byte) error {
+	return b.publish(&nats.Msg{Subject: b.subject(queue), Header: nats.Header{}, Data: msgBody})
+}
+
+// PublishDelayed публикация в очередь со временем выдачи в заголовке. Сообщение хранится в stream,
+// шина возвращает его JetStream с задержкой NakWithDelay до наступления времени выдачи
+func (b *NatsBus) PublishDelayed(queue string, msgBody []byte, delay time.Duration) error {
+	msg := &nats.Msg{Subject: b.subject(queue), Header: nats.Header{}, Data: msgBody}
+	msg.Header.Set(headerNotBefore, b.now().Add(delay).Format(time.RFC3339Nano))
+
+	return b.publish(msg)
+}
+
+// PublishRouted публикация в очередь канала по привязке топологии. Приоритет сохраняется в заголовке
+// для информации, срок актуальности - меньшее из expiration и времени жизни очереди
+func (b *NatsBus) PublishRouted(exchange string, routingKey string, msgBody []byte, priority uint8, expiration time.Duration) error {
+	b.mu.RLock()
+	queue, ok := b.bindings[exchange+"/"+routingKey]
+	ttl := b.ttl[queue]
+	b.mu.RUnlock()
+
+	// без привязки сообщение отбрасывается, как в RabbitMQ
+	if !ok {
+		return nil
+	}
+
+	if expiration > 0 && (ttl == 0 || expiration < ttl) {
+		ttl = expiration
+	}
+
+	msg := &nats.Msg{Subject: b.subject(queue), Header: nats.Header{}, Data: msgBody}
+	msg.Header.Set(headerPriority, strconv.Itoa(int(priority)))
+	if ttl > 0 {
+		msg.Header.Set(headerExpiresAt, b.now().Add(ttl).Format(time.RFC3339Nano))
+	}
+
+	return b.publish(msg)
+}
+
+// publish публикация с ожиданием подтверждения JetStream
+func (b *NatsBus) publish(msg *nats.Msg) error {
+	b.mu.RLock()
+	js := b.js
+	b.mu.RUnlock()
+
+	if js == nil {
+		b.logger.Error("NATS publish error", ErrNotConnected)
+		return ErrNotConnected
+	}
+
+	if _, err := js.PublishMsg(msg); err != nil {
+		b.logger.Error("NATS publish error", err)
+		return err
+	}
+
+	return nil
+}
+
+// Subscribe потребление очереди durable pull consumer'ом. Канал закрывается в Stop
+func (b *NatsBus) Subscribe(queue string) (<-chan dto.Delivery, error) {
+	b.mu.RLock()
+	js, prefetch := b.js, b.prefetch
+	b.mu.RUnlock()
+
+	if js == nil {
+		return nil, ErrNotConnected
+	}
+
+	sub, err := js.PullSubscribe(b.subject(queue), durableName(queue), nats.BindStream(b.stream), nats.ManualAck())
+	if err != nil {
+		b.logger.Error("NATS subscribe error", err)
+		return nil, err
+	}
+
+	// slots ограничение неподтвержденных сообщений подписчика, nil - без ограничения
+	var slots chan struct{}
+	if prefetch > 0 {
+		slots = make(chan struct{}, prefetch)
+	}
+
+	out := make(chan dto.Delivery)
+	go b.fetch(queue, sub, slots, out)
+
+	return out, nil
+}
+
+// fetch запрашивает сообщения по одному, пока у подписчика есть место в пределах Qos
+func (b *NatsBus) fetch(queue string, sub *nats.Subscription, slots chan struct{}, out chan<- dto.Delivery) {
+	defer close(out)
+
+	release := func() {}
+	for {
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-b.done:
+				return
+			}
+			release = func() { <-slots }
+		}
+
+		msgs, err := sub.Fetch(1, nats.MaxWait(natsFetchWait))
+		if err != nil {
+			release()
+			if errors.Is(err, nats.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+				continue
+			}
+			if errors.Is(err, nats.ErrConnectionClosed) || errors.Is(err, nats.ErrBadSubscription) {
+				return
+			}
+
+			b.logger.Error("NATS fetch error", err)
+			select {
+			case <-time.After(natsRetryDelay):
+				continue
+			case <-b.done:
+				return
+			}
+		}
+
+		for _, msg := range msgs {
+			delivery, ok := b.delivery(queue, msg, release)
+			if !ok {
+				continue
+			}
+
+			select {
+			case out <- delivery:
+			case <-b.done:
+				return
+			}
+		}
+	}
+}
+
+// delivery сообщение в формате шины. Просроченное сообщение уходит в очередь недоставленных
+// и подписчику не выдается, отложенное PublishDelayed возвращается JetStream до времени выдачи. Выданное сообщение продлевается до подтверждения, как в RabbitMQ
+// неподтвержденное сообщение выдается повторно только после обрыва соединения
+func (b *NatsBus) delivery(queue string, msg *nats.Msg, release func()) (dto.Delivery, bool) {
+	if expiresAt, err := time.Parse(time.RFC3339Nano, msg.Header.Get(headerExpiresAt)); err == nil && !b.now().Before(expiresAt) {
+		b.dead(queue, msg, fmt.Sprintf("expired in queue %v", queue))
+		release()
+		return dto.Delivery{}, false
+	}
+
+	if notBefore, err := time.Parse(time.RFC3339Nano, msg.Header.Get(headerNotBefore)); err == nil {
+		if wait := notBefore.Sub(b.now()); wait > 0 {
+			if err = msg.NakWithDelay(wait); err != nil {
+				b.logger.Error("NATS nak error", err)
+			}
+			release()
+			return dto.Delivery{}, false
+		}
+	}
+
+	acknowledger := &natsAcknowledger{bus: b, queue: queue, msg: msg, release: release, settled: make(chan struct{})}
+	go acknowledger.keepAlive()
+
+	delivery := dto.Delivery{
+		Body:         msg.Data,
+		DeathReason:  msg.Header.Get(headerDeathReason),
+		Acknowledger: acknowledger,
+	}
+
+	if priority, err := strconv.Atoi(msg.Header.Get(headerPriority)); err == nil {
+		delivery.Priority = uint8(priority)
+	}
+	if meta, err := msg.Metadata(); err == nil {
+		delivery.Redelivered = meta.NumDelivered > 1
+	}
+
+	return delivery, true
+}
+
+// dead перевод сообщения в очередь недоставленных топологии с причиной reason.
+// Без очереди недоставленных сообщение удаляется
+func (b *NatsBus) dead(queue string, msg *nats.Msg, reason string) {
+	b.mu.RLock()
+	dlq, ok := b.deadLetter[queue]
+	b.mu.RUnlock()
+
+	if ok {
+		dead := &nats.Msg{Subject: b.subject(dlq), Header: nats.Header{}, Data: msg.Data}
+		dead.Header.Set(headerPriority, msg.Header.Get(headerPriority))
+		dead.Header.Set(headerDeathReason, reason)
+
+		if err := b.publish(dead); err != nil {
+			// без подтверждения переноса сообщение остается в очереди и будет выдано повторно
+			_ = msg.Nak()
+			return
+		}
+	}
+
+	if err := msg.Term(); err != nil {
+		b.logger.Error("NATS term error", err)
+	}
+}
+
+// subject subject очереди в stream
+func (b *NatsBus) subject(queue string) string {
+	return b.stream + "." + queue
+}
+
+// Stop закрытие подписок и подключения, durable consumer'ы сохраняются в JetStream
+func (b *NatsBus) Stop() {
+	b.stopOnce.Do(func() { close(b.done) })
+
+	b.mu.RLock()
+	conn := b.conn
+	b.mu.RUnlock()
+
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+// natsAcknowledger подтверждение сообщения JetStream, освобождает место подписчика в пределах Qos
+type natsAcknowledger struct {
+	bus     *NatsBus
+	queue   string
+	msg     *nats.Msg
+	release func()
+	settled chan struct{}
+	once    sync.Once
+}
+
+func (a *natsAcknowledger) Ack() error {
+	defer a.settle()
+
+	return a.msg.Ack()
+}
+
+func (a *natsAcknowledger) Nack(requeue bool) error {
+	defer a.settle()
+
+	if requeue {
+		return a.msg.Nak()
+	}
+
+	a.bus.dead(a.queue, a.msg, fmt.Sprintf("rejected in queue %v", a.queue))
+
+	return nil
+}
+
+// settle освобождает место подписчика и останавливает продление сообщения
+func (a *natsAcknowledger) settle() {
+	a.once.Do(func() {
+		a.release()
+		close(a.settled)
+	})
+}
+
+// keepAlive продлевает срок подтверждения сообщения, пока оно не подтверждено или шина не остановлена
+func (a *natsAcknowledger) keepAlive() {
+	ticker := time.NewTicker(natsProgressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.msg.InProgress(); err != nil {
+				a.bus.logger.Error("NATS in progress error", err)
+			}
+		case <-a.settled:
+			return
+		case <-a.bus.done:
+			return
+		}
+	}
+}
+
+// durableName имя durable consumer'а очереди, точка в именах consumer'ов запрещена
+func durableName(queue string) string {
+	return strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_").Replace(queue)
+}
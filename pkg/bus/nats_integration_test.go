@@ -0,0 +1,108 @@
+//go:build integration
+// +build integration
+
+package bus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dhui/dktest"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atrian/go-notify-customer/internal/dto"
+	"github.com/atrian/go-notify-customer/pkg/logger"
+)
+
+var (
+	natsURL  = "nats://localhost:4222"
+	natsOpts = dktest.Options{
+		ReadyTimeout: 15 * time.Second,
+		Hostname:     "localhost",
+		Cmd:          []string{"-js"},
+		PortBindings: nat.PortMap{
+			nat.Port("4222/tcp"): []nat.PortBinding{{
+				HostIP:   "0.0.0.0",
+				HostPort: "4222",
+			}},
+		},
+		ReadyFunc: isNatsReady,
+	}
+
+	natsImage = "nats:2-alpine"
+)
+
+// TestNatsBus маршрутизация по топологии, повторная выдача после Nack и перевод
+// отклоненного сообщения в очередь недоставленных
+func TestNatsBus(t *testing.T) {
+	dktest.Run(t, natsImage, natsOpts, func(t *testing.T, c dktest.ContainerInfo) {
+		b := NewNatsBus(natsURL, "TEST", logger.NewZapLogger())
+		assert.NoError(t, b.Connect())
+		defer b.Stop()
+
+		topology := dto.Topology{Exchange: "notify", Channels: []string{"sms"}, DeadLetterQueue: "dead"}
+		assert.NoError(t, b.MigrateTopology(topology))
+
+		msgs, err := b.Subscribe(topology.Queue("sms"))
+		assert.NoError(t, err)
+		dead, err := b.Subscribe("dead")
+		assert.NoError(t, err)
+
+		assert.NoError(t, b.PublishRouted("notify", "sms", []byte("hello"), 5, time.Minute))
+		assert.NoError(t, b.PublishRouted("notify", "unknown", []byte("dropped"), 0, 0))
+
+		d := receiveNats(t, msgs)
+		assert.Equal(t, "hello", string(d.Body))
+		assert.Equal(t, uint8(5), d.Priority)
+		assert.False(t, d.Redelivered)
+		assert.NoError(t, d.Nack(true))
+
+		d = receiveNats(t, msgs)
+		assert.True(t, d.Redelivered)
+		assert.NoError(t, d.Nack(false))
+
+		d = receiveNats(t, dead)
+		assert.Equal(t, "hello", string(d.Body))
+		assert.Equal(t, "rejected in queue notify.sms", d.DeathReason)
+		assert.NoError(t, d.Ack())
+	})
+}
+
+// TestNatsBus_PublishDelayed отложенное сообщение не выдается до наступления времени выдачи
+func TestNatsBus_PublishDelayed(t *testing.T) {
+	dktest.Run(t, natsImage, natsOpts, func(t *testing.T, c dktest.ContainerInfo) {
+		b := NewNatsBus(natsURL, "TEST", logger.NewZapLogger())
+		assert.NoError(t, b.Connect())
+		defer b.Stop()
+
+		msgs, err := b.Subscribe("failed")
+		assert.NoError(t, err)
+
+		publishedAt := time.Now()
+		assert.NoError(t, b.PublishDelayed("failed", []byte("later"), 2*time.Second))
+
+		d := receiveNats(t, msgs)
+		assert.Equal(t, "later", string(d.Body))
+		assert.GreaterOrEqual(t, time.Since(publishedAt), 2*time.Second)
+		assert.NoError(t, d.Ack())
+	})
+}
+
+func receiveNats(t *testing.T, msgs <-chan dto.Delivery) dto.Delivery {
+	select {
+	case d := <-msgs:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery")
+		return dto.Delivery{}
+	}
+}
+
+func isNatsReady(ctx context.Context, c dktest.ContainerInfo) bool {
+	b := NewNatsBus(natsURL, "TEST", logger.NewFatalZapLogger())
+	defer b.Stop()
+
+	return b.Connect() == nil
+}
@@ -0,0 +1,66 @@
+// Package pgtest запуск PostgreSQL в docker контейнере для интеграционных тестов хранилищ
+package pgtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dhui/dktest"
+
+	"github.com/atrian/go-notify-customer/pkg/postgres"
+)
+
+const image = "postgres:15-alpine"
+
+var opts = dktest.Options{
+	ReadyTimeout: 15 * time.Second,
+	PortRequired: true,
+	Env:          map[string]string{"POSTGRES_PASSWORD": "postgres"},
+	ReadyFunc:    isReady,
+}
+
+// Run поднимает контейнер PostgreSQL, применяет миграции migrations и вызывает f с подключением к базе.
+// Подключение закрывается, а контейнер удаляется после возврата из f
+func Run(t *testing.T, migrations fs.FS, f func(t *testing.T, db *sql.DB)) {
+	dktest.Run(t, image, opts, func(t *testing.T, c dktest.ContainerInfo) {
+		ctx := context.Background()
+
+		db, err := postgres.Connect(ctx, dsn(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		if err = postgres.Migrate(ctx, db, migrations); err != nil {
+			t.Fatal(err)
+		}
+
+		f(t, db)
+	})
+}
+
+// Truncate очищает таблицы tables, чтобы тест начинался с пустого хранилища
+func Truncate(t *testing.T, db *sql.DB, tables ...string) {
+	if _, err := db.ExecContext(context.Background(), "TRUNCATE "+strings.Join(tables, ", ")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func dsn(c dktest.ContainerInfo) string {
+	host, port, _ := c.FirstPort()
+	return fmt.Sprintf("postgres://postgres:postgres@%v:%v/postgres?sslmode=disable", host, port)
+}
+
+func isReady(ctx context.Context, c dktest.ContainerInfo) bool {
+	db, err := postgres.Connect(ctx, dsn(c))
+	if err != nil {
+		return false
+	}
+
+	return db.Close() == nil
+}
@@ -0,0 +1,100 @@
+// Package postgres подключение к PostgreSQL и применение миграций схемы.
+// Миграции - SQL файлы в переданной файловой системе (обычно embed.FS),
+// применяются в лексикографическом порядке имен, каждая в отдельной транзакции.
+// Примененные версии хранятся в таблице schema_migrations.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+const migrationsTable = "schema_migrations"
+
+// Connect открывает пул соединений и проверяет доступность сервера
+func Connect(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Migrate применяет еще не примененные миграции *.sql из migrations
+func Migrate(ctx context.Context, db *sql.DB, migrations fs.FS) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+migrationsTable+` (
+		version    TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("create migrations table: %w", err)
+	}
+
+	files, err := fs.Glob(migrations, "*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		version := strings.TrimSuffix(file, ".sql")
+
+		if err = applyMigration(ctx, db, migrations, file, version); err != nil {
+			return fmt.Errorf("migration %v: %w", version, err)
+		}
+	}
+
+	return nil
+}
+
+// applyMigration применяет одну миграцию, если она еще не была применена
+func applyMigration(ctx context.Context, db *sql.DB, migrations fs.FS, file string, version string) error {
+	body, err := fs.ReadFile(migrations, file)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// блокировка таблицы исключает одновременное применение миграций несколькими экземплярами
+	_, err = tx.ExecContext(ctx, `LOCK TABLE `+migrationsTable+` IN EXCLUSIVE MODE`)
+	if err != nil {
+		return err
+	}
+
+	var applied bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM `+migrationsTable+` WHERE version = $1)`, version).Scan(&applied)
+	if err != nil || applied {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, string(body)); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO `+migrationsTable+` (version) VALUES ($1)`, version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}